@@ -13,11 +13,14 @@ import (
 	"github.com/cloudwego/hertz/pkg/app/server"
 
 	"github.com/test-tt/config"
+	"github.com/test-tt/internal/generation"
 	"github.com/test-tt/internal/middleware"
 	"github.com/test-tt/internal/router"
+	"github.com/test-tt/internal/service"
 	"github.com/test-tt/pkg/cache"
 	"github.com/test-tt/pkg/database"
 	"github.com/test-tt/pkg/logger"
+	"github.com/test-tt/pkg/storage"
 
 	_ "github.com/test-tt/docs" // swagger docs
 )
@@ -150,6 +153,66 @@ func main() {
 		})
 	}
 
+	// 初始化上传资源存储
+	if cfg.Storage != nil {
+		if err := storage.Init(&storage.Config{
+			Driver:   cfg.Storage.Driver,
+			LocalDir: cfg.Storage.LocalDir,
+		}); err != nil {
+			panic(fmt.Sprintf("init storage failed: %v", err))
+		}
+		logger.Infof("storage initialized", "driver", cfg.Storage.Driver)
+	}
+
+	// 启动回收站清理任务（需要数据库）
+	if cfg.Trash != nil && database.DB != nil {
+		stopTrashPurger := service.StartTrashPurger(cfg.Trash.Retention, cfg.Trash.PurgeInterval)
+		cleanups = append(cleanups, func() {
+			logger.Info("stopping trash purger...")
+			stopTrashPurger()
+		})
+		logger.Infof("trash purger started", "retention", cfg.Trash.Retention.String())
+	}
+
+	// 把旧版内联存储的项目内容迁入 content_blobs（需要数据库）
+	if database.DB != nil {
+		stopContentMigration := service.StartContentMigration()
+		cleanups = append(cleanups, func() {
+			logger.Info("stopping content migration...")
+			stopContentMigration()
+		})
+		logger.Info("content migration started")
+	}
+
+	if cfg.Analytics != nil && cfg.Analytics.Enabled && database.DB != nil && cache.RDB != nil {
+		stopAnalyticsRollup := service.StartAnalyticsRollup(cfg.Analytics.RollupInterval)
+		cleanups = append(cleanups, func() {
+			logger.Info("stopping analytics rollup...")
+			stopAnalyticsRollup()
+		})
+		logger.Infof("analytics rollup started", "interval", cfg.Analytics.RollupInterval.String())
+	}
+
+	// 定期用 MySQL 中的收藏重建作品库热度排行
+	if cfg.Gallery != nil && database.DB != nil {
+		stopGalleryReconciler := service.StartGalleryReconciler(cfg.Gallery.ReconcileInterval)
+		cleanups = append(cleanups, func() {
+			logger.Info("stopping gallery reconciler...")
+			stopGalleryReconciler()
+		})
+		logger.Infof("gallery reconciler started", "interval", cfg.Gallery.ReconcileInterval.String())
+	}
+
+	// AI 页面生成：刷新运行中任务的心跳，接管其他实例中断的任务
+	generationManager := generation.GetManager()
+	stopGenerationJanitor := generationManager.StartJanitor()
+	cleanups = append(cleanups, func() {
+		logger.Info("stopping generation jobs...")
+		stopGenerationJanitor()
+		generationManager.Shutdown()
+	})
+	logger.Infof("generation started", "provider", generationManager.ProviderName())
+
 	// 启动连接池指标收集器
 	stopMetricsCollector := middleware.StartPoolMetricsCollector(15 * time.Second)
 	cleanups = append(cleanups, func() {
@@ -2,6 +2,9 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -12,13 +15,22 @@ import (
 var Cfg *Config
 
 type Config struct {
-	Env       string           `mapstructure:"env"`
-	Server    *ServerConfig    `mapstructure:"server"`
-	MySQL     *MySQLConfig     `mapstructure:"mysql"`
-	Redis     *RedisConfig     `mapstructure:"redis"`
-	Log       *LogConfig       `mapstructure:"log"`
-	JWT       *JWTConfig       `mapstructure:"jwt"`
-	RateLimit *RateLimitConfig `mapstructure:"ratelimit"`
+	Env        string            `mapstructure:"env"`
+	Server     *ServerConfig     `mapstructure:"server"`
+	MySQL      *MySQLConfig      `mapstructure:"mysql"`
+	Redis      *RedisConfig      `mapstructure:"redis"`
+	Log        *LogConfig        `mapstructure:"log"`
+	JWT        *JWTConfig        `mapstructure:"jwt"`
+	RateLimit  *RateLimitConfig  `mapstructure:"ratelimit"`
+	Admin      *AdminConfig      `mapstructure:"admin"`
+	Storage    *StorageConfig    `mapstructure:"storage"`
+	Trash      *TrashConfig      `mapstructure:"trash"`
+	Plans      *PlansConfig      `mapstructure:"plans"`
+	Sanitize   *SanitizeConfig   `mapstructure:"sanitize"`
+	Analytics  *AnalyticsConfig  `mapstructure:"analytics"`
+	Gallery    *GalleryConfig    `mapstructure:"gallery"`
+	Generation *GenerationConfig `mapstructure:"generation"`
+	Metering   *MeteringConfig   `mapstructure:"metering"`
 }
 
 type ServerConfig struct {
@@ -72,6 +84,138 @@ type RateLimitConfig struct {
 	Burst int     `mapstructure:"burst"`
 }
 
+// AdminConfig 管理员配置
+type AdminConfig struct {
+	UserIDs []uint64 `mapstructure:"user_ids"` // 拥有管理权限的用户 ID
+}
+
+// StorageConfig 上传资源存储配置
+type StorageConfig struct {
+	Driver      string `mapstructure:"driver"`        // local, memory
+	LocalDir    string `mapstructure:"local_dir"`     // local 驱动的根目录
+	MaxFileSize int64  `mapstructure:"max_file_size"` // 单文件大小上限（字节）
+}
+
+// TrashConfig 回收站配置
+type TrashConfig struct {
+	Retention     time.Duration `mapstructure:"retention"`      // 项目在回收站保留的时长，超过后永久删除
+	PurgeInterval time.Duration `mapstructure:"purge_interval"` // 清理任务执行间隔
+}
+
+// PlansConfig 套餐配置
+type PlansConfig struct {
+	Default string                 `mapstructure:"default"` // 未指定套餐的用户使用的套餐
+	Limits  map[string]*PlanLimits `mapstructure:"limits"`  // 套餐名 -> 限额
+}
+
+// PlanLimits 套餐限额，0 表示不限制
+type PlanLimits struct {
+	MaxProjects    int64 `mapstructure:"max_projects"`     // 项目数量（不含回收站）
+	MaxProjectSize int64 `mapstructure:"max_project_size"` // 单个项目 HTML + CSS 字节数
+	MaxStorage     int64 `mapstructure:"max_storage"`      // 所有项目 HTML + CSS 总字节数
+	MaxAssetBytes  int64 `mapstructure:"max_asset_bytes"`  // 上传资源总字节数
+	DailyCredits   int64 `mapstructure:"daily_credits"`    // 每天可用的生成额度
+	MonthlyCredits int64 `mapstructure:"monthly_credits"`  // 每月可用的生成额度
+}
+
+// SanitizeConfig 项目 HTML/CSS 保存时的清洗策略
+type SanitizeConfig struct {
+	ScriptMode    string   `mapstructure:"script_mode"`    // strip: 删除脚本; flag: 保留并返回警告
+	ExternalURLs  string   `mapstructure:"external_urls"`  // 外部资源 URL: keep, rewrite, strip
+	RewritePrefix string   `mapstructure:"rewrite_prefix"` // external_urls=rewrite 时的前缀
+	ExtraTags     []string `mapstructure:"extra_tags"`     // 额外允许的标签
+	ExtraAttrs    []string `mapstructure:"extra_attrs"`    // 额外允许的属性
+}
+
+// AnalyticsConfig 发布页面访问统计配置
+type AnalyticsConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`         // 是否记录访问（需要 Redis）
+	RespectDNT     bool          `mapstructure:"respect_dnt"`     // 带 DNT 或 Sec-GPC 请求头的访问不记录
+	RollupInterval time.Duration `mapstructure:"rollup_interval"` // 从 Redis 汇总到 MySQL 的间隔
+	MaxRangeDays   int           `mapstructure:"max_range_days"`  // 单次查询的最大天数
+}
+
+// GalleryConfig 公开作品库配置
+type GalleryConfig struct {
+	Categories        []string      `mapstructure:"categories"`         // 可选分类
+	TrendingHalfLife  time.Duration `mapstructure:"trending_half_life"` // 收藏对热度的贡献每经过一个半衰期减半
+	ReconcileInterval time.Duration `mapstructure:"reconcile_interval"` // 用 MySQL 重建 Redis 热度排行的间隔
+	CacheTTL          time.Duration `mapstructure:"cache_ttl"`          // 列表缓存时间
+}
+
+// GenerationConfig AI 页面生成配置
+type GenerationConfig struct {
+	Provider          string        `mapstructure:"provider"`             // fake: 离线确定性输出; anthropic: Anthropic Messages API
+	Model             string        `mapstructure:"model"`                // 模型名称
+	APIKey            string        `mapstructure:"api_key"`              // 模型服务密钥，建议通过 APP_GENERATION_API_KEY 设置
+	BaseURL           string        `mapstructure:"base_url"`             // 模型服务地址，为空时使用官方地址
+	MaxTokens         int           `mapstructure:"max_tokens"`           // 单次生成的最大输出 token
+	ThinkingTokens    int           `mapstructure:"thinking_tokens"`      // 扩展思考预算，0 表示关闭
+	Timeout           time.Duration `mapstructure:"timeout"`              // 单个任务的最长生成时间
+	EventTTL          time.Duration `mapstructure:"event_ttl"`            // 任务结束后进度事件保留多久，期间可以续读
+	Retention         time.Duration `mapstructure:"retention"`            // 任务结束后记录保留多久
+	MaxActivePerUser  int           `mapstructure:"max_active_per_user"`  // 每个用户同时进行的任务数，包括排队中的
+	Workers           int           `mapstructure:"workers"`              // 每个实例同时运行的任务数，其余按用户轮转排队
+	MaxRunningPerUser int           `mapstructure:"max_running_per_user"` // 每个用户在一个实例上同时运行的任务数
+	QueueTimeout      time.Duration `mapstructure:"queue_timeout"`        // 排队超过该时间的任务失败
+	MaxPromptLength   int           `mapstructure:"max_prompt_length"`    // 提示词最大字符数
+	MaxPageBytes      int           `mapstructure:"max_page_bytes"`       // 生成的页面超过该大小时在质量报告中提示
+	MaxHistoryEntries int           `mapstructure:"max_history"`          // 带入上下文的历史消息条数
+	ContextBudget     int           `mapstructure:"context_budget"`       // 单次请求的输入 token 上限，放不下的早期消息被概括
+	SummaryTokens     int           `mapstructure:"summary_tokens"`       // 每段早期消息摘要的最大输出 token
+	SummaryTTL        time.Duration `mapstructure:"summary_ttl"`          // 早期消息摘要的缓存时间
+
+	// Providers 多个模型服务，按优先级和权重路由并在失败时切换；为空时只使用上面的 provider
+	Providers []GenerationProviderConfig `mapstructure:"providers"`
+	Routes    []GenerationRouteConfig    `mapstructure:"routes"`  // 按请求类型指定模型服务
+	Breaker   GenerationBreakerConfig    `mapstructure:"breaker"` // 每个模型服务的熔断
+}
+
+// GenerationProviderConfig 路由中的一个模型服务
+type GenerationProviderConfig struct {
+	Name             string        `mapstructure:"name"`               // 唯一名称，用于路由规则、指标和任务记录
+	Type             string        `mapstructure:"type"`               // fake 或 anthropic
+	Model            string        `mapstructure:"model"`              // 模型名称
+	APIKey           string        `mapstructure:"api_key"`            // 模型服务密钥
+	APIKeyEnv        string        `mapstructure:"api_key_env"`        // 从该环境变量读取密钥，优先于 api_key
+	BaseURL          string        `mapstructure:"base_url"`           // 模型服务地址，为空时使用官方地址
+	Priority         int           `mapstructure:"priority"`           // 数值小的先尝试
+	Weight           int           `mapstructure:"weight"`             // 同一优先级内按权重分配请求
+	Capabilities     []string      `mapstructure:"capabilities"`       // 可处理的请求类型：create、modify、fragment、summary，为空表示全部
+	FirstByteTimeout time.Duration `mapstructure:"first_byte_timeout"` // 超过该时间没有输出时切换到下一个服务，0 表示不限制
+}
+
+// Key returns the API key of the provider, read from api_key_env when it is set
+func (p *GenerationProviderConfig) Key() string {
+	if p.APIKeyEnv != "" {
+		return os.Getenv(p.APIKeyEnv)
+	}
+	return p.APIKey
+}
+
+// GenerationRouteConfig 一条路由规则，例如修改页面的请求交给指定的服务
+type GenerationRouteConfig struct {
+	Kind      string   `mapstructure:"kind"`      // create、modify、fragment 或 summary
+	Providers []string `mapstructure:"providers"` // 按顺序尝试的服务名称
+	Fallback  bool     `mapstructure:"fallback"`  // 列出的服务都失败时是否继续尝试其他服务
+}
+
+// GenerationBreakerConfig 模型服务熔断配置
+type GenerationBreakerConfig struct {
+	FailureRatio float64       `mapstructure:"failure_ratio"` // 触发熔断的失败率
+	MinRequests  uint32        `mapstructure:"min_requests"`  // 统计周期内达到该请求数才判断失败率
+	Interval     time.Duration `mapstructure:"interval"`      // 统计周期
+	OpenTimeout  time.Duration `mapstructure:"open_timeout"`  // 熔断后多久放行试探请求
+}
+
+// MeteringConfig 生成用量计费配置
+// 成本按 token 数和单价计算，额度 = 成本 / credit_value 向上取整
+type MeteringConfig struct {
+	InputPrice  float64 `mapstructure:"input_price"`  // 每百万输入 token 的成本（美元）
+	OutputPrice float64 `mapstructure:"output_price"` // 每百万输出 token 的成本（美元）
+	CreditValue float64 `mapstructure:"credit_value"` // 每个额度对应的成本（美元）
+}
+
 // Load 从配置文件和环境变量加载配置
 func Load(configPath string) (*Config, error) {
 	v := viper.New()
@@ -157,6 +301,78 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("ratelimit.rate", 100)
 	v.SetDefault("ratelimit.burst", 200)
 
+	// Admin
+	v.SetDefault("admin.user_ids", []uint64{})
+
+	// Storage
+	v.SetDefault("storage.driver", "local")
+	v.SetDefault("storage.local_dir", "data/storage")
+	v.SetDefault("storage.max_file_size", 2*1024*1024)
+
+	// Plans
+	v.SetDefault("plans.default", "free")
+	v.SetDefault("plans.limits", map[string]interface{}{
+		"free": map[string]interface{}{
+			"max_projects":     50,
+			"max_project_size": 2 * 1024 * 1024,
+			"max_storage":      50 * 1024 * 1024,
+			"max_asset_bytes":  100 * 1024 * 1024,
+			"daily_credits":    100,
+			"monthly_credits":  1000,
+		},
+	})
+
+	// Sanitize
+	v.SetDefault("sanitize.script_mode", "strip")
+	v.SetDefault("sanitize.external_urls", "keep")
+	v.SetDefault("sanitize.rewrite_prefix", "")
+
+	// Trash
+	v.SetDefault("trash.retention", "720h") // 30 天
+	v.SetDefault("trash.purge_interval", "1h")
+
+	// Analytics
+	v.SetDefault("analytics.enabled", true)
+	v.SetDefault("analytics.respect_dnt", true)
+	v.SetDefault("analytics.rollup_interval", "10m")
+	v.SetDefault("analytics.max_range_days", 366)
+
+	// Gallery
+	v.SetDefault("gallery.categories", []string{"landing", "portfolio", "blog", "shop", "game", "tool", "other"})
+	v.SetDefault("gallery.trending_half_life", "48h")
+	v.SetDefault("gallery.reconcile_interval", "10m")
+	v.SetDefault("gallery.cache_ttl", "1m")
+
+	// Generation
+	v.SetDefault("generation.provider", "fake")
+	v.SetDefault("generation.model", "claude-opus-4-5")
+	v.SetDefault("generation.api_key", "")
+	v.SetDefault("generation.base_url", "")
+	v.SetDefault("generation.max_tokens", 16000)
+	v.SetDefault("generation.thinking_tokens", 8000)
+	v.SetDefault("generation.timeout", "5m")
+	v.SetDefault("generation.event_ttl", "30m")
+	v.SetDefault("generation.retention", "720h")
+	v.SetDefault("generation.max_active_per_user", 2)
+	v.SetDefault("generation.workers", 8)
+	v.SetDefault("generation.max_running_per_user", 1)
+	v.SetDefault("generation.queue_timeout", "5m")
+	v.SetDefault("generation.max_prompt_length", 10000)
+	v.SetDefault("generation.max_page_bytes", 262144)
+	v.SetDefault("generation.max_history", 20)
+	v.SetDefault("generation.context_budget", 100000)
+	v.SetDefault("generation.summary_tokens", 1024)
+	v.SetDefault("generation.summary_ttl", "168h")
+	v.SetDefault("generation.breaker.failure_ratio", 0.5)
+	v.SetDefault("generation.breaker.min_requests", 5)
+	v.SetDefault("generation.breaker.interval", "1m")
+	v.SetDefault("generation.breaker.open_timeout", "30s")
+
+	// Metering
+	v.SetDefault("metering.input_price", 5.0)
+	v.SetDefault("metering.output_price", 25.0)
+	v.SetDefault("metering.credit_value", 0.01)
+
 	// Env
 	v.SetDefault("env", "dev")
 }
@@ -171,6 +387,19 @@ func (c *Config) IsProd() bool {
 	return c.Env == "prod"
 }
 
+// IsAdmin 是否管理员
+func (c *Config) IsAdmin(userID uint64) bool {
+	if c.Admin == nil || userID == 0 {
+		return false
+	}
+	for _, id := range c.Admin.UserIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
 // 默认不安全的 JWT Secret
 const defaultInsecureSecret = "your-secret-key-change-in-production"
 
@@ -183,6 +412,14 @@ func Validate(cfg *Config) error {
 	errs = append(errs, validateRedis(cfg.Redis)...)
 	errs = append(errs, validateServer(cfg.Server)...)
 	errs = append(errs, validateRateLimit(cfg.RateLimit)...)
+	errs = append(errs, validateStorage(cfg.Storage)...)
+	errs = append(errs, validateTrash(cfg.Trash)...)
+	errs = append(errs, validatePlans(cfg.Plans)...)
+	errs = append(errs, validateSanitize(cfg.Sanitize)...)
+	errs = append(errs, validateAnalytics(cfg.Analytics)...)
+	errs = append(errs, validateGallery(cfg.Gallery)...)
+	errs = append(errs, validateGeneration(cfg.Generation)...)
+	errs = append(errs, validateMetering(cfg.Metering)...)
 
 	if len(errs) > 0 {
 		return fmt.Errorf("config validation failed: %v", errs)
@@ -278,6 +515,287 @@ func validateRateLimit(cfg *RateLimitConfig) []string {
 	return errs
 }
 
+// validateStorage 验证 Storage 配置
+func validateStorage(cfg *StorageConfig) []string {
+	if cfg == nil {
+		return nil
+	}
+	var errs []string
+	if cfg.Driver != "local" && cfg.Driver != "memory" {
+		errs = append(errs, "storage.driver must be local or memory")
+	}
+	if cfg.MaxFileSize < 0 {
+		errs = append(errs, "storage.max_file_size must be non-negative")
+	}
+	return errs
+}
+
+// validateTrash 验证 Trash 配置
+func validateTrash(cfg *TrashConfig) []string {
+	if cfg == nil {
+		return nil
+	}
+	var errs []string
+	if cfg.Retention <= 0 {
+		errs = append(errs, "trash.retention must be positive")
+	}
+	if cfg.PurgeInterval <= 0 {
+		errs = append(errs, "trash.purge_interval must be positive")
+	}
+	return errs
+}
+
+// validatePlans 验证 Plans 配置
+func validatePlans(cfg *PlansConfig) []string {
+	if cfg == nil {
+		return nil
+	}
+	var errs []string
+	if _, ok := cfg.Limits[cfg.Default]; !ok {
+		errs = append(errs, fmt.Sprintf("plans.default %q is not defined in plans.limits", cfg.Default))
+	}
+	for name, limits := range cfg.Limits {
+		if limits == nil {
+			continue
+		}
+		if limits.MaxProjects < 0 || limits.MaxProjectSize < 0 || limits.MaxStorage < 0 || limits.MaxAssetBytes < 0 ||
+			limits.DailyCredits < 0 || limits.MonthlyCredits < 0 {
+			errs = append(errs, fmt.Sprintf("plans.limits.%s must be non-negative", name))
+		}
+	}
+	return errs
+}
+
+// validateSanitize 验证 Sanitize 配置
+func validateSanitize(cfg *SanitizeConfig) []string {
+	if cfg == nil {
+		return nil
+	}
+	var errs []string
+	if cfg.ScriptMode != "strip" && cfg.ScriptMode != "flag" {
+		errs = append(errs, "sanitize.script_mode must be strip or flag")
+	}
+	switch cfg.ExternalURLs {
+	case "keep", "strip":
+	case "rewrite":
+		if cfg.RewritePrefix == "" {
+			errs = append(errs, "sanitize.rewrite_prefix is required when external_urls is rewrite")
+		}
+	default:
+		errs = append(errs, "sanitize.external_urls must be keep, rewrite or strip")
+	}
+	return errs
+}
+
+// validateAnalytics 验证 Analytics 配置
+func validateAnalytics(cfg *AnalyticsConfig) []string {
+	if cfg == nil {
+		return nil
+	}
+	var errs []string
+	if cfg.RollupInterval <= 0 {
+		errs = append(errs, "analytics.rollup_interval must be positive")
+	}
+	if cfg.MaxRangeDays <= 0 {
+		errs = append(errs, "analytics.max_range_days must be positive")
+	}
+	return errs
+}
+
+// galleryCategoryPattern 分类名只能是小写字母、数字和连字符
+var galleryCategoryPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// validateGallery 验证 Gallery 配置
+func validateGallery(cfg *GalleryConfig) []string {
+	if cfg == nil {
+		return nil
+	}
+	var errs []string
+	if len(cfg.Categories) == 0 {
+		errs = append(errs, "gallery.categories must not be empty")
+	}
+	for _, c := range cfg.Categories {
+		if !galleryCategoryPattern.MatchString(c) {
+			errs = append(errs, fmt.Sprintf("gallery.categories: invalid category %q", c))
+		}
+	}
+	if cfg.TrendingHalfLife <= 0 {
+		errs = append(errs, "gallery.trending_half_life must be positive")
+	}
+	if cfg.ReconcileInterval <= 0 {
+		errs = append(errs, "gallery.reconcile_interval must be positive")
+	}
+	if cfg.CacheTTL < 0 {
+		errs = append(errs, "gallery.cache_ttl must not be negative")
+	}
+	return errs
+}
+
+func validateGeneration(cfg *GenerationConfig) []string {
+	if cfg == nil {
+		return nil
+	}
+	var errs []string
+	if len(cfg.Providers) == 0 {
+		switch cfg.Provider {
+		case "fake":
+		case "anthropic":
+			if cfg.APIKey == "" {
+				errs = append(errs, "generation.api_key is required for the anthropic provider")
+			}
+			if cfg.Model == "" {
+				errs = append(errs, "generation.model is required for the anthropic provider")
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("generation.provider must be fake or anthropic, got %q", cfg.Provider))
+		}
+	}
+	errs = append(errs, validateGenerationRouting(cfg)...)
+	if cfg.MaxTokens <= 0 {
+		errs = append(errs, "generation.max_tokens must be positive")
+	}
+	if cfg.ThinkingTokens < 0 || (cfg.ThinkingTokens > 0 && cfg.ThinkingTokens >= cfg.MaxTokens) {
+		errs = append(errs, "generation.thinking_tokens must be between 0 and max_tokens")
+	}
+	if cfg.Timeout <= 0 {
+		errs = append(errs, "generation.timeout must be positive")
+	}
+	if cfg.EventTTL <= 0 {
+		errs = append(errs, "generation.event_ttl must be positive")
+	}
+	if cfg.Retention <= 0 {
+		errs = append(errs, "generation.retention must be positive")
+	}
+	if cfg.MaxActivePerUser <= 0 {
+		errs = append(errs, "generation.max_active_per_user must be positive")
+	}
+	if cfg.Workers <= 0 {
+		errs = append(errs, "generation.workers must be positive")
+	}
+	if cfg.MaxRunningPerUser <= 0 {
+		errs = append(errs, "generation.max_running_per_user must be positive")
+	}
+	if cfg.QueueTimeout <= 0 {
+		errs = append(errs, "generation.queue_timeout must be positive")
+	}
+	if cfg.MaxPromptLength <= 0 {
+		errs = append(errs, "generation.max_prompt_length must be positive")
+	}
+	if cfg.MaxPageBytes <= 0 {
+		errs = append(errs, "generation.max_page_bytes must be positive")
+	}
+	if cfg.MaxHistoryEntries < 0 {
+		errs = append(errs, "generation.max_history must not be negative")
+	}
+	if cfg.ContextBudget <= 0 {
+		errs = append(errs, "generation.context_budget must be positive")
+	}
+	if cfg.SummaryTokens <= 0 || cfg.SummaryTokens >= cfg.ContextBudget {
+		errs = append(errs, "generation.summary_tokens must be between 1 and context_budget")
+	}
+	if cfg.SummaryTTL <= 0 {
+		errs = append(errs, "generation.summary_ttl must be positive")
+	}
+	return errs
+}
+
+// generationRequestKinds 路由规则和服务能力可以使用的请求类型
+var generationRequestKinds = []string{"create", "modify", "fragment", "summary"}
+
+// validateGenerationRouting 验证多个模型服务、路由规则和熔断配置
+func validateGenerationRouting(cfg *GenerationConfig) []string {
+	var errs []string
+	names := make(map[string]bool)
+	for i, p := range cfg.Providers {
+		field := fmt.Sprintf("generation.providers[%d]", i)
+		if p.Name == "" || len(p.Name) > 32 {
+			errs = append(errs, field+".name is required and at most 32 characters")
+		} else if names[p.Name] {
+			errs = append(errs, fmt.Sprintf("%s.name %q is used by another provider", field, p.Name))
+		}
+		names[p.Name] = true
+		switch p.Type {
+		case "fake":
+		case "anthropic":
+			if p.Key() == "" {
+				errs = append(errs, field+".api_key or api_key_env is required for the anthropic provider")
+			}
+			if p.Model == "" {
+				errs = append(errs, field+".model is required for the anthropic provider")
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("%s.type must be fake or anthropic, got %q", field, p.Type))
+		}
+		if p.Weight <= 0 {
+			errs = append(errs, field+".weight must be positive")
+		}
+		if p.FirstByteTimeout < 0 {
+			errs = append(errs, field+".first_byte_timeout must not be negative")
+		}
+		for _, c := range p.Capabilities {
+			if !slices.Contains(generationRequestKinds, c) {
+				errs = append(errs, fmt.Sprintf("%s.capabilities must be create, modify, fragment or summary, got %q", field, c))
+			}
+		}
+	}
+
+	kinds := make(map[string]bool)
+	for i, r := range cfg.Routes {
+		field := fmt.Sprintf("generation.routes[%d]", i)
+		if !slices.Contains(generationRequestKinds, r.Kind) {
+			errs = append(errs, fmt.Sprintf("%s.kind must be create, modify, fragment or summary, got %q", field, r.Kind))
+		} else if kinds[r.Kind] {
+			errs = append(errs, fmt.Sprintf("%s.kind %q has another route", field, r.Kind))
+		}
+		kinds[r.Kind] = true
+		if len(r.Providers) == 0 {
+			errs = append(errs, field+".providers is required")
+		}
+		for _, name := range r.Providers {
+			if !names[name] {
+				errs = append(errs, fmt.Sprintf("%s.providers: unknown provider %q", field, name))
+			}
+		}
+	}
+
+	if cfg.Breaker.FailureRatio <= 0 || cfg.Breaker.FailureRatio > 1 {
+		errs = append(errs, "generation.breaker.failure_ratio must be between 0 and 1")
+	}
+	if cfg.Breaker.MinRequests == 0 {
+		errs = append(errs, "generation.breaker.min_requests must be positive")
+	}
+	if cfg.Breaker.Interval <= 0 || cfg.Breaker.OpenTimeout <= 0 {
+		errs = append(errs, "generation.breaker.interval and open_timeout must be positive")
+	}
+	return errs
+}
+
+// validateMetering 验证 Metering 配置
+func validateMetering(cfg *MeteringConfig) []string {
+	if cfg == nil {
+		return nil
+	}
+	var errs []string
+	if cfg.InputPrice < 0 || cfg.OutputPrice < 0 {
+		errs = append(errs, "metering.input_price and metering.output_price must not be negative")
+	}
+	if cfg.CreditValue <= 0 {
+		errs = append(errs, "metering.credit_value must be positive")
+	}
+	return errs
+}
+
+// Plan 返回套餐限额，未知套餐使用默认套餐；未配置时返回 nil（不限制）
+func (c *Config) Plan(name string) *PlanLimits {
+	if c.Plans == nil {
+		return nil
+	}
+	if limits, ok := c.Plans.Limits[name]; ok && limits != nil {
+		return limits
+	}
+	return c.Plans.Limits[c.Plans.Default]
+}
+
 // MustValidate 验证配置，失败则 panic
 func MustValidate(cfg *Config) {
 	if err := Validate(cfg); err != nil {
@@ -231,6 +231,108 @@ func TestValidate_RateLimitConfig(t *testing.T) {
 	})
 }
 
+func TestValidate_TrashConfig(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		cfg := &Config{
+			Trash: &TrashConfig{
+				Retention:     30 * 24 * time.Hour,
+				PurgeInterval: time.Hour,
+			},
+		}
+
+		if err := Validate(cfg); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("zero retention", func(t *testing.T) {
+		cfg := &Config{
+			Trash: &TrashConfig{
+				Retention:     0,
+				PurgeInterval: time.Hour,
+			},
+		}
+
+		err := Validate(cfg)
+		if err == nil || !strings.Contains(err.Error(), "trash.retention") {
+			t.Errorf("expected trash.retention error, got %v", err)
+		}
+	})
+}
+
+func TestValidate_PlansConfig(t *testing.T) {
+	t.Run("undefined default", func(t *testing.T) {
+		cfg := &Config{
+			Plans: &PlansConfig{
+				Default: "free",
+				Limits:  map[string]*PlanLimits{"pro": {MaxProjects: 100}},
+			},
+		}
+
+		err := Validate(cfg)
+		if err == nil || !strings.Contains(err.Error(), "plans.default") {
+			t.Errorf("expected plans.default error, got %v", err)
+		}
+	})
+
+	t.Run("negative limit", func(t *testing.T) {
+		cfg := &Config{
+			Plans: &PlansConfig{
+				Default: "free",
+				Limits:  map[string]*PlanLimits{"free": {MaxStorage: -1}},
+			},
+		}
+
+		err := Validate(cfg)
+		if err == nil || !strings.Contains(err.Error(), "plans.limits.free") {
+			t.Errorf("expected plans.limits.free error, got %v", err)
+		}
+	})
+}
+
+func TestValidate_SanitizeConfig(t *testing.T) {
+	cfg := &Config{
+		Sanitize: &SanitizeConfig{
+			ScriptMode:   "strip",
+			ExternalURLs: "rewrite",
+		},
+	}
+
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "sanitize.rewrite_prefix") {
+		t.Errorf("expected sanitize.rewrite_prefix error, got %v", err)
+	}
+
+	cfg.Sanitize.RewritePrefix = "/proxy?url="
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	free := &PlanLimits{MaxProjects: 10}
+	pro := &PlanLimits{MaxProjects: 1000}
+	cfg := &Config{
+		Plans: &PlansConfig{
+			Default: "free",
+			Limits:  map[string]*PlanLimits{"free": free, "pro": pro},
+		},
+	}
+
+	if got := cfg.Plan("pro"); got != pro {
+		t.Errorf("Plan(pro) = %+v, want pro limits", got)
+	}
+	if got := cfg.Plan("unknown"); got != free {
+		t.Errorf("Plan(unknown) = %+v, want default limits", got)
+	}
+	if got := cfg.Plan(""); got != free {
+		t.Errorf("Plan(\"\") = %+v, want default limits", got)
+	}
+	if got := (&Config{}).Plan("free"); got != nil {
+		t.Errorf("Plan() without plans = %+v, want nil", got)
+	}
+}
+
 func TestIsDev(t *testing.T) {
 	tests := []struct {
 		env  string
@@ -272,3 +374,214 @@ func TestIsProd(t *testing.T) {
 		})
 	}
 }
+
+func TestIsAdmin(t *testing.T) {
+	cfg := &Config{Admin: &AdminConfig{UserIDs: []uint64{1, 42}}}
+	if !cfg.IsAdmin(42) {
+		t.Error("expected user 42 to be admin")
+	}
+	if cfg.IsAdmin(2) || cfg.IsAdmin(0) {
+		t.Error("expected users 2 and 0 not to be admin")
+	}
+	if (&Config{}).IsAdmin(1) {
+		t.Error("expected no admins without admin config")
+	}
+}
+
+func TestValidate_AnalyticsConfig(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		cfg := &Config{
+			Analytics: &AnalyticsConfig{
+				Enabled:        true,
+				RollupInterval: 10 * time.Minute,
+				MaxRangeDays:   366,
+			},
+		}
+
+		if err := Validate(cfg); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("zero rollup interval", func(t *testing.T) {
+		cfg := &Config{
+			Analytics: &AnalyticsConfig{
+				RollupInterval: 0,
+				MaxRangeDays:   366,
+			},
+		}
+
+		err := Validate(cfg)
+		if err == nil || !strings.Contains(err.Error(), "analytics.rollup_interval") {
+			t.Errorf("expected analytics.rollup_interval error, got %v", err)
+		}
+	})
+}
+
+func TestValidate_GalleryConfig(t *testing.T) {
+	valid := func() *GalleryConfig {
+		return &GalleryConfig{
+			Categories:        []string{"landing", "portfolio"},
+			TrendingHalfLife:  48 * time.Hour,
+			ReconcileInterval: 10 * time.Minute,
+			CacheTTL:          time.Minute,
+		}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		if err := Validate(&Config{Gallery: valid()}); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		modify func(*GalleryConfig)
+		want   string
+	}{
+		{"no categories", func(c *GalleryConfig) { c.Categories = nil }, "gallery.categories"},
+		{"invalid category", func(c *GalleryConfig) { c.Categories = []string{"Web Sites"} }, "gallery.categories"},
+		{"zero half-life", func(c *GalleryConfig) { c.TrendingHalfLife = 0 }, "gallery.trending_half_life"},
+		{"zero reconcile interval", func(c *GalleryConfig) { c.ReconcileInterval = 0 }, "gallery.reconcile_interval"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(cfg)
+			err := Validate(&Config{Gallery: cfg})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected %s error, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidate_GenerationConfig(t *testing.T) {
+	valid := func() *GenerationConfig {
+		return &GenerationConfig{
+			Provider:          "fake",
+			MaxTokens:         16000,
+			ThinkingTokens:    8000,
+			Timeout:           5 * time.Minute,
+			EventTTL:          30 * time.Minute,
+			Retention:         720 * time.Hour,
+			MaxActivePerUser:  2,
+			Workers:           8,
+			MaxRunningPerUser: 1,
+			QueueTimeout:      5 * time.Minute,
+			MaxPromptLength:   10000,
+			MaxPageBytes:      262144,
+			MaxHistoryEntries: 20,
+			ContextBudget:     100000,
+			SummaryTokens:     1024,
+			SummaryTTL:        168 * time.Hour,
+			Breaker:           GenerationBreakerConfig{FailureRatio: 0.5, MinRequests: 5, Interval: time.Minute, OpenTimeout: 30 * time.Second},
+		}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		if err := Validate(&Config{Generation: valid()}); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("valid routing", func(t *testing.T) {
+		t.Setenv("TEST_GENERATION_KEY", "secret")
+		cfg := valid()
+		cfg.Provider = ""
+		cfg.Providers = []GenerationProviderConfig{
+			{Name: "primary", Type: "anthropic", Model: "m", APIKeyEnv: "TEST_GENERATION_KEY", Weight: 1},
+			{Name: "backup", Type: "fake", Priority: 1, Weight: 1, Capabilities: []string{"modify"}},
+		}
+		cfg.Routes = []GenerationRouteConfig{{Kind: "modify", Providers: []string{"backup"}, Fallback: true}}
+		if err := Validate(&Config{Generation: cfg}); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		if key := cfg.Providers[0].Key(); key != "secret" {
+			t.Errorf("Key() = %q, want the environment variable", key)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		modify func(*GenerationConfig)
+		want   string
+	}{
+		{"unknown provider", func(c *GenerationConfig) { c.Provider = "openai" }, "generation.provider"},
+		{"anthropic without key", func(c *GenerationConfig) { c.Provider = "anthropic"; c.Model = "m" }, "generation.api_key"},
+		{"thinking over max tokens", func(c *GenerationConfig) { c.ThinkingTokens = 16000 }, "generation.thinking_tokens"},
+		{"zero timeout", func(c *GenerationConfig) { c.Timeout = 0 }, "generation.timeout"},
+		{"zero retention", func(c *GenerationConfig) { c.Retention = 0 }, "generation.retention"},
+		{"zero active jobs", func(c *GenerationConfig) { c.MaxActivePerUser = 0 }, "generation.max_active_per_user"},
+		{"zero workers", func(c *GenerationConfig) { c.Workers = 0 }, "generation.workers"},
+		{"zero running jobs", func(c *GenerationConfig) { c.MaxRunningPerUser = 0 }, "generation.max_running_per_user"},
+		{"zero queue timeout", func(c *GenerationConfig) { c.QueueTimeout = 0 }, "generation.queue_timeout"},
+		{"zero page size", func(c *GenerationConfig) { c.MaxPageBytes = 0 }, "generation.max_page_bytes"},
+		{"zero context budget", func(c *GenerationConfig) { c.ContextBudget = 0 }, "generation.context_budget"},
+		{"summary over budget", func(c *GenerationConfig) { c.SummaryTokens = c.ContextBudget }, "generation.summary_tokens"},
+		{"zero summary ttl", func(c *GenerationConfig) { c.SummaryTTL = 0 }, "generation.summary_ttl"},
+		{"unnamed provider", func(c *GenerationConfig) {
+			c.Providers = []GenerationProviderConfig{{Type: "fake", Weight: 1}}
+		}, "generation.providers[0].name"},
+		{"duplicate provider", func(c *GenerationConfig) {
+			c.Providers = []GenerationProviderConfig{{Name: "a", Type: "fake", Weight: 1}, {Name: "a", Type: "fake", Weight: 1}}
+		}, "generation.providers[1].name"},
+		{"provider without key", func(c *GenerationConfig) {
+			c.Providers = []GenerationProviderConfig{{Name: "a", Type: "anthropic", Model: "m", APIKeyEnv: "TEST_UNSET_GENERATION_KEY", Weight: 1}}
+		}, "generation.providers[0].api_key"},
+		{"zero provider weight", func(c *GenerationConfig) {
+			c.Providers = []GenerationProviderConfig{{Name: "a", Type: "fake"}}
+		}, "generation.providers[0].weight"},
+		{"unknown capability", func(c *GenerationConfig) {
+			c.Providers = []GenerationProviderConfig{{Name: "a", Type: "fake", Weight: 1, Capabilities: []string{"images"}}}
+		}, "generation.providers[0].capabilities"},
+		{"route to unknown provider", func(c *GenerationConfig) {
+			c.Routes = []GenerationRouteConfig{{Kind: "modify", Providers: []string{"b"}}}
+		}, "generation.routes[0].providers"},
+		{"unknown route kind", func(c *GenerationConfig) {
+			c.Routes = []GenerationRouteConfig{{Kind: "edit", Providers: []string{"b"}}}
+		}, "generation.routes[0].kind"},
+		{"zero breaker ratio", func(c *GenerationConfig) { c.Breaker.FailureRatio = 0 }, "generation.breaker.failure_ratio"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(cfg)
+			err := Validate(&Config{Generation: cfg})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected %s error, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidate_MeteringConfig(t *testing.T) {
+	valid := func() *MeteringConfig {
+		return &MeteringConfig{InputPrice: 5, OutputPrice: 25, CreditValue: 0.01}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		if err := Validate(&Config{Metering: valid()}); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		modify func(*MeteringConfig)
+		want   string
+	}{
+		{"negative price", func(c *MeteringConfig) { c.OutputPrice = -1 }, "metering.input_price"},
+		{"zero credit value", func(c *MeteringConfig) { c.CreditValue = 0 }, "metering.credit_value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(cfg)
+			err := Validate(&Config{Metering: cfg})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected %s error, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/database"
+)
+
+type AnalyticsDAO struct{}
+
+func NewAnalyticsDAO() *AnalyticsDAO {
+	return &AnalyticsDAO{}
+}
+
+// SaveDay writes the totals and referrers of one project and day, replacing earlier rollups
+func (d *AnalyticsDAO) SaveDay(ctx context.Context, daily *model.PageViewDaily, referrers []model.PageViewReferrer) error {
+	return database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			UpdateAll: true,
+		}).Create(daily).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("project_id = ? AND date = ?", daily.ProjectID, daily.Date).
+			Delete(&model.PageViewReferrer{}).Error; err != nil {
+			return err
+		}
+		if len(referrers) == 0 {
+			return nil
+		}
+		return tx.Create(&referrers).Error
+	})
+}
+
+// ListDaily retrieves the daily totals of a project between two dates (inclusive)
+func (d *AnalyticsDAO) ListDaily(ctx context.Context, projectID uint64, from, to time.Time) ([]model.PageViewDaily, error) {
+	var rows []model.PageViewDaily
+	if err := database.DB.WithContext(ctx).
+		Where("project_id = ? AND date BETWEEN ? AND ?", projectID, from, to).
+		Order("date ASC").
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// TopReferrers sums the views per referrer of a project between two dates (inclusive)
+func (d *AnalyticsDAO) TopReferrers(ctx context.Context, projectID uint64, from, to time.Time, limit int) ([]model.ReferrerCount, error) {
+	var counts []model.ReferrerCount
+	if err := database.DB.WithContext(ctx).Model(&model.PageViewReferrer{}).
+		Select("referrer, SUM(views) AS views").
+		Where("project_id = ? AND date BETWEEN ? AND ?", projectID, from, to).
+		Group("referrer").
+		Order("views DESC, referrer ASC").
+		Limit(limit).
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
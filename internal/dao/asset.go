@@ -0,0 +1,77 @@
+package dao
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/database"
+)
+
+type AssetDAO struct{}
+
+func NewAssetDAO() *AssetDAO {
+	return &AssetDAO{}
+}
+
+// GetByID retrieves an asset by ID
+func (d *AssetDAO) GetByID(ctx context.Context, id uint64) (*model.ProjectAsset, error) {
+	var asset model.ProjectAsset
+	if err := database.DB.WithContext(ctx).First(&asset, id).Error; err != nil {
+		return nil, err
+	}
+	return &asset, nil
+}
+
+// GetByStorageKey retrieves an asset of a project by storage key, returns nil if not found
+func (d *AssetDAO) GetByStorageKey(ctx context.Context, projectID uint64, key string) (*model.ProjectAsset, error) {
+	var asset model.ProjectAsset
+	if err := database.DB.WithContext(ctx).
+		Where("project_id = ? AND storage_key = ?", projectID, key).
+		First(&asset).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &asset, nil
+}
+
+// ListByProjectID lists assets of a project, newest first
+func (d *AssetDAO) ListByProjectID(ctx context.Context, projectID uint64) ([]model.ProjectAsset, error) {
+	assets := make([]model.ProjectAsset, 0)
+	if err := database.DB.WithContext(ctx).
+		Where("project_id = ?", projectID).
+		Order("id DESC").
+		Find(&assets).Error; err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+// SumSizeByUserID returns the total bytes of all assets owned by a user
+func (d *AssetDAO) SumSizeByUserID(ctx context.Context, userID uint64) (int64, error) {
+	var total int64
+	err := database.DB.WithContext(ctx).
+		Model(&model.ProjectAsset{}).
+		Select("COALESCE(SUM(size), 0)").
+		Where("user_id = ?", userID).
+		Scan(&total).Error
+	return total, err
+}
+
+// Create creates an asset record
+func (d *AssetDAO) Create(ctx context.Context, asset *model.ProjectAsset) error {
+	return database.DB.WithContext(ctx).Create(asset).Error
+}
+
+// Delete deletes an asset record by ID
+func (d *AssetDAO) Delete(ctx context.Context, id uint64) error {
+	return database.DB.WithContext(ctx).Delete(&model.ProjectAsset{}, id).Error
+}
+
+// DeleteByProjectID deletes all asset records of a project
+func (d *AssetDAO) DeleteByProjectID(ctx context.Context, projectID uint64) error {
+	return database.DB.WithContext(ctx).Where("project_id = ?", projectID).Delete(&model.ProjectAsset{}).Error
+}
@@ -0,0 +1,132 @@
+package dao
+
+import (
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/blob"
+)
+
+// contentHash 返回内容的 blob 哈希，空内容不建 blob
+func contentHash(content string) string {
+	if content == "" {
+		return ""
+	}
+	return blob.Hash(content)
+}
+
+// acquireBlob 保存内容并增加一次引用，返回其哈希
+// 已存在的 blob 只增加计数，不重复写入数据
+func acquireBlob(tx *gorm.DB, content string) (string, error) {
+	hash := contentHash(content)
+	if hash == "" {
+		return "", nil
+	}
+
+	res := tx.Model(&model.ContentBlob{}).
+		Where("hash = ?", hash).
+		UpdateColumn("ref_count", gorm.Expr("ref_count + 1"))
+	if res.Error != nil {
+		return "", res.Error
+	}
+	if res.RowsAffected > 0 {
+		return hash, nil
+	}
+
+	data, encoding, err := blob.Encode(content)
+	if err != nil {
+		return "", err
+	}
+	// 并发写入同一内容时由唯一主键兜底
+	err = tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{"ref_count": gorm.Expr("ref_count + 1")}),
+	}).Create(&model.ContentBlob{
+		Hash:     hash,
+		Size:     int64(len(content)),
+		Encoding: encoding,
+		Data:     data,
+		RefCount: 1,
+	}).Error
+	return hash, err
+}
+
+// replaceBlob 把一列内容从 oldHash 换成 content，内容未变时不产生任何写入
+func replaceBlob(tx *gorm.DB, oldHash, content string) (string, error) {
+	if contentHash(content) == oldHash {
+		return oldHash, nil
+	}
+	hash, err := acquireBlob(tx, content)
+	if err != nil {
+		return "", err
+	}
+	if err := releaseBlobs(tx, []string{oldHash}); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// releaseBlobs 每出现一次哈希就减少一次引用，并删除不再被引用的 blob
+func releaseBlobs(tx *gorm.DB, hashes []string) error {
+	counts := make(map[string]int64, len(hashes))
+	for _, h := range hashes {
+		if h != "" {
+			counts[h]++
+		}
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+
+	// 按固定顺序加锁，避免并发事务互相死锁
+	released := make([]string, 0, len(counts))
+	for h := range counts {
+		released = append(released, h)
+	}
+	sort.Strings(released)
+	for _, h := range released {
+		if err := tx.Model(&model.ContentBlob{}).
+			Where("hash = ?", h).
+			UpdateColumn("ref_count", gorm.Expr("ref_count - ?", counts[h])).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Where("hash IN ? AND ref_count <= 0", released).Delete(&model.ContentBlob{}).Error
+}
+
+// loadBlobs 批量读取并解码 blob
+func loadBlobs(db *gorm.DB, hashes []string) (map[string]string, error) {
+	wanted := make([]string, 0, len(hashes))
+	seen := make(map[string]struct{}, len(hashes))
+	for _, h := range hashes {
+		if _, ok := seen[h]; h != "" && !ok {
+			seen[h] = struct{}{}
+			wanted = append(wanted, h)
+		}
+	}
+	contents := make(map[string]string, len(wanted))
+	if len(wanted) == 0 {
+		return contents, nil
+	}
+
+	var blobs []model.ContentBlob
+	if err := db.Select("hash", "encoding", "data").Where("hash IN ?", wanted).Find(&blobs).Error; err != nil {
+		return nil, err
+	}
+	for _, b := range blobs {
+		content, err := blob.Decode(b.Data, b.Encoding)
+		if err != nil {
+			return nil, fmt.Errorf("decode blob %s: %w", b.Hash, err)
+		}
+		contents[b.Hash] = content
+	}
+	for _, h := range wanted {
+		if _, ok := contents[h]; !ok {
+			return nil, fmt.Errorf("blob %s is missing", h)
+		}
+	}
+	return contents, nil
+}
@@ -0,0 +1,172 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/database"
+)
+
+type CommentDAO struct{}
+
+func NewCommentDAO() *CommentDAO {
+	return &CommentDAO{}
+}
+
+// CommentListQuery filters the comment threads of a project
+type CommentListQuery struct {
+	ProjectID  uint64
+	Resolved   *bool  // nil lists both open and resolved threads
+	SnapshotID uint64 // 0 lists all revisions
+	Anchor     string // exact anchor, empty lists all
+	AfterID    uint64 // ID of the last thread of the previous page, 0 for the first page
+	Limit      int
+}
+
+// GetByID retrieves a comment by ID
+func (d *CommentDAO) GetByID(ctx context.Context, id uint64) (*model.ProjectComment, error) {
+	var comment model.ProjectComment
+	if err := database.DB.WithContext(ctx).First(&comment, id).Error; err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// ListThreads retrieves root comments of a project, newest first
+func (d *CommentDAO) ListThreads(ctx context.Context, q *CommentListQuery) ([]model.ProjectComment, error) {
+	db := database.DB.WithContext(ctx).
+		Where("project_id = ? AND parent_id IS NULL", q.ProjectID)
+	if q.Resolved != nil {
+		db = db.Where("resolved = ?", *q.Resolved)
+	}
+	if q.SnapshotID != 0 {
+		db = db.Where("snapshot_id = ?", q.SnapshotID)
+	}
+	if q.Anchor != "" {
+		db = db.Where("anchor = ?", q.Anchor)
+	}
+	if q.AfterID != 0 {
+		db = db.Where("id < ?", q.AfterID)
+	}
+
+	var comments []model.ProjectComment
+	if err := db.Order("id DESC").Limit(q.Limit).Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// ListReplies retrieves the replies of several threads, oldest first
+func (d *CommentDAO) ListReplies(ctx context.Context, parentIDs []uint64) ([]model.ProjectComment, error) {
+	var replies []model.ProjectComment
+	if len(parentIDs) == 0 {
+		return replies, nil
+	}
+	if err := database.DB.WithContext(ctx).
+		Where("parent_id IN ?", parentIDs).
+		Order("id ASC").
+		Find(&replies).Error; err != nil {
+		return nil, err
+	}
+	return replies, nil
+}
+
+// ListMentioning retrieves comments mentioning a user, newest first
+// 只包含未删除且用户仍可访问（所有者或成员）的项目
+func (d *CommentDAO) ListMentioning(ctx context.Context, userID, afterID uint64, limit int) ([]model.ProjectComment, error) {
+	db := database.DB.WithContext(ctx).
+		Table("project_comments AS c").
+		Select("c.*").
+		Joins("JOIN comment_mentions AS m ON m.comment_id = c.id").
+		Joins("JOIN projects AS p ON p.id = c.project_id").
+		Where("m.user_id = ? AND p.deleted_at IS NULL", userID).
+		Where("p.user_id = ? OR EXISTS (SELECT 1 FROM project_members AS pm WHERE pm.project_id = c.project_id AND pm.user_id = ?)", userID, userID)
+	if afterID != 0 {
+		db = db.Where("c.id < ?", afterID)
+	}
+
+	var comments []model.ProjectComment
+	if err := db.Order("c.id DESC").Limit(limit).Scan(&comments).Error; err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// ListMentions retrieves the mentioned user IDs of several comments, keyed by comment ID
+func (d *CommentDAO) ListMentions(ctx context.Context, commentIDs []uint64) (map[uint64][]uint64, error) {
+	result := make(map[uint64][]uint64, len(commentIDs))
+	if len(commentIDs) == 0 {
+		return result, nil
+	}
+
+	var mentions []model.CommentMention
+	if err := database.DB.WithContext(ctx).
+		Where("comment_id IN ?", commentIDs).
+		Order("user_id ASC").
+		Find(&mentions).Error; err != nil {
+		return nil, err
+	}
+	for _, m := range mentions {
+		result[m.CommentID] = append(result[m.CommentID], m.UserID)
+	}
+	return result, nil
+}
+
+// Create creates a comment with its mentions
+func (d *CommentDAO) Create(ctx context.Context, comment *model.ProjectComment, mentions []uint64) error {
+	return database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+		return createMentions(tx, comment.ID, mentions)
+	})
+}
+
+// UpdateBody changes the body of a comment and replaces its mentions
+func (d *CommentDAO) UpdateBody(ctx context.Context, comment *model.ProjectComment, mentions []uint64) error {
+	return database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(comment).Update("body", comment.Body).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("comment_id = ?", comment.ID).Delete(&model.CommentMention{}).Error; err != nil {
+			return err
+		}
+		return createMentions(tx, comment.ID, mentions)
+	})
+}
+
+// SetResolved resolves or reopens a thread
+func (d *CommentDAO) SetResolved(ctx context.Context, id uint64, resolved bool, by *uint64, at *time.Time) error {
+	return database.DB.WithContext(ctx).Model(&model.ProjectComment{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"resolved":    resolved,
+			"resolved_by": by,
+			"resolved_at": at,
+		}).Error
+}
+
+// Delete deletes a comment together with its replies and their mentions
+func (d *CommentDAO) Delete(ctx context.Context, id uint64) error {
+	return database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ids := tx.Model(&model.ProjectComment{}).Select("id").Where("id = ? OR parent_id = ?", id, id)
+		if err := tx.Where("comment_id IN (?)", ids).Delete(&model.CommentMention{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ? OR parent_id = ?", id, id).Delete(&model.ProjectComment{}).Error
+	})
+}
+
+func createMentions(tx *gorm.DB, commentID uint64, userIDs []uint64) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	rows := make([]model.CommentMention, len(userIDs))
+	for i, userID := range userIDs {
+		rows[i] = model.CommentMention{CommentID: commentID, UserID: userID}
+	}
+	return tx.Create(&rows).Error
+}
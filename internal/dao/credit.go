@@ -0,0 +1,63 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/database"
+)
+
+type CreditDAO struct{}
+
+func NewCreditDAO() *CreditDAO {
+	return &CreditDAO{}
+}
+
+// RecordUsage inserts the usage of a generation, a job is recorded at most once
+func (d *CreditDAO) RecordUsage(ctx context.Context, usage *model.GenerationUsage) error {
+	return database.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(usage).Error
+}
+
+// SumCredits sums the credits a user has used since a time
+func (d *CreditDAO) SumCredits(ctx context.Context, userID uint64, since time.Time) (int64, error) {
+	var total int64
+	err := database.DB.WithContext(ctx).Model(&model.GenerationUsage{}).
+		Select("COALESCE(SUM(credits), 0)").
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Scan(&total).Error
+	return total, err
+}
+
+// ListUsage retrieves a user's usage records newest first, beforeID 0 starts from the newest
+func (d *CreditDAO) ListUsage(ctx context.Context, userID, beforeID uint64, limit int) ([]model.GenerationUsage, error) {
+	db := database.DB.WithContext(ctx).Where("user_id = ?", userID)
+	if beforeID != 0 {
+		db = db.Where("id < ?", beforeID)
+	}
+
+	var usage []model.GenerationUsage
+	if err := db.Order("id DESC").Limit(limit).Find(&usage).Error; err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// CreateGrant inserts a credit grant
+func (d *CreditDAO) CreateGrant(ctx context.Context, grant *model.CreditGrant) error {
+	return database.DB.WithContext(ctx).Create(grant).Error
+}
+
+// ListGrants retrieves the grants given to a user since a time, newest first
+func (d *CreditDAO) ListGrants(ctx context.Context, userID uint64, since time.Time) ([]model.CreditGrant, error) {
+	var grants []model.CreditGrant
+	err := database.DB.WithContext(ctx).
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Order("id DESC").
+		Find(&grants).Error
+	return grants, err
+}
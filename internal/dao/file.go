@@ -0,0 +1,153 @@
+package dao
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/database"
+)
+
+type FileDAO struct{}
+
+func NewFileDAO() *FileDAO {
+	return &FileDAO{}
+}
+
+// ListByProjectID lists the extra files of a project without their content, ordered by path
+func (d *FileDAO) ListByProjectID(ctx context.Context, projectID uint64) ([]model.ProjectFileInfo, error) {
+	files := make([]model.ProjectFileInfo, 0)
+	if err := database.DB.WithContext(ctx).Model(&model.ProjectFile{}).
+		Select("path", "size", "updated_at").
+		Where("project_id = ?", projectID).
+		Order("path ASC").
+		Scan(&files).Error; err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// ListWithContent retrieves the extra files of a project with their content, ordered by path
+func (d *FileDAO) ListWithContent(ctx context.Context, projectID uint64) ([]model.ProjectFile, error) {
+	db := database.DB.WithContext(ctx)
+	files := make([]model.ProjectFile, 0)
+	if err := db.Where("project_id = ?", projectID).Order("path ASC").Find(&files).Error; err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, len(files))
+	for i, f := range files {
+		hashes[i] = f.Hash
+	}
+	contents, err := loadBlobs(db, hashes)
+	if err != nil {
+		return nil, err
+	}
+	for i := range files {
+		files[i].Content = contents[files[i].Hash]
+	}
+	return files, nil
+}
+
+// Get retrieves a file of a project by path, returns nil if it does not exist
+func (d *FileDAO) Get(ctx context.Context, projectID uint64, path string) (*model.ProjectFile, error) {
+	db := database.DB.WithContext(ctx)
+	var file model.ProjectFile
+	if err := db.Where("project_id = ? AND path = ?", projectID, path).Take(&file).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	contents, err := loadBlobs(db, []string{file.Hash})
+	if err != nil {
+		return nil, err
+	}
+	file.Content = contents[file.Hash]
+	return &file, nil
+}
+
+// Save writes and deletes files and saves the project's index.html and style.css in one transaction
+// 项目行加锁后重新读取，fn 基于最新的 html/css 修改入口文件，期间其他人对入口文件的修改不会被覆盖；
+// 同一项目的并发保存依次执行，内容未变的文件不产生写入，fn 返回错误时整个保存回滚
+func (d *FileDAO) Save(ctx context.Context, projectID uint64, files []model.ProjectFile, deleted []string, fn func(*model.Project) error) (*model.Project, error) {
+	var project model.Project
+	err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		old, err := lockProjectContent(tx, projectID)
+		if err != nil {
+			return err
+		}
+		if err := tx.First(&project, projectID).Error; err != nil {
+			return err
+		}
+		if err := hydrateProjects(tx, &project); err != nil {
+			return err
+		}
+		if err := fn(&project); err != nil {
+			return err
+		}
+
+		for _, path := range deleted {
+			var file model.ProjectFile
+			if err := tx.Where("project_id = ? AND path = ?", project.ID, path).Take(&file).Error; err != nil {
+				if err == gorm.ErrRecordNotFound {
+					continue
+				}
+				return err
+			}
+			if err := tx.Delete(&file).Error; err != nil {
+				return err
+			}
+			if err := releaseBlobs(tx, []string{file.Hash}); err != nil {
+				return err
+			}
+		}
+
+		for i := range files {
+			files[i].ProjectID = project.ID
+			var existing model.ProjectFile
+			err := tx.Where("project_id = ? AND path = ?", project.ID, files[i].Path).Take(&existing).Error
+			if err == gorm.ErrRecordNotFound {
+				if err := createFile(tx, &files[i]); err != nil {
+					return err
+				}
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			hash, err := replaceBlob(tx, existing.Hash, files[i].Content)
+			if err != nil {
+				return err
+			}
+			if hash != existing.Hash {
+				if err := tx.Model(&existing).Updates(map[string]interface{}{
+					"hash": hash,
+					"size": len(files[i].Content),
+				}).Error; err != nil {
+					return err
+				}
+			}
+			files[i].ID, files[i].Hash, files[i].Size = existing.ID, hash, int64(len(files[i].Content))
+			files[i].CreatedAt, files[i].UpdatedAt = existing.CreatedAt, existing.UpdatedAt
+		}
+
+		return saveProjectRow(tx, &project, old)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// createFile 在事务内存储文件内容并创建文件行
+func createFile(tx *gorm.DB, file *model.ProjectFile) error {
+	hash, err := acquireBlob(tx, file.Content)
+	if err != nil {
+		return err
+	}
+	file.Hash, file.Size = hash, int64(len(file.Content))
+	return tx.Create(file).Error
+}
@@ -0,0 +1,86 @@
+package dao
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/database"
+)
+
+type FolderDAO struct{}
+
+func NewFolderDAO() *FolderDAO {
+	return &FolderDAO{}
+}
+
+// GetByID retrieves a folder by ID
+func (d *FolderDAO) GetByID(ctx context.Context, id uint64) (*model.ProjectFolder, error) {
+	var folder model.ProjectFolder
+	if err := database.DB.WithContext(ctx).First(&folder, id).Error; err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// ListByUserID retrieves all folders of a user, ordered by name
+func (d *FolderDAO) ListByUserID(ctx context.Context, userID uint64) ([]model.ProjectFolder, error) {
+	var folders []model.ProjectFolder
+	if err := database.DB.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("name ASC, id ASC").
+		Find(&folders).Error; err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+// CountByUserID counts the folders of a user
+func (d *FolderDAO) CountByUserID(ctx context.Context, userID uint64) (int64, error) {
+	var count int64
+	err := database.DB.WithContext(ctx).Model(&model.ProjectFolder{}).
+		Where("user_id = ?", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// HasChildren checks if a folder contains subfolders
+func (d *FolderDAO) HasChildren(ctx context.Context, id uint64) (bool, error) {
+	var count int64
+	err := database.DB.WithContext(ctx).Model(&model.ProjectFolder{}).
+		Where("parent_id = ?", id).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Create creates a new folder
+func (d *FolderDAO) Create(ctx context.Context, folder *model.ProjectFolder) error {
+	return database.DB.WithContext(ctx).Create(folder).Error
+}
+
+// Update updates an existing folder
+func (d *FolderDAO) Update(ctx context.Context, folder *model.ProjectFolder) error {
+	return database.DB.WithContext(ctx).Save(folder).Error
+}
+
+// Delete deletes a folder and its subfolders, moving their projects (including trashed ones) to the root
+func (d *FolderDAO) Delete(ctx context.Context, id uint64) error {
+	return database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []uint64
+		if err := tx.Model(&model.ProjectFolder{}).
+			Where("id = ? OR parent_id = ?", id, id).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := tx.Unscoped().Model(&model.Project{}).
+			Where("folder_id IN ?", ids).
+			Update("folder_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Delete(&model.ProjectFolder{}).Error
+	})
+}
@@ -0,0 +1,262 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/database"
+)
+
+type GalleryDAO struct{}
+
+func NewGalleryDAO() *GalleryDAO {
+	return &GalleryDAO{}
+}
+
+// 作品库列表排序方式
+const (
+	GallerySortTrending = "trending"
+	GallerySortNew      = "new"
+	GallerySortTop      = "top"
+)
+
+// gallerySortColumns keyset 分页的排序字段，trending 按位置分页
+var gallerySortColumns = map[string]string{
+	GallerySortNew:      "g.listed_at",
+	GallerySortTop:      "g.stars",
+	GallerySortTrending: "g.trending_score",
+}
+
+// GalleryListQuery gallery listing filter, sort and position
+type GalleryListQuery struct {
+	Category   string
+	Sort       string      // trending, new or top
+	AfterValue interface{} // Sort value of the last item of the previous page (new and top)
+	AfterID    uint64      // Project ID of the last item of the previous page, 0 for the first page
+	Offset     int         // Items to skip (trending)
+	Limit      int
+}
+
+// visibleEntries 只包含仍已发布且不在回收站中的项目
+func visibleEntries(db *gorm.DB) *gorm.DB {
+	return db.Table("gallery_entries AS g").
+		Select("g.project_id, p.name, pub.slug, g.description, g.category, g.stars, u.name AS author_name, g.listed_at").
+		Joins("JOIN projects AS p ON p.id = g.project_id AND p.deleted_at IS NULL").
+		Joins("JOIN project_publications AS pub ON pub.project_id = g.project_id").
+		Joins("JOIN users AS u ON u.id = g.user_id")
+}
+
+// List retrieves a page of visible gallery items
+func (d *GalleryDAO) List(ctx context.Context, q *GalleryListQuery) ([]model.GalleryItem, error) {
+	column, ok := gallerySortColumns[q.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unsupported gallery sort %q", q.Sort)
+	}
+
+	db := visibleEntries(database.DB.WithContext(ctx))
+	if q.Category != "" {
+		db = db.Where("g.category = ?", q.Category)
+	}
+	if q.AfterID > 0 {
+		db = db.Where(fmt.Sprintf("(%[1]s < ? OR (%[1]s = ? AND g.project_id < ?))", column),
+			q.AfterValue, q.AfterValue, q.AfterID)
+	}
+
+	items := make([]model.GalleryItem, 0, q.Limit)
+	if err := db.
+		Order(fmt.Sprintf("%s DESC, g.project_id DESC", column)).
+		Offset(q.Offset).
+		Limit(q.Limit).
+		Scan(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// ListByProjectIDs retrieves the visible gallery items among projectIDs, in the given order
+func (d *GalleryDAO) ListByProjectIDs(ctx context.Context, projectIDs []uint64) ([]model.GalleryItem, error) {
+	if len(projectIDs) == 0 {
+		return []model.GalleryItem{}, nil
+	}
+	var found []model.GalleryItem
+	if err := visibleEntries(database.DB.WithContext(ctx)).
+		Where("g.project_id IN ?", projectIDs).
+		Scan(&found).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint64]model.GalleryItem, len(found))
+	for _, item := range found {
+		byID[item.ProjectID] = item
+	}
+	items := make([]model.GalleryItem, 0, len(found))
+	for _, id := range projectIDs {
+		if item, ok := byID[id]; ok {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// Get retrieves the gallery entry of a project, returns nil if it is not listed
+func (d *GalleryDAO) Get(ctx context.Context, projectID uint64) (*model.GalleryEntry, error) {
+	var entry model.GalleryEntry
+	if err := database.DB.WithContext(ctx).Take(&entry, projectID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Save lists a project or updates its category and description, keeping listed_at and stars
+// 新加入时星标数取自已有的收藏（项目可能曾被移出作品库）
+func (d *GalleryDAO) Save(ctx context.Context, entry *model.GalleryEntry) error {
+	return database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.ProjectStar{}).
+			Where("project_id = ?", entry.ProjectID).
+			Count(&entry.Stars).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"category", "description", "updated_at"}),
+		}).Create(entry).Error; err != nil {
+			return err
+		}
+		// 冲突更新时 entry 中的 listed_at 不是库中的值，重新读取
+		return tx.Take(entry, entry.ProjectID).Error
+	})
+}
+
+// Delete removes a project from the gallery, its stars are kept
+func (d *GalleryDAO) Delete(ctx context.Context, projectID uint64) error {
+	return database.DB.WithContext(ctx).Delete(&model.GalleryEntry{}, projectID).Error
+}
+
+// Star adds a user's star, returns nil if the user had already starred the project
+func (d *GalleryDAO) Star(ctx context.Context, projectID, userID uint64) (*model.ProjectStar, error) {
+	star := &model.ProjectStar{ProjectID: projectID, UserID: userID}
+	err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(star)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			star = nil
+			return nil
+		}
+		return tx.Model(&model.GalleryEntry{}).
+			Where("project_id = ?", projectID).
+			UpdateColumn("stars", gorm.Expr("stars + 1")).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return star, nil
+}
+
+// Unstar removes a user's star, returns nil if the user had not starred the project
+func (d *GalleryDAO) Unstar(ctx context.Context, projectID, userID uint64) (*model.ProjectStar, error) {
+	var star *model.ProjectStar
+	err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing model.ProjectStar
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("project_id = ? AND user_id = ?", projectID, userID).
+			Take(&existing).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil
+			}
+			return err
+		}
+		if err := tx.Where("project_id = ? AND user_id = ?", projectID, userID).
+			Delete(&model.ProjectStar{}).Error; err != nil {
+			return err
+		}
+		star = &existing
+		return tx.Model(&model.GalleryEntry{}).
+			Where("project_id = ? AND stars > 0", projectID).
+			UpdateColumn("stars", gorm.Expr("stars - 1")).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return star, nil
+}
+
+// CountStars counts the stars of a project
+func (d *GalleryDAO) CountStars(ctx context.Context, projectID uint64) (int64, error) {
+	var count int64
+	err := database.DB.WithContext(ctx).Model(&model.ProjectStar{}).
+		Where("project_id = ?", projectID).
+		Count(&count).Error
+	return count, err
+}
+
+// StarredAmong returns which of the projects the user starred
+func (d *GalleryDAO) StarredAmong(ctx context.Context, userID uint64, projectIDs []uint64) (map[uint64]bool, error) {
+	starred := make(map[uint64]bool, len(projectIDs))
+	if len(projectIDs) == 0 {
+		return starred, nil
+	}
+	var ids []uint64
+	if err := database.DB.WithContext(ctx).Model(&model.ProjectStar{}).
+		Where("user_id = ? AND project_id IN ?", userID, projectIDs).
+		Pluck("project_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		starred[id] = true
+	}
+	return starred, nil
+}
+
+// ListStarsSince retrieves the stars given to listed projects since a time
+func (d *GalleryDAO) ListStarsSince(ctx context.Context, since time.Time) ([]model.ProjectStar, error) {
+	var stars []model.ProjectStar
+	if err := database.DB.WithContext(ctx).
+		Table("project_stars AS s").
+		Select("s.project_id, s.created_at").
+		Joins("JOIN gallery_entries AS g ON g.project_id = s.project_id").
+		Where("s.created_at >= ?", since).
+		Scan(&stars).Error; err != nil {
+		return nil, err
+	}
+	return stars, nil
+}
+
+// ListVisibleCategories returns the category of every visible gallery entry, keyed by project ID
+func (d *GalleryDAO) ListVisibleCategories(ctx context.Context) (map[uint64]string, error) {
+	var rows []model.GalleryItem
+	if err := visibleEntries(database.DB.WithContext(ctx)).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	categories := make(map[uint64]string, len(rows))
+	for _, r := range rows {
+		categories[r.ProjectID] = r.Category
+	}
+	return categories, nil
+}
+
+// Reconcile recounts stars and stores trending scores, entries missing from scores get 0
+func (d *GalleryDAO) Reconcile(ctx context.Context, scores map[uint64]float64) error {
+	return database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("UPDATE gallery_entries AS g SET g.stars = " +
+			"(SELECT COUNT(*) FROM project_stars AS s WHERE s.project_id = g.project_id), g.trending_score = 0").Error; err != nil {
+			return err
+		}
+		for projectID, score := range scores {
+			if err := tx.Model(&model.GalleryEntry{}).
+				Where("project_id = ?", projectID).
+				UpdateColumn("trending_score", score).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,107 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/database"
+)
+
+// activeGenerationStatuses 尚未结束的任务状态
+var activeGenerationStatuses = []string{model.GenerationPending, model.GenerationGenerating}
+
+type GenerationJobDAO struct{}
+
+func NewGenerationJobDAO() *GenerationJobDAO {
+	return &GenerationJobDAO{}
+}
+
+// Create inserts a generation job
+func (d *GenerationJobDAO) Create(ctx context.Context, job *model.GenerationJob) error {
+	return database.DB.WithContext(ctx).Create(job).Error
+}
+
+// Get retrieves a generation job by ID, nil if it does not exist
+func (d *GenerationJobDAO) Get(ctx context.Context, id string) (*model.GenerationJob, error) {
+	var job model.GenerationJob
+	err := database.DB.WithContext(ctx).Where("id = ?", id).First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Update saves a job that has not ended yet and reports whether it was saved
+// 已结束的任务不会被覆盖，运行实例和超时清理之间只有先完成的一方生效
+func (d *GenerationJobDAO) Update(ctx context.Context, job *model.GenerationJob) (bool, error) {
+	result := database.DB.WithContext(ctx).Model(&model.GenerationJob{}).
+		Where("id = ? AND status IN ?", job.ID, activeGenerationStatuses).
+		Select("provider", "status", "content", "result", "context", "error", "last_seq", "instance", "heartbeat_at", "updated_at", "finished_at").
+		Updates(job)
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountActive counts a user's jobs that have not ended
+func (d *GenerationJobDAO) CountActive(ctx context.Context, userID uint64) (int64, error) {
+	var count int64
+	err := database.DB.WithContext(ctx).Model(&model.GenerationJob{}).
+		Where("user_id = ? AND status IN ?", userID, activeGenerationStatuses).
+		Count(&count).Error
+	return count, err
+}
+
+// Heartbeat records that an instance is still running the given jobs and returns those that ended elsewhere
+// 其他实例取消任务或判定任务中断时只修改任务状态，运行实例通过心跳发现后停止生成
+func (d *GenerationJobDAO) Heartbeat(ctx context.Context, instance string, ids []string, at time.Time) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	db := database.DB.WithContext(ctx)
+	err := db.Model(&model.GenerationJob{}).
+		Where("id IN ? AND instance = ? AND status IN ?", ids, instance, activeGenerationStatuses).
+		Update("heartbeat_at", at).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var active []string
+	if err := db.Model(&model.GenerationJob{}).
+		Where("id IN ? AND status IN ?", ids, activeGenerationStatuses).
+		Pluck("id", &active).Error; err != nil {
+		return nil, err
+	}
+	ended := make([]string, 0)
+	for _, id := range ids {
+		if !slices.Contains(active, id) {
+			ended = append(ended, id)
+		}
+	}
+	return ended, nil
+}
+
+// ListStale retrieves unfinished jobs whose instance has not sent a heartbeat since before
+func (d *GenerationJobDAO) ListStale(ctx context.Context, before time.Time, limit int) ([]model.GenerationJob, error) {
+	var jobs []model.GenerationJob
+	err := database.DB.WithContext(ctx).
+		Where("status IN ? AND heartbeat_at < ?", activeGenerationStatuses, before).
+		Order("heartbeat_at ASC").
+		Limit(limit).
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// DeleteFinishedBefore deletes jobs that ended before the given time and returns how many were deleted
+func (d *GenerationJobDAO) DeleteFinishedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := database.DB.WithContext(ctx).
+		Where("finished_at < ?", before).
+		Delete(&model.GenerationJob{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,69 @@
+package dao
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/database"
+)
+
+type MemberDAO struct{}
+
+func NewMemberDAO() *MemberDAO {
+	return &MemberDAO{}
+}
+
+// Get retrieves a user's membership of a project, returns nil if the user is not a member
+func (d *MemberDAO) Get(ctx context.Context, projectID, userID uint64) (*model.ProjectMember, error) {
+	var member model.ProjectMember
+	if err := database.DB.WithContext(ctx).
+		Where("project_id = ? AND user_id = ?", projectID, userID).
+		First(&member).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+// ListByProjectID retrieves the members of a project with their names, oldest first
+func (d *MemberDAO) ListByProjectID(ctx context.Context, projectID uint64) ([]model.ProjectMemberInfo, error) {
+	var members []model.ProjectMemberInfo
+	if err := database.DB.WithContext(ctx).
+		Table("project_members AS m").
+		Select("m.user_id, u.name, u.email, m.role, m.created_at").
+		Joins("JOIN users AS u ON u.id = m.user_id").
+		Where("m.project_id = ?", projectID).
+		Order("m.created_at ASC, m.user_id ASC").
+		Scan(&members).Error; err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// CountByProjectID counts the members of a project
+func (d *MemberDAO) CountByProjectID(ctx context.Context, projectID uint64) (int64, error) {
+	var count int64
+	err := database.DB.WithContext(ctx).Model(&model.ProjectMember{}).
+		Where("project_id = ?", projectID).
+		Count(&count).Error
+	return count, err
+}
+
+// Upsert adds a member or changes the role of an existing one
+func (d *MemberDAO) Upsert(ctx context.Context, member *model.ProjectMember) error {
+	return database.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{DoUpdates: clause.AssignmentColumns([]string{"role"})}).
+		Create(member).Error
+}
+
+// Delete removes a member from a project
+func (d *MemberDAO) Delete(ctx context.Context, projectID, userID uint64) error {
+	return database.DB.WithContext(ctx).
+		Where("project_id = ? AND user_id = ?", projectID, userID).
+		Delete(&model.ProjectMember{}).Error
+}
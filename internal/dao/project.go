@@ -2,8 +2,12 @@ package dao
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/test-tt/internal/model"
 	"github.com/test-tt/pkg/database"
@@ -18,32 +22,351 @@ func NewProjectDAO() *ProjectDAO {
 // GetByID retrieves a project by ID
 func (d *ProjectDAO) GetByID(ctx context.Context, id uint64) (*model.Project, error) {
 	var project model.Project
-	if err := database.DB.WithContext(ctx).First(&project, id).Error; err != nil {
+	db := database.DB.WithContext(ctx)
+	if err := db.First(&project, id).Error; err != nil {
+		return nil, err
+	}
+	if err := hydrateProjects(db, &project); err != nil {
 		return nil, err
 	}
 	return &project, nil
 }
 
-// GetByUserID retrieves all projects for a user, ordered by updated_at desc
-func (d *ProjectDAO) GetByUserID(ctx context.Context, userID uint64) ([]model.Project, error) {
-	var projects []model.Project
-	if err := database.DB.WithContext(ctx).
-		Where("user_id = ?", userID).
-		Order("updated_at DESC").
-		Find(&projects).Error; err != nil {
+// projectSortColumns 项目列表允许的排序字段
+var projectSortColumns = map[string]struct{}{
+	"updated_at": {},
+	"created_at": {},
+	"name":       {},
+}
+
+// summaryHTMLHeadLen 列表只读取 html 开头部分用于生成缩略文本
+const summaryHTMLHeadLen = 2048
+
+// ProjectListQuery project list filter, sort and keyset position
+type ProjectListQuery struct {
+	UserID     uint64
+	Keyword    string      // Substring match on name
+	FolderID   *uint64     // Only projects in this folder, 0 for projects outside any folder
+	Tag        string      // Only projects with this tag
+	SortBy     string      // updated_at, created_at or name
+	Asc        bool        // Ascending order, default descending
+	AfterValue interface{} // Sort value of the last item of the previous page
+	AfterID    uint64      // ID of the last item of the previous page, 0 for the first page
+	Limit      int
+}
+
+// ValidProjectSort reports whether a project list sort field is supported
+func ValidProjectSort(sortBy string) bool {
+	_, ok := projectSortColumns[sortBy]
+	return ok
+}
+
+// ListSummaries retrieves a page of project summaries using keyset pagination on (sort column, id)
+func (d *ProjectDAO) ListSummaries(ctx context.Context, q *ProjectListQuery) ([]model.ProjectSummary, error) {
+	if !ValidProjectSort(q.SortBy) {
+		q.SortBy = "updated_at"
+	}
+	op, dir := "<", "DESC"
+	if q.Asc {
+		op, dir = ">", "ASC"
+	}
+
+	db := database.DB.WithContext(ctx).Model(&model.Project{}).
+		Select("id", "name", "folder_id", "created_at", "updated_at",
+			"content_size AS size", "html_head").
+		Where("user_id = ?", q.UserID)
+	if q.Keyword != "" {
+		db = db.Where("name LIKE ?", "%"+escapeLike(q.Keyword)+"%")
+	}
+	if q.FolderID != nil {
+		if *q.FolderID == 0 {
+			db = db.Where("folder_id IS NULL")
+		} else {
+			db = db.Where("folder_id = ?", *q.FolderID)
+		}
+	}
+	if q.Tag != "" {
+		db = db.Where("id IN (?)", database.DB.Model(&model.ProjectTag{}).Select("project_id").Where("tag = ?", q.Tag))
+	}
+	if q.AfterID > 0 {
+		db = db.Where(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", q.SortBy, op),
+			q.AfterValue, q.AfterValue, q.AfterID)
+	}
+
+	summaries := make([]model.ProjectSummary, 0, q.Limit)
+	if err := db.
+		Order(fmt.Sprintf("%s %s, id %s", q.SortBy, dir, dir)).
+		Limit(q.Limit).
+		Scan(&summaries).Error; err != nil {
 		return nil, err
 	}
-	return projects, nil
+	return summaries, nil
 }
 
-// Create creates a new project
-func (d *ProjectDAO) Create(ctx context.Context, project *model.Project) error {
-	return database.DB.WithContext(ctx).Create(project).Error
+// escapeLike 转义 LIKE 通配符，关键词按字面匹配
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// Create creates a new project with its extra files, storing all content as blobs
+func (d *ProjectDAO) Create(ctx context.Context, project *model.Project, files ...model.ProjectFile) error {
+	return database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var filesSize int64
+		for _, f := range files {
+			filesSize += int64(len(f.Content))
+		}
+		row, err := storeProjectContent(tx, project, "", "", filesSize)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(row).Error; err != nil {
+			return err
+		}
+		project.ID, project.CreatedAt, project.UpdatedAt = row.ID, row.CreatedAt, row.UpdatedAt
+
+		for i := range files {
+			files[i].ProjectID = project.ID
+			if err := createFile(tx, &files[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 // Update updates an existing project
+// html/css 未变化时不写 blob，变化时换用新 blob 并释放旧的引用
 func (d *ProjectDAO) Update(ctx context.Context, project *model.Project) error {
-	return database.DB.WithContext(ctx).Save(project).Error
+	return database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		old, err := lockProjectContent(tx, project.ID)
+		if err != nil {
+			return err
+		}
+		return saveProjectRow(tx, project, old)
+	})
+}
+
+// UpdateLocked reloads a project under a row lock, lets fn change it and saves it in the same transaction
+// fn 基于锁定时的最新内容修改，返回错误时整个更新回滚；回收站中的项目视为不存在
+func (d *ProjectDAO) UpdateLocked(ctx context.Context, id uint64, fn func(*model.Project) error) (*model.Project, error) {
+	var project model.Project
+	err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		old, err := lockProjectContent(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.First(&project, id).Error; err != nil {
+			return err
+		}
+		if err := hydrateProjects(tx, &project); err != nil {
+			return err
+		}
+		if err := fn(&project); err != nil {
+			return err
+		}
+		return saveProjectRow(tx, &project, old)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// lockProjectContent 锁定项目行并返回其当前引用的 blob
+func lockProjectContent(tx *gorm.DB, id uint64) (*contentRefs, error) {
+	var old contentRefs
+	if err := tx.Unscoped().Model(&model.Project{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("html_hash", "css_hash").
+		Where("id = ?", id).
+		Take(&old).Error; err != nil {
+		return nil, err
+	}
+	return &old, nil
+}
+
+// saveProjectRow 保存已锁定的项目行，内容大小包含其余文件
+func saveProjectRow(tx *gorm.DB, project *model.Project, old *contentRefs) error {
+	var filesSize int64
+	if err := tx.Model(&model.ProjectFile{}).
+		Select("COALESCE(SUM(size), 0)").
+		Where("project_id = ?", project.ID).
+		Scan(&filesSize).Error; err != nil {
+		return err
+	}
+	row, err := storeProjectContent(tx, project, old.HTMLHash, old.CSSHash, filesSize)
+	if err != nil {
+		return err
+	}
+	if err := tx.Save(row).Error; err != nil {
+		return err
+	}
+	project.UpdatedAt = row.UpdatedAt
+	return nil
+}
+
+// contentRefs html/css 列引用的 blob 哈希
+type contentRefs struct {
+	HTMLHash string
+	CSSHash  string
+}
+
+// storeProjectContent 把 html/css 存为 blob 并回填哈希、总大小和缩略文本
+// 返回写库用的副本，其内联 html/css 列置空；调用方的 project 保留完整内容
+func storeProjectContent(tx *gorm.DB, project *model.Project, oldHTMLHash, oldCSSHash string, filesSize int64) (*model.Project, error) {
+	htmlHash, err := replaceBlob(tx, oldHTMLHash, project.HTML)
+	if err != nil {
+		return nil, err
+	}
+	cssHash, err := replaceBlob(tx, oldCSSHash, project.CSS)
+	if err != nil {
+		return nil, err
+	}
+	project.HTMLHash, project.CSSHash = htmlHash, cssHash
+	project.HTMLHead = htmlHead(project.HTML)
+	project.ContentSize = int64(len(project.HTML)+len(project.CSS)) + filesSize
+
+	row := *project
+	row.HTML, row.CSS = "", ""
+	return &row, nil
+}
+
+// htmlHead 截取 html 开头的 summaryHTMLHeadLen 个字符
+func htmlHead(html string) string {
+	n := 0
+	for i := range html {
+		if n == summaryHTMLHeadLen {
+			return html[:i]
+		}
+		n++
+	}
+	return html
+}
+
+// hydrateProjects 从 blob 填充 html/css，尚未迁移的行沿用内联列
+func hydrateProjects(db *gorm.DB, projects ...*model.Project) error {
+	hashes := make([]string, 0, 2*len(projects))
+	for _, p := range projects {
+		hashes = append(hashes, p.HTMLHash, p.CSSHash)
+	}
+	contents, err := loadBlobs(db, hashes)
+	if err != nil {
+		return err
+	}
+	for _, p := range projects {
+		if p.HTMLHash != "" {
+			p.HTML = contents[p.HTMLHash]
+		}
+		if p.CSSHash != "" {
+			p.CSS = contents[p.CSSHash]
+		}
+	}
+	return nil
+}
+
+// deleteProjectsWhere 永久删除匹配的项目（含回收站中的）及其文件和快照，并释放它们引用的 blob
+func deleteProjectsWhere(tx *gorm.DB, query string, args ...interface{}) error {
+	projects := tx.Unscoped().Model(&model.Project{}).Where(query, args...)
+	var refs []contentRefs
+	if err := projects.Session(&gorm.Session{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("html_hash", "css_hash").
+		Scan(&refs).Error; err != nil {
+		return err
+	}
+
+	ids := projects.Session(&gorm.Session{}).Select("id")
+	var fileHashes []string
+	if err := tx.Model(&model.ProjectFile{}).
+		Where("project_id IN (?)", ids).
+		Pluck("hash", &fileHashes).Error; err != nil {
+		return err
+	}
+	var snapshotRefs []contentRefs
+	if err := tx.Model(&model.ProjectSnapshot{}).
+		Select("html_hash", "css_hash").
+		Where("project_id IN (?)", ids).
+		Scan(&snapshotRefs).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("project_id IN (?)", ids).Delete(&model.ProjectFile{}).Error; err != nil {
+		return err
+	}
+	snapshotFileHashes, err := deleteSnapshotFiles(tx, tx.Model(&model.ProjectSnapshot{}).Select("id").Where("project_id IN (?)", ids))
+	if err != nil {
+		return err
+	}
+	// 先删快照，发布记录随外键级联删除
+	if err := tx.Where("project_id IN (?)", ids).Delete(&model.ProjectSnapshot{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where(query, args...).Delete(&model.Project{}).Error; err != nil {
+		return err
+	}
+
+	hashes := make([]string, 0, 2*(len(refs)+len(snapshotRefs))+len(fileHashes)+len(snapshotFileHashes))
+	hashes = append(hashes, fileHashes...)
+	hashes = append(hashes, snapshotFileHashes...)
+	for _, r := range append(refs, snapshotRefs...) {
+		hashes = append(hashes, r.HTMLHash, r.CSSHash)
+	}
+	return releaseBlobs(tx, hashes)
+}
+
+// inlineContentCond 匹配仍把内容存在 html/css 列中的行
+const inlineContentCond = "(html_hash = '' AND html <> '') OR (css_hash = '' AND css <> '')"
+
+// moveInline 把一列内联内容存为 blob，已有哈希或内容为空时不处理
+func moveInline(tx *gorm.DB, hash *string, inline string) error {
+	if *hash != "" || inline == "" {
+		return nil
+	}
+	h, err := acquireBlob(tx, inline)
+	if err != nil {
+		return err
+	}
+	*hash = h
+	return nil
+}
+
+// MigrateInlineContent moves up to limit projects still storing html/css inline into content blobs
+// 只改写内容列（html_head 和 content_size 已由迁移脚本回填），不更新 updated_at；返回迁移的行数
+func (d *ProjectDAO) MigrateInlineContent(ctx context.Context, limit int) (int, error) {
+	var ids []uint64
+	if err := database.DB.WithContext(ctx).Unscoped().Model(&model.Project{}).
+		Where(inlineContentCond).
+		Limit(limit).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	for i, id := range ids {
+		err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var project model.Project
+			if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&project, id).Error; err != nil {
+				if err == gorm.ErrRecordNotFound {
+					return nil
+				}
+				return err
+			}
+			if err := moveInline(tx, &project.HTMLHash, project.HTML); err != nil {
+				return err
+			}
+			if err := moveInline(tx, &project.CSSHash, project.CSS); err != nil {
+				return err
+			}
+			return tx.Unscoped().Model(&model.Project{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+				"html":      "",
+				"css":       "",
+				"html_hash": project.HTMLHash,
+				"css_hash":  project.CSSHash,
+			}).Error
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(ids), nil
 }
 
 // UpdateFields updates specific fields of a project
@@ -51,14 +374,123 @@ func (d *ProjectDAO) UpdateFields(ctx context.Context, id uint64, fields map[str
 	return database.DB.WithContext(ctx).Model(&model.Project{}).Where("id = ?", id).Updates(fields).Error
 }
 
-// Delete deletes a project by ID
+// Delete moves a project to the trash (soft delete)
 func (d *ProjectDAO) Delete(ctx context.Context, id uint64) error {
 	return database.DB.WithContext(ctx).Delete(&model.Project{}, id).Error
 }
 
-// DeleteByUserID deletes all projects for a user
+// HardDelete permanently removes a project, including a trashed one
+func (d *ProjectDAO) HardDelete(ctx context.Context, id uint64) error {
+	return database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return deleteProjectsWhere(tx, "id = ?", id)
+	})
+}
+
+// DeleteByUserID permanently deletes all projects for a user, including trashed ones
 func (d *ProjectDAO) DeleteByUserID(ctx context.Context, userID uint64) error {
-	return database.DB.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.Project{}).Error
+	return database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return deleteProjectsWhere(tx, "user_id = ?", userID)
+	})
+}
+
+// GetTrashedByID retrieves a project in the trash by ID
+func (d *ProjectDAO) GetTrashedByID(ctx context.Context, id uint64) (*model.Project, error) {
+	var project model.Project
+	db := database.DB.WithContext(ctx)
+	if err := db.Unscoped().
+		Where("deleted_at IS NOT NULL").
+		First(&project, id).Error; err != nil {
+		return nil, err
+	}
+	if err := hydrateProjects(db, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// GetTrashedByUserID retrieves all trashed projects for a user, most recently deleted first
+func (d *ProjectDAO) GetTrashedByUserID(ctx context.Context, userID uint64) ([]model.Project, error) {
+	var projects []model.Project
+	db := database.DB.WithContext(ctx)
+	if err := db.Unscoped().
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Order("deleted_at DESC").
+		Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	ptrs := make([]*model.Project, len(projects))
+	for i := range projects {
+		ptrs[i] = &projects[i]
+	}
+	if err := hydrateProjects(db, ptrs...); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// ListTrashedIDsBefore returns up to limit IDs of projects trashed before the cutoff
+func (d *ProjectDAO) ListTrashedIDsBefore(ctx context.Context, cutoff time.Time, limit int) ([]uint64, error) {
+	var ids []uint64
+	if err := database.DB.WithContext(ctx).Unscoped().Model(&model.Project{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Order("deleted_at ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Restore moves a trashed project back out of the trash
+func (d *ProjectDAO) Restore(ctx context.Context, id uint64) error {
+	return database.DB.WithContext(ctx).Unscoped().Model(&model.Project{}).
+		Where("id = ?", id).
+		Update("deleted_at", nil).Error
+}
+
+// ExistsByID checks if a project exists and is not in the trash
+func (d *ProjectDAO) ExistsByID(ctx context.Context, id uint64) (bool, error) {
+	var count int64
+	err := database.DB.WithContext(ctx).Model(&model.Project{}).
+		Where("id = ?", id).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CountByIDsAndUserID counts how many of the given projects belong to a user (trash excluded)
+func (d *ProjectDAO) CountByIDsAndUserID(ctx context.Context, ids []uint64, userID uint64) (int64, error) {
+	var count int64
+	err := database.DB.WithContext(ctx).Model(&model.Project{}).
+		Where("id IN ? AND user_id = ?", ids, userID).
+		Count(&count).Error
+	return count, err
+}
+
+// MoveToFolder sets the folder of several projects, nil moves them to the root
+// 只更新 folder_id，不修改 updated_at
+func (d *ProjectDAO) MoveToFolder(ctx context.Context, ids []uint64, folderID *uint64) error {
+	return database.DB.WithContext(ctx).Model(&model.Project{}).
+		Where("id IN ?", ids).
+		UpdateColumn("folder_id", folderID).Error
+}
+
+// CountByUserID counts the projects of a user (trash excluded)
+func (d *ProjectDAO) CountByUserID(ctx context.Context, userID uint64) (int64, error) {
+	var count int64
+	err := database.DB.WithContext(ctx).Model(&model.Project{}).
+		Where("user_id = ?", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// SumContentSizeByUserID sums the html + css bytes of a user's projects (trash excluded)
+func (d *ProjectDAO) SumContentSizeByUserID(ctx context.Context, userID uint64) (int64, error) {
+	var total int64
+	err := database.DB.WithContext(ctx).Model(&model.Project{}).
+		Select("COALESCE(SUM(content_size), 0)").
+		Where("user_id = ?", userID).
+		Scan(&total).Error
+	return total, err
 }
 
 // ExistsByIDAndUserID checks if a project exists and belongs to a user
@@ -73,7 +505,8 @@ func (d *ProjectDAO) ExistsByIDAndUserID(ctx context.Context, id, userID uint64)
 // GetLatestByUserID retrieves the most recently updated project for a user
 func (d *ProjectDAO) GetLatestByUserID(ctx context.Context, userID uint64) (*model.Project, error) {
 	var project model.Project
-	if err := database.DB.WithContext(ctx).
+	db := database.DB.WithContext(ctx)
+	if err := db.
 		Where("user_id = ?", userID).
 		Order("updated_at DESC").
 		First(&project).Error; err != nil {
@@ -82,5 +515,8 @@ func (d *ProjectDAO) GetLatestByUserID(ctx context.Context, userID uint64) (*mod
 		}
 		return nil, err
 	}
+	if err := hydrateProjects(db, &project); err != nil {
+		return nil, err
+	}
 	return &project, nil
 }
@@ -0,0 +1,97 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/database"
+)
+
+type PromptDAO struct{}
+
+func NewPromptDAO() *PromptDAO {
+	return &PromptDAO{}
+}
+
+// CreateVersion inserts a template as the next version of its name
+// 锁住同名的最新版本，并发创建时版本号依次递增
+func (d *PromptDAO) CreateVersion(ctx context.Context, template *model.PromptTemplate) error {
+	return database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var latest model.PromptTemplate
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "version").
+			Where("name = ?", template.Name).
+			Order("version DESC").
+			First(&latest).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		template.Version = latest.Version + 1
+		return tx.Create(template).Error
+	})
+}
+
+// Get retrieves a template version by ID, nil if it does not exist
+func (d *PromptDAO) Get(ctx context.Context, id uint64) (*model.PromptTemplate, error) {
+	var template model.PromptTemplate
+	err := database.DB.WithContext(ctx).Where("id = ?", id).First(&template).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// List retrieves the versions of a template newest first, all templates when name is empty
+func (d *PromptDAO) List(ctx context.Context, name string) ([]model.PromptTemplate, error) {
+	db := database.DB.WithContext(ctx)
+	if name != "" {
+		db = db.Where("name = ?", name)
+	}
+
+	templates := make([]model.PromptTemplate, 0)
+	if err := db.Order("name ASC, version DESC").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// ListEnabled retrieves the versions of a template with traffic, oldest first
+func (d *PromptDAO) ListEnabled(ctx context.Context, name string) ([]model.PromptTemplate, error) {
+	var templates []model.PromptTemplate
+	err := database.DB.WithContext(ctx).
+		Where("name = ? AND weight > 0", name).
+		Order("version ASC").
+		Find(&templates).Error
+	return templates, err
+}
+
+// UpdateWeight sets the traffic weight of a template version
+func (d *PromptDAO) UpdateWeight(ctx context.Context, id uint64, weight int) error {
+	return database.DB.WithContext(ctx).Model(&model.PromptTemplate{}).
+		Where("id = ?", id).
+		Update("weight", weight).Error
+}
+
+// Stats summarizes the metered generations of each version of a template since a time
+func (d *PromptDAO) Stats(ctx context.Context, name string, since time.Time) ([]model.PromptVariantStats, error) {
+	stats := make([]model.PromptVariantStats, 0)
+	err := database.DB.WithContext(ctx).Model(&model.GenerationUsage{}).
+		Select("prompt_version AS version, COUNT(*) AS generations, "+
+			"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS completed, "+
+			"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS failed, "+
+			"AVG(output_tokens) AS avg_output_tokens, AVG(wall_time_ms) AS avg_wall_time_ms, AVG(credits) AS avg_credits",
+			model.GenerationCompleted, model.GenerationError).
+		Where("prompt_name = ? AND created_at >= ?", name, since).
+		Group("prompt_version").
+		Order("prompt_version ASC").
+		Scan(&stats).Error
+	return stats, err
+}
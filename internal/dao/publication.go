@@ -0,0 +1,68 @@
+package dao
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/database"
+)
+
+type PublicationDAO struct{}
+
+func NewPublicationDAO() *PublicationDAO {
+	return &PublicationDAO{}
+}
+
+// GetByProjectID retrieves the publication of a project, returns nil if the project is not published
+func (d *PublicationDAO) GetByProjectID(ctx context.Context, projectID uint64) (*model.ProjectPublication, error) {
+	var publication model.ProjectPublication
+	if err := database.DB.WithContext(ctx).
+		Where("project_id = ?", projectID).
+		First(&publication).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &publication, nil
+}
+
+// GetBySlug retrieves a publication by its public slug, returns nil if no project uses the slug
+func (d *PublicationDAO) GetBySlug(ctx context.Context, slug string) (*model.ProjectPublication, error) {
+	var publication model.ProjectPublication
+	if err := database.DB.WithContext(ctx).
+		Where("slug = ?", slug).
+		First(&publication).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &publication, nil
+}
+
+// Save creates or updates a publication
+// 唯一索引冲突（slug 已被占用，或同一项目并发首次发布）时返回 gorm.ErrDuplicatedKey
+func (d *PublicationDAO) Save(ctx context.Context, publication *model.ProjectPublication) error {
+	db := database.DB.WithContext(ctx)
+	return translateError(db, db.Save(publication).Error)
+}
+
+// DeleteByProjectID removes the publication of a project
+func (d *PublicationDAO) DeleteByProjectID(ctx context.Context, projectID uint64) error {
+	return database.DB.WithContext(ctx).Where("project_id = ?", projectID).Delete(&model.ProjectPublication{}).Error
+}
+
+// translateError 把数据库驱动的错误码转换为 gorm 错误（如 MySQL 1062 转为 gorm.ErrDuplicatedKey）
+// 连接未开启 TranslateError，需要判断约束冲突的调用方自行转换
+func translateError(db *gorm.DB, err error) error {
+	if err == nil {
+		return nil
+	}
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok {
+		return translator.Translate(err)
+	}
+	return err
+}
@@ -0,0 +1,217 @@
+package dao
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/database"
+)
+
+type SnapshotDAO struct{}
+
+func NewSnapshotDAO() *SnapshotDAO {
+	return &SnapshotDAO{}
+}
+
+// GetByID retrieves a snapshot by ID
+func (d *SnapshotDAO) GetByID(ctx context.Context, id uint64) (*model.ProjectSnapshot, error) {
+	var snapshot model.ProjectSnapshot
+	db := database.DB.WithContext(ctx)
+	if err := db.First(&snapshot, id).Error; err != nil {
+		return nil, err
+	}
+	contents, err := loadBlobs(db, []string{snapshot.HTMLHash, snapshot.CSSHash})
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.HTMLHash != "" {
+		snapshot.HTML = contents[snapshot.HTMLHash]
+	}
+	if snapshot.CSSHash != "" {
+		snapshot.CSS = contents[snapshot.CSSHash]
+	}
+	return &snapshot, nil
+}
+
+// ListFiles retrieves the files a snapshot kept besides index.html and style.css with their content, ordered by path
+func (d *SnapshotDAO) ListFiles(ctx context.Context, snapshotID uint64) ([]model.ProjectFile, error) {
+	db := database.DB.WithContext(ctx)
+	var rows []model.ProjectSnapshotFile
+	if err := db.Where("snapshot_id = ?", snapshotID).Order("path ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, len(rows))
+	for i, r := range rows {
+		hashes[i] = r.Hash
+	}
+	contents, err := loadBlobs(db, hashes)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]model.ProjectFile, len(rows))
+	for i, r := range rows {
+		files[i] = model.ProjectFile{Path: r.Path, Content: contents[r.Hash], Hash: r.Hash, Size: r.Size}
+	}
+	return files, nil
+}
+
+// GetFile retrieves a file a snapshot kept by path, returns nil if it does not exist
+func (d *SnapshotDAO) GetFile(ctx context.Context, snapshotID uint64, path string) (*model.ProjectFile, error) {
+	db := database.DB.WithContext(ctx)
+	var row model.ProjectSnapshotFile
+	if err := db.Where("snapshot_id = ? AND path = ?", snapshotID, path).Take(&row).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	contents, err := loadBlobs(db, []string{row.Hash})
+	if err != nil {
+		return nil, err
+	}
+	return &model.ProjectFile{Path: row.Path, Content: contents[row.Hash], Hash: row.Hash, Size: row.Size}, nil
+}
+
+// HasFiles reports whether a snapshot kept files besides index.html and style.css
+func (d *SnapshotDAO) HasFiles(ctx context.Context, snapshotID uint64) (bool, error) {
+	var ids []uint64
+	if err := database.DB.WithContext(ctx).Model(&model.ProjectSnapshotFile{}).
+		Where("snapshot_id = ?", snapshotID).
+		Limit(1).
+		Pluck("id", &ids).Error; err != nil {
+		return false, err
+	}
+	return len(ids) > 0, nil
+}
+
+// ListByProjectID lists snapshots of a project without their content, newest first
+func (d *SnapshotDAO) ListByProjectID(ctx context.Context, projectID uint64) ([]model.ProjectSnapshotSummary, error) {
+	snapshots := make([]model.ProjectSnapshotSummary, 0)
+	if err := database.DB.WithContext(ctx).
+		Model(&model.ProjectSnapshot{}).
+		Select("id", "project_id", "label", "created_at").
+		Where("project_id = ?", projectID).
+		Order("id DESC").
+		Scan(&snapshots).Error; err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// Create creates a new snapshot, sharing content blobs with the project it was taken from
+func (d *SnapshotDAO) Create(ctx context.Context, snapshot *model.ProjectSnapshot) error {
+	return database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		htmlHash, err := acquireBlob(tx, snapshot.HTML)
+		if err != nil {
+			return err
+		}
+		cssHash, err := acquireBlob(tx, snapshot.CSS)
+		if err != nil {
+			return err
+		}
+		snapshot.HTMLHash, snapshot.CSSHash = htmlHash, cssHash
+
+		row := *snapshot
+		row.HTML, row.CSS, row.Files = "", "", nil
+		if err := tx.Create(&row).Error; err != nil {
+			return err
+		}
+		snapshot.ID, snapshot.CreatedAt = row.ID, row.CreatedAt
+
+		for _, f := range snapshot.Files {
+			hash, err := acquireBlob(tx, f.Content)
+			if err != nil {
+				return err
+			}
+			file := model.ProjectSnapshotFile{SnapshotID: row.ID, Path: f.Path, Hash: hash, Size: int64(len(f.Content))}
+			if err := tx.Create(&file).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteByProjectID deletes all snapshots of a project and releases their content blobs
+func (d *SnapshotDAO) DeleteByProjectID(ctx context.Context, projectID uint64) error {
+	return database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var refs []contentRefs
+		if err := tx.Model(&model.ProjectSnapshot{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("html_hash", "css_hash").
+			Where("project_id = ?", projectID).
+			Scan(&refs).Error; err != nil {
+			return err
+		}
+		fileHashes, err := deleteSnapshotFiles(tx, tx.Model(&model.ProjectSnapshot{}).Select("id").Where("project_id = ?", projectID))
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("project_id = ?", projectID).Delete(&model.ProjectSnapshot{}).Error; err != nil {
+			return err
+		}
+		hashes := make([]string, 0, 2*len(refs)+len(fileHashes))
+		hashes = append(hashes, fileHashes...)
+		for _, r := range refs {
+			hashes = append(hashes, r.HTMLHash, r.CSSHash)
+		}
+		return releaseBlobs(tx, hashes)
+	})
+}
+
+// MigrateInlineContent moves up to limit snapshots still storing html/css inline into content blobs
+func (d *SnapshotDAO) MigrateInlineContent(ctx context.Context, limit int) (int, error) {
+	var ids []uint64
+	if err := database.DB.WithContext(ctx).Model(&model.ProjectSnapshot{}).
+		Where(inlineContentCond).
+		Limit(limit).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	for i, id := range ids {
+		err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var snapshot model.ProjectSnapshot
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&snapshot, id).Error; err != nil {
+				if err == gorm.ErrRecordNotFound {
+					return nil
+				}
+				return err
+			}
+			if err := moveInline(tx, &snapshot.HTMLHash, snapshot.HTML); err != nil {
+				return err
+			}
+			if err := moveInline(tx, &snapshot.CSSHash, snapshot.CSS); err != nil {
+				return err
+			}
+			return tx.Model(&model.ProjectSnapshot{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+				"html":      "",
+				"css":       "",
+				"html_hash": snapshot.HTMLHash,
+				"css_hash":  snapshot.CSSHash,
+			}).Error
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(ids), nil
+}
+
+// deleteSnapshotFiles 删除 snapshotIDs 子查询所选快照的文件行，返回其内容哈希供调用方释放
+func deleteSnapshotFiles(tx *gorm.DB, snapshotIDs *gorm.DB) ([]string, error) {
+	var hashes []string
+	if err := tx.Model(&model.ProjectSnapshotFile{}).
+		Where("snapshot_id IN (?)", snapshotIDs).
+		Pluck("hash", &hashes).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("snapshot_id IN (?)", snapshotIDs).Delete(&model.ProjectSnapshotFile{}).Error; err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
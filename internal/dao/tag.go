@@ -0,0 +1,91 @@
+package dao
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/database"
+)
+
+type TagDAO struct{}
+
+func NewTagDAO() *TagDAO {
+	return &TagDAO{}
+}
+
+// ListByProjectIDs retrieves the tags of several projects, keyed by project ID
+func (d *TagDAO) ListByProjectIDs(ctx context.Context, projectIDs []uint64) (map[uint64][]string, error) {
+	result := make(map[uint64][]string, len(projectIDs))
+	if len(projectIDs) == 0 {
+		return result, nil
+	}
+
+	var tags []model.ProjectTag
+	if err := database.DB.WithContext(ctx).
+		Where("project_id IN ?", projectIDs).
+		Order("tag ASC").
+		Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	for _, t := range tags {
+		result[t.ProjectID] = append(result[t.ProjectID], t.Tag)
+	}
+	return result, nil
+}
+
+// CountByUserID lists the tags used by a user's projects (trash excluded) with usage counts
+func (d *TagDAO) CountByUserID(ctx context.Context, userID uint64) ([]model.TagCount, error) {
+	var counts []model.TagCount
+	if err := database.DB.WithContext(ctx).
+		Table("project_tags AS t").
+		Select("t.tag AS tag, COUNT(*) AS count").
+		Joins("JOIN projects AS p ON p.id = t.project_id").
+		Where("p.user_id = ? AND p.deleted_at IS NULL", userID).
+		Group("t.tag").
+		Order("t.tag ASC").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// Replace sets the tags of a project, removing any not in the list
+func (d *TagDAO) Replace(ctx context.Context, projectID uint64, tags []string) error {
+	return database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("project_id = ?", projectID).Delete(&model.ProjectTag{}).Error; err != nil {
+			return err
+		}
+		return createTags(tx, []uint64{projectID}, tags)
+	})
+}
+
+// Add adds tags to several projects, ignoring tags they already have
+func (d *TagDAO) Add(ctx context.Context, projectIDs []uint64, tags []string) error {
+	return createTags(database.DB.WithContext(ctx), projectIDs, tags)
+}
+
+// Remove removes tags from several projects
+func (d *TagDAO) Remove(ctx context.Context, projectIDs []uint64, tags []string) error {
+	if len(projectIDs) == 0 || len(tags) == 0 {
+		return nil
+	}
+	return database.DB.WithContext(ctx).
+		Where("project_id IN ? AND tag IN ?", projectIDs, tags).
+		Delete(&model.ProjectTag{}).Error
+}
+
+func createTags(db *gorm.DB, projectIDs []uint64, tags []string) error {
+	if len(projectIDs) == 0 || len(tags) == 0 {
+		return nil
+	}
+	rows := make([]model.ProjectTag, 0, len(projectIDs)*len(tags))
+	for _, id := range projectIDs {
+		for _, tag := range tags {
+			rows = append(rows, model.ProjectTag{ProjectID: id, Tag: tag})
+		}
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
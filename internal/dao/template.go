@@ -0,0 +1,59 @@
+package dao
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/database"
+)
+
+type TemplateDAO struct{}
+
+func NewTemplateDAO() *TemplateDAO {
+	return &TemplateDAO{}
+}
+
+// GetByKey retrieves a curated template by key, returns nil if not found
+func (d *TemplateDAO) GetByKey(ctx context.Context, key string) (*model.ProjectTemplate, error) {
+	var template model.ProjectTemplate
+	if err := database.DB.WithContext(ctx).Where("`key` = ?", key).First(&template).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+// ListSummaries lists curated templates without content
+func (d *TemplateDAO) ListSummaries(ctx context.Context, includeDisabled bool) ([]model.ProjectTemplate, error) {
+	templates := make([]model.ProjectTemplate, 0)
+	query := database.DB.WithContext(ctx).
+		Select("id", "`key`", "name", "description", "category", "sort", "enabled", "created_at", "updated_at").
+		Order("sort ASC, id ASC")
+	if !includeDisabled {
+		query = query.Where("enabled = ?", true)
+	}
+	if err := query.Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// Create creates a curated template
+func (d *TemplateDAO) Create(ctx context.Context, template *model.ProjectTemplate) error {
+	return database.DB.WithContext(ctx).Create(template).Error
+}
+
+// Update saves a curated template
+func (d *TemplateDAO) Update(ctx context.Context, template *model.ProjectTemplate) error {
+	return database.DB.WithContext(ctx).Save(template).Error
+}
+
+// DeleteByKey deletes a curated template by key
+func (d *TemplateDAO) DeleteByKey(ctx context.Context, key string) (bool, error) {
+	result := database.DB.WithContext(ctx).Where("`key` = ?", key).Delete(&model.ProjectTemplate{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,170 @@
+package generation
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/bytedance/sonic"
+)
+
+const (
+	anthropicBaseURL = "https://api.anthropic.com"
+	anthropicVersion = "2023-06-01"
+	// 单个 SSE 数据行的上限，足够容纳任何一个增量片段
+	maxStreamLineSize = 1 << 20
+)
+
+// AnthropicProvider calls the Anthropic Messages API with streaming
+type AnthropicProvider struct {
+	apiKey  string
+	baseURL string
+	model   string
+	client  *http.Client
+}
+
+// NewAnthropicProvider creates an Anthropic provider, an empty baseURL uses the public API
+func NewAnthropicProvider(apiKey, baseURL, model string) *AnthropicProvider {
+	if baseURL == "" {
+		baseURL = anthropicBaseURL
+	}
+	return &AnthropicProvider{
+		apiKey:  apiKey,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   model,
+		// 生成时间由调用方的 ctx 控制，不设置整体超时
+		client: &http.Client{},
+	}
+}
+
+func (p *AnthropicProvider) Name() string {
+	return "anthropic"
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	System    string             `json:"system,omitempty"`
+	Messages  []anthropicMessage `json:"messages"`
+	Stream    bool               `json:"stream"`
+	Thinking  *anthropicThinking `json:"thinking,omitempty"`
+}
+
+// anthropicEvent 流式响应中我们关心的字段
+type anthropicEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type     string `json:"type"`
+		Text     string `json:"text"`
+		Thinking string `json:"thinking"`
+	} `json:"delta"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+	// message_start 携带输入用量，message_delta 携带累计的输出用量
+	Message struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	Usage anthropicUsage `json:"usage"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+}
+
+func (p *AnthropicProvider) Stream(ctx context.Context, req *Request, onDelta func(Delta)) (*Completion, error) {
+	body := anthropicRequest{
+		Model:     p.model,
+		MaxTokens: req.MaxTokens,
+		System:    req.System,
+		Messages:  []anthropicMessage{{Role: "user", Content: req.Prompt}},
+		Stream:    true,
+	}
+	if req.ThinkingTokens > 0 {
+		body.Thinking = &anthropicThinking{Type: "enabled", BudgetTokens: req.ThinkingTokens}
+	}
+	data, err := sonic.Marshal(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v1/messages", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set("x-api-key", p.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %v", ErrProviderUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return nil, fmt.Errorf("%w: status %d: %s", ErrProviderUnavailable, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	var output strings.Builder
+	var usage Usage
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), maxStreamLineSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		var event anthropicEvent
+		if err := sonic.Unmarshal(bytes.TrimSpace(line[len("data:"):]), &event); err != nil {
+			continue
+		}
+		switch event.Type {
+		case "message_start":
+			usage.InputTokens = event.Message.Usage.InputTokens
+			usage.OutputTokens = event.Message.Usage.OutputTokens
+		case "message_delta":
+			usage.OutputTokens = event.Usage.OutputTokens
+		case "content_block_delta":
+			switch event.Delta.Type {
+			case "text_delta":
+				output.WriteString(event.Delta.Text)
+				onDelta(Delta{Kind: DeltaText, Text: event.Delta.Text})
+			case "thinking_delta":
+				onDelta(Delta{Kind: DeltaThinking, Text: event.Delta.Thinking})
+			}
+		case "error":
+			return &Completion{Usage: usage}, fmt.Errorf("%w: %s: %s", ErrProviderUnavailable, event.Error.Type, event.Error.Message)
+		case "message_stop":
+			return &Completion{Text: output.String(), Usage: usage}, nil
+		}
+	}
+	if ctx.Err() != nil {
+		return &Completion{Usage: usage}, ctx.Err()
+	}
+	if err := scanner.Err(); err != nil {
+		return &Completion{Usage: usage}, fmt.Errorf("%w: %v", ErrProviderUnavailable, err)
+	}
+	return &Completion{Usage: usage}, fmt.Errorf("%w: stream ended before message_stop", ErrProviderUnavailable)
+}
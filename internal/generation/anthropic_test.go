@@ -0,0 +1,64 @@
+package generation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bytedance/sonic"
+)
+
+// TestAnthropicProvider_Stream tests request encoding and stream parsing against a local server
+func TestAnthropicProvider_Stream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" || r.Header.Get("x-api-key") != "key" || r.Header.Get("anthropic-version") == "" {
+			http.Error(w, `{"type":"error"}`, http.StatusUnauthorized)
+			return
+		}
+		var body anthropicRequest
+		if err := sonic.ConfigDefault.NewDecoder(r.Body).Decode(&body); err != nil || !body.Stream || body.Thinking == nil || body.Thinking.BudgetTokens != 100 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":12,\"output_tokens\":1}}}\n\n")
+		fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"thinking_delta\",\"thinking\":\"hmm\"}}\n\n")
+		fmt.Fprint(w, "event: ping\ndata: {\"type\":\"ping\"}\n\n")
+		for _, part := range []string{"<p>", body.Messages[0].Content, "</p>"} {
+			data, _ := sonic.MarshalString(part)
+			fmt.Fprintf(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"text_delta\",\"text\":%s}}\n\n", data)
+		}
+		fmt.Fprint(w, "event: message_delta\ndata: {\"type\":\"message_delta\",\"usage\":{\"output_tokens\":9}}\n\n")
+		fmt.Fprint(w, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+	}))
+	defer server.Close()
+
+	var thinking, text strings.Builder
+	p := NewAnthropicProvider("key", server.URL+"/", "model")
+	output, err := p.Stream(context.Background(), &Request{Prompt: "hi", MaxTokens: 1000, ThinkingTokens: 100}, func(d Delta) {
+		if d.Kind == DeltaThinking {
+			thinking.WriteString(d.Text)
+		} else {
+			text.WriteString(d.Text)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if output.Text != "<p>hi</p>" || text.String() != output.Text || thinking.String() != "hmm" {
+		t.Errorf("Stream() = %q, text %q, thinking %q", output.Text, text.String(), thinking.String())
+	}
+	if output.Usage != (Usage{InputTokens: 12, OutputTokens: 9}) {
+		t.Errorf("Stream() usage = %+v, want 12 in 9 out", output.Usage)
+	}
+
+	p = NewAnthropicProvider("wrong", server.URL, "model")
+	if _, err := p.Stream(context.Background(), &Request{Prompt: "hi", MaxTokens: 1000}, func(Delta) {}); !errors.Is(err, ErrProviderUnavailable) {
+		t.Errorf("Stream() with a bad key error = %v, want %v", err, ErrProviderUnavailable)
+	}
+}
@@ -0,0 +1,316 @@
+package generation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/logger"
+)
+
+const (
+	// generationSummaryKey 早期对话摘要的缓存，按 provider 和对话内容的哈希区分
+	generationSummaryKey = "generation:summary:%s"
+	// summaryChunkTurns 每段摘要覆盖的消息条数；分段按消息在整个对话中的位置对齐，对话变长时已有分段的摘要仍可复用
+	summaryChunkTurns = 10
+	// maxSummaryMessageLen 生成摘要时每条消息的截断长度
+	maxSummaryMessageLen = 4000
+	maxMemorySummaries   = 1000
+
+	// RequestSummary 概括早期消息的请求类型，其余请求类型与提示词模板名称相同
+	RequestSummary = "summary"
+)
+
+// summarySystemPrompt 概括早期对话的系统提示词，修改后旧的缓存自然失效
+const summarySystemPrompt = `You summarize the earlier part of a conversation between a user and a web page designer.
+
+Keep everything the designer needs to continue the work:
+- What the page is about and who it is for
+- Every change the user asked for, in order, and whether it was done
+- Design decisions: colors, fonts, layout, sections, animations
+- Anything the user asked to keep, remove or avoid
+
+Write plain text in short sentences, at most 200 words. Do not include HTML or CSS code.
+Respond with ONLY the summary.`
+
+// Tokenizer counts the tokens of a text for context budgeting
+type Tokenizer interface {
+	Name() string
+	Count(text string) int64
+}
+
+// EstimateTokenizer counts tokens with EstimateTokens, for providers without a tokenizer
+type EstimateTokenizer struct{}
+
+func (EstimateTokenizer) Name() string {
+	return "estimate"
+}
+
+func (EstimateTokenizer) Count(text string) int64 {
+	return EstimateTokens(text)
+}
+
+// SummaryCache keeps the summaries of earlier conversation turns
+type SummaryCache interface {
+	// Get 返回缓存的摘要，不存在时返回空字符串
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, summary string) error
+}
+
+// redisSummaryCache 多实例共享的摘要缓存
+type redisSummaryCache struct {
+	rdb *redis.Client
+	ttl time.Duration
+}
+
+// NewRedisSummaryCache creates a summary cache on Redis keeping each summary for ttl
+func NewRedisSummaryCache(rdb *redis.Client, ttl time.Duration) SummaryCache {
+	return &redisSummaryCache{rdb: rdb, ttl: ttl}
+}
+
+func (c *redisSummaryCache) Get(ctx context.Context, key string) (string, error) {
+	summary, err := c.rdb.Get(ctx, fmt.Sprintf(generationSummaryKey, key)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return summary, err
+}
+
+func (c *redisSummaryCache) Set(ctx context.Context, key, summary string) error {
+	return c.rdb.Set(ctx, fmt.Sprintf(generationSummaryKey, key), summary, c.ttl).Err()
+}
+
+type memorySummary struct {
+	text    string
+	expires time.Time
+}
+
+// memorySummaryCache 单实例部署（没有 Redis）时使用的摘要缓存，条数有上限
+type memorySummaryCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	summaries map[string]memorySummary
+}
+
+// NewMemorySummaryCache creates a summary cache kept in process memory
+func NewMemorySummaryCache(ttl time.Duration) SummaryCache {
+	return &memorySummaryCache{ttl: ttl, summaries: make(map[string]memorySummary)}
+}
+
+func (c *memorySummaryCache) Get(_ context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.summaries[key]
+	if !ok || time.Now().After(s.expires) {
+		return "", nil
+	}
+	return s.text, nil
+}
+
+func (c *memorySummaryCache) Set(_ context.Context, key, summary string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if len(c.summaries) >= maxMemorySummaries {
+		for k, s := range c.summaries {
+			if now.After(s.expires) {
+				delete(c.summaries, k)
+			}
+		}
+		// 仍然已满时随机淘汰一条
+		for k := range c.summaries {
+			if len(c.summaries) < maxMemorySummaries {
+				break
+			}
+			delete(c.summaries, k)
+		}
+	}
+	c.summaries[key] = memorySummary{text: summary, expires: now.Add(c.ttl)}
+	return nil
+}
+
+// ContextBuilder fits the conversation of an input into the context budget of a request
+// 当前页面和本次请求总是完整带入；最近的消息原样带入，放不下的早期消息通过 provider 概括，摘要按分段缓存
+type ContextBuilder struct {
+	provider      LLMProvider
+	tokenizer     Tokenizer
+	cache         SummaryCache
+	budget        int64 // 请求的输入 token 上限，0 表示不限制
+	summaryTokens int   // 每段摘要的最大输出 token
+}
+
+// NewContextBuilder creates a context builder, a nil tokenizer counts with EstimateTokens
+func NewContextBuilder(provider LLMProvider, tokenizer Tokenizer, cache SummaryCache, budget, summaryTokens int) *ContextBuilder {
+	if tokenizer == nil {
+		tokenizer = EstimateTokenizer{}
+	}
+	return &ContextBuilder{
+		provider:      provider,
+		tokenizer:     tokenizer,
+		cache:         cache,
+		budget:        int64(budget),
+		summaryTokens: summaryTokens,
+	}
+}
+
+// Fit returns a copy of the input whose history fits the budget, how the history was cut,
+// and the tokens spent summarizing earlier turns
+func (b *ContextBuilder) Fit(ctx context.Context, in *Input, system string) (*Input, *model.GenerationContext, Usage) {
+	var spent Usage
+	report := &model.GenerationContext{
+		Tokenizer:    b.tokenizer.Name(),
+		Budget:       b.budget,
+		PageTokens:   b.tokenizer.Count(in.CurrentHTML),
+		HistoryTurns: len(in.History),
+		LimitedTurns: in.Skipped,
+	}
+	out := *in
+	out.History, out.Summary = nil, ""
+
+	fixed := b.tokenizer.Count(system) + b.tokenizer.Count(BuildRequest(&out, 0, 0).Prompt)
+	lines := make([]int64, len(in.History))
+	var total int64
+	for i, msg := range in.History {
+		lines[i] = b.tokenizer.Count(historyLine(msg))
+		total += lines[i]
+	}
+	if b.budget <= 0 || fixed+total <= b.budget {
+		out.History = in.History
+		report.IncludedTurns = len(in.History)
+		report.TruncatedTurns = truncatedTurns(in.History)
+		return &out, report, spent
+	}
+
+	remaining := b.budget - fixed
+	if remaining <= 0 {
+		report.OverBudget = true
+		report.DroppedTurns = len(in.History)
+		return &out, report, spent
+	}
+	// 为摘要预留空间，其余按从新到旧的顺序放入原样的消息
+	summaryBudget := min(int64(b.summaryTokens), remaining/2)
+	remaining -= summaryBudget
+	cut := len(in.History)
+	for cut > 0 && lines[cut-1] <= remaining {
+		remaining -= lines[cut-1]
+		cut--
+	}
+	out.History = in.History[cut:]
+	report.IncludedTurns = len(out.History)
+	report.TruncatedTurns = truncatedTurns(out.History)
+
+	// 早期消息按在整个对话中的位置分段，从最新的分段开始放入摘要，放不下的更早分段舍弃
+	older := in.History[:cut]
+	summaryBudget += remaining
+	var summaries []string
+	end := len(older)
+	for end > 0 {
+		start := max(0, end-((in.Skipped+end-1)%summaryChunkTurns+1))
+		chunk := older[start:end]
+		end = start
+		if ctx.Err() != nil {
+			report.DroppedTurns += len(chunk)
+			continue
+		}
+
+		summary, cached, usage, err := b.summarize(ctx, chunk)
+		spent.InputTokens += usage.InputTokens
+		spent.OutputTokens += usage.OutputTokens
+		if err != nil {
+			logger.WarnCtxf(ctx, "failed to summarize earlier conversation", "turns", len(chunk), "error", err)
+			report.DroppedTurns += len(chunk)
+			continue
+		}
+		tokens := b.tokenizer.Count(summary)
+		if tokens > summaryBudget {
+			report.DroppedTurns += len(chunk) + end
+			break
+		}
+		summaryBudget -= tokens
+		summaries = append([]string{summary}, summaries...)
+		report.SummarizedTurns += len(chunk)
+		if cached {
+			report.CachedSummaries++
+		}
+	}
+	out.Summary = strings.Join(summaries, "\n\n")
+	return &out, report, spent
+}
+
+// summarize 概括一段消息，优先使用缓存；缓存读写失败不影响生成
+func (b *ContextBuilder) summarize(ctx context.Context, turns []Message) (string, bool, Usage, error) {
+	var conversation strings.Builder
+	for _, msg := range turns {
+		role := "User"
+		if msg.Role == RoleAssistant {
+			role = "Assistant"
+		}
+		content := msg.Content
+		if runes := []rune(content); len(runes) > maxSummaryMessageLen {
+			content = string(runes[:maxSummaryMessageLen]) + "..."
+		}
+		fmt.Fprintf(&conversation, "%s: %s\n", role, content)
+	}
+
+	sum := sha256.Sum256([]byte(b.provider.Name() + "\x00" + summarySystemPrompt + "\x00" + conversation.String()))
+	key := hex.EncodeToString(sum[:])
+	if b.cache != nil {
+		summary, err := b.cache.Get(ctx, key)
+		if err != nil {
+			logger.WarnCtxf(ctx, "failed to read conversation summary cache", "error", err)
+		} else if summary != "" {
+			return summary, true, Usage{}, nil
+		}
+	}
+
+	req := &Request{
+		Kind:      RequestSummary,
+		System:    summarySystemPrompt,
+		Prompt:    "Conversation to summarize:\n\n" + conversation.String(),
+		MaxTokens: b.summaryTokens,
+	}
+	completion, err := b.provider.Stream(ctx, req, func(Delta) {})
+	var usage Usage
+	if completion != nil {
+		usage = completion.Usage
+	}
+	if err != nil {
+		return "", false, usage, err
+	}
+	if usage.InputTokens == 0 {
+		usage.InputTokens = EstimateTokens(req.System) + EstimateTokens(req.Prompt)
+	}
+	if usage.OutputTokens == 0 {
+		usage.OutputTokens = EstimateTokens(completion.Text)
+	}
+	summary := strings.TrimSpace(completion.Text)
+	if summary == "" {
+		return "", false, usage, errors.New("empty summary")
+	}
+
+	if b.cache != nil {
+		if err := b.cache.Set(ctx, key, summary); err != nil {
+			logger.WarnCtxf(ctx, "failed to cache conversation summary", "error", err)
+		}
+	}
+	return summary, false, usage, nil
+}
+
+// truncatedTurns 统计带入上下文时被截断的消息条数
+func truncatedTurns(history []Message) int {
+	n := 0
+	for _, msg := range history {
+		if len([]rune(msg.Content)) > maxHistoryMessageLen {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,152 @@
+package generation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// wordTokenizer 按单词计数，便于精确构造预算
+type wordTokenizer struct{}
+
+func (wordTokenizer) Name() string {
+	return "words"
+}
+
+func (wordTokenizer) Count(text string) int64 {
+	return int64(len(strings.Fields(text)))
+}
+
+// summaryProvider 每次返回 10 个单词的摘要并记录被概括的第一条消息
+type summaryProvider struct {
+	mu    sync.Mutex
+	calls []string
+	err   error
+}
+
+func (p *summaryProvider) Name() string {
+	return "summary"
+}
+
+func (p *summaryProvider) Stream(_ context.Context, req *Request, _ func(Delta)) (*Completion, error) {
+	first := strings.Fields(strings.TrimPrefix(req.Prompt, "Conversation to summarize:"))[1]
+	p.mu.Lock()
+	p.calls = append(p.calls, first)
+	p.mu.Unlock()
+	if p.err != nil {
+		return &Completion{Usage: Usage{InputTokens: 5}}, p.err
+	}
+	return &Completion{Text: "summary from " + first + " a b c d e f g", Usage: Usage{InputTokens: 100, OutputTokens: 10}}, nil
+}
+
+// turns 生成 n 条每条 10 个单词的历史消息，编号从 from 开始
+func turns(from, n int) []Message {
+	history := make([]Message, n)
+	for i := range history {
+		role := RoleUser
+		if i%2 == 1 {
+			role = RoleAssistant
+		}
+		history[i] = Message{Role: role, Content: fmt.Sprintf("m%02d a b c d e f g h", from+i)}
+	}
+	return history
+}
+
+// fixedTokens 当前页面、请求和系统提示词的 token 数
+func fixedTokens(in *Input) int64 {
+	base := *in
+	base.History = nil
+	req := BuildRequest(&base, 0, 0)
+	return wordTokenizer{}.Count(req.System) + wordTokenizer{}.Count(req.Prompt)
+}
+
+// TestContextBuilder_Fits tests that a conversation within the budget is kept as it is
+func TestContextBuilder_Fits(t *testing.T) {
+	provider := &summaryProvider{}
+	in := &Input{Prompt: "add a footer", CurrentHTML: "<p>page</p>", History: append(turns(0, 3), Message{Role: RoleUser, Content: strings.Repeat("x", 600)})}
+	b := NewContextBuilder(provider, wordTokenizer{}, NewMemorySummaryCache(time.Hour), int(fixedTokens(in))+40, 20)
+
+	out, report, spent := b.Fit(context.Background(), in, modifySystemPrompt)
+	if len(out.History) != 4 || out.Summary != "" || len(provider.calls) != 0 || spent != (Usage{}) {
+		t.Errorf("Fit() = %d turns, summary %q, %d calls", len(out.History), out.Summary, len(provider.calls))
+	}
+	if report.Tokenizer != "words" || report.IncludedTurns != 4 || report.TruncatedTurns != 1 || report.DroppedTurns != 0 || report.PageTokens != 1 {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+// TestContextBuilder_Summarize tests that older turns are summarized in aligned chunks, cached and dropped when they do not fit
+func TestContextBuilder_Summarize(t *testing.T) {
+	ctx := context.Background()
+	provider := &summaryProvider{}
+	cache := NewMemorySummaryCache(time.Hour)
+
+	// 前 3 条消息已被 max_history 去掉，剩余的消息在整个对话中的位置是 3..27
+	in := &Input{Prompt: "add a footer", CurrentHTML: "<p>page</p>", History: turns(3, 25), Skipped: 3}
+	// 摘要预留 25，原样放入最近的 5 条消息
+	budget := int(fixedTokens(in)) + 25 + 50
+	b := NewContextBuilder(provider, wordTokenizer{}, cache, budget, 25)
+
+	out, report, spent := b.Fit(ctx, in, modifySystemPrompt)
+	if len(out.History) != 5 || out.History[0].Content != "m23 a b c d e f g h" {
+		t.Fatalf("included history = %+v", out.History)
+	}
+	// 早期消息按 20..22、10..19、3..9 分段，只放得下前两段的摘要
+	if got := strings.Join(provider.calls, ","); got != "m20,m10,m03" {
+		t.Errorf("summarized chunks = %s", got)
+	}
+	if out.Summary != "summary from m10 a b c d e f g\n\nsummary from m20 a b c d e f g" {
+		t.Errorf("summary = %q", out.Summary)
+	}
+	if report.IncludedTurns != 5 || report.SummarizedTurns != 13 || report.DroppedTurns != 7 || report.LimitedTurns != 3 || report.CachedSummaries != 0 {
+		t.Errorf("report = %+v", report)
+	}
+	if spent.InputTokens != 300 || spent.OutputTokens != 30 {
+		t.Errorf("summary usage = %+v", spent)
+	}
+	req := BuildRequest(out, 0, 0)
+	if !strings.Contains(req.Prompt, "Summary of the earlier conversation:\nsummary from m10") || !strings.Contains(req.Prompt, "<p>page</p>") {
+		t.Errorf("request prompt = %q", req.Prompt)
+	}
+	tok := wordTokenizer{}
+	if tokens := tok.Count(req.System) + tok.Count(req.Prompt); tokens > int64(budget)+10 {
+		t.Errorf("request tokens = %d, budget %d", tokens, budget)
+	}
+
+	// 对话变长后，已经完整的分段复用缓存
+	provider.calls = nil
+	in = &Input{Prompt: "add a footer", CurrentHTML: "<p>page</p>", History: turns(3, 27), Skipped: 3}
+	out, report, _ = b.Fit(ctx, in, modifySystemPrompt)
+	if got := strings.Join(provider.calls, ","); got != "m20" {
+		t.Errorf("summarized chunks = %s, want only the new one", got)
+	}
+	if out.History[0].Content != "m25 a b c d e f g h" || report.CachedSummaries != 1 || report.SummarizedTurns != 15 {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+// TestContextBuilder_Failures tests the budget taken by the page alone and failing summaries
+func TestContextBuilder_Failures(t *testing.T) {
+	ctx := context.Background()
+	provider := &summaryProvider{err: errors.New("upstream exploded")}
+	in := &Input{Prompt: "add a footer", CurrentHTML: strings.Repeat("<p>page</p> ", 50), History: turns(0, 20)}
+
+	b := NewContextBuilder(provider, wordTokenizer{}, nil, int(fixedTokens(in))-1, 10)
+	out, report, _ := b.Fit(ctx, in, modifySystemPrompt)
+	if !report.OverBudget || report.DroppedTurns != 20 || len(out.History) != 0 || out.CurrentHTML != in.CurrentHTML {
+		t.Errorf("over budget report = %+v", report)
+	}
+
+	b = NewContextBuilder(provider, wordTokenizer{}, nil, int(fixedTokens(in))+10+30, 10)
+	out, report, spent := b.Fit(ctx, in, modifySystemPrompt)
+	if len(out.History) != 3 || out.Summary != "" || report.DroppedTurns != 17 || report.SummarizedTurns != 0 {
+		t.Errorf("failed summary report = %+v, %d turns", report, len(out.History))
+	}
+	if spent.InputTokens != 10 {
+		t.Errorf("usage of failed summaries = %+v", spent)
+	}
+}
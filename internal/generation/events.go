@@ -0,0 +1,201 @@
+package generation
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// generationEventsKey 每个任务一个 Stream，条目 ID 为 <seq>-0，续读时直接从客户端的 Last-Event-ID 开始
+	generationEventsKey = "generation:events:%s"
+	eventField          = "e"
+	maxEventsPerRead    = 500
+)
+
+// EventLog carries the progress events of jobs between API instances
+type EventLog interface {
+	// Append 追加一个事件，event.Seq 由调用方按顺序分配
+	Append(ctx context.Context, jobID string, event *Event) error
+	// Read 返回序号大于 after 的事件；没有新事件时最多等待 wait，wait 为 0 时立即返回
+	Read(ctx context.Context, jobID string, after int64, wait time.Duration) ([]Event, error)
+	// Last 返回最新事件的序号，没有事件时返回 0
+	Last(ctx context.Context, jobID string) (int64, error)
+	// Expire 设置任务事件的保留时间
+	Expire(ctx context.Context, jobID string, ttl time.Duration) error
+}
+
+// redisEventLog 基于 Redis Stream 的事件日志，任何实例都可以读取任何任务的事件
+type redisEventLog struct {
+	rdb *redis.Client
+	// maxLife 事件的最长保留时间，防止运行实例崩溃后 Stream 永久残留
+	maxLife time.Duration
+}
+
+// NewRedisEventLog creates an event log on Redis streams
+func NewRedisEventLog(rdb *redis.Client, maxLife time.Duration) EventLog {
+	return &redisEventLog{rdb: rdb, maxLife: maxLife}
+}
+
+func (l *redisEventLog) Append(ctx context.Context, jobID string, event *Event) error {
+	data, err := sonic.MarshalString(event)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf(generationEventsKey, jobID)
+	pipe := l.rdb.Pipeline()
+	pipe.XAdd(ctx, &redis.XAddArgs{
+		Stream: key,
+		ID:     strconv.FormatInt(event.Seq, 10) + "-0",
+		Values: map[string]interface{}{eventField: data},
+	})
+	if event.Seq == 1 {
+		pipe.Expire(ctx, key, l.maxLife)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (l *redisEventLog) Read(ctx context.Context, jobID string, after int64, wait time.Duration) ([]Event, error) {
+	key := fmt.Sprintf(generationEventsKey, jobID)
+	var messages []redis.XMessage
+	if wait > 0 {
+		streams, err := l.rdb.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{key, strconv.FormatInt(after, 10) + "-0"},
+			Count:   maxEventsPerRead,
+			Block:   wait,
+		}).Result()
+		if err == redis.Nil {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range streams {
+			messages = append(messages, s.Messages...)
+		}
+	} else {
+		var err error
+		messages, err = l.rdb.XRange(ctx, key, strconv.FormatInt(after+1, 10)+"-0", "+").Result()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	events := make([]Event, 0, len(messages))
+	for _, msg := range messages {
+		data, _ := msg.Values[eventField].(string)
+		var event Event
+		if err := sonic.UnmarshalString(data, &event); err != nil {
+			return nil, fmt.Errorf("invalid generation event %s: %w", msg.ID, err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (l *redisEventLog) Last(ctx context.Context, jobID string) (int64, error) {
+	messages, err := l.rdb.XRevRangeN(ctx, fmt.Sprintf(generationEventsKey, jobID), "+", "-", 1).Result()
+	if err != nil || len(messages) == 0 {
+		return 0, err
+	}
+	seq, _, _ := strings.Cut(messages[0].ID, "-")
+	return strconv.ParseInt(seq, 10, 64)
+}
+
+func (l *redisEventLog) Expire(ctx context.Context, jobID string, ttl time.Duration) error {
+	return l.rdb.Expire(ctx, fmt.Sprintf(generationEventsKey, jobID), ttl).Err()
+}
+
+// memoryStream 一个任务在内存中的事件
+type memoryStream struct {
+	events []Event
+	// changed 在每次追加事件时关闭并替换，用于唤醒所有等待中的读者
+	changed chan struct{}
+}
+
+// memoryEventLog 单实例部署（没有 Redis）时使用的事件日志
+type memoryEventLog struct {
+	mu      sync.Mutex
+	streams map[string]*memoryStream
+}
+
+// NewMemoryEventLog creates an event log kept in process memory
+func NewMemoryEventLog() EventLog {
+	return &memoryEventLog{streams: make(map[string]*memoryStream)}
+}
+
+func (l *memoryEventLog) stream(jobID string) *memoryStream {
+	s, ok := l.streams[jobID]
+	if !ok {
+		s = &memoryStream{changed: make(chan struct{})}
+		l.streams[jobID] = s
+	}
+	return s
+}
+
+func (l *memoryEventLog) Append(_ context.Context, jobID string, event *Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := l.stream(jobID)
+	s.events = append(s.events, *event)
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return nil
+}
+
+func (l *memoryEventLog) Read(ctx context.Context, jobID string, after int64, wait time.Duration) ([]Event, error) {
+	var timeout <-chan time.Time
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	for {
+		l.mu.Lock()
+		s := l.stream(jobID)
+		var events []Event
+		for _, e := range s.events {
+			if e.Seq > after {
+				events = append(events, e)
+			}
+		}
+		changed := s.changed
+		l.mu.Unlock()
+
+		if len(events) > 0 || wait <= 0 {
+			return events, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout:
+			return nil, nil
+		case <-changed:
+		}
+	}
+}
+
+func (l *memoryEventLog) Last(_ context.Context, jobID string) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if s, ok := l.streams[jobID]; ok && len(s.events) > 0 {
+		return s.events[len(s.events)-1].Seq, nil
+	}
+	return 0, nil
+}
+
+func (l *memoryEventLog) Expire(_ context.Context, jobID string, ttl time.Duration) error {
+	time.AfterFunc(ttl, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.streams, jobID)
+	})
+	return nil
+}
@@ -0,0 +1,133 @@
+package generation
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"strings"
+	"time"
+)
+
+// fakePalettes 根据提示词选择配色，同一提示词总是得到同一页面
+var fakePalettes = [][3]string{
+	{"#1d3557", "#f1faee", "#e63946"},
+	{"#2b2d42", "#edf2f4", "#ef233c"},
+	{"#283618", "#fefae0", "#dda15e"},
+	{"#03071e", "#f8f9fa", "#ffba08"},
+}
+
+// FakeProvider returns a deterministic page for each prompt without calling any service
+// 用于开发环境和测试：输出只取决于提示词，可设置片段大小和延迟模拟流式生成
+type FakeProvider struct {
+	ChunkSize int           // 每个文本片段的字节数
+	Delay     time.Duration // 片段之间的延迟
+	Err       error         // 非空时在输出前返回该错误
+}
+
+// NewFakeProvider creates a fake provider streaming 256-byte chunks without delay
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{ChunkSize: 256}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) Stream(ctx context.Context, req *Request, onDelta func(Delta)) (*Completion, error) {
+	if p.Err != nil {
+		return nil, p.Err
+	}
+	title := fakeTitle(req.Prompt)
+
+	if req.ThinkingTokens > 0 {
+		onDelta(Delta{Kind: DeltaThinking, Text: "Planning a page for: " + title})
+	}
+
+	output := FakePage(req.Prompt)
+	switch {
+	case req.System == summarySystemPrompt:
+		output = "Earlier the user asked for: " + fakeTitle(strings.TrimPrefix(req.Prompt, "Conversation to summarize:"))
+	case strings.Contains(req.Prompt, selectedElementHeading):
+		output = FakeFragment(req.Prompt)
+	}
+	usage := Usage{InputTokens: EstimateTokens(req.System) + EstimateTokens(req.Prompt)}
+	size := p.ChunkSize
+	if size <= 0 {
+		size = len(output)
+	}
+	for start := 0; start < len(output); start += size {
+		if p.Delay > 0 {
+			select {
+			case <-ctx.Done():
+				return &Completion{Usage: usage}, ctx.Err()
+			case <-time.After(p.Delay):
+			}
+		} else if err := ctx.Err(); err != nil {
+			return &Completion{Usage: usage}, err
+		}
+		end := start + size
+		if end > len(output) {
+			end = len(output)
+		}
+		onDelta(Delta{Kind: DeltaText, Text: output[start:end]})
+		usage.OutputTokens += EstimateTokens(output[start:end])
+	}
+	return &Completion{Text: output, Usage: usage}, nil
+}
+
+// FakePage is the output of FakeProvider for a prompt, wrapped in a markdown code block like a real model
+func FakePage(prompt string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(prompt))
+	palette := fakePalettes[h.Sum32()%uint32(len(fakePalettes))]
+	title := html.EscapeString(fakeTitle(prompt))
+
+	return fmt.Sprintf("```html\n"+`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>%[1]s</title>
+    <style>
+        :root { --ink: %[2]s; --paper: %[3]s; --accent: %[4]s; }
+        * { margin: 0; padding: 0; box-sizing: border-box; }
+        body { font-family: Georgia, serif; background: var(--paper); color: var(--ink); }
+        header { padding: 96px 24px; text-align: center; border-bottom: 4px solid var(--accent); }
+        h1 { font-size: 48px; margin-bottom: 16px; }
+    </style>
+</head>
+<body>
+    <header>
+        <h1>%[1]s</h1>
+        <p>Generated offline by the fake provider.</p>
+    </header>
+</body>
+</html>`+"\n```", title, palette[0], palette[1], palette[2])
+}
+
+// FakeFragment is the output of FakeProvider when only a selected element is regenerated
+func FakeFragment(prompt string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(prompt))
+	palette := fakePalettes[h.Sum32()%uint32(len(fakePalettes))]
+
+	return fmt.Sprintf("```html\n"+`<section class="fake-fragment" style="background: %s; color: %s;">
+    <h2>Regenerated offline by the fake provider.</h2>
+</section>`+"\n```", palette[0], palette[1])
+}
+
+// fakeTitle 取提示词的第一行作为标题
+func fakeTitle(prompt string) string {
+	title := strings.TrimSpace(prompt)
+	if i := strings.IndexByte(title, '\n'); i >= 0 {
+		title = strings.TrimSpace(title[:i])
+	}
+	if runes := []rune(title); len(runes) > 60 {
+		title = string(runes[:60])
+	}
+	if title == "" {
+		title = "Untitled"
+	}
+	return title
+}
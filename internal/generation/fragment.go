@@ -0,0 +1,458 @@
+package generation
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	maxTargetLength = 500
+	// maxContextCSS、maxContextSibling 带入上下文的样式表和相邻元素的截断长度
+	maxContextCSS     = 8000
+	maxContextSibling = 1000
+)
+
+var (
+	ErrTargetWithoutPage = errors.New("target requires current_html")
+	ErrTargetInvalid     = errors.New("target must be a CSS selector or element path such as main > section:nth-of-type(2)")
+	ErrTargetNotFound    = errors.New("target matches no element of the page")
+	ErrTargetAmbiguous   = errors.New("target matches more than one element of the page")
+	ErrTargetUnsupported = errors.New("target must be an element inside the page body with an end tag")
+	ErrFragmentInvalid   = errors.New("the model returned an element that is not well-formed, please try again")
+)
+
+// impliedEnd 开始这些标签时隐式结束栈顶的元素
+var impliedEnd = map[string]map[string]struct{}{
+	"li":     {"li": {}},
+	"dt":     {"dt": {}, "dd": {}},
+	"dd":     {"dt": {}, "dd": {}},
+	"option": {"option": {}},
+	"tr":     {"tr": {}, "td": {}, "th": {}},
+	"td":     {"td": {}, "th": {}},
+	"th":     {"td": {}, "th": {}},
+	"p": {"address": {}, "article": {}, "aside": {}, "blockquote": {}, "details": {}, "div": {}, "dl": {},
+		"fieldset": {}, "figcaption": {}, "figure": {}, "footer": {}, "form": {}, "h1": {}, "h2": {}, "h3": {},
+		"h4": {}, "h5": {}, "h6": {}, "header": {}, "hr": {}, "main": {}, "nav": {}, "ol": {}, "p": {},
+		"pre": {}, "section": {}, "table": {}, "ul": {}},
+}
+
+// untargetable 不能单独重新生成的元素
+var untargetable = map[string]struct{}{
+	"html": {}, "head": {}, "body": {}, "script": {}, "style": {}, "title": {}, "meta": {}, "link": {},
+}
+
+var fragmentBlockPattern = regexp.MustCompile("(?s)```(?:html)?\\s*(.*?)```")
+
+// element 按标记流还原的元素树节点，记录元素在源码中的字节范围，用于原样替换
+type element struct {
+	tag      string
+	attrs    map[string]string
+	parent   *element
+	children []*element
+	start    int
+	end      int
+	closed   bool // 有显式的结束标签
+}
+
+// parseElements 还原元素树，根节点是虚拟的；同时返回结构问题，片段校验时使用
+func parseElements(src string) (*element, []string) {
+	root := &element{end: len(src), closed: true}
+	stack := []*element{root}
+	var problems []string
+	z := html.NewTokenizer(strings.NewReader(src))
+	pos := 0
+	for {
+		tt := z.Next()
+		start := pos
+		pos += len(z.Raw())
+		top := stack[len(stack)-1]
+
+		switch tt {
+		case html.ErrorToken:
+			if err := z.Err(); !errors.Is(err, io.EOF) {
+				problems = append(problems, err.Error())
+			}
+			for i := len(stack) - 1; i > 0; i-- {
+				stack[i].end = len(src)
+				if _, optional := optionalEndElements[stack[i].tag]; !optional {
+					problems = append(problems, "unclosed <"+stack[i].tag+">")
+				}
+			}
+			return root, problems
+
+		case html.DoctypeToken:
+			problems = append(problems, "doctype")
+
+		case html.TextToken:
+			if top == root && strings.TrimSpace(string(z.Raw())) != "" {
+				problems = append(problems, "text outside an element")
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			e := &element{tag: string(name), attrs: make(map[string]string), start: start}
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				e.attrs[string(key)] = string(val)
+			}
+			for len(stack) > 1 {
+				if _, ok := impliedEnd[stack[len(stack)-1].tag][e.tag]; !ok {
+					break
+				}
+				stack[len(stack)-1].end = start
+				stack = stack[:len(stack)-1]
+			}
+			parent := stack[len(stack)-1]
+			e.parent = parent
+			parent.children = append(parent.children, e)
+			if _, void := voidElements[e.tag]; void || tt == html.SelfClosingTagToken {
+				e.end, e.closed = pos, true
+				continue
+			}
+			stack = append(stack, e)
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if _, void := voidElements[tag]; void {
+				continue
+			}
+			i := len(stack) - 1
+			for i > 0 && stack[i].tag != tag {
+				i--
+			}
+			if i == 0 {
+				problems = append(problems, "stray </"+tag+">")
+				continue
+			}
+			for _, open := range stack[i+1:] {
+				open.end = start
+				if _, optional := optionalEndElements[open.tag]; !optional {
+					problems = append(problems, "unclosed <"+open.tag+">")
+				}
+			}
+			stack[i].end, stack[i].closed = pos, true
+			stack = stack[:i]
+		}
+	}
+}
+
+// selectorPart 选择器中的一个复合选择器，combinator 是它与左侧部分的关系：' ' 后代，'>' 子元素
+type selectorPart struct {
+	combinator byte
+	tag        string
+	id         string
+	classes    []string
+	attrs      []attrMatch
+	nthChild   int
+	nthOfType  int
+	lastChild  bool
+}
+
+type attrMatch struct {
+	name  string
+	value string
+	exact bool // 为 false 时只要求属性存在
+}
+
+var (
+	selectorIdentPattern  = regexp.MustCompile(`^-?[_a-zA-Z][_a-zA-Z0-9-]*`)
+	selectorAttrPattern   = regexp.MustCompile(`^\[\s*([_a-zA-Z][_a-zA-Z0-9:-]*)\s*(?:=\s*(?:"([^"]*)"|'([^']*)'|([^\]\s]+))\s*)?\]`)
+	selectorPseudoPattern = regexp.MustCompile(`^:(nth-child|nth-of-type)\(\s*(\d+)\s*\)|^:(first-child|last-child|first-of-type)`)
+)
+
+// parseSelector 解析 CSS 选择器或元素路径，支持标签、#id、.class、[attr]、[attr=value]、
+// :nth-child(n)、:nth-of-type(n)、:first-child、:last-child、:first-of-type 以及后代和子元素组合
+func parseSelector(s string) ([]selectorPart, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || len(s) > maxTargetLength {
+		return nil, ErrTargetInvalid
+	}
+	var parts []selectorPart
+	combinator := byte(' ')
+	for s != "" {
+		part := selectorPart{combinator: combinator}
+		matched := false
+		if s[0] == '*' {
+			s, matched = s[1:], true
+		} else if tag := selectorIdentPattern.FindString(s); tag != "" {
+			part.tag, s, matched = strings.ToLower(tag), s[len(tag):], true
+		}
+	simple:
+		for s != "" {
+			switch s[0] {
+			case '#', '.':
+				ident := selectorIdentPattern.FindString(s[1:])
+				if ident == "" {
+					return nil, ErrTargetInvalid
+				}
+				if s[0] == '#' {
+					part.id = ident
+				} else {
+					part.classes = append(part.classes, ident)
+				}
+				s = s[1+len(ident):]
+			case '[':
+				m := selectorAttrPattern.FindStringSubmatch(s)
+				if m == nil {
+					return nil, ErrTargetInvalid
+				}
+				attr := attrMatch{name: strings.ToLower(m[1])}
+				if strings.Contains(m[0], "=") {
+					attr.exact, attr.value = true, m[2]+m[3]+m[4]
+				}
+				part.attrs = append(part.attrs, attr)
+				s = s[len(m[0]):]
+			case ':':
+				m := selectorPseudoPattern.FindStringSubmatch(s)
+				if m == nil {
+					return nil, ErrTargetInvalid
+				}
+				n, _ := strconv.Atoi(m[2])
+				if m[1] != "" && n < 1 {
+					return nil, ErrTargetInvalid
+				}
+				switch m[1] + m[3] {
+				case "nth-child":
+					part.nthChild = n
+				case "nth-of-type":
+					part.nthOfType = n
+				case "first-child":
+					part.nthChild = 1
+				case "first-of-type":
+					part.nthOfType = 1
+				case "last-child":
+					part.lastChild = true
+				}
+				s = s[len(m[0]):]
+			default:
+				break simple
+			}
+			matched = true
+		}
+		if !matched {
+			return nil, ErrTargetInvalid
+		}
+		parts = append(parts, part)
+
+		rest := strings.TrimLeft(s, " \t\n")
+		combinator = ' '
+		if strings.HasPrefix(rest, ">") {
+			combinator = '>'
+			rest = strings.TrimLeft(rest[1:], " \t\n")
+		} else if len(rest) == len(s) && rest != "" {
+			return nil, ErrTargetInvalid
+		}
+		if rest == "" && combinator == '>' {
+			return nil, ErrTargetInvalid
+		}
+		s = rest
+	}
+	return parts, nil
+}
+
+// matches 判断元素是否满足单个复合选择器
+func (p *selectorPart) matches(e *element) bool {
+	if e.parent == nil || (p.tag != "" && p.tag != e.tag) {
+		return false
+	}
+	if p.id != "" && e.attrs["id"] != p.id {
+		return false
+	}
+	classes := strings.Fields(e.attrs["class"])
+	for _, c := range p.classes {
+		found := false
+		for _, have := range classes {
+			if have == c {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, a := range p.attrs {
+		v, ok := e.attrs[a.name]
+		if !ok || (a.exact && v != a.value) {
+			return false
+		}
+	}
+
+	siblings := e.parent.children
+	if p.lastChild && siblings[len(siblings)-1] != e {
+		return false
+	}
+	if p.nthChild > 0 && (p.nthChild > len(siblings) || siblings[p.nthChild-1] != e) {
+		return false
+	}
+	if p.nthOfType > 0 {
+		n := 0
+		for _, s := range siblings {
+			if s.tag == e.tag {
+				n++
+			}
+			if s == e {
+				break
+			}
+		}
+		if n != p.nthOfType {
+			return false
+		}
+	}
+	return true
+}
+
+// matchSelector 从右向左匹配整个选择器
+func matchSelector(parts []selectorPart, e *element) bool {
+	last := len(parts) - 1
+	if !parts[last].matches(e) {
+		return false
+	}
+	if last == 0 {
+		return true
+	}
+	if parts[last].combinator == '>' {
+		return e.parent != nil && matchSelector(parts[:last], e.parent)
+	}
+	for a := e.parent; a != nil; a = a.parent {
+		if matchSelector(parts[:last], a) {
+			return true
+		}
+	}
+	return false
+}
+
+// Target is the element of a page selected for regeneration
+type Target struct {
+	page string
+	root *element
+	node *element
+}
+
+// FindTarget locates the single element of page matched by a CSS selector or element path
+func FindTarget(page, selector string) (*Target, error) {
+	if strings.TrimSpace(page) == "" {
+		return nil, ErrTargetWithoutPage
+	}
+	parts, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	root, _ := parseElements(page)
+	var found []*element
+	var walk func(e *element)
+	walk = func(e *element) {
+		for _, c := range e.children {
+			if matchSelector(parts, c) {
+				found = append(found, c)
+			}
+			walk(c)
+		}
+	}
+	walk(root)
+
+	switch {
+	case len(found) == 0:
+		return nil, ErrTargetNotFound
+	case len(found) > 1:
+		return nil, fmt.Errorf("%w (%d elements)", ErrTargetAmbiguous, len(found))
+	}
+	node := found[0]
+	if _, ok := untargetable[node.tag]; ok || !node.closed {
+		return nil, ErrTargetUnsupported
+	}
+	return &Target{page: page, root: root, node: node}, nil
+}
+
+// HTML returns the source of the target element
+func (t *Target) HTML() string {
+	return t.page[t.node.start:t.node.end]
+}
+
+// Path describes where the target is, such as html > body > main#menu > section.dishes
+func (t *Target) Path() string {
+	var names []string
+	for e := t.node; e != nil && e.parent != nil; e = e.parent {
+		name := e.tag
+		if id := e.attrs["id"]; id != "" {
+			name += "#" + id
+		}
+		for _, c := range strings.Fields(e.attrs["class"]) {
+			name += "." + c
+		}
+		names = append([]string{name}, names...)
+	}
+	return strings.Join(names, " > ")
+}
+
+// CSS returns the style blocks of the page, so that the rewrite can reuse its variables and classes
+func (t *Target) CSS() string {
+	var parts []string
+	var walk func(e *element)
+	walk = func(e *element) {
+		for _, c := range e.children {
+			if c.tag == "style" && c.closed {
+				src := t.page[c.start:c.end]
+				if open := strings.IndexByte(src, '>'); open >= 0 {
+					if close := strings.LastIndex(src, "</"); close > open {
+						parts = append(parts, strings.TrimSpace(src[open+1:close]))
+					}
+				}
+			}
+			walk(c)
+		}
+	}
+	walk(t.root)
+	return truncate(strings.Join(parts, "\n\n"), maxContextCSS)
+}
+
+// Siblings returns the source of the elements right before and after the target, empty when there is none
+func (t *Target) Siblings() (before, after string) {
+	siblings := t.node.parent.children
+	for i, s := range siblings {
+		if s != t.node {
+			continue
+		}
+		if i > 0 {
+			before = truncate(t.page[siblings[i-1].start:siblings[i-1].end], maxContextSibling)
+		}
+		if i < len(siblings)-1 {
+			after = truncate(t.page[siblings[i+1].start:siblings[i+1].end], maxContextSibling)
+		}
+	}
+	return before, after
+}
+
+// Splice replaces the target with the element in a model response and returns the new page
+// 回复必须是一个结构完整的元素，页面的其余部分保持原样
+func (t *Target) Splice(response string) (string, error) {
+	fragment := strings.TrimSpace(response)
+	if m := fragmentBlockPattern.FindStringSubmatch(fragment); m != nil {
+		fragment = strings.TrimSpace(m[1])
+	}
+
+	root, problems := parseElements(fragment)
+	if len(problems) > 0 || len(root.children) != 1 {
+		return "", ErrFragmentInvalid
+	}
+	if _, ok := untargetable[root.children[0].tag]; ok {
+		return "", ErrFragmentInvalid
+	}
+	return t.page[:t.node.start] + fragment + t.page[t.node.end:], nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "\n..."
+}
@@ -0,0 +1,126 @@
+package generation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const targetPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<title>Bakery</title>
+<style>:root { --accent: #e63946; }</style>
+</head>
+<body>
+<header id="top" class="hero dark"><h1>Bakery</h1></header>
+<main>
+  <section class="menu"><h2>Bread</h2><ul><li>Rye<li>Sourdough</ul></section>
+  <section class="menu special" data-kind="cake"><h2>Cake</h2><p>Daily
+  <img src="cake.png" alt="cake"></section>
+  <section><h2>Contact</h2></section>
+</main>
+</body>
+</html>`
+
+// TestFindTarget tests the supported selectors and element paths
+func TestFindTarget(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     string // 选中元素源码的开头
+		err      error
+	}{
+		{"#top", `<header id="top"`, nil},
+		{"header.hero.dark > h1", "<h1>Bakery", nil},
+		{"html > body > main > section:nth-of-type(2)", `<section class="menu special"`, nil},
+		{"main section:nth-child(3)", "<section><h2>Contact", nil},
+		{"section:last-child h2", "<h2>Contact", nil},
+		{`[data-kind="cake"] > h2`, "<h2>Cake", nil},
+		{`[data-kind='cake'] p`, "", ErrTargetUnsupported},
+		{"section.special img", `<img src="cake.png"`, nil},
+		{"ul > li:first-child", "<li>Rye", ErrTargetUnsupported},
+		{"section.menu", "", ErrTargetAmbiguous},
+		{"footer", "", ErrTargetNotFound},
+		{"main > section:nth-of-type(9)", "", ErrTargetNotFound},
+		{"body", "", ErrTargetUnsupported},
+		{"section:hover", "", ErrTargetInvalid},
+		{"main >", "", ErrTargetInvalid},
+		{"li:nth-child(0)", "", ErrTargetInvalid},
+		{"", "", ErrTargetInvalid},
+	}
+	for _, tt := range tests {
+		target, err := FindTarget(targetPage, tt.selector)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("FindTarget(%q) error = %v, want %v", tt.selector, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("FindTarget(%q) error = %v", tt.selector, err)
+			continue
+		}
+		if got := target.HTML(); !strings.HasPrefix(got, tt.want) {
+			t.Errorf("FindTarget(%q) = %.40q, want %q...", tt.selector, got, tt.want)
+		}
+	}
+
+	if _, err := FindTarget("  ", "main"); !errors.Is(err, ErrTargetWithoutPage) {
+		t.Errorf("FindTarget() without page error = %v, want %v", err, ErrTargetWithoutPage)
+	}
+}
+
+// TestTarget_Context tests the context sent with the selected element
+func TestTarget_Context(t *testing.T) {
+	target, err := FindTarget(targetPage, "section.special")
+	if err != nil {
+		t.Fatalf("FindTarget() error = %v", err)
+	}
+	if got := target.HTML(); !strings.HasSuffix(got, `alt="cake"></section>`) {
+		t.Errorf("HTML() = %q, want the section up to its end tag", got)
+	}
+	if got := target.Path(); got != "html > body > main > section.menu.special" {
+		t.Errorf("Path() = %q", got)
+	}
+	if got := target.CSS(); got != ":root { --accent: #e63946; }" {
+		t.Errorf("CSS() = %q", got)
+	}
+	before, after := target.Siblings()
+	if !strings.HasPrefix(before, "<section class=\"menu\"><h2>Bread") || after != "<section><h2>Contact</h2></section>" {
+		t.Errorf("Siblings() = %q, %q", before, after)
+	}
+}
+
+// TestTarget_Splice tests that a well-formed element replaces the target and anything else is rejected
+func TestTarget_Splice(t *testing.T) {
+	target, err := FindTarget(targetPage, "main > section:nth-of-type(2)")
+	if err != nil {
+		t.Fatalf("FindTarget() error = %v", err)
+	}
+
+	fragment := `<section class="menu special"><style>.special { color: var(--accent); }</style><h2>Cakes</h2><br></section>`
+	page, err := target.Splice("Here you go:\n```html\n" + fragment + "\n```")
+	if err != nil {
+		t.Fatalf("Splice() error = %v", err)
+	}
+	want := strings.Replace(targetPage, target.HTML(), fragment, 1)
+	if page != want {
+		t.Errorf("Splice() = %q, want only the section replaced", page)
+	}
+
+	rejected := []string{
+		"",
+		"<section><h2>Cakes</section>",
+		"<section><h2>Cakes</h2></section></div>",
+		"<section></section><section></section>",
+		"Cakes <section></section>",
+		"<!DOCTYPE html><section></section>",
+		"<body><section></section></body>",
+		"<section><p>a</span></section>",
+	}
+	for _, response := range rejected {
+		if _, err := target.Splice(response); !errors.Is(err, ErrFragmentInvalid) {
+			t.Errorf("Splice(%q) error = %v, want %v", response, err, ErrFragmentInvalid)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package generation
+
+import (
+	"time"
+
+	"github.com/test-tt/internal/model"
+)
+
+// EventType is the type of a progress event
+type EventType string
+
+const (
+	EventStatus   EventType = "status"   // 状态变化
+	EventQueued   EventType = "queued"   // 排队位置变化
+	EventThinking EventType = "thinking" // 思考过程片段
+	EventContent  EventType = "content"  // 输出片段
+	EventComplete EventType = "complete" // 生成完成，带结果
+	EventError    EventType = "error"    // 生成失败，带错误信息
+	EventCanceled EventType = "canceled" // 被用户取消
+)
+
+// Event is one progress update of a job, numbered from 1 in order
+type Event struct {
+	Seq      int64                   `json:"seq"`
+	Type     EventType               `json:"type"`
+	Status   string                  `json:"status,omitempty"`
+	Position int                     `json:"position,omitempty"` // Place in the queue of a queued event, 1 is next
+	Delta    string                  `json:"delta,omitempty"`
+	Result   *model.GenerationResult `json:"result,omitempty"`
+	Error    string                  `json:"error,omitempty"`
+	Time     time.Time               `json:"time"`
+}
+
+// Final reports whether the event ends the job's stream
+func (e *Event) Final() bool {
+	return e.Type == EventComplete || e.Type == EventError || e.Type == EventCanceled
+}
+
+// finalEvent 由任务的最终状态构造结束事件，用于事件已过期或结束事件未能写入时
+func finalEvent(job *model.GenerationJob) *Event {
+	event := &Event{Seq: job.LastSeq, Status: job.Status, Time: job.UpdatedAt}
+	switch job.Status {
+	case model.GenerationCompleted:
+		event.Type = EventComplete
+		event.Result = job.Result
+	case model.GenerationCanceled:
+		event.Type = EventCanceled
+		event.Error = job.Error
+	default:
+		event.Type = EventError
+		event.Error = job.Error
+	}
+	return event
+}
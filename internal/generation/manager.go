@@ -0,0 +1,928 @@
+package generation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/google/uuid"
+
+	"github.com/test-tt/config"
+	"github.com/test-tt/internal/dao"
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/internal/service"
+	"github.com/test-tt/pkg/cache"
+	"github.com/test-tt/pkg/database"
+	"github.com/test-tt/pkg/logger"
+)
+
+const (
+	// heartbeatInterval 运行实例刷新心跳的间隔
+	heartbeatInterval = 10 * time.Second
+	// staleAfter 超过该时间没有心跳的任务视为运行实例已退出
+	staleAfter     = time.Minute
+	staleBatchSize = 100
+	purgeInterval  = 10 * time.Minute
+	// subscribeWait 订阅时每次等待新事件的时间，超时后检查任务是否已在别处结束
+	subscribeWait = 5 * time.Second
+	// finishTimeout 任务结束时写入状态的超时，不受任务自身 ctx 影响
+	finishTimeout = 10 * time.Second
+	// shutdownWait 停止时等待运行中任务写入中断状态的时间
+	shutdownWait = 5 * time.Second
+)
+
+var (
+	ErrJobNotFound    = errors.New("generation job not found")
+	ErrTooManyJobs    = errors.New("too many generations in progress")
+	ErrPromptEmpty    = errors.New("prompt is required")
+	ErrPromptTooLong  = errors.New("prompt is too long")
+	ErrEmptyGenerated = errors.New("the model returned no page")
+	ErrJobFinished    = errors.New("generation job has already ended")
+
+	// errInterrupted 实例停止或退出导致任务中断
+	errInterrupted = errors.New("generation was interrupted, please try again")
+	// errCanceled 用户取消了任务
+	errCanceled = errors.New("generation was canceled")
+	// errQueueTimeout 排队时间过长
+	errQueueTimeout = errors.New("too many generations are waiting, please try again later")
+)
+
+// Options limits and budgets of a manager
+type Options struct {
+	MaxTokens         int
+	ThinkingTokens    int
+	Timeout           time.Duration // 单个任务的最长生成时间
+	EventTTL          time.Duration // 任务结束后进度事件保留多久
+	Retention         time.Duration // 任务结束后记录保留多久
+	MaxActivePerUser  int
+	Workers           int           // 本实例同时运行的任务数
+	MaxRunningPerUser int           // 每个用户在本实例同时运行的任务数
+	QueueTimeout      time.Duration // 最长排队时间
+	MaxPromptLength   int
+	MaxPageBytes      int // 质量报告提示页面过大的阈值
+	MaxHistory        int
+	ContextBudget     int           // 单次请求的输入 token 上限，0 表示不限制
+	SummaryTokens     int           // 每段早期消息摘要的最大输出 token
+	SummaryTTL        time.Duration // 早期消息摘要的缓存时间
+}
+
+// OptionsFromConfig converts the generation configuration, nil uses the defaults
+func OptionsFromConfig(cfg *config.GenerationConfig) Options {
+	if cfg == nil {
+		return Options{
+			MaxTokens:         16000,
+			ThinkingTokens:    8000,
+			Timeout:           5 * time.Minute,
+			EventTTL:          30 * time.Minute,
+			Retention:         30 * 24 * time.Hour,
+			MaxActivePerUser:  2,
+			Workers:           8,
+			MaxRunningPerUser: 1,
+			QueueTimeout:      5 * time.Minute,
+			MaxPromptLength:   10000,
+			MaxPageBytes:      256 << 10,
+			MaxHistory:        20,
+			ContextBudget:     100000,
+			SummaryTokens:     1024,
+			SummaryTTL:        7 * 24 * time.Hour,
+		}
+	}
+	return Options{
+		MaxTokens:         cfg.MaxTokens,
+		ThinkingTokens:    cfg.ThinkingTokens,
+		Timeout:           cfg.Timeout,
+		EventTTL:          cfg.EventTTL,
+		Retention:         cfg.Retention,
+		MaxActivePerUser:  cfg.MaxActivePerUser,
+		Workers:           cfg.Workers,
+		MaxRunningPerUser: cfg.MaxRunningPerUser,
+		QueueTimeout:      cfg.QueueTimeout,
+		MaxPromptLength:   cfg.MaxPromptLength,
+		MaxPageBytes:      cfg.MaxPageBytes,
+		MaxHistory:        cfg.MaxHistoryEntries,
+		ContextBudget:     cfg.ContextBudget,
+		SummaryTokens:     cfg.SummaryTokens,
+		SummaryTTL:        cfg.SummaryTTL,
+	}
+}
+
+// Meter enforces the credit budgets and records the usage of generations
+type Meter interface {
+	// Check 在创建任务前检查用户是否还有额度
+	Check(ctx context.Context, userID uint64) error
+	// Record 记录一次结束的生成的用量，同一任务只记录一次
+	Record(ctx context.Context, usage *model.GenerationUsage) error
+}
+
+// PromptSource picks the system prompt version of a user
+type PromptSource interface {
+	// Choose 返回 nil 表示没有启用的版本，使用内置提示词
+	Choose(ctx context.Context, name string, userID uint64, vars map[string]string) (*service.PromptChoice, error)
+}
+
+// ProjectWriter saves completed generations into their project
+type ProjectWriter interface {
+	// ApplyGeneration 以发起生成的用户身份写入页面并追加对话，权限和限额在写入时重新检查
+	ApplyGeneration(ctx context.Context, id, userID uint64, page *service.GeneratedPage) (*service.SavedProject, error)
+}
+
+// Hooks connects a manager to metering, prompt templates, projects and context budgeting, any of them may be nil
+type Hooks struct {
+	Meter     Meter         // nil 表示不计量
+	Prompts   PromptSource  // nil 表示只使用内置提示词
+	Projects  ProjectWriter // nil 表示不写入项目，由前端保存
+	Tokenizer Tokenizer     // nil 表示按文本长度估算 token
+	Summaries SummaryCache  // nil 表示摘要缓存在内存中
+}
+
+// Manager runs generation jobs in the background
+// 任务保存在 JobStore，进度事件写入 EventLog，任何实例都可以查询和订阅任何任务
+type Manager struct {
+	provider LLMProvider
+	store    JobStore
+	events   EventLog
+	hooks    Hooks
+	opts     Options
+	context  *ContextBuilder
+	instance string
+
+	// running 本实例排队和运行中任务的取消函数
+	mu      sync.Mutex
+	running map[string]context.CancelCauseFunc
+	wg      sync.WaitGroup
+
+	// queueMu 串行化排队、调度和排队位置事件的写入
+	queueMu sync.Mutex
+	queue   *fairQueue
+}
+
+// NewManager creates a manager generating with the given provider
+func NewManager(provider LLMProvider, store JobStore, events EventLog, hooks Hooks, opts Options) *Manager {
+	host, _ := os.Hostname()
+	summaries := hooks.Summaries
+	if summaries == nil {
+		summaries = NewMemorySummaryCache(opts.SummaryTTL)
+	}
+	return &Manager{
+		provider: provider,
+		store:    store,
+		events:   events,
+		hooks:    hooks,
+		opts:     opts,
+		context:  NewContextBuilder(provider, hooks.Tokenizer, summaries, opts.ContextBudget, opts.SummaryTokens),
+		instance: fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8]),
+		running:  make(map[string]context.CancelCauseFunc),
+		queue:    newFairQueue(opts.Workers, opts.MaxRunningPerUser),
+	}
+}
+
+var (
+	managerOnce sync.Once
+	manager     *Manager
+)
+
+// GetManager returns the process-wide manager built from the configuration
+// 没有 MySQL 或 Redis 时退回内存存储，任务只在当前实例可见且重启后丢失
+func GetManager() *Manager {
+	managerOnce.Do(func() {
+		var cfg *config.GenerationConfig
+		if config.Cfg != nil {
+			cfg = config.Cfg.Generation
+		}
+		opts := OptionsFromConfig(cfg)
+
+		var provider LLMProvider = NewFakeProvider()
+		if cfg != nil {
+			p, err := NewRouterFromConfig(cfg)
+			if err != nil {
+				logger.Errorf("failed to create generation provider, using fake", "error", err)
+			} else {
+				provider = p
+			}
+		}
+
+		store := NewMemoryJobStore()
+		var hooks Hooks
+		if database.DB != nil {
+			store = dao.NewGenerationJobDAO()
+			hooks = Hooks{
+				Meter:    service.NewCreditService(),
+				Prompts:  service.NewPromptService(),
+				Projects: service.NewProjectService(),
+			}
+		} else {
+			logger.Warnf("generation jobs are kept in memory and not metered because MySQL is unavailable")
+		}
+		events := NewMemoryEventLog()
+		if cache.RDB != nil {
+			events = NewRedisEventLog(cache.RDB, opts.QueueTimeout+opts.Timeout+opts.EventTTL)
+			hooks.Summaries = NewRedisSummaryCache(cache.RDB, opts.SummaryTTL)
+		} else {
+			logger.Warnf("generation events are kept in memory because Redis is unavailable")
+		}
+
+		manager = NewManager(provider, store, events, hooks, opts)
+	})
+	return manager
+}
+
+// ProviderName returns the name of the provider generating the jobs
+func (m *Manager) ProviderName() string {
+	return m.provider.Name()
+}
+
+// Start creates a job for the input and queues it to be generated in the background
+// projectID 为 nil 表示不关联项目，调用方负责检查项目权限
+func (m *Manager) Start(ctx context.Context, userID uint64, projectID *uint64, in *Input) (*model.GenerationJob, error) {
+	in.Prompt = strings.TrimSpace(in.Prompt)
+	if in.Prompt == "" {
+		return nil, ErrPromptEmpty
+	}
+	if m.opts.MaxPromptLength > 0 && len([]rune(in.Prompt)) > m.opts.MaxPromptLength {
+		return nil, ErrPromptTooLong
+	}
+	in.Target = strings.TrimSpace(in.Target)
+	if in.Target != "" {
+		// 选中的元素必须唯一存在，避免排队后才失败
+		if _, err := FindTarget(in.CurrentHTML, in.Target); err != nil {
+			return nil, err
+		}
+	}
+	// 只带入最近的历史消息
+	if m.opts.MaxHistory >= 0 && len(in.History) > m.opts.MaxHistory {
+		in.Skipped += len(in.History) - m.opts.MaxHistory
+		in.History = in.History[len(in.History)-m.opts.MaxHistory:]
+	}
+
+	if m.opts.MaxActivePerUser > 0 {
+		active, err := m.store.CountActive(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if active >= int64(m.opts.MaxActivePerUser) {
+			return nil, ErrTooManyJobs
+		}
+	}
+	if m.hooks.Meter != nil {
+		if err := m.hooks.Meter.Check(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	input, err := sonic.MarshalString(in)
+	if err != nil {
+		return nil, err
+	}
+	prompt := m.choosePrompt(ctx, userID, in)
+	job := &model.GenerationJob{
+		ID:            uuid.NewString(),
+		UserID:        userID,
+		ProjectID:     projectID,
+		Provider:      m.provider.Name(),
+		PromptName:    prompt.Name,
+		PromptVersion: prompt.Version,
+		Status:        model.GenerationPending,
+		Prompt:        in.Prompt,
+		Input:         input,
+		LastSeq:       1,
+		Instance:      m.instance,
+		HeartbeatAt:   time.Now(),
+	}
+	if err := m.store.Create(ctx, job); err != nil {
+		return nil, err
+	}
+	if err := m.events.Append(ctx, job.ID, &Event{Seq: 1, Type: EventStatus, Status: model.GenerationPending, Time: time.Now()}); err != nil {
+		logger.WarnCtxf(ctx, "failed to record generation event", "jobID", job.ID, "error", err)
+	}
+
+	runCtx, cancel := context.WithCancelCause(context.Background())
+	m.mu.Lock()
+	m.running[job.ID] = cancel
+	m.mu.Unlock()
+
+	queued := *job
+	m.queueMu.Lock()
+	m.queue.push(&queuedJob{
+		id:       job.ID,
+		userID:   userID,
+		job:      &queued,
+		in:       in,
+		prompt:   prompt,
+		ctx:      runCtx,
+		queuedAt: time.Now(),
+	})
+	m.dispatch()
+	m.queueMu.Unlock()
+	return job, nil
+}
+
+// dispatch 在有空闲名额时按轮转顺序开始排队的任务，并向位置变化的任务写入排队事件，调用方持有 queueMu
+func (m *Manager) dispatch() {
+	for q := m.queue.pop(); q != nil; q = m.queue.pop() {
+		m.wg.Add(1)
+		go m.run(q.ctx, q.job, q.in, q.prompt)
+	}
+
+	positions := m.queue.positions()
+	if len(positions) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), finishTimeout)
+	defer cancel()
+	for _, queue := range m.queue.queues {
+		for _, q := range queue {
+			position := positions[q.id]
+			if position == q.position {
+				continue
+			}
+			q.position = position
+			q.job.LastSeq++
+			event := &Event{Seq: q.job.LastSeq, Type: EventQueued, Status: model.GenerationPending, Position: position, Time: time.Now()}
+			if err := m.events.Append(ctx, q.id, event); err != nil {
+				logger.WarnCtxf(ctx, "failed to record generation event", "jobID", q.id, "error", err)
+			}
+		}
+	}
+}
+
+// abandon 结束一个没有开始的排队任务
+func (m *Manager) abandon(q *queuedJob, cause error) {
+	m.mu.Lock()
+	cancel := m.running[q.id]
+	delete(m.running, q.id)
+	m.mu.Unlock()
+	if cancel != nil {
+		cancel(cause)
+	}
+
+	ctx, finishCancel := context.WithTimeout(context.Background(), finishTimeout)
+	defer finishCancel()
+	q.job.Status = failedStatus(cause)
+	q.job.Error = publicError(cause)
+	m.finish(ctx, q.job, q.job.LastSeq)
+}
+
+// Cancel stops one of a user's jobs, whether it is queued or generating on any instance
+// 在其他实例运行的任务先标记为取消，运行实例在下次心跳时停止生成
+func (m *Manager) Cancel(ctx context.Context, jobID string, userID uint64) error {
+	job, err := m.lookup(ctx, jobID, userID)
+	if err != nil {
+		return err
+	}
+	if model.GenerationDone(job.Status) {
+		return ErrJobFinished
+	}
+
+	m.queueMu.Lock()
+	q := m.queue.remove(jobID)
+	if q != nil {
+		m.dispatch()
+	}
+	m.queueMu.Unlock()
+	if q != nil {
+		m.abandon(q, errCanceled)
+		return nil
+	}
+
+	m.mu.Lock()
+	cancel, ok := m.running[jobID]
+	m.mu.Unlock()
+	if ok {
+		cancel(errCanceled)
+		return nil
+	}
+
+	last, err := m.events.Last(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	job.Status = model.GenerationCanceled
+	job.Error = errCanceled.Error()
+	m.finish(ctx, job, max(last, job.LastSeq))
+	return nil
+}
+
+// choosePrompt 选择用户的系统提示词版本；没有启用的版本或读取失败时使用内置提示词（版本 0，System 为空）
+func (m *Manager) choosePrompt(ctx context.Context, userID uint64, in *Input) *service.PromptChoice {
+	name := promptName(in)
+	builtin := &service.PromptChoice{Name: name}
+	if m.hooks.Prompts == nil {
+		return builtin
+	}
+
+	vars := map[string]string{"prompt": in.Prompt, "today": time.Now().Format(time.DateOnly)}
+	choice, err := m.hooks.Prompts.Choose(ctx, name, userID, vars)
+	if err != nil {
+		logger.WarnCtxf(ctx, "failed to choose prompt template, using the built-in prompt", "name", name, "error", err)
+		return builtin
+	}
+	if choice == nil {
+		return builtin
+	}
+	return choice
+}
+
+// run 调用 provider 生成页面并记录进度，结束时保存结果并写入完成或失败事件
+func (m *Manager) run(ctx context.Context, job *model.GenerationJob, in *Input, prompt *service.PromptChoice) {
+	defer m.wg.Done()
+	defer func() {
+		m.mu.Lock()
+		cancel := m.running[job.ID]
+		delete(m.running, job.ID)
+		m.mu.Unlock()
+		if cancel != nil {
+			cancel(nil)
+		}
+
+		m.queueMu.Lock()
+		m.queue.done(job.UserID)
+		m.dispatch()
+		m.queueMu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, m.opts.Timeout)
+	defer cancel()
+
+	started := time.Now()
+	seq := job.LastSeq
+	emit := func(event Event) {
+		seq++
+		event.Seq = seq
+		event.Time = time.Now()
+		if err := m.events.Append(ctx, job.ID, &event); err != nil {
+			logger.Warnf("failed to record generation event", "jobID", job.ID, "error", err)
+		}
+	}
+
+	job.Status = model.GenerationGenerating
+	job.LastSeq = seq + 1
+	if _, err := m.store.Update(ctx, job); err != nil {
+		logger.Warnf("failed to update generation job", "jobID", job.ID, "error", err)
+	}
+	emit(Event{Type: EventStatus, Status: model.GenerationGenerating})
+
+	system := prompt.System
+	if system == "" {
+		system = BuildRequest(in, 0, 0).System
+	}
+	fitted, report, summaryUsage := m.context.Fit(ctx, in, system)
+	req := BuildRequest(fitted, m.opts.MaxTokens, m.opts.ThinkingTokens)
+	req.System = system
+	report.Tokens = m.context.tokenizer.Count(req.System) + m.context.tokenizer.Count(req.Prompt)
+	job.Context = report
+
+	var content strings.Builder
+	completion, err := m.provider.Stream(ctx, req, func(d Delta) {
+		if d.Text == "" {
+			return
+		}
+		if d.Kind == DeltaText {
+			content.WriteString(d.Text)
+			emit(Event{Type: EventContent, Delta: d.Text})
+		} else {
+			emit(Event{Type: EventThinking, Delta: d.Text})
+		}
+	})
+	if completion != nil && completion.Provider != "" {
+		job.Provider = completion.Provider
+	}
+
+	if err == nil {
+		var page string
+		if page, err = pageFrom(in, completion.Text); err == nil {
+			job.Result = &model.GenerationResult{
+				HTML:    page,
+				CSS:     ExtractCSS(page),
+				Message: summary(in),
+				Quality: CheckQuality(page, m.opts.MaxPageBytes),
+			}
+			job.Content = completion.Text
+		}
+	}
+	// 输出结束后才收到的取消同样生效，不再写入项目
+	if cause := context.Cause(ctx); errors.Is(cause, errInterrupted) || errors.Is(cause, errCanceled) {
+		err = cause
+	}
+	if err != nil {
+		logger.Warnf("generation failed", "jobID", job.ID, "provider", job.Provider, "error", err)
+		job.Status = failedStatus(err)
+		job.Error = publicError(err)
+		job.Content = content.String()
+		job.Result = nil
+	} else {
+		job.Status = model.GenerationCompleted
+	}
+
+	// 任务的 ctx 可能已超时或取消，结束状态用独立的 ctx 写入
+	finishCtx, finishCancel := context.WithTimeout(context.Background(), finishTimeout)
+	defer finishCancel()
+	if job.Status == model.GenerationCompleted {
+		m.save(finishCtx, job, in)
+	}
+	m.finish(finishCtx, job, seq)
+	m.record(finishCtx, job, req, completion, content.String(), summaryUsage, time.Since(started))
+}
+
+// pageFrom 从模型回复中取出页面；重新生成选中元素时把回复中的元素替换回原页面
+func pageFrom(in *Input, response string) (string, error) {
+	if in.IsTargeted() {
+		target, err := FindTarget(in.CurrentHTML, in.Target)
+		if err != nil {
+			return "", err
+		}
+		return target.Splice(response)
+	}
+	page := ExtractHTML(response)
+	if page == "" {
+		return "", ErrEmptyGenerated
+	}
+	return page, nil
+}
+
+// active 确认任务仍未结束：其他实例上的取消或中断判定要到下次心跳才通知本实例，以存储中的状态为准
+func (m *Manager) active(ctx context.Context, job *model.GenerationJob) (bool, error) {
+	stored, err := m.store.Get(ctx, job.ID)
+	if err != nil {
+		return false, err
+	}
+	return stored != nil && !model.GenerationDone(stored.Status), nil
+}
+
+// save 把完成的页面和本轮对话写入任务关联的项目；写入失败不影响任务完成，原因记录在结果中
+func (m *Manager) save(ctx context.Context, job *model.GenerationJob, in *Input) {
+	if m.hooks.Projects == nil || job.ProjectID == nil {
+		return
+	}
+	// 任务已在别处被取消或判定中断时不写入项目；无法确认时同样不写入
+	active, err := m.active(ctx, job)
+	if err != nil {
+		logger.ErrorCtxf(ctx, "failed to check generation job before saving into project", "jobID", job.ID, "error", err)
+		job.Result.SaveError = saveError(err)
+		return
+	}
+	if !active {
+		return
+	}
+	page := &service.GeneratedPage{
+		HTML: job.Result.HTML,
+		Turns: []model.ChatMessage{
+			{Type: model.ChatMessageUser, Content: in.Prompt},
+			{Type: model.ChatMessageAI, Content: job.Result.Message},
+		},
+		Quality: job.Result.Quality,
+	}
+	// 重新生成的元素替换到项目当前的页面中，而不是请求带来的 current_html
+	if in.IsTargeted() {
+		fragment := job.Content
+		page.Splice = func(current string) (string, error) {
+			target, err := FindTarget(current, in.Target)
+			if err != nil {
+				return "", err
+			}
+			return target.Splice(fragment)
+		}
+	}
+	_, err = m.hooks.Projects.ApplyGeneration(ctx, *job.ProjectID, job.UserID, page)
+	if err != nil {
+		logger.WarnCtxf(ctx, "failed to save generation into project", "jobID", job.ID, "projectID", *job.ProjectID, "error", err)
+		job.Result.SaveError = saveError(err)
+		return
+	}
+	job.Result.Saved = true
+}
+
+// saveError 返回可以展示给用户的项目写入失败原因
+func saveError(err error) string {
+	switch {
+	case errors.Is(err, service.ErrProjectNotFound):
+		return "the project no longer exists"
+	case errors.Is(err, service.ErrProjectNotOwned), errors.Is(err, service.ErrProjectReadOnly):
+		return "you can no longer edit the project"
+	case errors.Is(err, service.ErrProjectTooLarge):
+		return "the page exceeds the project size limit of the owner's plan"
+	case errors.Is(err, service.ErrStorageLimitExceeded):
+		return "the project owner's storage is full"
+	case errors.Is(err, service.ErrChatHistoryInvalid):
+		return "the project's chat history could not be read"
+	case errors.Is(err, ErrTargetNotFound), errors.Is(err, ErrTargetAmbiguous), errors.Is(err, ErrTargetUnsupported):
+		return "the selected element has changed in the project, please select it again"
+	case errors.Is(err, ErrFragmentInvalid):
+		return err.Error()
+	default:
+		return "the page could not be saved into the project, please save it again"
+	}
+}
+
+// record 记录任务消耗的 token 和时间；provider 没有报告用量时按文本长度估算
+// 失败的任务同样计量，已经输出的部分也产生了成本；summaries 是概括早期消息消耗的 token
+func (m *Manager) record(ctx context.Context, job *model.GenerationJob, req *Request, completion *Completion, content string, summaries Usage, elapsed time.Duration) {
+	if m.hooks.Meter == nil {
+		return
+	}
+	var usage Usage
+	if completion != nil {
+		usage = completion.Usage
+	}
+	if usage.InputTokens == 0 {
+		usage.InputTokens = EstimateTokens(req.System) + EstimateTokens(req.Prompt)
+	}
+	if usage.OutputTokens == 0 {
+		usage.OutputTokens = EstimateTokens(content)
+	}
+	usage.InputTokens += summaries.InputTokens
+	usage.OutputTokens += summaries.OutputTokens
+
+	err := m.hooks.Meter.Record(ctx, &model.GenerationUsage{
+		JobID:         job.ID,
+		UserID:        job.UserID,
+		ProjectID:     job.ProjectID,
+		Provider:      job.Provider,
+		Status:        job.Status,
+		PromptName:    job.PromptName,
+		PromptVersion: job.PromptVersion,
+		InputTokens:   usage.InputTokens,
+		OutputTokens:  usage.OutputTokens,
+		WallTimeMs:    elapsed.Milliseconds(),
+	})
+	if err != nil {
+		logger.ErrorCtxf(ctx, "failed to record generation usage", "jobID", job.ID, "error", err)
+	}
+}
+
+// finish 保存任务的最终状态，保存成功后写入结束事件并设置事件的保留时间
+// 任务已被其他实例判定为中断时不再覆盖
+func (m *Manager) finish(ctx context.Context, job *model.GenerationJob, lastSeq int64) {
+	now := time.Now()
+	job.FinishedAt = &now
+	job.UpdatedAt = now
+	job.LastSeq = lastSeq + 1
+	saved, err := m.store.Update(ctx, job)
+	if err != nil {
+		logger.ErrorCtxf(ctx, "failed to save generation job", "jobID", job.ID, "error", err)
+		return
+	}
+	if !saved {
+		return
+	}
+	if err := m.events.Append(ctx, job.ID, finalEvent(job)); err != nil {
+		logger.WarnCtxf(ctx, "failed to record generation event", "jobID", job.ID, "error", err)
+	}
+	if err := m.events.Expire(ctx, job.ID, m.opts.EventTTL); err != nil {
+		logger.WarnCtxf(ctx, "failed to expire generation events", "jobID", job.ID, "error", err)
+	}
+}
+
+// failedStatus 返回没有完成的任务的最终状态
+func failedStatus(err error) string {
+	if errors.Is(err, errCanceled) {
+		return model.GenerationCanceled
+	}
+	return model.GenerationError
+}
+
+// publicError 返回可以展示给用户的错误信息，不暴露模型服务的细节
+func publicError(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "generation timed out"
+	case errors.Is(err, errInterrupted), errors.Is(err, errCanceled), errors.Is(err, errQueueTimeout),
+		errors.Is(err, ErrEmptyGenerated), errors.Is(err, ErrFragmentInvalid):
+		return err.Error()
+	case errors.Is(err, context.Canceled):
+		return "generation was canceled"
+	default:
+		return "generation failed, please try again"
+	}
+}
+
+// lookup 返回用户自己的任务；其他用户的任务视为不存在
+func (m *Manager) lookup(ctx context.Context, jobID string, userID uint64) (*model.GenerationJob, error) {
+	job, err := m.store.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil || job.UserID != userID {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// Get returns one of a user's jobs, with the output so far while it is generating
+func (m *Manager) Get(ctx context.Context, jobID string, userID uint64) (*model.GenerationJob, error) {
+	job, err := m.lookup(ctx, jobID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if model.GenerationDone(job.Status) {
+		return job, nil
+	}
+
+	events, err := m.events.Read(ctx, jobID, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	var content strings.Builder
+	for _, e := range events {
+		switch e.Type {
+		case EventContent:
+			content.WriteString(e.Delta)
+		case EventQueued:
+			job.QueuePosition = e.Position
+		case EventStatus:
+			if e.Status != model.GenerationPending {
+				job.QueuePosition = 0
+			}
+		}
+		job.LastSeq = e.Seq
+	}
+	job.Content = content.String()
+	return job, nil
+}
+
+// Subscribe calls fn for every event after seq `after` in order, waiting for new ones until the job ends
+// 任务结束、ctx 取消或 fn 返回错误时返回；事件已过期时以任务的最终状态补发结束事件
+func (m *Manager) Subscribe(ctx context.Context, jobID string, userID uint64, after int64, fn func(*Event) error) error {
+	job, err := m.lookup(ctx, jobID, userID)
+	if err != nil {
+		return err
+	}
+
+	next := max(after, 0)
+	for {
+		if model.GenerationDone(job.Status) && next >= job.LastSeq {
+			return nil
+		}
+		wait := subscribeWait
+		if model.GenerationDone(job.Status) {
+			wait = 0
+		}
+		events, err := m.events.Read(ctx, jobID, next, wait)
+		if err != nil {
+			return err
+		}
+		for i := range events {
+			if err := fn(&events[i]); err != nil {
+				return err
+			}
+			next = events[i].Seq
+			if events[i].Final() {
+				return nil
+			}
+		}
+		if len(events) > 0 {
+			continue
+		}
+
+		// 一段时间没有新事件：确认任务是否已经结束
+		if job, err = m.store.Get(ctx, jobID); err != nil {
+			return err
+		}
+		if job == nil {
+			return ErrJobNotFound
+		}
+		if model.GenerationDone(job.Status) && next < job.LastSeq {
+			return fn(finalEvent(job))
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// Maintain sends heartbeats for the jobs queued and running here, stops those canceled elsewhere,
+// fails jobs queued too long or whose instance is gone and, when purge is set, deletes jobs past the retention
+func (m *Manager) Maintain(ctx context.Context, now time.Time, purge bool) {
+	m.mu.Lock()
+	ids := make([]string, 0, len(m.running))
+	for id := range m.running {
+		ids = append(ids, id)
+	}
+	m.mu.Unlock()
+	ended, err := m.store.Heartbeat(ctx, m.instance, ids, now)
+	if err != nil {
+		logger.WarnCtxf(ctx, "failed to send generation heartbeat", "error", err)
+	}
+	m.stop(ended)
+
+	var expired []*queuedJob
+	if m.opts.QueueTimeout > 0 {
+		m.queueMu.Lock()
+		expired = m.queue.expired(now, m.opts.QueueTimeout)
+		if len(expired) > 0 {
+			m.dispatch()
+		}
+		m.queueMu.Unlock()
+	}
+	for _, q := range expired {
+		m.abandon(q, errQueueTimeout)
+		logger.WarnCtxf(ctx, "generation job waited too long in the queue", "jobID", q.id, "userID", q.userID)
+	}
+
+	stale, err := m.store.ListStale(ctx, now.Add(-staleAfter), staleBatchSize)
+	if err != nil {
+		logger.WarnCtxf(ctx, "failed to list stale generation jobs", "error", err)
+	}
+	for i := range stale {
+		job := &stale[i]
+		last, err := m.events.Last(ctx, job.ID)
+		if err != nil {
+			logger.WarnCtxf(ctx, "failed to read generation events", "jobID", job.ID, "error", err)
+			continue
+		}
+		job.Status = model.GenerationError
+		job.Error = errInterrupted.Error()
+		m.finish(ctx, job, max(last, job.LastSeq))
+		logger.WarnCtxf(ctx, "generation job interrupted", "jobID", job.ID, "instance", job.Instance)
+	}
+
+	if purge && m.opts.Retention > 0 {
+		n, err := m.store.DeleteFinishedBefore(ctx, now.Add(-m.opts.Retention))
+		if err != nil {
+			logger.WarnCtxf(ctx, "failed to purge generation jobs", "error", err)
+		} else if n > 0 {
+			logger.InfoCtxf(ctx, "purged generation jobs", "count", n)
+		}
+	}
+}
+
+// stop 停止已在其他实例结束的任务，任务状态已经保存，这里只释放本实例的资源
+func (m *Manager) stop(ids []string) {
+	for _, id := range ids {
+		m.queueMu.Lock()
+		q := m.queue.remove(id)
+		if q != nil {
+			m.dispatch()
+		}
+		m.queueMu.Unlock()
+
+		m.mu.Lock()
+		cancel, ok := m.running[id]
+		if q != nil {
+			delete(m.running, id)
+		}
+		m.mu.Unlock()
+		if ok {
+			cancel(errCanceled)
+		}
+	}
+}
+
+// Shutdown interrupts the jobs queued and running here and waits briefly for them to record it
+func (m *Manager) Shutdown() {
+	m.queueMu.Lock()
+	queued := m.queue.drain()
+	m.queueMu.Unlock()
+	for _, q := range queued {
+		m.abandon(q, errInterrupted)
+	}
+
+	m.mu.Lock()
+	for _, cancel := range m.running {
+		cancel(errInterrupted)
+	}
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(shutdownWait):
+	}
+}
+
+// StartJanitor 定期刷新心跳、接管中断的任务并清理过期记录，返回停止函数
+func (m *Manager) StartJanitor() func() {
+	ticker := time.NewTicker(heartbeatInterval)
+	stopChan := make(chan struct{})
+
+	go func() {
+		lastPurge := time.Time{}
+		for {
+			select {
+			case now := <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), heartbeatInterval)
+				purge := now.Sub(lastPurge) >= purgeInterval
+				m.Maintain(ctx, now, purge)
+				if purge {
+					lastPurge = now
+				}
+				cancel()
+			case <-stopChan:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(stopChan)
+	}
+}
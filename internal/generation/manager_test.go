@@ -0,0 +1,727 @@
+package generation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/internal/service"
+	"github.com/test-tt/pkg/sanitize"
+)
+
+func testOptions() Options {
+	opts := OptionsFromConfig(nil)
+	opts.Timeout = 5 * time.Second
+	return opts
+}
+
+func newTestManager(provider LLMProvider, opts Options) *Manager {
+	return NewManager(provider, NewMemoryJobStore(), NewMemoryEventLog(), Hooks{}, opts)
+}
+
+// collect 订阅任务直到结束并返回所有事件
+func collect(t *testing.T, m *Manager, jobID string, userID uint64, after int64) []Event {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var events []Event
+	err := m.Subscribe(ctx, jobID, userID, after, func(e *Event) error {
+		events = append(events, *e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	return events
+}
+
+// TestManager_Generate tests the job lifecycle with the fake provider
+func TestManager_Generate(t *testing.T) {
+	ctx := context.Background()
+	m := newTestManager(&FakeProvider{ChunkSize: 64, Delay: time.Millisecond}, testOptions())
+
+	projectID := uint64(7)
+	job, err := m.Start(ctx, 1, &projectID, &Input{Prompt: "  A bakery landing page  "})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if job.Status != model.GenerationPending || job.Prompt != "A bakery landing page" || job.Provider != "fake" || *job.ProjectID != 7 {
+		t.Errorf("Start() = %+v", job)
+	}
+
+	events := collect(t, m, job.ID, 1, 0)
+	if events[0].Type != EventStatus || events[0].Status != model.GenerationPending {
+		t.Errorf("first event = %+v, want pending", events[0])
+	}
+	if events[1].Type != EventStatus || events[1].Status != model.GenerationGenerating {
+		t.Errorf("second event = %+v, want generating", events[1])
+	}
+	var content strings.Builder
+	for i, e := range events {
+		if e.Seq != int64(i)+1 {
+			t.Fatalf("event %d has seq %d", i, e.Seq)
+		}
+		if e.Type == EventContent {
+			content.WriteString(e.Delta)
+		}
+	}
+	if want := FakePage(BuildRequest(&Input{Prompt: "A bakery landing page"}, 0, 0).Prompt); content.String() != want {
+		t.Errorf("streamed content = %.60q, want the fake page", content.String())
+	}
+
+	last := events[len(events)-1]
+	if last.Type != EventComplete || last.Result == nil {
+		t.Fatalf("last event = %+v, want complete", last)
+	}
+	if !strings.HasPrefix(last.Result.HTML, "<!DOCTYPE html>") || strings.Contains(last.Result.HTML, "```") {
+		t.Errorf("result html was not extracted: %.40q", last.Result.HTML)
+	}
+	if !strings.Contains(last.Result.CSS, "--accent") {
+		t.Errorf("result css = %q", last.Result.CSS)
+	}
+
+	got, err := m.Get(ctx, job.ID, 1)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Status != model.GenerationCompleted || got.Content != content.String() || got.LastSeq != last.Seq || got.FinishedAt == nil {
+		t.Errorf("Get() = status %s, last_seq %d", got.Status, got.LastSeq)
+	}
+
+	// 从中间续读只返回之后的事件
+	resumed := collect(t, m, job.ID, 1, last.Seq-1)
+	if len(resumed) != 1 || resumed[0].Seq != last.Seq {
+		t.Errorf("resumed events = %d, want only the last one", len(resumed))
+	}
+	if after := collect(t, m, job.ID, 1, last.Seq); len(after) != 0 {
+		t.Errorf("events after the last one = %d, want 0", len(after))
+	}
+
+	// 其他用户看不到该任务
+	if _, err := m.Get(ctx, job.ID, 2); err != ErrJobNotFound {
+		t.Errorf("Get() by another user error = %v, want %v", err, ErrJobNotFound)
+	}
+	if err := m.Subscribe(ctx, job.ID, 2, 0, func(*Event) error { return nil }); err != ErrJobNotFound {
+		t.Errorf("Subscribe() by another user error = %v, want %v", err, ErrJobNotFound)
+	}
+
+	// 同一提示词总是得到同一页面
+	again, _ := m.Start(ctx, 1, nil, &Input{Prompt: "A bakery landing page"})
+	collect(t, m, again.ID, 1, 0)
+	if second, _ := m.Get(ctx, again.ID, 1); second.Result.HTML != last.Result.HTML {
+		t.Error("fake provider is not deterministic")
+	}
+}
+
+// TestManager_OtherInstance tests that a second manager sharing the store and event log serves the job
+func TestManager_OtherInstance(t *testing.T) {
+	ctx := context.Background()
+	store, events := NewMemoryJobStore(), NewMemoryEventLog()
+	a := NewManager(&FakeProvider{ChunkSize: 16, Delay: time.Millisecond}, store, events, Hooks{}, testOptions())
+	b := NewManager(NewFakeProvider(), store, events, Hooks{}, testOptions())
+
+	job, _ := a.Start(ctx, 1, nil, &Input{Prompt: "shared"})
+	got := collect(t, b, job.ID, 1, 2)
+	if got[0].Seq != 3 || !got[len(got)-1].Final() {
+		t.Errorf("other instance events = %d..%d", got[0].Seq, got[len(got)-1].Seq)
+	}
+
+	// 事件过期后以任务记录补发结束事件
+	done, _ := store.Get(ctx, job.ID)
+	expired := NewManager(NewFakeProvider(), store, NewMemoryEventLog(), Hooks{}, testOptions())
+	got = collect(t, expired, job.ID, 1, 5)
+	if len(got) != 1 || got[0].Type != EventComplete || got[0].Seq != done.LastSeq || got[0].Result.HTML != done.Result.HTML {
+		t.Errorf("events after expiry = %+v", got)
+	}
+}
+
+// TestManager_Interrupted tests that jobs of a vanished instance are failed and their streams ended
+func TestManager_Interrupted(t *testing.T) {
+	ctx := context.Background()
+	store, events := NewMemoryJobStore(), NewMemoryEventLog()
+	crashed := &model.GenerationJob{
+		ID:          "crashed",
+		UserID:      1,
+		Status:      model.GenerationGenerating,
+		Instance:    "gone",
+		LastSeq:     2,
+		HeartbeatAt: time.Now().Add(-2 * staleAfter),
+	}
+	_ = store.Create(ctx, crashed)
+	_ = events.Append(ctx, "crashed", &Event{Seq: 1, Type: EventStatus})
+	_ = events.Append(ctx, "crashed", &Event{Seq: 2, Type: EventStatus})
+	_ = events.Append(ctx, "crashed", &Event{Seq: 3, Type: EventContent, Delta: "<p>"})
+
+	m := NewManager(NewFakeProvider(), store, events, Hooks{}, testOptions())
+	result := make(chan []Event)
+	go func() { result <- collect(t, m, "crashed", 1, 3) }()
+
+	m.Maintain(ctx, time.Now(), false)
+	got := <-result
+	if len(got) != 1 || got[0].Type != EventError || got[0].Seq != 4 || got[0].Error != errInterrupted.Error() {
+		t.Errorf("events = %+v, want an interrupted error with seq 4", got)
+	}
+	if job, _ := m.Get(ctx, "crashed", 1); job.Status != model.GenerationError || job.LastSeq != 4 {
+		t.Errorf("job = %s, last_seq %d", job.Status, job.LastSeq)
+	}
+
+	// 本实例正在运行的任务靠心跳保持活跃
+	running, _ := m.Start(ctx, 1, nil, &Input{Prompt: "page"})
+	later := time.Now().Add(2 * staleAfter)
+	m.Maintain(ctx, later, false)
+	collect(t, m, running.ID, 1, 0)
+	if job, _ := m.Get(ctx, running.ID, 1); job.Status == model.GenerationError && job.Error == errInterrupted.Error() {
+		t.Error("a job running on this instance was failed as interrupted")
+	}
+}
+
+// TestManager_Failures tests provider errors, timeouts, shutdown and input limits
+func TestManager_Failures(t *testing.T) {
+	ctx := context.Background()
+	m := newTestManager(&FakeProvider{Err: errors.New("upstream exploded")}, testOptions())
+	job, _ := m.Start(ctx, 1, nil, &Input{Prompt: "page"})
+	events := collect(t, m, job.ID, 1, 0)
+	last := events[len(events)-1]
+	if last.Type != EventError || last.Error == "" || strings.Contains(last.Error, "exploded") {
+		t.Errorf("last event = %+v, want a generic error", last)
+	}
+
+	opts := testOptions()
+	opts.Timeout = 20 * time.Millisecond
+	m = newTestManager(&FakeProvider{ChunkSize: 1, Delay: 10 * time.Millisecond}, opts)
+	job, _ = m.Start(ctx, 1, nil, &Input{Prompt: "slow page"})
+	collect(t, m, job.ID, 1, 0)
+	if got, _ := m.Get(ctx, job.ID, 1); got.Status != model.GenerationError || got.Error != "generation timed out" || got.Content == "" {
+		t.Errorf("timed out job = %s %q, content %d bytes", got.Status, got.Error, len(got.Content))
+	}
+
+	opts = testOptions()
+	opts.MaxActivePerUser = 1
+	opts.MaxPromptLength = 10
+	m = newTestManager(&FakeProvider{ChunkSize: 1, Delay: 10 * time.Millisecond}, opts)
+	if _, err := m.Start(ctx, 1, nil, &Input{Prompt: "   "}); err != ErrPromptEmpty {
+		t.Errorf("Start(blank) error = %v, want %v", err, ErrPromptEmpty)
+	}
+	if _, err := m.Start(ctx, 1, nil, &Input{Prompt: strings.Repeat("a", 11)}); err != ErrPromptTooLong {
+		t.Errorf("Start(long) error = %v, want %v", err, ErrPromptTooLong)
+	}
+	first, err := m.Start(ctx, 1, nil, &Input{Prompt: "first"})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if _, err := m.Start(ctx, 1, nil, &Input{Prompt: "second"}); err != ErrTooManyJobs {
+		t.Errorf("Start() over limit error = %v, want %v", err, ErrTooManyJobs)
+	}
+	if _, err := m.Start(ctx, 2, nil, &Input{Prompt: "other user"}); err != nil {
+		t.Errorf("Start() for another user error = %v", err)
+	}
+
+	m.Shutdown()
+	if got, _ := m.Get(ctx, first.ID, 1); got.Status != model.GenerationError || got.Error != errInterrupted.Error() {
+		t.Errorf("job after shutdown = %s %q", got.Status, got.Error)
+	}
+}
+
+// replyProvider 总是返回同一段文本
+type replyProvider struct {
+	text string
+}
+
+func (p replyProvider) Name() string {
+	return "reply"
+}
+
+func (p replyProvider) Stream(_ context.Context, _ *Request, onDelta func(Delta)) (*Completion, error) {
+	onDelta(Delta{Kind: DeltaText, Text: p.text})
+	return &Completion{Text: p.text}, nil
+}
+
+// TestManager_Target tests regenerating a selected element of the current page
+func TestManager_Target(t *testing.T) {
+	ctx := context.Background()
+	m := newTestManager(NewFakeProvider(), testOptions())
+
+	if _, err := m.Start(ctx, 1, nil, &Input{Prompt: "red", CurrentHTML: targetPage, Target: "section.menu"}); !errors.Is(err, ErrTargetAmbiguous) {
+		t.Errorf("Start(ambiguous target) error = %v, want %v", err, ErrTargetAmbiguous)
+	}
+	if _, err := m.Start(ctx, 1, nil, &Input{Prompt: "red", Target: "main"}); !errors.Is(err, ErrTargetWithoutPage) {
+		t.Errorf("Start(target without page) error = %v, want %v", err, ErrTargetWithoutPage)
+	}
+
+	in := &Input{Prompt: "make it red", CurrentHTML: targetPage, Target: " #top "}
+	job, err := m.Start(ctx, 1, nil, in)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if job.PromptName != model.PromptFragment {
+		t.Errorf("prompt name = %s, want %s", job.PromptName, model.PromptFragment)
+	}
+	events := collect(t, m, job.ID, 1, 0)
+	last := events[len(events)-1]
+	if last.Type != EventComplete {
+		t.Fatalf("last event = %+v, want complete", last)
+	}
+	page := last.Result.HTML
+	if !strings.Contains(page, `<main>`) || strings.Contains(page, `id="top"`) || !strings.Contains(page, `<section class="fake-fragment"`) {
+		t.Errorf("result html = %q, want the header replaced", page)
+	}
+	if !strings.Contains(last.Result.CSS, "--accent") || !strings.Contains(last.Result.Message, "#top") {
+		t.Errorf("result = css %q, message %q", last.Result.CSS, last.Result.Message)
+	}
+
+	m = newTestManager(replyProvider{text: "```html\n<header><h1>Red</header>\n```"}, testOptions())
+	job, _ = m.Start(ctx, 1, nil, &Input{Prompt: "make it red", CurrentHTML: targetPage, Target: "#top"})
+	collect(t, m, job.ID, 1, 0)
+	if got, _ := m.Get(ctx, job.ID, 1); got.Status != model.GenerationError || got.Error != ErrFragmentInvalid.Error() || got.Content == "" {
+		t.Errorf("job with a broken element = %s %q", got.Status, got.Error)
+	}
+}
+
+// TestManager_Purge tests that only finished jobs past the retention are removed
+func TestManager_Purge(t *testing.T) {
+	ctx := context.Background()
+	opts := testOptions()
+	opts.Retention = time.Hour
+	m := newTestManager(NewFakeProvider(), opts)
+	job, _ := m.Start(ctx, 1, nil, &Input{Prompt: "page"})
+	collect(t, m, job.ID, 1, 0)
+
+	m.Maintain(ctx, time.Now(), true)
+	if _, err := m.Get(ctx, job.ID, 1); err != nil {
+		t.Errorf("Get() before the retention error = %v", err)
+	}
+	m.Maintain(ctx, time.Now().Add(2*time.Hour), true)
+	if _, err := m.Get(ctx, job.ID, 1); err != ErrJobNotFound {
+		t.Errorf("Get() after the retention error = %v, want %v", err, ErrJobNotFound)
+	}
+}
+
+// testMeter 记录用量，exhausted 中的用户没有额度
+type testMeter struct {
+	mu        sync.Mutex
+	exhausted map[uint64]bool
+	usage     []model.GenerationUsage
+}
+
+var errTestExhausted = errors.New("credits exhausted")
+
+func (m *testMeter) Check(_ context.Context, userID uint64) error {
+	if m.exhausted[userID] {
+		return errTestExhausted
+	}
+	return nil
+}
+
+func (m *testMeter) Record(_ context.Context, usage *model.GenerationUsage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usage = append(m.usage, *usage)
+	return nil
+}
+
+// TestManager_Meter tests that budgets are checked before a job starts and usage is recorded when it ends
+func TestManager_Meter(t *testing.T) {
+	ctx := context.Background()
+	meter := &testMeter{exhausted: map[uint64]bool{2: true}}
+	m := NewManager(NewFakeProvider(), NewMemoryJobStore(), NewMemoryEventLog(), Hooks{Meter: meter}, testOptions())
+
+	if _, err := m.Start(ctx, 2, nil, &Input{Prompt: "page"}); err != errTestExhausted {
+		t.Errorf("Start() without credits error = %v, want %v", err, errTestExhausted)
+	}
+
+	job, err := m.Start(ctx, 1, nil, &Input{Prompt: "page"})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	collect(t, m, job.ID, 1, 0)
+	failed := newTestManager(&FakeProvider{Err: errors.New("down")}, testOptions())
+	failed.hooks.Meter = meter
+	failedJob, _ := failed.Start(ctx, 1, nil, &Input{Prompt: "page"})
+	collect(t, failed, failedJob.ID, 1, 0)
+	// 用量在结束事件之后记录，等待任务协程退出
+	m.Shutdown()
+	failed.Shutdown()
+
+	if len(meter.usage) != 2 {
+		t.Fatalf("recorded usage = %d, want 2", len(meter.usage))
+	}
+	got := meter.usage[0]
+	want := EstimateTokens(FakePage(BuildRequest(&Input{Prompt: "page"}, 0, 0).Prompt))
+	if got.JobID != job.ID || got.UserID != 1 || got.Status != model.GenerationCompleted || got.Provider != "fake" {
+		t.Errorf("usage = %+v", got)
+	}
+	if got.InputTokens == 0 || got.OutputTokens < want-8 || got.OutputTokens > want+8 {
+		t.Errorf("usage tokens = %d in %d out, want about %d out", got.InputTokens, got.OutputTokens, want)
+	}
+	// 失败的任务只按输入计量
+	if failedUsage := meter.usage[1]; failedUsage.Status != model.GenerationError || failedUsage.InputTokens == 0 || failedUsage.OutputTokens != 0 {
+		t.Errorf("failed usage = %+v", failedUsage)
+	}
+}
+
+// TestManager_Context tests that long conversations are summarized to fit the budget and the decisions are reported
+func TestManager_Context(t *testing.T) {
+	ctx := context.Background()
+	meter := &testMeter{}
+	base := BuildRequest(&Input{Prompt: "add a footer", CurrentHTML: "<p>page</p>"}, 0, 0)
+	opts := testOptions()
+	opts.MaxHistory = 30
+	opts.SummaryTokens = 100
+	opts.ContextBudget = int(EstimateTokens(base.System)+EstimateTokens(base.Prompt)) + 200
+	m := NewManager(NewFakeProvider(), NewMemoryJobStore(), NewMemoryEventLog(), Hooks{Meter: meter}, opts)
+
+	in := &Input{Prompt: "add a footer", CurrentHTML: "<p>page</p>", History: turns(0, 32)}
+	job, err := m.Start(ctx, 1, nil, in)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	collect(t, m, job.ID, 1, 0)
+	m.Shutdown()
+	got, _ := m.Get(ctx, job.ID, 1)
+	report := got.Context
+	if got.Status != model.GenerationCompleted || report == nil {
+		t.Fatalf("job = %s, context %+v", got.Status, report)
+	}
+	if report.LimitedTurns != 2 || report.HistoryTurns != 30 || report.SummarizedTurns == 0 || report.IncludedTurns == 0 ||
+		report.IncludedTurns+report.SummarizedTurns+report.DroppedTurns != 30 {
+		t.Errorf("context = %+v", report)
+	}
+	if report.Tokens == 0 || report.Tokens > int64(opts.ContextBudget)+20 || report.Tokenizer != "estimate" {
+		t.Errorf("context tokens = %d, budget %d", report.Tokens, opts.ContextBudget)
+	}
+	// 概括早期消息的用量计入任务
+	if usage := meter.usage[0]; usage.InputTokens <= report.Tokens {
+		t.Errorf("usage input tokens = %d, want more than the request's %d", usage.InputTokens, report.Tokens)
+	}
+}
+
+// testPrompts 只给用户 1 的新建页面选择版本 3，修改页面时读取失败
+type testPrompts struct{}
+
+func (testPrompts) Choose(_ context.Context, name string, userID uint64, vars map[string]string) (*service.PromptChoice, error) {
+	if name == model.PromptModify {
+		return nil, errors.New("store down")
+	}
+	if userID != 1 {
+		return nil, nil
+	}
+	return &service.PromptChoice{Name: name, Version: 3, System: "Design for: " + vars["prompt"]}, nil
+}
+
+// systemRecorder 记录每次请求的系统提示词
+type systemRecorder struct {
+	*FakeProvider
+	mu      sync.Mutex
+	systems []string
+}
+
+func (p *systemRecorder) Stream(ctx context.Context, req *Request, onDelta func(Delta)) (*Completion, error) {
+	p.mu.Lock()
+	p.systems = append(p.systems, req.System)
+	p.mu.Unlock()
+	return p.FakeProvider.Stream(ctx, req, onDelta)
+}
+
+// TestManager_Prompts tests that the chosen prompt version is used and recorded
+func TestManager_Prompts(t *testing.T) {
+	ctx := context.Background()
+	provider := &systemRecorder{FakeProvider: NewFakeProvider()}
+	meter := &testMeter{}
+	m := NewManager(provider, NewMemoryJobStore(), NewMemoryEventLog(), Hooks{Meter: meter, Prompts: testPrompts{}}, testOptions())
+
+	inputs := []struct {
+		userID      uint64
+		in          *Input
+		wantName    string
+		wantVersion int
+		wantSystem  string
+	}{
+		{1, &Input{Prompt: "a bakery"}, model.PromptCreate, 3, "Design for: a bakery"},
+		{2, &Input{Prompt: "a bakery"}, model.PromptCreate, 0, systemPrompt},
+		{1, &Input{Prompt: "add a footer", CurrentHTML: "<p>hi</p>"}, model.PromptModify, 0, modifySystemPrompt},
+	}
+	for i, tt := range inputs {
+		job, err := m.Start(ctx, tt.userID, nil, tt.in)
+		if err != nil {
+			t.Fatalf("Start() error = %v", err)
+		}
+		if job.PromptName != tt.wantName || job.PromptVersion != tt.wantVersion {
+			t.Errorf("job %d prompt = %s v%d, want %s v%d", i, job.PromptName, job.PromptVersion, tt.wantName, tt.wantVersion)
+		}
+		collect(t, m, job.ID, tt.userID, 0)
+		m.Shutdown()
+		if got := provider.systems[i]; got != tt.wantSystem {
+			t.Errorf("job %d system prompt = %.40q, want %.40q", i, got, tt.wantSystem)
+		}
+		if usage := meter.usage[i]; usage.PromptName != tt.wantName || usage.PromptVersion != tt.wantVersion {
+			t.Errorf("job %d usage prompt = %s v%d", i, usage.PromptName, usage.PromptVersion)
+		}
+	}
+}
+
+// testProjects 保存写入的项目内容，readOnly 中的项目拒绝写入；CSS 与 ProjectService 一样由清洗页面得到
+type testProjects struct {
+	mu       sync.Mutex
+	saved    map[uint64][]model.ChatMessage
+	html     map[uint64]string
+	css      map[uint64]string
+	readOnly map[uint64]bool
+}
+
+func (p *testProjects) ApplyGeneration(_ context.Context, id, _ uint64, page *service.GeneratedPage) (*service.SavedProject, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.readOnly[id] {
+		return nil, service.ErrProjectReadOnly
+	}
+	if page.Quality == nil {
+		return nil, errors.New("page saved without a quality report")
+	}
+	if p.html == nil {
+		p.html, p.css = make(map[uint64]string), make(map[uint64]string)
+	}
+	html := page.HTML
+	if page.Splice != nil {
+		var err error
+		if html, err = page.Splice(p.html[id]); err != nil {
+			return nil, err
+		}
+	}
+	p.saved[id] = append(p.saved[id], page.Turns...)
+	p.html[id] = html
+	p.css[id] = sanitize.DefaultPolicy().Sanitize(html, "").CSS
+	return &service.SavedProject{Project: &model.Project{ID: id, HTML: html, Quality: page.Quality}}, nil
+}
+
+// TestManager_Projects tests that completed pages are saved into their project and failures are reported in the result
+func TestManager_Projects(t *testing.T) {
+	ctx := context.Background()
+	projects := &testProjects{saved: make(map[uint64][]model.ChatMessage), readOnly: map[uint64]bool{2: true}}
+	m := NewManager(NewFakeProvider(), NewMemoryJobStore(), NewMemoryEventLog(), Hooks{Projects: projects}, testOptions())
+
+	one, two := uint64(1), uint64(2)
+	tests := []struct {
+		projectID *uint64
+		wantSaved bool
+		wantError bool
+	}{
+		{&one, true, false},
+		{&two, false, true},
+		{nil, false, false},
+	}
+	for i, tt := range tests {
+		job, err := m.Start(ctx, 7, tt.projectID, &Input{Prompt: "a bakery"})
+		if err != nil {
+			t.Fatalf("Start() error = %v", err)
+		}
+		collect(t, m, job.ID, 7, 0)
+		got, err := m.Get(ctx, job.ID, 7)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if got.Status != model.GenerationCompleted {
+			t.Fatalf("job %d status = %s, want completed", i, got.Status)
+		}
+		if got.Result.Saved != tt.wantSaved || (got.Result.SaveError != "") != tt.wantError {
+			t.Errorf("job %d saved = %v, save error = %q", i, got.Result.Saved, got.Result.SaveError)
+		}
+	}
+
+	turns := projects.saved[1]
+	if len(turns) != 2 || turns[0].Type != model.ChatMessageUser || turns[0].Content != "a bakery" || turns[1].Type != model.ChatMessageAI {
+		t.Errorf("saved turns = %+v", turns)
+	}
+	if len(projects.saved) != 1 {
+		t.Errorf("saved projects = %d, want 1", len(projects.saved))
+	}
+	// 页面的样式只保存一份
+	css := ExtractCSS(FakePage(BuildRequest(&Input{Prompt: "a bakery"}, 0, 0).Prompt))
+	if got := projects.css[1]; css == "" || strings.Count(got, css) != 1 {
+		t.Errorf("saved css = %q, want %q once", got, css)
+	}
+}
+
+// TestManager_ProjectsTarget tests that a regenerated element is spliced into the project's current page
+func TestManager_ProjectsTarget(t *testing.T) {
+	ctx := context.Background()
+	projects := &testProjects{
+		saved: make(map[uint64][]model.ChatMessage),
+		html: map[uint64]string{
+			1: "<main><h1>Old</h1><p>Edited after the request</p></main>",
+			2: "<main><p>Heading removed</p></main>",
+		},
+		css: make(map[uint64]string),
+	}
+	m := NewManager(replyProvider{text: "<h1>New</h1>"}, NewMemoryJobStore(), NewMemoryEventLog(), Hooks{Projects: projects}, testOptions())
+
+	one, two := uint64(1), uint64(2)
+	in := &Input{Prompt: "a bolder title", CurrentHTML: "<main><h1>Old</h1></main>", Target: "main > h1"}
+	job, _ := m.Start(ctx, 7, &one, in)
+	collect(t, m, job.ID, 7, 0)
+	failed, _ := m.Start(ctx, 7, &two, in)
+	collect(t, m, failed.ID, 7, 0)
+	m.Shutdown()
+
+	if got := projects.html[1]; got != "<main><h1>New</h1><p>Edited after the request</p></main>" {
+		t.Errorf("saved html = %q", got)
+	}
+	if got, _ := m.Get(ctx, job.ID, 7); !got.Result.Saved || got.Result.HTML != "<main><h1>New</h1></main>" {
+		t.Errorf("result = %+v", got.Result)
+	}
+	got, _ := m.Get(ctx, failed.ID, 7)
+	if got.Status != model.GenerationCompleted || got.Result.Saved || got.Result.SaveError == "" {
+		t.Errorf("result without the element = %+v", got.Result)
+	}
+	if projects.html[2] != "<main><p>Heading removed</p></main>" {
+		t.Errorf("project without the element changed: %q", projects.html[2])
+	}
+}
+
+// gateProvider 开始生成时关闭 started，等待 release 关闭后才返回页面
+type gateProvider struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p gateProvider) Name() string {
+	return "gate"
+}
+
+func (p gateProvider) Stream(ctx context.Context, req *Request, onDelta func(Delta)) (*Completion, error) {
+	close(p.started)
+	<-p.release
+	return NewFakeProvider().Stream(ctx, req, onDelta)
+}
+
+// TestManager_ProjectsCanceledElsewhere tests that a job canceled on another instance does not overwrite its project
+func TestManager_ProjectsCanceledElsewhere(t *testing.T) {
+	ctx := context.Background()
+	projects := &testProjects{saved: make(map[uint64][]model.ChatMessage)}
+	store, events := NewMemoryJobStore(), NewMemoryEventLog()
+	provider := gateProvider{started: make(chan struct{}), release: make(chan struct{})}
+	m := NewManager(provider, store, events, Hooks{Projects: projects}, testOptions())
+	other := NewManager(NewFakeProvider(), store, events, Hooks{}, testOptions())
+
+	one := uint64(1)
+	job, err := m.Start(ctx, 7, &one, &Input{Prompt: "a bakery"})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	<-provider.started
+	if err := other.Cancel(ctx, job.ID, 7); err != nil {
+		t.Fatalf("Cancel() on the other instance error = %v", err)
+	}
+	close(provider.release)
+	// 等待任务协程自然结束，Shutdown 会把运行中的任务判定中断
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job did not finish")
+	}
+
+	got, err := m.Get(ctx, job.ID, 7)
+	if err != nil || got.Status != model.GenerationCanceled {
+		t.Fatalf("Get() = %+v, %v", got, err)
+	}
+	if len(projects.saved) != 0 {
+		t.Errorf("saved projects = %d, want none", len(projects.saved))
+	}
+}
+
+// TestManager_Queue tests that jobs over the limits wait in turn and report their place
+func TestManager_Queue(t *testing.T) {
+	ctx := context.Background()
+	opts := testOptions()
+	opts.Workers = 1
+	opts.MaxActivePerUser = 5
+	m := newTestManager(&FakeProvider{ChunkSize: 64, Delay: 5 * time.Millisecond}, opts)
+
+	first, _ := m.Start(ctx, 1, nil, &Input{Prompt: "first"})
+	second, _ := m.Start(ctx, 1, nil, &Input{Prompt: "second"})
+	other, _ := m.Start(ctx, 2, nil, &Input{Prompt: "other"})
+	if job, _ := m.Get(ctx, other.ID, 2); job.Status != model.GenerationPending || job.QueuePosition != 1 {
+		t.Errorf("queued job = %s at %d, want pending at 1", job.Status, job.QueuePosition)
+	}
+
+	events := collect(t, m, second.ID, 1, 0)
+	var positions []int
+	for _, e := range events {
+		if e.Type == EventQueued {
+			positions = append(positions, e.Position)
+		}
+	}
+	// 用户 2 还没有轮到过，排到用户 1 的第二个任务之前
+	if len(positions) != 3 || positions[0] != 1 || positions[1] != 2 || positions[2] != 1 {
+		t.Errorf("queued positions = %v, want [1 2 1]", positions)
+	}
+	if last := events[len(events)-1]; last.Type != EventComplete {
+		t.Errorf("last event = %s, want complete", last.Type)
+	}
+
+	a, _ := m.Get(ctx, first.ID, 1)
+	b, _ := m.Get(ctx, other.ID, 2)
+	c, _ := m.Get(ctx, second.ID, 1)
+	if !a.FinishedAt.Before(*b.FinishedAt) || !b.FinishedAt.Before(*c.FinishedAt) {
+		t.Error("jobs did not finish in round-robin order")
+	}
+}
+
+// TestManager_Cancel tests canceling queued and running jobs, here and on another instance
+func TestManager_Cancel(t *testing.T) {
+	ctx := context.Background()
+	opts := testOptions()
+	opts.Workers = 1
+	store, events := NewMemoryJobStore(), NewMemoryEventLog()
+	provider := &FakeProvider{ChunkSize: 1, Delay: 5 * time.Millisecond}
+	m := NewManager(provider, store, events, Hooks{}, opts)
+
+	running, _ := m.Start(ctx, 1, nil, &Input{Prompt: "running"})
+	queued, _ := m.Start(ctx, 2, nil, &Input{Prompt: "queued"})
+	if err := m.Cancel(ctx, queued.ID, 1); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Cancel() of another user's job error = %v, want %v", err, ErrJobNotFound)
+	}
+	if err := m.Cancel(ctx, queued.ID, 2); err != nil {
+		t.Fatalf("Cancel(queued) error = %v", err)
+	}
+	got := collect(t, m, queued.ID, 2, 0)
+	if last := got[len(got)-1]; last.Type != EventCanceled || last.Error != errCanceled.Error() {
+		t.Errorf("last event of queued job = %+v", last)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if err := m.Cancel(ctx, running.ID, 1); err != nil {
+		t.Fatalf("Cancel(running) error = %v", err)
+	}
+	collect(t, m, running.ID, 1, 0)
+	job, _ := m.Get(ctx, running.ID, 1)
+	if job.Status != model.GenerationCanceled || job.Content == "" {
+		t.Errorf("canceled job = %s with %d bytes of content", job.Status, len(job.Content))
+	}
+	if err := m.Cancel(ctx, running.ID, 1); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("Cancel() of ended job error = %v, want %v", err, ErrJobFinished)
+	}
+
+	// 其他实例取消的任务在下次心跳时停止
+	remote, _ := m.Start(ctx, 1, nil, &Input{Prompt: "remote"})
+	other := NewManager(NewFakeProvider(), store, events, Hooks{}, opts)
+	if err := other.Cancel(ctx, remote.ID, 1); err != nil {
+		t.Fatalf("Cancel() from another instance error = %v", err)
+	}
+	m.Maintain(ctx, time.Now(), false)
+	m.mu.Lock()
+	left := len(m.running)
+	m.mu.Unlock()
+	m.Shutdown()
+	if job, _ := m.Get(ctx, remote.ID, 1); job.Status != model.GenerationCanceled || left > 1 {
+		t.Errorf("remotely canceled job = %s, %d jobs left here", job.Status, left)
+	}
+}
@@ -0,0 +1,262 @@
+package generation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/test-tt/internal/model"
+)
+
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+
+	// maxHistoryMessageLen 历史消息带入上下文时的截断长度，避免上下文过长
+	maxHistoryMessageLen = 500
+	maxSummaryPromptLen  = 100
+)
+
+// Message is an earlier turn of the conversation
+type Message struct {
+	Role    string `json:"role"` // user or assistant
+	Content string `json:"content"`
+}
+
+// Input is what a user asks to generate
+type Input struct {
+	Prompt      string    `json:"prompt"`
+	History     []Message `json:"history,omitempty"`
+	CurrentHTML string    `json:"current_html,omitempty"` // 非空时在现有页面上修改
+	Target      string    `json:"target,omitempty"`       // CSS 选择器或元素路径，非空时只重新生成页面中的这个元素
+	Skipped     int       `json:"skipped,omitempty"`      // 超过历史消息条数上限、没有带入的早期消息数
+	Summary     string    `json:"-"`                      // 放不进上下文的早期消息的摘要，生成时填入
+}
+
+// IsModification reports whether the input modifies an existing page
+func (in *Input) IsModification() bool {
+	return strings.TrimSpace(in.CurrentHTML) != ""
+}
+
+// IsTargeted reports whether the input regenerates a single element of the current page
+func (in *Input) IsTargeted() bool {
+	return in.IsModification() && in.Target != ""
+}
+
+// systemPrompt 新建页面的系统提示词
+const systemPrompt = `You are an expert frontend designer and developer. Your task is to create distinctive, production-grade web interfaces that are visually striking and memorable.
+
+## Design Philosophy
+
+Before coding, commit to a BOLD aesthetic direction:
+- **Purpose**: Understand what problem this interface solves and who uses it
+- **Tone**: Choose a clear direction - brutally minimal, maximalist, retro-futuristic, organic/natural, luxury/refined, playful, editorial/magazine, brutalist, art deco, soft/pastel, industrial, etc.
+- **Differentiation**: What makes this UNFORGETTABLE? What's the one thing someone will remember?
+
+## Aesthetic Guidelines
+
+**Typography**:
+- Choose distinctive, characterful fonts - NEVER use generic fonts like Arial, Inter, Roboto
+- Use Google Fonts: Playfair Display, Libre Baskerville, Space Mono, Bebas Neue, Crimson Pro, Archivo Black, DM Serif Display, Cormorant Garamond, Oswald, Lora, etc.
+- Pair a distinctive display font with a refined body font
+
+**Color & Theme**:
+- Commit to a cohesive aesthetic with CSS variables
+- Use dominant colors with sharp accents - avoid timid, evenly-distributed palettes
+- Vary between light and dark themes based on context
+
+**Motion & Interactions**:
+- Add meaningful animations for page load, hover states, transitions
+- Use CSS animations with staggered reveals (animation-delay)
+- Create surprising hover effects and scroll-triggered animations
+
+**Spatial Composition**:
+- Embrace unexpected layouts: asymmetry, overlap, diagonal flow
+- Use generous negative space OR controlled density
+- Break the grid occasionally for visual interest
+
+**Backgrounds & Visual Details**:
+- Create atmosphere with gradient meshes, noise textures, geometric patterns
+- Add layered transparencies, dramatic shadows, decorative borders
+- NEVER default to plain solid backgrounds
+
+## Technical Requirements
+
+1. Create a complete HTML file with embedded CSS (in <style> tags)
+2. Include Google Fonts via @import or <link>
+3. Use CSS variables for consistent theming
+4. Make designs responsive with media queries
+5. Add CSS keyframe animations for effects
+6. Include proper semantic HTML structure
+
+CRITICAL: Avoid generic "AI-generated" aesthetics - no purple gradients on white, no predictable layouts, no cookie-cutter designs. Each design should feel genuinely crafted for its specific context.
+
+Always output the complete HTML code that can be directly rendered in a browser.
+Do NOT use external CSS files or JavaScript libraries unless specifically requested.
+Respond with ONLY the HTML code, no explanations before or after.`
+
+// modifySystemPrompt 多轮对话中修改现有页面的系统提示词
+const modifySystemPrompt = `You are an expert frontend designer and developer. The user has an existing web page and wants to make modifications or additions.
+
+## CRITICAL RULES - YOU MUST FOLLOW:
+1. You will receive the CURRENT HTML CODE of the page - this is the foundation you MUST build upon
+2. NEVER discard the existing content - keep ALL existing sections, elements, and styling
+3. ADD the new requested content to the existing page structure
+4. If user says "add X", you must INSERT X into the existing page, not replace the page with X
+5. Maintain consistent styling with the existing design (colors, fonts, spacing, animations)
+6. Output the COMPLETE modified HTML including ALL original content plus the new additions
+
+Example:
+- If existing page has "Introduction to Changsha" section
+- And user says "add introduction to Mao Zedong"
+- You MUST output: Original Changsha content + New Mao Zedong section
+
+## Design Enhancement Guidelines
+
+When adding new content, maintain the page's aesthetic while making it even better:
+- Match the existing typography choices and font pairings
+- Follow the established color scheme with CSS variables
+- Add smooth entrance animations for new sections (fade-in, slide-up)
+- Ensure visual hierarchy and spatial flow with existing content
+- Keep the distinctive, non-generic aesthetic of the original design
+
+Always output the complete HTML code that can be directly rendered in a browser.
+Respond with ONLY the HTML code, no explanations before or after.`
+
+// selectedElementHeading 请求中选中元素的标题，也用于识别只重新生成元素的请求
+const selectedElementHeading = "=== SELECTED ELEMENT (REWRITE THIS) ==="
+
+// fragmentSystemPrompt 只重新生成页面中选中元素的系统提示词
+const fragmentSystemPrompt = `You are an expert frontend designer and developer. The user has an existing web page and wants to change ONE element of it.
+
+## CRITICAL RULES - YOU MUST FOLLOW:
+1. You will receive the SELECTED ELEMENT, where it sits in the page, the elements around it and the page CSS
+2. Rewrite ONLY the selected element according to the user's request
+3. Output exactly ONE root element that replaces the selected element - no <!DOCTYPE>, <html>, <head> or <body>
+4. Close every tag you open, the element is put back into the page exactly as you write it
+5. Reuse the page's CSS variables, classes and fonts so the element still fits the design
+6. If new CSS is needed, use a <style> element INSIDE the root element or inline styles
+7. Keep the tag, id and classes of the root element unless the user asks otherwise
+
+Respond with ONLY the HTML of the element in a ` + "```html" + ` code block, no explanations before or after.`
+
+var (
+	htmlBlockPattern = regexp.MustCompile("(?s)```html\\s*(.*?)```")
+	htmlDocPattern   = regexp.MustCompile(`(?is)<!DOCTYPE.*</html>`)
+	stylePattern     = regexp.MustCompile(`(?is)<style[^>]*>(.*?)</style>`)
+)
+
+// BuildRequest builds the provider request for an input
+func BuildRequest(in *Input, maxTokens, thinkingTokens int) *Request {
+	var b strings.Builder
+	if in.Summary != "" {
+		fmt.Fprintf(&b, "Summary of the earlier conversation:\n%s\n\n", in.Summary)
+	}
+	if len(in.History) > 0 {
+		b.WriteString("Previous conversation:\n")
+		for _, msg := range in.History {
+			b.WriteString(historyLine(msg))
+		}
+		b.WriteString("\n")
+	}
+
+	system := systemPrompt
+	target, err := FindTarget(in.CurrentHTML, in.Target)
+	if in.IsTargeted() && err == nil {
+		system = fragmentSystemPrompt
+		if css := target.CSS(); css != "" {
+			fmt.Fprintf(&b, "=== PAGE CSS ===\n```css\n%s\n```\n\n", css)
+		}
+		fmt.Fprintf(&b, "=== LOCATION OF THE SELECTED ELEMENT ===\n%s\n\n", target.Path())
+		before, after := target.Siblings()
+		if before != "" {
+			fmt.Fprintf(&b, "=== ELEMENT BEFORE (DO NOT OUTPUT) ===\n```html\n%s\n```\n\n", before)
+		}
+		fmt.Fprintf(&b, "%s\n```html\n%s\n```\n\n", selectedElementHeading, target.HTML())
+		if after != "" {
+			fmt.Fprintf(&b, "=== ELEMENT AFTER (DO NOT OUTPUT) ===\n```html\n%s\n```\n\n", after)
+		}
+		fmt.Fprintf(&b, "User modification request: %q\n\n"+
+			"Output ONLY the rewritten selected element as a single root element.", in.Prompt)
+	} else if in.IsModification() {
+		system = modifySystemPrompt
+		fmt.Fprintf(&b, "=== CURRENT PAGE HTML (YOU MUST PRESERVE THIS) ===\n```html\n%s\n```\n=== END OF CURRENT PAGE ===\n\n", in.CurrentHTML)
+		fmt.Fprintf(&b, "User modification request: %q\n\n"+
+			"IMPORTANT: The user wants to MODIFY/ADD to the existing page shown above.\n"+
+			"- DO NOT create a new page from scratch\n"+
+			"- KEEP all existing content from the current HTML code\n"+
+			"- ADD or MODIFY only what the user requested\n"+
+			"- Output the COMPLETE HTML with both old and new content combined.", in.Prompt)
+	} else {
+		fmt.Fprintf(&b, "User request: %s\n\nGenerate a complete HTML page with embedded CSS for this request. Output ONLY the HTML code.", in.Prompt)
+	}
+
+	return &Request{
+		Kind:           promptName(in),
+		System:         system,
+		Prompt:         b.String(),
+		MaxTokens:      maxTokens,
+		ThinkingTokens: thinkingTokens,
+	}
+}
+
+// promptName 输入对应的提示词模板名称，也是请求的类型
+func promptName(in *Input) string {
+	switch {
+	case in.IsTargeted():
+		return model.PromptFragment
+	case in.IsModification():
+		return model.PromptModify
+	default:
+		return model.PromptCreate
+	}
+}
+
+// historyLine 历史消息在请求中的一行，过长的消息被截断
+func historyLine(msg Message) string {
+	role := "User"
+	if msg.Role == RoleAssistant {
+		role = "Assistant"
+	}
+	content := msg.Content
+	if runes := []rune(content); len(runes) > maxHistoryMessageLen {
+		content = string(runes[:maxHistoryMessageLen]) + "..."
+	}
+	return fmt.Sprintf("%s: %s\n", role, content)
+}
+
+// ExtractHTML takes the page out of a model response: a markdown html block, a full document, or the response itself
+func ExtractHTML(response string) string {
+	if m := htmlBlockPattern.FindStringSubmatch(response); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	if m := htmlDocPattern.FindString(response); m != "" {
+		return strings.TrimSpace(m)
+	}
+	return strings.TrimSpace(response)
+}
+
+// ExtractCSS returns the content of the first style block of a page
+func ExtractCSS(page string) string {
+	if m := stylePattern.FindStringSubmatch(page); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	return ""
+}
+
+// summary 生成完成后回复给用户的消息
+func summary(in *Input) string {
+	prompt := in.Prompt
+	if runes := []rune(prompt); len(runes) > maxSummaryPromptLen {
+		prompt = string(runes[:maxSummaryPromptLen]) + "..."
+	}
+	if in.IsTargeted() {
+		return fmt.Sprintf("I've regenerated the selected element `%s` based on your request:\n\n**%q**\n\n"+
+			"The rest of the page is unchanged. Feel free to request more changes!", in.Target, prompt)
+	}
+	if in.IsModification() {
+		return fmt.Sprintf("I've applied the following changes based on your request:\n\n**%q**\n\n"+
+			"The modifications have been made while preserving the existing structure. Feel free to request more changes!", prompt)
+	}
+	return fmt.Sprintf("I've generated a web page based on your request:\n\n**%q**\n\nFeel free to customize it further!", prompt)
+}
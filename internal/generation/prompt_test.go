@@ -0,0 +1,72 @@
+package generation
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestExtractHTML tests extracting the page from model responses
+func TestExtractHTML(t *testing.T) {
+	doc := "<!DOCTYPE html><html><head><style>\nbody { color: red; }\n</style></head><body></body></html>"
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{"markdown block", "Here you go:\n```html\n" + doc + "\n```\nEnjoy!", doc},
+		{"raw document", "Sure.\n" + doc + "\nDone.", doc},
+		{"fragment", "  <div>hi</div>\n", "<div>hi</div>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractHTML(tt.response); got != tt.want {
+				t.Errorf("ExtractHTML() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if css := ExtractCSS(doc); css != "body { color: red; }" {
+		t.Errorf("ExtractCSS() = %q", css)
+	}
+	if css := ExtractCSS("<p>no style</p>"); css != "" {
+		t.Errorf("ExtractCSS() without style = %q", css)
+	}
+}
+
+// TestBuildRequest tests prompts for new pages and modifications
+func TestBuildRequest(t *testing.T) {
+	req := BuildRequest(&Input{Prompt: "a blog"}, 1000, 500)
+	if req.System != systemPrompt || !strings.Contains(req.Prompt, "User request: a blog") {
+		t.Errorf("new page request = %q", req.Prompt)
+	}
+	if req.MaxTokens != 1000 || req.ThinkingTokens != 500 {
+		t.Errorf("budgets = %d/%d", req.MaxTokens, req.ThinkingTokens)
+	}
+
+	req = BuildRequest(&Input{
+		Prompt:      "add a footer",
+		History:     []Message{{Role: RoleUser, Content: "a blog"}, {Role: RoleAssistant, Content: strings.Repeat("x", 600)}},
+		CurrentHTML: "<p>existing</p>",
+	}, 1000, 0)
+	if req.System != modifySystemPrompt {
+		t.Error("modification did not use the modify system prompt")
+	}
+	for _, want := range []string{"User: a blog", "Assistant: " + strings.Repeat("x", maxHistoryMessageLen) + "...", "<p>existing</p>", `"add a footer"`} {
+		if !strings.Contains(req.Prompt, want) {
+			t.Errorf("modification prompt is missing %.40q", want)
+		}
+	}
+
+	req = BuildRequest(&Input{Prompt: "make it red", CurrentHTML: targetPage, Target: "main > section:nth-of-type(2)"}, 1000, 0)
+	if req.System != fragmentSystemPrompt {
+		t.Error("targeted modification did not use the fragment system prompt")
+	}
+	for _, want := range []string{"--accent", "html > body > main > section.menu.special", "<h2>Bread</h2>", "<h2>Contact</h2>", selectedElementHeading + "\n```html\n<section class=\"menu special\""} {
+		if !strings.Contains(req.Prompt, want) {
+			t.Errorf("targeted prompt is missing %.40q", want)
+		}
+	}
+	if strings.Contains(req.Prompt, "<title>") {
+		t.Error("targeted prompt includes the whole page")
+	}
+}
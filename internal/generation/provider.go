@@ -0,0 +1,61 @@
+// Package generation generates pages from prompts with a pluggable LLM provider
+// and tracks each generation as a job whose progress can be streamed.
+package generation
+
+import (
+	"context"
+	"errors"
+	"unicode/utf8"
+)
+
+// DeltaKind 流式输出片段的类型
+type DeltaKind string
+
+const (
+	DeltaThinking DeltaKind = "thinking" // 模型的思考过程
+	DeltaText     DeltaKind = "text"     // 最终输出
+)
+
+// ErrProviderUnavailable is returned when the provider cannot be reached or rejects the request
+var ErrProviderUnavailable = errors.New("generation provider unavailable")
+
+// Request is one completion request sent to a provider
+type Request struct {
+	Kind           string // create、modify、fragment 或 summary，用于选择模型服务
+	System         string
+	Prompt         string
+	MaxTokens      int
+	ThinkingTokens int // 0 表示不使用扩展思考
+}
+
+// Delta is a piece of streamed provider output
+type Delta struct {
+	Kind DeltaKind
+	Text string
+}
+
+// Usage is the number of tokens a request consumed
+type Usage struct {
+	InputTokens  int64
+	OutputTokens int64
+}
+
+// Completion is the complete output of a request
+type Completion struct {
+	Text     string
+	Usage    Usage
+	Provider string // 实际生成的模型服务，经过路由时设置
+}
+
+// LLMProvider generates text for a request
+// Stream 在生成过程中按顺序回调输出片段，返回完整的输出和用量；ctx 取消时应尽快返回
+// 出错时 Completion 可以为 nil，非 nil 时其中的用量是出错前已经消耗的部分
+type LLMProvider interface {
+	Name() string
+	Stream(ctx context.Context, req *Request, onDelta func(Delta)) (*Completion, error)
+}
+
+// EstimateTokens roughly counts the tokens of a text, for providers that do not report usage
+func EstimateTokens(text string) int64 {
+	return int64(utf8.RuneCountInString(text)+3) / 4
+}
@@ -0,0 +1,556 @@
+package generation
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/test-tt/internal/model"
+)
+
+// 质量问题代码
+const (
+	QualityInvalidMarkup   = "invalid_markup"   // 无法解析的标记
+	QualityUnclosedTag     = "unclosed_tag"     // 没有结束标签的元素
+	QualityStrayEndTag     = "stray_end_tag"    // 没有对应开始标签的结束标签
+	QualityDuplicateID     = "duplicate_id"     // 重复的 id
+	QualityMissingAlt      = "missing_alt"      // 图片缺少 alt
+	QualityMissingViewport = "missing_viewport" // 缺少 viewport meta，移动端按桌面宽度缩放
+	QualityMissingTitle    = "missing_title"
+	QualityMissingLang     = "missing_lang"    // html 缺少 lang
+	QualityLowContrast     = "low_contrast"    // 文字与背景的对比度低于 WCAG AA
+	QualityExternalScript  = "external_script" // 从其他站点加载脚本
+	QualityOversized       = "oversized"       // 页面超过大小上限
+)
+
+const (
+	// minContrastRatio WCAG AA 对正文的最低对比度
+	minContrastRatio = 4.5
+	// maxQualityIssues 报告中最多保留的问题条数，计数仍包括全部问题
+	maxQualityIssues = 50
+	maxIssueDetail   = 120
+	maxVarDepth      = 10
+)
+
+// voidElements 没有结束标签的元素
+var voidElements = map[string]struct{}{
+	"area": {}, "base": {}, "br": {}, "col": {}, "embed": {}, "hr": {}, "img": {}, "input": {},
+	"link": {}, "meta": {}, "param": {}, "source": {}, "track": {}, "wbr": {},
+}
+
+// optionalEndElements 可以省略结束标签的元素
+var optionalEndElements = map[string]struct{}{
+	"html": {}, "head": {}, "body": {}, "p": {}, "li": {}, "dt": {}, "dd": {}, "option": {}, "optgroup": {},
+	"rb": {}, "rt": {}, "rtc": {}, "rp": {}, "tr": {}, "td": {}, "th": {}, "thead": {}, "tbody": {}, "tfoot": {}, "colgroup": {},
+}
+
+var (
+	cssCommentPattern = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	cssVarPattern     = regexp.MustCompile(`var\(\s*(--[\w-]+)\s*(?:,\s*([^()]*(?:\([^()]*\)[^()]*)*))?\)`)
+)
+
+// namedColors 常用的颜色名称
+var namedColors = map[string][3]float64{
+	"black": {0, 0, 0}, "white": {255, 255, 255}, "gray": {128, 128, 128}, "grey": {128, 128, 128},
+	"silver": {192, 192, 192}, "red": {255, 0, 0}, "maroon": {128, 0, 0}, "orange": {255, 165, 0},
+	"yellow": {255, 255, 0}, "olive": {128, 128, 0}, "lime": {0, 255, 0}, "green": {0, 128, 0},
+	"aqua": {0, 255, 255}, "cyan": {0, 255, 255}, "teal": {0, 128, 128}, "blue": {0, 0, 255},
+	"navy": {0, 0, 128}, "fuchsia": {255, 0, 255}, "magenta": {255, 0, 255}, "purple": {128, 0, 128},
+	"lightgray": {211, 211, 211}, "lightgrey": {211, 211, 211}, "darkgray": {169, 169, 169}, "darkgrey": {169, 169, 169},
+	"whitesmoke": {245, 245, 245}, "gainsboro": {220, 220, 220},
+}
+
+// CheckQuality reports structural, accessibility and size problems of a generated page
+// maxBytes 为 0 时不检查大小
+func CheckQuality(page string, maxBytes int) *model.QualityReport {
+	c := &qualityChecker{ids: make(map[string]int), issues: make(map[issueKey]*model.QualityIssue)}
+	c.markup(page)
+	c.contrast()
+	if maxBytes > 0 && len(page) > maxBytes {
+		c.add(QualityOversized, model.QualityWarning, fmt.Sprintf("%d bytes, limit %d", len(page), maxBytes))
+	}
+	return c.report(len(page))
+}
+
+type issueKey struct {
+	code   string
+	detail string
+}
+
+type qualityChecker struct {
+	issues map[issueKey]*model.QualityIssue
+	ids    map[string]int
+	// styles <style> 块的内容；inline 带 style 属性的元素
+	styles   []string
+	inline   []inlineStyle
+	document bool
+	viewport bool
+	title    bool
+}
+
+type inlineStyle struct {
+	tag   string
+	style string
+}
+
+func (c *qualityChecker) add(code, severity, detail string) {
+	if len(detail) > maxIssueDetail {
+		detail = detail[:maxIssueDetail] + "..."
+	}
+	key := issueKey{code: code, detail: detail}
+	if issue, ok := c.issues[key]; ok {
+		issue.Count++
+		return
+	}
+	c.issues[key] = &model.QualityIssue{Code: code, Severity: severity, Detail: detail, Count: 1}
+}
+
+// markup 按标记流检查结构，html.Parse 会自动修复结构问题，所以这里用 Tokenizer
+func (c *qualityChecker) markup(page string) {
+	z := html.NewTokenizer(strings.NewReader(page))
+	var stack []string
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if err := z.Err(); !errors.Is(err, io.EOF) {
+				c.add(QualityInvalidMarkup, model.QualityError, err.Error())
+			}
+			for _, tag := range stack {
+				c.unclosed(tag)
+			}
+			c.finishDocument()
+			return
+
+		case html.TextToken:
+			if len(stack) == 0 {
+				continue
+			}
+			switch stack[len(stack)-1] {
+			case "style":
+				c.styles = append(c.styles, string(z.Text()))
+			case "title":
+				if strings.TrimSpace(string(z.Text())) != "" {
+					c.title = true
+				}
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tag := c.element(z)
+			if _, void := voidElements[tag]; void || tt == html.SelfClosingTagToken {
+				continue
+			}
+			stack = append(stack, tag)
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if _, void := voidElements[tag]; void {
+				continue
+			}
+			i := len(stack) - 1
+			for i >= 0 && stack[i] != tag {
+				i--
+			}
+			if i < 0 {
+				c.add(QualityStrayEndTag, model.QualityError, tag)
+				continue
+			}
+			for _, open := range stack[i+1:] {
+				c.unclosed(open)
+			}
+			stack = stack[:i]
+		}
+	}
+}
+
+func (c *qualityChecker) unclosed(tag string) {
+	if _, optional := optionalEndElements[tag]; !optional {
+		c.add(QualityUnclosedTag, model.QualityError, tag)
+	}
+}
+
+// element 检查开始标签的属性，返回标签名
+func (c *qualityChecker) element(z *html.Tokenizer) string {
+	name, hasAttr := z.TagName()
+	tag := string(name)
+	attrs := make(map[string]string)
+	for hasAttr {
+		var key, val []byte
+		key, val, hasAttr = z.TagAttr()
+		attrs[string(key)] = string(val)
+	}
+
+	if id := strings.TrimSpace(attrs["id"]); id != "" {
+		if c.ids[id]++; c.ids[id] == 2 {
+			c.add(QualityDuplicateID, model.QualityWarning, id)
+		}
+	}
+	if style, ok := attrs["style"]; ok {
+		c.inline = append(c.inline, inlineStyle{tag: tag, style: style})
+	}
+
+	switch tag {
+	case "html":
+		c.document = true
+		if strings.TrimSpace(attrs["lang"]) == "" {
+			c.add(QualityMissingLang, model.QualityWarning, "")
+		}
+	case "img":
+		if _, ok := attrs["alt"]; !ok {
+			c.add(QualityMissingAlt, model.QualityError, attrs["src"])
+		}
+	case "input":
+		if _, ok := attrs["alt"]; !ok && strings.EqualFold(attrs["type"], "image") {
+			c.add(QualityMissingAlt, model.QualityError, attrs["src"])
+		}
+	case "meta":
+		if strings.EqualFold(strings.TrimSpace(attrs["name"]), "viewport") {
+			c.viewport = true
+		}
+	case "script":
+		if src := strings.TrimSpace(attrs["src"]); isExternalURL(src) {
+			c.add(QualityExternalScript, model.QualityWarning, urlHost(src))
+		}
+	}
+	return tag
+}
+
+// finishDocument 检查只对完整文档有意义的项目
+func (c *qualityChecker) finishDocument() {
+	if !c.document {
+		return
+	}
+	if !c.viewport {
+		c.add(QualityMissingViewport, model.QualityWarning, "")
+	}
+	if !c.title {
+		c.add(QualityMissingTitle, model.QualityWarning, "")
+	}
+}
+
+// contrast 检查同一规则（或 style 属性）中文字颜色和背景色的对比度，颜色可以来自 CSS 变量
+func (c *qualityChecker) contrast() {
+	var rules []cssRule
+	for _, css := range c.styles {
+		rules = append(rules, parseCSS(css)...)
+	}
+	for _, s := range c.inline {
+		rules = append(rules, cssRule{selector: "<" + s.tag + " style>", decls: parseDeclarations(s.style)})
+	}
+
+	vars := make(map[string]string)
+	for _, rule := range rules {
+		for name, value := range rule.decls {
+			if strings.HasPrefix(name, "--") {
+				vars[name] = value
+			}
+		}
+	}
+
+	for _, rule := range rules {
+		fgValue, ok := rule.decls["color"]
+		if !ok {
+			continue
+		}
+		bgValue, ok := rule.decls["background-color"]
+		if !ok {
+			if bgValue, ok = rule.decls["background"]; !ok {
+				continue
+			}
+		}
+		fgText, bgText := resolveVars(fgValue, vars, 0), resolveVars(bgValue, vars, 0)
+		fg, ok := parseColor(fgText)
+		if !ok {
+			continue
+		}
+		bg, ok := backgroundColor(bgText)
+		if !ok {
+			continue
+		}
+		if ratio := contrastRatio(fg, bg); ratio < minContrastRatio {
+			c.add(QualityLowContrast, model.QualityWarning,
+				fmt.Sprintf("%s: %s on %s (%.2f:1)", rule.selector, fgText, bgText, ratio))
+		}
+	}
+}
+
+func (c *qualityChecker) report(size int) *model.QualityReport {
+	report := &model.QualityReport{Bytes: size, Issues: make([]model.QualityIssue, 0, len(c.issues)), CheckedAt: time.Now()}
+	for _, issue := range c.issues {
+		if issue.Severity == model.QualityError {
+			report.Errors += issue.Count
+		} else {
+			report.Warnings += issue.Count
+		}
+		report.Issues = append(report.Issues, *issue)
+	}
+	sort.Slice(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.Severity != b.Severity {
+			return a.Severity == model.QualityError
+		}
+		if a.Code != b.Code {
+			return a.Code < b.Code
+		}
+		return a.Detail < b.Detail
+	})
+	if len(report.Issues) > maxQualityIssues {
+		report.Issues = report.Issues[:maxQualityIssues]
+	}
+	return report
+}
+
+// cssRule 一条样式规则的选择器和声明，同名属性后者覆盖前者
+type cssRule struct {
+	selector string
+	decls    map[string]string
+}
+
+// parseCSS 粗略解析样式表，进入 @media 等条件规则，跳过 @keyframes 等其他块
+func parseCSS(css string) []cssRule {
+	css = cssCommentPattern.ReplaceAllString(css, "")
+	var rules []cssRule
+	for len(css) > 0 {
+		open := strings.IndexByte(css, '{')
+		if open < 0 {
+			break
+		}
+		prelude := strings.TrimSpace(css[:open])
+		// 跳过块前的 @import、@charset 等语句
+		if i := strings.LastIndexByte(prelude, ';'); i >= 0 {
+			prelude = strings.TrimSpace(prelude[i+1:])
+		}
+		end := matchingBrace(css, open)
+		body := css[open+1 : end]
+		if end < len(css) {
+			end++
+		}
+		css = css[end:]
+
+		if strings.HasPrefix(prelude, "@") {
+			at := strings.ToLower(strings.Fields(prelude)[0])
+			if at == "@media" || at == "@supports" || at == "@layer" || at == "@container" {
+				rules = append(rules, parseCSS(body)...)
+			}
+			continue
+		}
+		rules = append(rules, cssRule{selector: prelude, decls: parseDeclarations(body)})
+	}
+	return rules
+}
+
+// matchingBrace 返回与 open 处的 { 对应的 } 的位置，没有时返回字符串长度
+func matchingBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
+
+func parseDeclarations(body string) map[string]string {
+	decls := make(map[string]string)
+	for _, decl := range strings.Split(body, ";") {
+		name, value, ok := strings.Cut(decl, ":")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		if !strings.HasPrefix(name, "--") {
+			name = strings.ToLower(name)
+		}
+		value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "!important"))
+		if name != "" && value != "" {
+			decls[name] = value
+		}
+	}
+	return decls
+}
+
+// resolveVars 展开 var()，未定义的变量使用默认值
+func resolveVars(value string, vars map[string]string, depth int) string {
+	if depth >= maxVarDepth || !strings.Contains(value, "var(") {
+		return value
+	}
+	resolved := cssVarPattern.ReplaceAllStringFunc(value, func(m string) string {
+		sub := cssVarPattern.FindStringSubmatch(m)
+		if v, ok := vars[sub[1]]; ok {
+			return v
+		}
+		return strings.TrimSpace(sub[2])
+	})
+	return resolveVars(resolved, vars, depth+1)
+}
+
+// backgroundColor 从 background 或 background-color 中取出颜色，渐变和图片背景无法判断
+func backgroundColor(value string) ([3]float64, bool) {
+	lower := strings.ToLower(value)
+	if strings.Contains(lower, "gradient") || strings.Contains(lower, "url(") {
+		return [3]float64{}, false
+	}
+	if color, ok := parseColor(value); ok {
+		return color, true
+	}
+	for _, part := range strings.Fields(value) {
+		if color, ok := parseColor(part); ok {
+			return color, true
+		}
+	}
+	return [3]float64{}, false
+}
+
+// parseColor 解析不透明的颜色，带透明度的颜色依赖下层背景，视为无法判断
+func parseColor(value string) ([3]float64, bool) {
+	v := strings.ToLower(strings.TrimSpace(value))
+	if c, ok := namedColors[v]; ok {
+		return c, true
+	}
+	if strings.HasPrefix(v, "#") {
+		return parseHexColor(v[1:])
+	}
+
+	fn, args, ok := strings.Cut(v, "(")
+	if !ok || !strings.HasSuffix(args, ")") {
+		return [3]float64{}, false
+	}
+	parts := strings.FieldsFunc(strings.TrimSuffix(args, ")"), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '/'
+	})
+	if len(parts) != 3 && len(parts) != 4 {
+		return [3]float64{}, false
+	}
+	if len(parts) == 4 {
+		if alpha, ok := parseNumber(parts[3], 1); !ok || alpha < 1 {
+			return [3]float64{}, false
+		}
+	}
+
+	switch fn {
+	case "rgb", "rgba":
+		var c [3]float64
+		for i := range 3 {
+			n, ok := parseNumber(parts[i], 255)
+			if !ok {
+				return [3]float64{}, false
+			}
+			c[i] = math.Max(0, math.Min(255, n))
+		}
+		return c, true
+	case "hsl", "hsla":
+		h, err := strconv.ParseFloat(strings.TrimSuffix(parts[0], "deg"), 64)
+		s, okS := parseNumber(parts[1], 1)
+		l, okL := parseNumber(parts[2], 1)
+		if err != nil || !okS || !okL {
+			return [3]float64{}, false
+		}
+		return hslToRGB(h, s, l), true
+	}
+	return [3]float64{}, false
+}
+
+func parseHexColor(hex string) ([3]float64, bool) {
+	switch len(hex) {
+	case 3, 4:
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]}) + strings.Repeat(hex[3:], 2)
+	case 6, 8:
+	default:
+		return [3]float64{}, false
+	}
+	n, err := strconv.ParseUint(hex, 16, 64)
+	if err != nil {
+		return [3]float64{}, false
+	}
+	if len(hex) == 8 {
+		if n&0xff != 0xff {
+			return [3]float64{}, false
+		}
+		n >>= 8
+	}
+	return [3]float64{float64(n >> 16 & 0xff), float64(n >> 8 & 0xff), float64(n & 0xff)}, true
+}
+
+// parseNumber 解析数字或百分比，百分比按 scale 换算
+func parseNumber(s string, scale float64) (float64, bool) {
+	if p, ok := strings.CutSuffix(s, "%"); ok {
+		n, err := strconv.ParseFloat(p, 64)
+		return n / 100 * scale, err == nil
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	return n, err == nil
+}
+
+func hslToRGB(h, s, l float64) [3]float64 {
+	h = math.Mod(math.Mod(h, 360)+360, 360) / 360
+	s, l = math.Max(0, math.Min(1, s)), math.Max(0, math.Min(1, l))
+	if s == 0 {
+		return [3]float64{l * 255, l * 255, l * 255}
+	}
+	q := l * (1 + s)
+	if l >= 0.5 {
+		q = l + s - l*s
+	}
+	p := 2*l - q
+	hue := func(t float64) float64 {
+		t = math.Mod(t+1, 1)
+		switch {
+		case t < 1.0/6:
+			return p + (q-p)*6*t
+		case t < 0.5:
+			return q
+		case t < 2.0/3:
+			return p + (q-p)*(2.0/3-t)*6
+		}
+		return p
+	}
+	return [3]float64{hue(h+1.0/3) * 255, hue(h) * 255, hue(h-1.0/3) * 255}
+}
+
+// contrastRatio 按 WCAG 2 计算两种颜色的对比度
+func contrastRatio(a, b [3]float64) float64 {
+	la, lb := luminance(a), luminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+func luminance(c [3]float64) float64 {
+	channel := func(v float64) float64 {
+		v /= 255
+		if v <= 0.03928 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(c[0]) + 0.7152*channel(c[1]) + 0.0722*channel(c[2])
+}
+
+// isExternalURL 判断是否指向其他站点
+func isExternalURL(raw string) bool {
+	lower := strings.ToLower(raw)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "//")
+}
+
+func urlHost(raw string) string {
+	rest := raw[strings.Index(raw, "//")+2:]
+	if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+		rest = rest[:i]
+	}
+	return rest
+}
@@ -0,0 +1,143 @@
+package generation
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/test-tt/internal/model"
+)
+
+func findIssue(r *model.QualityReport, code string) *model.QualityIssue {
+	for i := range r.Issues {
+		if r.Issues[i].Code == code {
+			return &r.Issues[i]
+		}
+	}
+	return nil
+}
+
+// TestCheckQuality_Clean tests that a well-formed page has no issues
+func TestCheckQuality_Clean(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1.0">
+<title>Bakery</title>
+<style>
+:root { --text: #1a1a1a; --bg: #ffffff; }
+body { color: var(--text); background: var(--bg); }
+</style>
+</head>
+<body>
+<ul><li>Bread<li>Cake</ul>
+<p>Fresh every day
+<img src="bread.png" alt="">
+<svg viewBox="0 0 10 10"><path d="M0 0h10"/></svg>
+</body>
+</html>`
+	r := CheckQuality(page, 0)
+	if len(r.Issues) != 0 || r.Errors != 0 || r.Warnings != 0 {
+		t.Errorf("issues = %+v", r.Issues)
+	}
+	if r.Bytes != len(page) {
+		t.Errorf("bytes = %d, want %d", r.Bytes, len(page))
+	}
+}
+
+// TestCheckQuality_Problems tests that each kind of problem is reported
+func TestCheckQuality_Problems(t *testing.T) {
+	page := `<html>
+<head><style>
+:root { --muted: #aaaaaa; --surface: var(--white, #fff); }
+@media (min-width: 600px) { .hero { color: var(--muted); background: var(--surface) no-repeat; } }
+.gradient { color: #aaa; background: linear-gradient(#fff, #000); }
+</style>
+<script src="https://cdn.example.com/lib.js?v=1"></script>
+<script src="/local.js"></script>
+</head>
+<body>
+<div id="a"><section id="a">
+<img src="hero.png"><img src="hero.png">
+<span style="color: rgb(200, 200, 200); background-color: white">faint</span>
+</div></span>
+</body>
+</html>`
+	r := CheckQuality(page, 100)
+
+	tests := []struct {
+		code     string
+		severity string
+		detail   string
+		count    int
+	}{
+		{QualityUnclosedTag, model.QualityError, "section", 1},
+		{QualityStrayEndTag, model.QualityError, "span", 1},
+		{QualityMissingAlt, model.QualityError, "hero.png", 2},
+		{QualityDuplicateID, model.QualityWarning, "a", 1},
+		{QualityMissingViewport, model.QualityWarning, "", 1},
+		{QualityMissingTitle, model.QualityWarning, "", 1},
+		{QualityMissingLang, model.QualityWarning, "", 1},
+		{QualityExternalScript, model.QualityWarning, "cdn.example.com", 1},
+		{QualityOversized, model.QualityWarning, "", 1},
+	}
+	for _, tt := range tests {
+		issue := findIssue(r, tt.code)
+		if issue == nil {
+			t.Errorf("missing %s issue", tt.code)
+			continue
+		}
+		if issue.Severity != tt.severity || issue.Count != tt.count || (tt.detail != "" && issue.Detail != tt.detail) {
+			t.Errorf("%s issue = %+v", tt.code, issue)
+		}
+	}
+
+	var contrast []string
+	for _, issue := range r.Issues {
+		if issue.Code == QualityLowContrast {
+			contrast = append(contrast, issue.Detail)
+		}
+	}
+	if len(contrast) != 2 {
+		t.Fatalf("low contrast issues = %v, want the .hero rule and the span", contrast)
+	}
+	if !strings.Contains(strings.Join(contrast, "\n"), ".hero: #aaaaaa on #fff no-repeat") {
+		t.Errorf("low contrast issues = %v, want variables resolved", contrast)
+	}
+	if r.Errors != 4 || r.Warnings != 8 || r.Issues[0].Severity != model.QualityError {
+		t.Errorf("errors = %d, warnings = %d", r.Errors, r.Warnings)
+	}
+}
+
+// TestParseColor tests the supported color syntaxes
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want [3]float64
+		ok   bool
+	}{
+		{"#fff", [3]float64{255, 255, 255}, true},
+		{"#336699", [3]float64{51, 102, 153}, true},
+		{"#336699ff", [3]float64{51, 102, 153}, true},
+		{"#33669980", [3]float64{}, false},
+		{"rgb(10, 20, 30)", [3]float64{10, 20, 30}, true},
+		{"rgb(100% 0% 0% / 1)", [3]float64{255, 0, 0}, true},
+		{"rgba(0,0,0,0.5)", [3]float64{}, false},
+		{"hsl(120, 100%, 25%)", [3]float64{0, 127.5, 0}, true},
+		{"Navy", [3]float64{0, 0, 128}, true},
+		{"transparent", [3]float64{}, false},
+		{"currentColor", [3]float64{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseColor(tt.in)
+		near := math.Abs(got[0]-tt.want[0]) < 0.5 && math.Abs(got[1]-tt.want[1]) < 0.5 && math.Abs(got[2]-tt.want[2]) < 0.5
+		if ok != tt.ok || (ok && !near) {
+			t.Errorf("parseColor(%q) = %v, %v, want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if ratio := contrastRatio([3]float64{0, 0, 0}, [3]float64{255, 255, 255}); math.Abs(ratio-21) > 0.01 {
+		t.Errorf("contrastRatio(black, white) = %.2f, want 21", ratio)
+	}
+}
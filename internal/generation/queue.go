@@ -0,0 +1,192 @@
+package generation
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/internal/service"
+)
+
+// queuedJob 在本实例排队等待开始的任务
+type queuedJob struct {
+	id       string
+	userID   uint64
+	job      *model.GenerationJob
+	in       *Input
+	prompt   *service.PromptChoice
+	ctx      context.Context
+	queuedAt time.Time
+	position int // 最近一次报告的排队位置
+}
+
+// fairQueue schedules the jobs of an instance round-robin across users
+// 每次从最久没有轮到的用户取下一个任务，从未轮到的用户最先；同时运行的任务不超过 workers，
+// 每个用户不超过 perUser。不是并发安全的，由 Manager 加锁
+type fairQueue struct {
+	workers   int
+	perUser   int
+	running   int
+	runningBy map[uint64]int
+	queues    map[uint64][]*queuedJob
+	// served 用户最近一次轮到时的 turn，没有排队和运行中的任务时重置
+	served map[uint64]uint64
+	turn   uint64
+	// arrivals 用户开始排队的先后，served 相同时先到的优先
+	arrivals map[uint64]uint64
+	arrived  uint64
+}
+
+func newFairQueue(workers, perUser int) *fairQueue {
+	return &fairQueue{
+		workers:   workers,
+		perUser:   perUser,
+		runningBy: make(map[uint64]int),
+		queues:    make(map[uint64][]*queuedJob),
+		served:    make(map[uint64]uint64),
+		arrivals:  make(map[uint64]uint64),
+	}
+}
+
+// push 把任务加到用户队列的末尾
+func (q *fairQueue) push(job *queuedJob) {
+	if len(q.queues[job.userID]) == 0 {
+		q.arrived++
+		q.arrivals[job.userID] = q.arrived
+	}
+	q.queues[job.userID] = append(q.queues[job.userID], job)
+}
+
+// before 判断用户 a 是否应先于用户 b 轮到
+func (q *fairQueue) before(a, b uint64, served map[uint64]uint64) bool {
+	if served[a] != served[b] {
+		return served[a] < served[b]
+	}
+	return q.arrivals[a] < q.arrivals[b]
+}
+
+// pop 取出下一个可以开始的任务并计入运行数，没有时返回 nil
+func (q *fairQueue) pop() *queuedJob {
+	if q.workers > 0 && q.running >= q.workers {
+		return nil
+	}
+	var userID uint64
+	found := false
+	for u := range q.queues {
+		if q.perUser > 0 && q.runningBy[u] >= q.perUser {
+			continue
+		}
+		if !found || q.before(u, userID, q.served) {
+			userID, found = u, true
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	job := q.take(userID, 0)
+	q.turn++
+	q.served[userID] = q.turn
+	q.running++
+	q.runningBy[userID]++
+	return job
+}
+
+// take 从用户队列中取出第 i 个任务
+func (q *fairQueue) take(userID uint64, i int) *queuedJob {
+	queue := q.queues[userID]
+	job := queue[i]
+	if len(queue) == 1 {
+		delete(q.queues, userID)
+		delete(q.arrivals, userID)
+		q.reset()
+	} else {
+		q.queues[userID] = slices.Delete(queue, i, i+1)
+	}
+	return job
+}
+
+// done 释放一个运行中任务占用的名额
+func (q *fairQueue) done(userID uint64) {
+	q.running--
+	if q.runningBy[userID]--; q.runningBy[userID] <= 0 {
+		delete(q.runningBy, userID)
+	}
+	q.reset()
+}
+
+// reset 没有排队和运行中的任务时清空轮转记录，之前的先后不再重要
+func (q *fairQueue) reset() {
+	if len(q.queues) == 0 && q.running == 0 {
+		clear(q.served)
+		q.turn = 0
+	}
+}
+
+// remove 取出一个还在排队的任务，任务已经开始或不在本实例时返回 nil
+func (q *fairQueue) remove(id string) *queuedJob {
+	for userID, queue := range q.queues {
+		for i, job := range queue {
+			if job.id == id {
+				return q.take(userID, i)
+			}
+		}
+	}
+	return nil
+}
+
+// positions 按轮转顺序估算每个排队任务前面还有多少任务，从 1 开始
+// 不考虑运行名额的占用，名额满时实际位置可能靠后
+func (q *fairQueue) positions() map[string]int {
+	positions := make(map[string]int)
+	served := maps.Clone(q.served)
+	taken := make(map[uint64]int, len(q.queues))
+	turn := q.turn
+	for position := 1; ; position++ {
+		var userID uint64
+		found := false
+		for u, queue := range q.queues {
+			if taken[u] < len(queue) && (!found || q.before(u, userID, served)) {
+				userID, found = u, true
+			}
+		}
+		if !found {
+			return positions
+		}
+		positions[q.queues[userID][taken[userID]].id] = position
+		taken[userID]++
+		turn++
+		served[userID] = turn
+	}
+}
+
+// expired 取出排队超过 timeout 的任务
+func (q *fairQueue) expired(now time.Time, timeout time.Duration) []*queuedJob {
+	var ids []string
+	for _, queue := range q.queues {
+		for _, job := range queue {
+			if now.Sub(job.queuedAt) > timeout {
+				ids = append(ids, job.id)
+			}
+		}
+	}
+	jobs := make([]*queuedJob, 0, len(ids))
+	for _, id := range ids {
+		jobs = append(jobs, q.remove(id))
+	}
+	return jobs
+}
+
+// drain 取出所有排队的任务
+func (q *fairQueue) drain() []*queuedJob {
+	var jobs []*queuedJob
+	for _, queue := range q.queues {
+		jobs = append(jobs, queue...)
+	}
+	clear(q.queues)
+	clear(q.arrivals)
+	clear(q.served)
+	return jobs
+}
@@ -0,0 +1,113 @@
+package generation
+
+import (
+	"testing"
+	"time"
+)
+
+func pushJobs(q *fairQueue, jobs ...queuedJob) {
+	for i := range jobs {
+		job := jobs[i]
+		q.push(&job)
+	}
+}
+
+// popAll 依次取出任务，每个任务取出后立即结束
+func popAll(q *fairQueue) string {
+	var order string
+	for job := q.pop(); job != nil; job = q.pop() {
+		order += job.id
+		q.done(job.userID)
+	}
+	return order
+}
+
+// TestFairQueue_RoundRobin tests that users take turns regardless of how many jobs they queued
+// and that users who have not had a turn go first
+func TestFairQueue_RoundRobin(t *testing.T) {
+	q := newFairQueue(1, 1)
+	pushJobs(q, queuedJob{id: "x", userID: 3})
+	if job := q.pop(); job == nil || job.id != "x" {
+		t.Fatalf("pop() = %v, want x", job)
+	}
+
+	pushJobs(q,
+		queuedJob{id: "a", userID: 1}, queuedJob{id: "b", userID: 1}, queuedJob{id: "c", userID: 1},
+		queuedJob{id: "d", userID: 2}, queuedJob{id: "e", userID: 2},
+	)
+	if job := q.pop(); job != nil {
+		t.Fatalf("pop() with no free worker = %s", job.id)
+	}
+
+	want := map[string]int{"a": 1, "d": 2, "b": 3, "e": 4, "c": 5}
+	for id, position := range q.positions() {
+		if want[id] != position {
+			t.Errorf("position of %s = %d, want %d", id, position, want[id])
+		}
+	}
+
+	q.done(3)
+	if job := q.pop(); job == nil || job.id != "a" {
+		t.Fatalf("pop() = %v, want a", job)
+	}
+	// 用户 3 比用户 1 更早轮到过，回来排队时排在 b 之前
+	pushJobs(q, queuedJob{id: "y", userID: 3})
+	q.done(1)
+	if got := popAll(q); got != "dybec" {
+		t.Errorf("order = %s, want dybec", got)
+	}
+}
+
+// TestFairQueue_Limits tests the worker and per-user limits
+func TestFairQueue_Limits(t *testing.T) {
+	q := newFairQueue(3, 2)
+	pushJobs(q,
+		queuedJob{id: "a", userID: 1}, queuedJob{id: "b", userID: 1}, queuedJob{id: "c", userID: 1},
+		queuedJob{id: "d", userID: 2},
+	)
+
+	var started string
+	for job := q.pop(); job != nil; job = q.pop() {
+		started += job.id
+	}
+	if started != "adb" {
+		t.Errorf("started = %s, want adb", started)
+	}
+
+	// 有空闲名额但用户 1 已达上限
+	q.done(2)
+	if job := q.pop(); job != nil {
+		t.Errorf("pop() over the per-user limit = %s", job.id)
+	}
+	q.done(1)
+	if job := q.pop(); job == nil || job.id != "c" {
+		t.Errorf("pop() = %v, want c", job)
+	}
+}
+
+// TestFairQueue_Remove tests that removed, expired and drained jobs leave the rotation intact
+func TestFairQueue_Remove(t *testing.T) {
+	now := time.Now()
+	q := newFairQueue(1, 1)
+	pushJobs(q,
+		queuedJob{id: "a", userID: 1, queuedAt: now.Add(-time.Hour)},
+		queuedJob{id: "b", userID: 2, queuedAt: now},
+		queuedJob{id: "c", userID: 2, queuedAt: now},
+		queuedJob{id: "d", userID: 3, queuedAt: now},
+	)
+
+	if q.remove("b") == nil || q.remove("b") != nil {
+		t.Error("remove() should take a queued job exactly once")
+	}
+	if expired := q.expired(now, time.Minute); len(expired) != 1 || expired[0].id != "a" {
+		t.Errorf("expired() = %v, want a", expired)
+	}
+	if got := popAll(q); got != "cd" {
+		t.Errorf("order = %s, want cd", got)
+	}
+
+	pushJobs(q, queuedJob{id: "e", userID: 1}, queuedJob{id: "f", userID: 2})
+	if drained := q.drain(); len(drained) != 2 || len(q.positions()) != 0 || q.pop() != nil {
+		t.Errorf("drain() = %d jobs, queue not empty", len(drained))
+	}
+}
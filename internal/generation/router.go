@@ -0,0 +1,333 @@
+package generation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/test-tt/config"
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/breaker"
+	"github.com/test-tt/pkg/logger"
+)
+
+// 每次向模型服务发出请求的结果，用于指标
+const (
+	outcomeSuccess  = "success"
+	outcomeError    = "error"
+	outcomeTimeout  = "timeout"  // 超过 first_byte_timeout 没有输出
+	outcomeOpen     = "open"     // 熔断中，没有发出请求
+	outcomeCanceled = "canceled" // 调用方取消或超时，不计为服务失败
+)
+
+var (
+	// ErrNoProvider is returned when no provider of a router can handle a kind of request
+	ErrNoProvider = fmt.Errorf("%w: no provider can handle the request", ErrProviderUnavailable)
+
+	errFirstByteTimeout = errors.New("provider produced no output in time")
+)
+
+var (
+	// providerRequests 每个模型服务的请求数
+	providerRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "generation_provider_requests_total",
+			Help: "Total number of generation provider requests by outcome",
+		},
+		[]string{"provider", "kind", "outcome"},
+	)
+
+	// providerDuration 每个模型服务的请求耗时
+	providerDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "generation_provider_request_duration_seconds",
+			Help:    "Generation provider request duration in seconds",
+			Buckets: []float64{1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+		},
+		[]string{"provider", "outcome"},
+	)
+
+	// providerFirstDelta 从发出请求到第一段输出的时间
+	providerFirstDelta = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "generation_provider_first_delta_seconds",
+			Help:    "Time until a generation provider streams its first output",
+			Buckets: []float64{0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60},
+		},
+		[]string{"provider"},
+	)
+
+	// providerBreakerOpen 模型服务是否处于熔断中
+	providerBreakerOpen = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "generation_provider_breaker_open",
+			Help: "Whether the circuit breaker of a generation provider is open",
+		},
+		[]string{"provider"},
+	)
+)
+
+// RoutedProvider is one provider of a router
+type RoutedProvider struct {
+	Provider         LLMProvider
+	Name             string        // 为空时使用 Provider.Name()
+	Priority         int           // 数值小的先尝试
+	Weight           int           // 同一优先级内按权重分配请求
+	Capabilities     []string      // 可处理的请求类型，为空表示全部
+	FirstByteTimeout time.Duration // 超过该时间没有输出时切换到下一个服务，0 表示不限制
+}
+
+// handles 判断服务能否处理某类请求
+func (p *RoutedProvider) handles(kind string) bool {
+	return len(p.Capabilities) == 0 || slices.Contains(p.Capabilities, kind)
+}
+
+// Route sends one kind of request to the listed providers in order
+type Route struct {
+	Providers []string
+	Fallback  bool // 列出的服务都失败时继续按优先级尝试其他服务
+}
+
+// Router sends each request to one of several providers and falls back to the next one when it fails
+// 没有路由规则时按优先级从小到大、同一优先级内按权重随机选择；每个服务有独立的熔断器
+// 已经输出内容后失败不再切换，避免重复的输出
+type Router struct {
+	providers []*RoutedProvider
+	routes    map[string]Route
+	breakers  *breaker.Manager
+	// pick 返回 [0, n) 内的随机数，测试时可以替换
+	pick func(n int) int
+}
+
+// NewRouter creates a router, routes are keyed by request kind
+func NewRouter(providers []*RoutedProvider, routes map[string]Route, breakers *breaker.Manager) *Router {
+	for _, p := range providers {
+		if p.Name == "" {
+			p.Name = p.Provider.Name()
+		}
+		if p.Weight <= 0 {
+			p.Weight = 1
+		}
+		providerBreakerOpen.WithLabelValues(p.Name).Set(0)
+	}
+	return &Router{providers: providers, routes: routes, breakers: breakers, pick: rand.IntN}
+}
+
+// Name returns the name of the only provider, or "router" when there are several
+func (r *Router) Name() string {
+	if len(r.providers) == 1 {
+		return r.providers[0].Name
+	}
+	return "router"
+}
+
+func (r *Router) Stream(ctx context.Context, req *Request, onDelta func(Delta)) (*Completion, error) {
+	kind := req.Kind
+	if kind == "" {
+		kind = model.PromptCreate
+	}
+	candidates := r.candidates(kind)
+	if len(candidates) == 0 {
+		return nil, ErrNoProvider
+	}
+
+	// 失败的尝试同样消耗了 token，用量累加到最终结果
+	var spent Usage
+	err := ErrNoProvider
+	for i, p := range candidates {
+		completion, streamed, attemptErr := r.attempt(ctx, p, kind, req, onDelta)
+		if completion != nil {
+			spent.InputTokens += completion.Usage.InputTokens
+			spent.OutputTokens += completion.Usage.OutputTokens
+		}
+		if attemptErr == nil {
+			completion.Usage = spent
+			completion.Provider = p.Name
+			return completion, nil
+		}
+		err = attemptErr
+		if streamed || ctx.Err() != nil {
+			return &Completion{Usage: spent, Provider: p.Name}, err
+		}
+		if i < len(candidates)-1 {
+			logger.WarnCtxf(ctx, "generation provider failed, trying the next one", "provider", p.Name, "kind", kind, "error", err)
+		}
+	}
+	return &Completion{Usage: spent, Provider: candidates[len(candidates)-1].Name}, err
+}
+
+// attempt 通过熔断器向一个服务发出请求，返回是否已经有输出
+func (r *Router) attempt(ctx context.Context, p *RoutedProvider, kind string, req *Request, onDelta func(Delta)) (*Completion, bool, error) {
+	cb := r.breakers.Get(p.Name)
+	attemptCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	start := time.Now()
+	var streamed atomic.Bool
+	if p.FirstByteTimeout > 0 {
+		timer := time.AfterFunc(p.FirstByteTimeout, func() {
+			if !streamed.Load() {
+				cancel(errFirstByteTimeout)
+			}
+		})
+		defer timer.Stop()
+	}
+
+	var completion *Completion
+	var streamErr error
+	ran := false
+	_, err := cb.Execute(func() (interface{}, error) {
+		ran = true
+		completion, streamErr = p.Provider.Stream(attemptCtx, req, func(d Delta) {
+			if !streamed.Swap(true) {
+				providerFirstDelta.WithLabelValues(p.Name).Observe(time.Since(start).Seconds())
+			}
+			onDelta(d)
+		})
+		// 调用方取消或超时不是服务的问题，不计入失败率
+		if streamErr != nil && ctx.Err() != nil {
+			return nil, nil
+		}
+		return nil, streamErr
+	})
+
+	outcome := outcomeSuccess
+	switch {
+	case !ran:
+		outcome = outcomeOpen
+		streamErr = fmt.Errorf("%w: %s: %v", ErrProviderUnavailable, p.Name, err)
+	case streamErr == nil:
+	case ctx.Err() != nil:
+		outcome = outcomeCanceled
+	case errors.Is(context.Cause(attemptCtx), errFirstByteTimeout):
+		outcome = outcomeTimeout
+		streamErr = fmt.Errorf("%w: %s: %w", ErrProviderUnavailable, p.Name, errFirstByteTimeout)
+	default:
+		outcome = outcomeError
+	}
+
+	providerRequests.WithLabelValues(p.Name, kind, outcome).Inc()
+	if ran {
+		providerDuration.WithLabelValues(p.Name, outcome).Observe(time.Since(start).Seconds())
+	}
+	open := 0.0
+	if cb.IsOpen() {
+		open = 1
+	}
+	providerBreakerOpen.WithLabelValues(p.Name).Set(open)
+	return completion, streamed.Load(), streamErr
+}
+
+// candidates 按尝试顺序返回能处理该类请求的服务：先按路由规则，再按优先级和权重
+func (r *Router) candidates(kind string) []*RoutedProvider {
+	var capable []*RoutedProvider
+	for _, p := range r.providers {
+		if p.handles(kind) {
+			capable = append(capable, p)
+		}
+	}
+
+	var ordered []*RoutedProvider
+	if route, ok := r.routes[kind]; ok {
+		for _, name := range route.Providers {
+			i := slices.IndexFunc(capable, func(p *RoutedProvider) bool { return p.Name == name })
+			if i >= 0 {
+				ordered = append(ordered, capable[i])
+				capable = slices.Delete(capable, i, i+1)
+			}
+		}
+		if !route.Fallback {
+			return ordered
+		}
+	}
+
+	slices.SortStableFunc(capable, func(a, b *RoutedProvider) int { return a.Priority - b.Priority })
+	for len(capable) > 0 {
+		// 同一优先级内按权重不放回地抽取
+		end := 1
+		for end < len(capable) && capable[end].Priority == capable[0].Priority {
+			end++
+		}
+		group := slices.Clone(capable[:end])
+		capable = capable[end:]
+		for len(group) > 0 {
+			total := 0
+			for _, p := range group {
+				total += p.Weight
+			}
+			n := r.pick(total)
+			i := 0
+			for n >= group[i].Weight {
+				n -= group[i].Weight
+				i++
+			}
+			ordered = append(ordered, group[i])
+			group = slices.Delete(group, i, i+1)
+		}
+	}
+	return ordered
+}
+
+// NewRouterFromConfig creates the router of the generation configuration
+// 没有配置 providers 时只包含 provider、model、api_key 和 base_url 指定的服务
+func NewRouterFromConfig(cfg *config.GenerationConfig) (*Router, error) {
+	entries := cfg.Providers
+	if len(entries) == 0 {
+		entries = []config.GenerationProviderConfig{{
+			Name:    cfg.Provider,
+			Type:    cfg.Provider,
+			Model:   cfg.Model,
+			APIKey:  cfg.APIKey,
+			BaseURL: cfg.BaseURL,
+			Weight:  1,
+		}}
+	}
+
+	providers := make([]*RoutedProvider, 0, len(entries))
+	for _, e := range entries {
+		var provider LLMProvider
+		switch e.Type {
+		case "", "fake":
+			provider = NewFakeProvider()
+		case "anthropic":
+			provider = NewAnthropicProvider(e.Key(), e.BaseURL, e.Model)
+		default:
+			return nil, fmt.Errorf("unknown generation provider %q", e.Type)
+		}
+		name := e.Name
+		if name == "" {
+			name = provider.Name()
+		}
+		providers = append(providers, &RoutedProvider{
+			Provider:         provider,
+			Name:             name,
+			Priority:         e.Priority,
+			Weight:           e.Weight,
+			Capabilities:     e.Capabilities,
+			FirstByteTimeout: e.FirstByteTimeout,
+		})
+	}
+
+	routes := make(map[string]Route, len(cfg.Routes))
+	for _, route := range cfg.Routes {
+		routes[route.Kind] = Route{Providers: route.Providers, Fallback: route.Fallback}
+	}
+
+	b := cfg.Breaker
+	breakers := breaker.NewManager(&breaker.Config{
+		Name:         "generation",
+		MaxRequests:  1,
+		Interval:     b.Interval,
+		Timeout:      b.OpenTimeout,
+		FailureRatio: b.FailureRatio,
+		MinRequests:  b.MinRequests,
+	})
+	return NewRouter(providers, routes, breakers), nil
+}
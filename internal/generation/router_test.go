@@ -0,0 +1,226 @@
+package generation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/test-tt/config"
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/breaker"
+)
+
+// stubProvider 按设置输出或失败并记录被调用的次数
+type stubProvider struct {
+	name   string
+	err    error
+	output string        // 失败前也会先输出
+	delay  time.Duration // 输出前的等待
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (p *stubProvider) Name() string {
+	return p.name
+}
+
+func (p *stubProvider) Stream(ctx context.Context, _ *Request, onDelta func(Delta)) (*Completion, error) {
+	p.mu.Lock()
+	p.calls++
+	p.mu.Unlock()
+	usage := Usage{InputTokens: 10}
+	if p.delay > 0 {
+		select {
+		case <-ctx.Done():
+			return &Completion{Usage: usage}, ctx.Err()
+		case <-time.After(p.delay):
+		}
+	}
+	if p.output != "" {
+		onDelta(Delta{Kind: DeltaText, Text: p.output})
+		usage.OutputTokens = 5
+	}
+	return &Completion{Text: p.output, Usage: usage}, p.err
+}
+
+func (p *stubProvider) called() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+func testBreakers() *breaker.Manager {
+	return breaker.NewManager(&breaker.Config{MaxRequests: 1, Interval: time.Minute, Timeout: time.Minute, FailureRatio: 0.5, MinRequests: 2})
+}
+
+// TestRouter_Fallback tests that a failing provider is skipped until one answers and the usage of every attempt is kept
+func TestRouter_Fallback(t *testing.T) {
+	down := &stubProvider{name: "rt-down", err: errors.New("upstream exploded")}
+	slow := &stubProvider{name: "rt-slow", output: "late", delay: time.Second}
+	up := &stubProvider{name: "rt-up", output: "page"}
+	r := NewRouter([]*RoutedProvider{
+		{Provider: up, Priority: 2},
+		{Provider: down, Priority: 0},
+		{Provider: slow, Priority: 1, FirstByteTimeout: 20 * time.Millisecond},
+	}, nil, testBreakers())
+
+	var streamed strings.Builder
+	completion, err := r.Stream(context.Background(), &Request{Prompt: "p"}, func(d Delta) { streamed.WriteString(d.Text) })
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if completion.Provider != "rt-up" || completion.Text != "page" || streamed.String() != "page" {
+		t.Errorf("Stream() = %+v, streamed %q", completion, streamed.String())
+	}
+	if completion.Usage.InputTokens != 30 || completion.Usage.OutputTokens != 5 {
+		t.Errorf("usage = %+v, want all three attempts", completion.Usage)
+	}
+	if down.called() != 1 || slow.called() != 1 || r.Name() != "router" {
+		t.Errorf("calls = %d, %d", down.called(), slow.called())
+	}
+
+	// 已经输出内容后失败不再切换
+	partial := &stubProvider{name: "rt-partial", output: "<html>", err: errors.New("connection reset")}
+	r = NewRouter([]*RoutedProvider{{Provider: partial}, {Provider: up, Priority: 1}}, nil, testBreakers())
+	completion, err = r.Stream(context.Background(), &Request{}, func(Delta) {})
+	if err == nil || completion.Provider != "rt-partial" || up.called() != 1 {
+		t.Errorf("Stream() after output = %+v, %v; fallback calls %d", completion, err, up.called())
+	}
+}
+
+// TestRouter_Candidates tests the order of providers from priorities, weights, capabilities and routes
+func TestRouter_Candidates(t *testing.T) {
+	providers := []*RoutedProvider{
+		{Provider: &stubProvider{name: "a"}, Priority: 1},
+		{Provider: &stubProvider{name: "b"}, Weight: 1},
+		{Provider: &stubProvider{name: "c"}, Weight: 3},
+		{Provider: &stubProvider{name: "s"}, Priority: 1, Capabilities: []string{RequestSummary}},
+	}
+	routes := map[string]Route{
+		model.PromptModify:   {Providers: []string{"a"}},
+		model.PromptFragment: {Providers: []string{"a", "s"}, Fallback: true},
+	}
+	r := NewRouter(providers, routes, testBreakers())
+	names := func(kind string, pick int) string {
+		r.pick = func(n int) int { return min(pick, n-1) }
+		var out []string
+		for _, p := range r.candidates(kind) {
+			out = append(out, p.Name)
+		}
+		return strings.Join(out, ",")
+	}
+
+	tests := []struct {
+		kind string
+		pick int
+		want string
+	}{
+		{model.PromptCreate, 0, "b,c,a"},
+		{model.PromptCreate, 1, "c,b,a"},
+		{RequestSummary, 0, "b,c,a,s"},
+		{model.PromptModify, 0, "a"},
+		{model.PromptFragment, 0, "a,b,c"},
+	}
+	for _, tt := range tests {
+		if got := names(tt.kind, tt.pick); got != tt.want {
+			t.Errorf("candidates(%s, pick %d) = %s, want %s", tt.kind, tt.pick, got, tt.want)
+		}
+	}
+
+	r = NewRouter([]*RoutedProvider{{Provider: &stubProvider{name: "s"}, Capabilities: []string{RequestSummary}}}, nil, testBreakers())
+	if _, err := r.Stream(context.Background(), &Request{Kind: model.PromptCreate}, func(Delta) {}); !errors.Is(err, ErrProviderUnavailable) {
+		t.Errorf("Stream() without a capable provider error = %v", err)
+	}
+}
+
+// TestRouter_Breaker tests that a provider failing too often is skipped and caller cancellations do not count
+func TestRouter_Breaker(t *testing.T) {
+	down := &stubProvider{name: "br-down", err: errors.New("upstream exploded")}
+	up := &stubProvider{name: "br-up", output: "page"}
+	r := NewRouter([]*RoutedProvider{{Provider: down}, {Provider: up, Priority: 1}}, nil, testBreakers())
+
+	for i := 0; i < 3; i++ {
+		completion, err := r.Stream(context.Background(), &Request{}, func(Delta) {})
+		if err != nil || completion.Provider != "br-up" {
+			t.Fatalf("Stream() = %+v, %v", completion, err)
+		}
+	}
+	if down.called() != 2 {
+		t.Errorf("calls to the failing provider = %d, want 2 before the breaker opens", down.called())
+	}
+	if !r.breakers.Get("br-down").IsOpen() {
+		t.Error("breaker of the failing provider should be open")
+	}
+
+	slow := &stubProvider{name: "br-slow", output: "page", delay: time.Second}
+	r = NewRouter([]*RoutedProvider{{Provider: slow}, {Provider: up, Priority: 1}}, nil, testBreakers())
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		_, err := r.Stream(ctx, &Request{}, func(Delta) {})
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Stream() error = %v, want the caller's deadline", err)
+		}
+	}
+	if slow.called() != 3 || up.called() != 3 || r.breakers.Get("br-slow").IsOpen() {
+		t.Errorf("calls = %d, %d; breaker open %v", slow.called(), up.called(), r.breakers.Get("br-slow").IsOpen())
+	}
+}
+
+// TestNewRouterFromConfig tests the single provider configuration and the provider list
+func TestNewRouterFromConfig(t *testing.T) {
+	cfg := &config.GenerationConfig{Provider: "fake", Breaker: config.GenerationBreakerConfig{FailureRatio: 0.5, MinRequests: 5, Interval: time.Minute, OpenTimeout: time.Minute}}
+	r, err := NewRouterFromConfig(cfg)
+	if err != nil || r.Name() != "fake" {
+		t.Fatalf("NewRouterFromConfig() = %v, %v", r, err)
+	}
+	completion, err := r.Stream(context.Background(), BuildRequest(&Input{Prompt: "a bakery"}, 0, 0), func(Delta) {})
+	if err != nil || completion.Provider != "fake" || !strings.Contains(completion.Text, "<!DOCTYPE html>") {
+		t.Errorf("Stream() = %+v, %v", completion, err)
+	}
+
+	cfg.Providers = []config.GenerationProviderConfig{
+		{Name: "primary", Type: "anthropic", Model: "m", APIKey: "k", Weight: 1},
+		{Name: "backup", Type: "fake", Priority: 1, Weight: 2, Capabilities: []string{"modify"}},
+	}
+	cfg.Routes = []config.GenerationRouteConfig{{Kind: "modify", Providers: []string{"backup"}}}
+	if r, err = NewRouterFromConfig(cfg); err != nil {
+		t.Fatalf("NewRouterFromConfig() error = %v", err)
+	}
+	if got := r.candidates(model.PromptModify); len(got) != 1 || got[0].Name != "backup" || got[0].Weight != 2 {
+		t.Errorf("modify candidates = %+v", got)
+	}
+
+	cfg.Providers[0].Type = "openai"
+	if _, err := NewRouterFromConfig(cfg); err == nil {
+		t.Error("NewRouterFromConfig() with an unknown type should fail")
+	}
+}
+
+// TestManager_Router tests that a job records the provider that answered after a fallback
+func TestManager_Router(t *testing.T) {
+	ctx := context.Background()
+	meter := &testMeter{}
+	down := &stubProvider{name: "mr-down", err: errors.New("upstream exploded")}
+	r := NewRouter([]*RoutedProvider{{Provider: down}, {Provider: NewFakeProvider(), Name: "mr-backup", Priority: 1}}, nil, testBreakers())
+	m := NewManager(r, NewMemoryJobStore(), NewMemoryEventLog(), Hooks{Meter: meter}, testOptions())
+
+	job, err := m.Start(ctx, 1, nil, &Input{Prompt: "page"})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	collect(t, m, job.ID, 1, 0)
+	m.Shutdown()
+
+	got, err := m.Get(ctx, job.ID, 1)
+	if err != nil || got.Status != model.GenerationCompleted || got.Provider != "mr-backup" || down.called() != 1 {
+		t.Fatalf("Get() = %+v, %v", got, err)
+	}
+	if len(meter.usage) != 1 || meter.usage[0].Provider != "mr-backup" || meter.usage[0].InputTokens < 10 {
+		t.Errorf("usage = %+v", meter.usage)
+	}
+}
@@ -0,0 +1,127 @@
+package generation
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/test-tt/internal/model"
+)
+
+// JobStore persists generation jobs, dao.GenerationJobDAO stores them in MySQL
+type JobStore interface {
+	Create(ctx context.Context, job *model.GenerationJob) error
+	// Get 返回任务，不存在时返回 nil
+	Get(ctx context.Context, id string) (*model.GenerationJob, error)
+	// Update 只保存尚未结束的任务，返回是否保存
+	Update(ctx context.Context, job *model.GenerationJob) (bool, error)
+	CountActive(ctx context.Context, userID uint64) (int64, error)
+	// Heartbeat 刷新本实例运行中任务的心跳，返回其中已在别处结束（被取消或判定中断）的任务
+	Heartbeat(ctx context.Context, instance string, ids []string, at time.Time) ([]string, error)
+	ListStale(ctx context.Context, before time.Time, limit int) ([]model.GenerationJob, error)
+	DeleteFinishedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// memoryJobStore 单实例、无数据库时使用的任务存储，重启后丢失
+type memoryJobStore struct {
+	mu   sync.Mutex
+	jobs map[string]model.GenerationJob
+}
+
+// NewMemoryJobStore creates a job store kept in process memory
+func NewMemoryJobStore() JobStore {
+	return &memoryJobStore{jobs: make(map[string]model.GenerationJob)}
+}
+
+func (s *memoryJobStore) Create(_ context.Context, job *model.GenerationJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	job.CreatedAt, job.UpdatedAt = now, now
+	s.jobs[job.ID] = *job
+	return nil
+}
+
+func (s *memoryJobStore) Get(_ context.Context, id string) (*model.GenerationJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, nil
+	}
+	return &job, nil
+}
+
+func (s *memoryJobStore) Update(_ context.Context, job *model.GenerationJob) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.jobs[job.ID]
+	if !ok || model.GenerationDone(old.Status) {
+		return false, nil
+	}
+	updated := *job
+	updated.CreatedAt = old.CreatedAt
+	updated.UpdatedAt = time.Now()
+	s.jobs[job.ID] = updated
+	return true, nil
+}
+
+func (s *memoryJobStore) CountActive(_ context.Context, userID uint64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for _, job := range s.jobs {
+		if job.UserID == userID && !model.GenerationDone(job.Status) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *memoryJobStore) Heartbeat(_ context.Context, instance string, ids []string, at time.Time) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ended []string
+	for _, id := range ids {
+		job, ok := s.jobs[id]
+		if !ok || model.GenerationDone(job.Status) {
+			ended = append(ended, id)
+			continue
+		}
+		if job.Instance == instance {
+			job.HeartbeatAt = at
+			s.jobs[id] = job
+		}
+	}
+	return ended, nil
+}
+
+func (s *memoryJobStore) ListStale(_ context.Context, before time.Time, limit int) ([]model.GenerationJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var jobs []model.GenerationJob
+	for _, job := range s.jobs {
+		if !model.GenerationDone(job.Status) && job.HeartbeatAt.Before(before) {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].HeartbeatAt.Before(jobs[j].HeartbeatAt) })
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	return jobs, nil
+}
+
+func (s *memoryJobStore) DeleteFinishedBefore(_ context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for id, job := range s.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(before) {
+			delete(s.jobs, id)
+			n++
+		}
+	}
+	return n, nil
+}
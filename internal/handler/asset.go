@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/test-tt/internal/middleware"
+	"github.com/test-tt/internal/service"
+	"github.com/test-tt/pkg/errcode"
+	"github.com/test-tt/pkg/logger"
+	"github.com/test-tt/pkg/response"
+)
+
+type AssetHandler struct {
+	assetService *service.AssetService
+}
+
+func NewAssetHandler() *AssetHandler {
+	return &AssetHandler{
+		assetService: service.NewAssetService(),
+	}
+}
+
+// Upload godoc
+// @Summary      Upload asset
+// @Description  Upload an image or font to a project. The content type is sniffed from the file content.
+// @Tags         Assets
+// @Security     BearerAuth
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        id    path      int   true  "Project ID"
+// @Param        file  formData  file  true  "Asset file"
+// @Success      200   {object}  response.Response{data=model.ProjectAsset}
+// @Failure      400   {object}  response.Response
+// @Failure      401   {object}  response.Response
+// @Failure      404   {object}  response.Response
+// @Failure      413   {object}  response.Response
+// @Router       /projects/{id}/assets [post]
+func (h *AssetHandler) Upload(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage("file is required"))
+		return
+	}
+	if fileHeader.Size > h.assetService.MaxFileSize() {
+		response.Fail(c, errcode.ErrAssetTooLarge)
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage("file is unreadable"))
+		return
+	}
+	defer file.Close()
+
+	asset, err := h.assetService.Upload(ctx, id, userID, fileHeader.Filename, file)
+	if err != nil {
+		h.fail(ctx, c, err, "upload asset", id)
+		return
+	}
+
+	response.Success(c, asset)
+}
+
+// List godoc
+// @Summary      List assets
+// @Description  List the uploaded assets of a project
+// @Tags         Assets
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "Project ID"
+// @Success      200  {object}  response.Response{data=[]model.ProjectAsset}
+// @Failure      401  {object}  response.Response
+// @Failure      404  {object}  response.Response
+// @Router       /projects/{id}/assets [get]
+func (h *AssetHandler) List(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	assets, err := h.assetService.List(ctx, id, userID)
+	if err != nil {
+		h.fail(ctx, c, err, "list assets", id)
+		return
+	}
+
+	response.Success(c, assets)
+}
+
+// Delete godoc
+// @Summary      Delete asset
+// @Tags         Assets
+// @Security     BearerAuth
+// @Param        id        path      int  true  "Project ID"
+// @Param        asset_id  path      int  true  "Asset ID"
+// @Success      200       {object}  response.Response
+// @Failure      401       {object}  response.Response
+// @Failure      404       {object}  response.Response
+// @Router       /projects/{id}/assets/{asset_id} [delete]
+func (h *AssetHandler) Delete(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id, assetID uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+	if _, err := parseUint64(c.Param("asset_id"), &assetID); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	if err := h.assetService.Delete(ctx, id, assetID, userID); err != nil {
+		h.fail(ctx, c, err, "delete asset", id)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// Serve 公开访问上传资源（响应头由 AssetHeaders 设置）
+func (h *AssetHandler) Serve(ctx context.Context, c *app.RequestContext) {
+	projectID, err := strconv.ParseUint(c.Param("project_id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusNotFound, "File not found")
+		return
+	}
+
+	asset, rc, err := h.assetService.Open(ctx, projectID, c.Param("file"))
+	if err != nil {
+		if !errors.Is(err, service.ErrAssetNotFound) {
+			logger.ErrorCtxf(ctx, "failed to open asset", "error", err, "projectID", projectID)
+		}
+		c.String(http.StatusNotFound, "File not found")
+		return
+	}
+
+	c.Header("Content-Type", asset.ContentType)
+	c.SetBodyStream(rc, int(asset.Size))
+}
+
+// fail 将资源相关错误映射为响应
+func (h *AssetHandler) fail(ctx context.Context, c *app.RequestContext, err error, action string, projectID uint64) {
+	switch {
+	case errors.Is(err, service.ErrAssetNotFound):
+		response.Fail(c, errcode.ErrNotFound.WithMessage("asset not found"))
+	case errors.Is(err, service.ErrAssetTooLarge):
+		response.Fail(c, errcode.ErrAssetTooLarge)
+	case errors.Is(err, service.ErrAssetTypeNotAllowed):
+		response.Fail(c, errcode.ErrAssetTypeNotAllowed)
+	default:
+		failProjectError(ctx, c, err, action, projectID)
+	}
+}
@@ -16,12 +16,14 @@ import (
 )
 
 type AuthHandler struct {
-	authService *service.AuthService
+	authService  *service.AuthService
+	quotaService *service.QuotaService
 }
 
 func NewAuthHandler() *AuthHandler {
 	return &AuthHandler{
-		authService: service.NewAuthService(),
+		authService:  service.NewAuthService(),
+		quotaService: service.NewQuotaService(),
 	}
 }
 
@@ -187,6 +189,33 @@ func (h *AuthHandler) GetProfile(ctx context.Context, c *app.RequestContext) {
 	response.Success(c, user)
 }
 
+// GetUsage godoc
+// @Summary      Get current user usage
+// @Description  Get the plan of currently authenticated user and usage against its limits (0 means unlimited)
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  response.Response{data=service.Usage}
+// @Failure      401  {object}  response.Response
+// @Security     Bearer
+// @Router       /auth/usage [get]
+func (h *AuthHandler) GetUsage(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserID(ctx)
+	if userID == 0 {
+		response.Fail(c, errcode.ErrLoginRequired)
+		return
+	}
+
+	usage, err := h.quotaService.Usage(ctx, userID)
+	if err != nil {
+		logger.ErrorCtxf(ctx, "failed to get usage", "error", err, "userID", userID)
+		response.Fail(c, errcode.ErrDatabase)
+		return
+	}
+
+	response.Success(c, usage)
+}
+
 // UpdateProfileRequest update profile request
 type UpdateProfileRequest struct {
 	Name  string `json:"name" validate:"omitempty,min=2,max=50"`
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"regexp"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/test-tt/internal/middleware"
+	"github.com/test-tt/internal/service"
+	"github.com/test-tt/pkg/errcode"
+	"github.com/test-tt/pkg/logger"
+	"github.com/test-tt/pkg/response"
+)
+
+// unsafeFilenameChars 导出文件名中需要替换的字符
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Export godoc
+// @Summary      Export project
+// @Description  Download a project as a ZIP bundle (index.html, style.css, messages.json, manifest.json and the project's other files at their paths)
+// @Tags         Projects
+// @Security     BearerAuth
+// @Produce      application/zip
+// @Param        id   path      int  true  "Project ID"
+// @Success      200  {file}    file
+// @Failure      401  {object}  response.Response
+// @Failure      404  {object}  response.Response
+// @Router       /projects/{id}/export [get]
+func (h *ProjectHandler) Export(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	project, err := h.projectService.GetByID(ctx, id, userID)
+	if err != nil {
+		failProjectError(ctx, c, err, "export project", id)
+		return
+	}
+	files, err := h.projectService.ListFiles(ctx, id)
+	if err != nil {
+		failProjectError(ctx, c, err, "export project", id)
+		return
+	}
+
+	// 通过管道边压缩边输出，避免在内存中构建完整的 ZIP
+	pr, pw := io.Pipe()
+	go func() {
+		err := service.WriteBundle(pw, project, files)
+		if err != nil {
+			logger.WarnCtxf(ctx, "failed to stream project bundle", "error", err, "projectID", id)
+		}
+		_ = pw.CloseWithError(err)
+	}()
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, bundleFilename(project.Name, id)))
+	c.SetBodyStream(pr, -1)
+}
+
+// Import godoc
+// @Summary      Import project
+// @Description  Create a project from a ZIP bundle, uploaded as multipart field "file" or as an application/zip body
+// @Tags         Projects
+// @Security     BearerAuth
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file  formData  file    true   "Project bundle"
+// @Param        name  formData  string  false  "Project name (defaults to manifest name)"
+// @Success      200   {object}  response.Response{data=service.SavedProject}
+// @Failure      400   {object}  response.Response
+// @Failure      401   {object}  response.Response
+// @Failure      413   {object}  response.Response
+// @Router       /projects/import [post]
+func (h *ProjectHandler) Import(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var (
+		reader io.ReaderAt
+		size   int64
+		name   string
+	)
+	if strings.HasPrefix(string(c.ContentType()), "application/zip") {
+		body := c.Request.Body()
+		reader, size = bytes.NewReader(body), int64(len(body))
+		name = c.Query("name")
+	} else {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			response.Fail(c, errcode.ErrInvalidParams.WithMessage("bundle file is required"))
+			return
+		}
+		if fileHeader.Size > service.MaxBundleSize {
+			response.Fail(c, errcode.ErrBundleTooLarge)
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			response.Fail(c, errcode.ErrInvalidParams.WithMessage("bundle file is unreadable"))
+			return
+		}
+		defer func(f multipart.File) { _ = f.Close() }(file)
+		// multipart.File 实现了 io.ReaderAt，可直接交给 zip.Reader 随机读取
+		reader, size = file, fileHeader.Size
+		name = string(c.FormValue("name"))
+	}
+
+	bundle, err := service.ReadBundle(reader, size)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrBundleTooLarge):
+			response.Fail(c, errcode.ErrBundleTooLarge)
+		default:
+			response.Fail(c, errcode.ErrBundleInvalid.WithMessage(err.Error()))
+		}
+		return
+	}
+
+	project, err := h.projectService.Import(ctx, userID, strings.TrimSpace(name), bundle)
+	if err != nil {
+		if failQuotaError(c, err) {
+			return
+		}
+		logger.ErrorCtxf(ctx, "failed to import project", "error", err, "userID", userID)
+		response.Fail(c, errcode.ErrDatabase)
+		return
+	}
+
+	response.Success(c, project)
+}
+
+// bundleFilename 生成 ASCII 安全的下载文件名
+func bundleFilename(name string, id uint64) string {
+	base := strings.Trim(unsafeFilenameChars.ReplaceAllString(name, "-"), "-.")
+	if base == "" {
+		base = fmt.Sprintf("project-%d", id)
+	}
+	if len(base) > 100 {
+		base = base[:100]
+	}
+	return base + ".zip"
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/hertz-contrib/websocket"
+
+	"github.com/test-tt/internal/middleware"
+	"github.com/test-tt/internal/service"
+	"github.com/test-tt/pkg/errcode"
+	"github.com/test-tt/pkg/logger"
+	"github.com/test-tt/pkg/response"
+)
+
+const (
+	collabWriteWait  = 10 * time.Second
+	collabPongWait   = 60 * time.Second
+	collabPingPeriod = 30 * time.Second // 必须小于 collabPongWait
+)
+
+type CollabHandler struct {
+	hub            *service.CollabHub
+	projectService *service.ProjectService
+	upgrader       *websocket.HertzUpgrader
+}
+
+func NewCollabHandler() *CollabHandler {
+	return &CollabHandler{
+		hub:            service.GetCollabHub(),
+		projectService: service.NewProjectService(),
+		// 默认只接受同源的握手请求
+		upgrader: &websocket.HertzUpgrader{},
+	}
+}
+
+// Connect godoc
+// @Summary      Collaborate on a project
+// @Description  Open a WebSocket for real-time editing with the project's owner and members. Browsers pass the JWT as ?token= since they cannot set headers on the handshake.
+// @Description  Messages are JSON objects with a type: the server sends welcome (document, versions and peers) on connect; clients send edit {region, base_version, content}, presence {presence}, chat {text} and ping.
+// @Description  An edit is saved only if base_version matches the region's current version, otherwise the server replies conflict with the latest content. Accepted edits are acked to the sender and broadcast as edit to everyone else.
+// @Tags         Collaboration
+// @Security     BearerAuth
+// @Param        id     path   int     true   "Project ID"
+// @Param        token  query  string  false  "JWT, alternative to the Authorization header"
+// @Success      101
+// @Failure      401  {object}  response.Response
+// @Failure      403  {object}  response.Response
+// @Failure      404  {object}  response.Response
+// @Router       /projects/{id}/ws [get]
+func (h *CollabHandler) Connect(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+	username := middleware.GetUsernameFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	_, role, err := h.projectService.Access(ctx, id, userID)
+	if err != nil {
+		failProjectError(ctx, c, err, "open collaboration", id)
+		return
+	}
+
+	client := service.NewCollabClient(id, userID, username, role)
+	if err := h.upgrader.Upgrade(c, func(conn *websocket.Conn) {
+		h.serve(ctx, conn, client)
+	}); err != nil {
+		logger.WarnCtxf(ctx, "websocket upgrade failed", "projectID", id, "error", err)
+	}
+}
+
+// serve 读取客户端消息直到连接断开；写入由 writeLoop 单独负责
+func (h *CollabHandler) serve(ctx context.Context, conn *websocket.Conn, client *service.CollabClient) {
+	welcome, err := h.hub.Join(ctx, client)
+	if err != nil {
+		logger.WarnCtxf(ctx, "failed to join collaboration", "projectID", client.ProjectID, "userID", client.UserID, "error", err)
+		data, _ := sonic.Marshal(&service.CollabMessage{Type: service.CollabTypeError, Error: err.Error()})
+		_ = conn.SetWriteDeadline(time.Now().Add(collabWriteWait))
+		_ = conn.WriteMessage(websocket.TextMessage, data)
+		_ = conn.Close()
+		return
+	}
+	logger.InfoCtxf(ctx, "collaborator joined", "projectID", client.ProjectID, "userID", client.UserID, "session", client.Session)
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		h.writeLoop(ctx, conn, client, welcome)
+	}()
+
+	conn.SetReadLimit(service.MaxCollabMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(collabPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(collabPongWait))
+	})
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(collabPongWait))
+		h.hub.Handle(ctx, client, data)
+	}
+
+	h.hub.Leave(ctx, client)
+	// 连接缓冲区在返回后会被回收，必须等写协程退出
+	<-stopped
+	logger.InfoCtxf(ctx, "collaborator left", "projectID", client.ProjectID, "userID", client.UserID, "session", client.Session)
+}
+
+// writeLoop 发送欢迎消息、房间广播和心跳，客户端关闭或写入失败时断开连接
+func (h *CollabHandler) writeLoop(ctx context.Context, conn *websocket.Conn, client *service.CollabClient, welcome *service.CollabMessage) {
+	ticker := time.NewTicker(collabPingPeriod)
+	defer func() {
+		ticker.Stop()
+		client.Close()
+		_ = conn.Close()
+	}()
+
+	data, err := sonic.Marshal(welcome)
+	if err != nil {
+		return
+	}
+	if err := h.write(conn, websocket.TextMessage, data); err != nil {
+		return
+	}
+
+	for {
+		select {
+		case data := <-client.Send():
+			if err := h.write(conn, websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := h.write(conn, websocket.PingMessage, nil); err != nil {
+				return
+			}
+			h.hub.Touch(ctx, client)
+		case <-client.Done():
+			_ = h.write(conn, websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		}
+	}
+}
+
+func (h *CollabHandler) write(conn *websocket.Conn, messageType int, data []byte) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(collabWriteWait)); err != nil {
+		return err
+	}
+	return conn.WriteMessage(messageType, data)
+}
@@ -0,0 +1,375 @@
+package handler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/test-tt/internal/middleware"
+	"github.com/test-tt/internal/service"
+	"github.com/test-tt/pkg/errcode"
+	"github.com/test-tt/pkg/logger"
+	"github.com/test-tt/pkg/pagination"
+	"github.com/test-tt/pkg/response"
+	"github.com/test-tt/pkg/validate"
+)
+
+type CommentHandler struct {
+	commentService *service.CommentService
+}
+
+func NewCommentHandler() *CommentHandler {
+	return &CommentHandler{
+		commentService: service.NewCommentService(),
+	}
+}
+
+// CreateCommentRequest start thread request
+type CreateCommentRequest struct {
+	SnapshotID uint64   `json:"snapshot_id" validate:"required"`
+	Anchor     string   `json:"anchor" validate:"required,max=512"` // CSS selector or element path
+	Body       string   `json:"body" validate:"required,max=5000"`
+	Mentions   []uint64 `json:"mentions" validate:"max=20"`
+}
+
+// CommentBodyRequest reply or edit request
+type CommentBodyRequest struct {
+	Body     string   `json:"body" validate:"required,max=5000"`
+	Mentions []uint64 `json:"mentions" validate:"max=20"`
+}
+
+// List godoc
+// @Summary      List comments
+// @Description  Get the comment threads of a project, newest first, each with its replies oldest first. Available to the owner and members.
+// @Tags         Comments
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id           path      int     true   "Project ID"
+// @Param        resolved     query     bool    false  "Only resolved (true) or open (false) threads"
+// @Param        snapshot_id  query     int     false  "Only threads on this snapshot"
+// @Param        anchor       query     string  false  "Only threads on this anchor"
+// @Param        cursor       query     string  false  "next_cursor of the previous page"
+// @Param        limit        query     int     false  "Page size"
+// @Success      200          {object}  response.Response{data=pagination.CursorResult{list=[]model.CommentView}}
+// @Failure      400          {object}  response.Response
+// @Failure      401          {object}  response.Response
+// @Failure      403          {object}  response.Response
+// @Failure      404          {object}  response.Response
+// @Router       /projects/{id}/comments [get]
+func (h *CommentHandler) List(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+	page := pagination.GetCursorFromQuery(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	opts := &service.CommentListOptions{
+		Anchor: c.Query("anchor"),
+		Cursor: page.Cursor,
+		Limit:  page.Limit,
+	}
+	switch c.Query("resolved") {
+	case "":
+	case "true":
+		resolved := true
+		opts.Resolved = &resolved
+	case "false":
+		resolved := false
+		opts.Resolved = &resolved
+	default:
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage("resolved must be true or false"))
+		return
+	}
+	if snapshot := c.Query("snapshot_id"); snapshot != "" {
+		if _, err := parseUint64(snapshot, &opts.SnapshotID); err != nil {
+			response.Fail(c, errcode.ErrInvalidParams.WithMessage("invalid snapshot_id"))
+			return
+		}
+	}
+
+	comments, nextCursor, err := h.commentService.List(ctx, id, userID, opts)
+	if err != nil {
+		h.fail(ctx, c, err, "list comments", id)
+		return
+	}
+
+	response.Success(c, pagination.NewCursorResult(comments, nextCursor))
+}
+
+// Create godoc
+// @Summary      Create comment
+// @Description  Start a thread anchored to an element (CSS selector or element path) of a project snapshot. Mentions must be the owner or members.
+// @Tags         Comments
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                   true  "Project ID"
+// @Param        request  body      CreateCommentRequest  true  "Comment"
+// @Success      200      {object}  response.Response{data=model.CommentView}
+// @Failure      400      {object}  response.Response
+// @Failure      401      {object}  response.Response
+// @Failure      403      {object}  response.Response
+// @Failure      404      {object}  response.Response
+// @Router       /projects/{id}/comments [post]
+func (h *CommentHandler) Create(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	var req CreateCommentRequest
+	if err := c.BindJSON(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(validate.FirstError(err)))
+		return
+	}
+
+	comment, err := h.commentService.Create(ctx, id, userID, req.SnapshotID, req.Anchor, req.Body, req.Mentions)
+	if err != nil {
+		h.fail(ctx, c, err, "create comment", id)
+		return
+	}
+
+	response.Success(c, comment)
+}
+
+// Reply godoc
+// @Summary      Reply to comment
+// @Description  Reply to a thread. Replying to a reply adds to the same thread.
+// @Tags         Comments
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id          path      int                 true  "Project ID"
+// @Param        comment_id  path      int                 true  "Comment ID"
+// @Param        request     body      CommentBodyRequest  true  "Reply"
+// @Success      200         {object}  response.Response{data=model.CommentView}
+// @Failure      400         {object}  response.Response
+// @Failure      401         {object}  response.Response
+// @Failure      403         {object}  response.Response
+// @Failure      404         {object}  response.Response
+// @Router       /projects/{id}/comments/{comment_id}/replies [post]
+func (h *CommentHandler) Reply(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	id, commentID, ok := commentParams(c)
+	if !ok {
+		return
+	}
+
+	var req CommentBodyRequest
+	if err := c.BindJSON(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(validate.FirstError(err)))
+		return
+	}
+
+	reply, err := h.commentService.Reply(ctx, id, commentID, userID, req.Body, req.Mentions)
+	if err != nil {
+		h.fail(ctx, c, err, "reply to comment", id)
+		return
+	}
+
+	response.Success(c, reply)
+}
+
+// Update godoc
+// @Summary      Edit comment
+// @Description  Change the body and mentions of your own comment
+// @Tags         Comments
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id          path      int                 true  "Project ID"
+// @Param        comment_id  path      int                 true  "Comment ID"
+// @Param        request     body      CommentBodyRequest  true  "Comment"
+// @Success      200         {object}  response.Response{data=model.CommentView}
+// @Failure      400         {object}  response.Response
+// @Failure      401         {object}  response.Response
+// @Failure      403         {object}  response.Response
+// @Failure      404         {object}  response.Response
+// @Router       /projects/{id}/comments/{comment_id} [put]
+func (h *CommentHandler) Update(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	id, commentID, ok := commentParams(c)
+	if !ok {
+		return
+	}
+
+	var req CommentBodyRequest
+	if err := c.BindJSON(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(validate.FirstError(err)))
+		return
+	}
+
+	comment, err := h.commentService.Update(ctx, id, commentID, userID, req.Body, req.Mentions)
+	if err != nil {
+		h.fail(ctx, c, err, "update comment", id)
+		return
+	}
+
+	response.Success(c, comment)
+}
+
+// Delete godoc
+// @Summary      Delete comment
+// @Description  Delete a comment; deleting a thread deletes its replies. Allowed for the author and the project owner.
+// @Tags         Comments
+// @Security     BearerAuth
+// @Param        id          path      int  true  "Project ID"
+// @Param        comment_id  path      int  true  "Comment ID"
+// @Success      200         {object}  response.Response
+// @Failure      401         {object}  response.Response
+// @Failure      403         {object}  response.Response
+// @Failure      404         {object}  response.Response
+// @Router       /projects/{id}/comments/{comment_id} [delete]
+func (h *CommentHandler) Delete(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	id, commentID, ok := commentParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.commentService.Delete(ctx, id, commentID, userID); err != nil {
+		h.fail(ctx, c, err, "delete comment", id)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// Resolve godoc
+// @Summary      Resolve thread
+// @Description  Mark a thread as resolved. Allowed for the thread author, the owner and editors.
+// @Tags         Comments
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id          path      int  true  "Project ID"
+// @Param        comment_id  path      int  true  "Thread ID"
+// @Success      200         {object}  response.Response{data=model.CommentView}
+// @Failure      400         {object}  response.Response
+// @Failure      401         {object}  response.Response
+// @Failure      403         {object}  response.Response
+// @Failure      404         {object}  response.Response
+// @Router       /projects/{id}/comments/{comment_id}/resolve [post]
+func (h *CommentHandler) Resolve(ctx context.Context, c *app.RequestContext) {
+	h.setResolved(ctx, c, true)
+}
+
+// Unresolve godoc
+// @Summary      Reopen thread
+// @Description  Mark a resolved thread as open again
+// @Tags         Comments
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id          path      int  true  "Project ID"
+// @Param        comment_id  path      int  true  "Thread ID"
+// @Success      200         {object}  response.Response{data=model.CommentView}
+// @Failure      400         {object}  response.Response
+// @Failure      401         {object}  response.Response
+// @Failure      403         {object}  response.Response
+// @Failure      404         {object}  response.Response
+// @Router       /projects/{id}/comments/{comment_id}/resolve [delete]
+func (h *CommentHandler) Unresolve(ctx context.Context, c *app.RequestContext) {
+	h.setResolved(ctx, c, false)
+}
+
+func (h *CommentHandler) setResolved(ctx context.Context, c *app.RequestContext, resolved bool) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	id, commentID, ok := commentParams(c)
+	if !ok {
+		return
+	}
+
+	comment, err := h.commentService.SetResolved(ctx, id, commentID, userID, resolved)
+	if err != nil {
+		h.fail(ctx, c, err, "resolve comment", id)
+		return
+	}
+
+	response.Success(c, comment)
+}
+
+// ListMentions godoc
+// @Summary      List mentions
+// @Description  Get the comments mentioning the authenticated user in projects they can still access, newest first
+// @Tags         Comments
+// @Security     BearerAuth
+// @Produce      json
+// @Param        cursor  query     string  false  "next_cursor of the previous page"
+// @Param        limit   query     int     false  "Page size"
+// @Success      200     {object}  response.Response{data=pagination.CursorResult{list=[]model.CommentView}}
+// @Failure      400     {object}  response.Response
+// @Failure      401     {object}  response.Response
+// @Router       /comments/mentions [get]
+func (h *CommentHandler) ListMentions(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+	page := pagination.GetCursorFromQuery(c)
+
+	comments, nextCursor, err := h.commentService.ListMentions(ctx, userID, page.Cursor, page.Limit)
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			response.Fail(c, errcode.ErrInvalidParams.WithMessage("invalid cursor"))
+			return
+		}
+		logger.ErrorCtxf(ctx, "failed to list mentions", "error", err, "userID", userID)
+		response.Fail(c, errcode.ErrDatabase)
+		return
+	}
+
+	response.Success(c, pagination.NewCursorResult(comments, nextCursor))
+}
+
+// commentParams 解析项目 ID 和评论 ID，失败时已写入响应
+func commentParams(c *app.RequestContext) (uint64, uint64, bool) {
+	var id, commentID uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return 0, 0, false
+	}
+	if _, err := parseUint64(c.Param("comment_id"), &commentID); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return 0, 0, false
+	}
+	return id, commentID, true
+}
+
+// fail 将评论相关错误映射为响应
+func (h *CommentHandler) fail(ctx context.Context, c *app.RequestContext, err error, action string, projectID uint64) {
+	switch {
+	case errors.Is(err, service.ErrCommentNotFound):
+		response.Fail(c, errcode.ErrNotFound.WithMessage("comment not found"))
+	case errors.Is(err, service.ErrSnapshotNotFound):
+		response.Fail(c, errcode.ErrNotFound.WithMessage("snapshot not found"))
+	case errors.Is(err, service.ErrCommentForbidden):
+		response.Fail(c, errcode.ErrForbidden.WithMessage("you cannot change this comment"))
+	case errors.Is(err, service.ErrCommentNotThread):
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage("only threads can be resolved"))
+	case errors.Is(err, service.ErrCommentEmpty), errors.Is(err, service.ErrAnchorInvalid), errors.Is(err, service.ErrMentionInvalid):
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(err.Error()))
+	case errors.Is(err, pagination.ErrInvalidCursor):
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage("invalid cursor"))
+	default:
+		failProjectError(ctx, c, err, action, projectID)
+	}
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/test-tt/internal/middleware"
+	"github.com/test-tt/internal/service"
+	"github.com/test-tt/pkg/errcode"
+	"github.com/test-tt/pkg/logger"
+	"github.com/test-tt/pkg/pagination"
+	"github.com/test-tt/pkg/response"
+	"github.com/test-tt/pkg/validate"
+)
+
+type CreditHandler struct {
+	creditService *service.CreditService
+}
+
+func NewCreditHandler() *CreditHandler {
+	return &CreditHandler{
+		creditService: service.NewCreditService(),
+	}
+}
+
+// GrantCreditsRequest admin credit grant request
+type GrantCreditsRequest struct {
+	Period  string `json:"period" validate:"required,oneof=day month"`           // Budget the credits are added to, for the current day or month only
+	Credits int64  `json:"credits" validate:"required,min=-1000000,max=1000000"` // Negative to take credits away
+	Reason  string `json:"reason" validate:"max=255"`
+}
+
+// Balance godoc
+// @Summary      Get generation credits
+// @Description  Get the credits used today and this month against the budgets of the current user's plan, including credits granted by admins
+// @Tags         Generation
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {object}  response.Response{data=service.CreditBalance}
+// @Failure      401  {object}  response.Response
+// @Router       /credits [get]
+func (h *CreditHandler) Balance(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	balance, err := h.creditService.Balance(ctx, userID)
+	if err != nil {
+		logger.ErrorCtxf(ctx, "failed to get credit balance", "error", err, "userID", userID)
+		response.Fail(c, errcode.ErrDatabase)
+		return
+	}
+
+	response.Success(c, balance)
+}
+
+// History godoc
+// @Summary      List generation usage
+// @Description  List the tokens, time, cost and credits of the current user's generations, newest first
+// @Tags         Generation
+// @Security     BearerAuth
+// @Produce      json
+// @Param        cursor  query     string  false  "next_cursor of the previous page"
+// @Param        limit   query     int     false  "Page size"
+// @Success      200     {object}  response.Response{data=pagination.CursorResult{list=[]model.GenerationUsage}}
+// @Failure      400     {object}  response.Response
+// @Failure      401     {object}  response.Response
+// @Router       /credits/usage [get]
+func (h *CreditHandler) History(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+	page := pagination.GetCursorFromQuery(c)
+
+	usage, nextCursor, err := h.creditService.History(ctx, userID, page.Cursor, page.Limit)
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			response.Fail(c, errcode.ErrInvalidParams.WithMessage("invalid cursor"))
+			return
+		}
+		logger.ErrorCtxf(ctx, "failed to list generation usage", "error", err, "userID", userID)
+		response.Fail(c, errcode.ErrDatabase)
+		return
+	}
+
+	response.Success(c, pagination.NewCursorResult(usage, nextCursor))
+}
+
+// UserBalance godoc
+// @Summary      Get a user's generation credits (admin)
+// @Tags         Admin
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "User ID"
+// @Success      200  {object}  response.Response{data=service.CreditBalance}
+// @Failure      400  {object}  response.Response
+// @Failure      403  {object}  response.Response
+// @Router       /admin/users/{id}/credits [get]
+func (h *CreditHandler) UserBalance(ctx context.Context, c *app.RequestContext) {
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidUserID)
+		return
+	}
+
+	balance, err := h.creditService.Balance(ctx, id)
+	if err != nil {
+		logger.ErrorCtxf(ctx, "failed to get credit balance", "error", err, "userID", id)
+		response.Fail(c, errcode.ErrDatabase)
+		return
+	}
+
+	response.Success(c, balance)
+}
+
+// Grant godoc
+// @Summary      Grant generation credits (admin)
+// @Description  Add credits to a user's daily or monthly budget. Grants only apply to the day or month they are made in.
+// @Tags         Admin
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                  true  "User ID"
+// @Param        request  body      GrantCreditsRequest  true  "Grant"
+// @Success      200      {object}  response.Response{data=model.CreditGrant}
+// @Failure      400      {object}  response.Response
+// @Failure      403      {object}  response.Response
+// @Failure      404      {object}  response.Response
+// @Router       /admin/users/{id}/credits [post]
+func (h *CreditHandler) Grant(ctx context.Context, c *app.RequestContext) {
+	adminID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidUserID)
+		return
+	}
+
+	var req GrantCreditsRequest
+	if err := c.BindJSON(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(validate.FirstError(err)))
+		return
+	}
+
+	grant, err := h.creditService.Grant(ctx, adminID, id, req.Period, req.Credits, req.Reason)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			response.Fail(c, errcode.ErrUserNotFound)
+			return
+		}
+		logger.ErrorCtxf(ctx, "failed to grant credits", "error", err, "userID", id)
+		response.Fail(c, errcode.ErrDatabase)
+		return
+	}
+
+	response.Success(c, grant)
+}
@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/test-tt/internal/middleware"
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/internal/service"
+	"github.com/test-tt/pkg/errcode"
+	"github.com/test-tt/pkg/response"
+	"github.com/test-tt/pkg/validate"
+)
+
+type FileHandler struct {
+	fileService *service.FileService
+}
+
+func NewFileHandler() *FileHandler {
+	return &FileHandler{
+		fileService: service.NewFileService(),
+	}
+}
+
+// PutFileRequest create or replace file request
+type PutFileRequest struct {
+	Content *string `json:"content" validate:"required"`
+}
+
+// SaveFilesRequest atomic multi-file save request
+type SaveFilesRequest struct {
+	Files   []FileContent `json:"files" validate:"max=102,dive"`
+	Deleted []string      `json:"deleted" validate:"max=100"`
+}
+
+// FileContent is a file written by a multi-file save
+type FileContent struct {
+	Path    string `json:"path" validate:"required"`
+	Content string `json:"content"`
+}
+
+// List godoc
+// @Summary      List project files
+// @Description  Get the file tree of a project without content. index.html and style.css (the project's html and css) come first, then the other files by path. Available to the owner and members.
+// @Tags         Files
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "Project ID"
+// @Success      200  {object}  response.Response{data=[]model.ProjectFileInfo}
+// @Failure      401  {object}  response.Response
+// @Failure      403  {object}  response.Response
+// @Failure      404  {object}  response.Response
+// @Router       /projects/{id}/files [get]
+func (h *FileHandler) List(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	files, err := h.fileService.List(ctx, id, userID)
+	if err != nil {
+		h.fail(ctx, c, err, "list project files", id)
+		return
+	}
+
+	response.Success(c, files)
+}
+
+// Get godoc
+// @Summary      Get project file
+// @Description  Get a file with its content. Available to the owner and members.
+// @Tags         Files
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id    path      int     true  "Project ID"
+// @Param        path  path      string  true  "File path, e.g. about/index.html"
+// @Success      200   {object}  response.Response{data=model.ProjectFile}
+// @Failure      400   {object}  response.Response
+// @Failure      401   {object}  response.Response
+// @Failure      403   {object}  response.Response
+// @Failure      404   {object}  response.Response
+// @Router       /projects/{id}/files/{path} [get]
+func (h *FileHandler) Get(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	file, err := h.fileService.Get(ctx, id, userID, c.Param("path"))
+	if err != nil {
+		h.fail(ctx, c, err, "get project file", id)
+		return
+	}
+
+	response.Success(c, file)
+}
+
+// Put godoc
+// @Summary      Create or replace project file
+// @Description  Write a single file. Writing index.html or style.css updates the project's html or css. Content is sanitized like project saves; <style> blocks of pages move to style.css. Owner and editors only.
+// @Tags         Files
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int             true  "Project ID"
+// @Param        path     path      string          true  "File path ending in .html, .css or .js"
+// @Param        request  body      PutFileRequest  true  "File content"
+// @Success      200      {object}  response.Response{data=service.SavedFiles}
+// @Failure      400      {object}  response.Response
+// @Failure      401      {object}  response.Response
+// @Failure      403      {object}  response.Response
+// @Failure      404      {object}  response.Response
+// @Failure      413      {object}  response.Response
+// @Router       /projects/{id}/files/{path} [put]
+func (h *FileHandler) Put(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	var req PutFileRequest
+	if err := c.BindJSON(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(validate.FirstError(err)))
+		return
+	}
+
+	saved, err := h.fileService.Put(ctx, id, userID, c.Param("path"), *req.Content)
+	if err != nil {
+		h.fail(ctx, c, err, "save project file", id)
+		return
+	}
+
+	response.Success(c, saved)
+}
+
+// Delete godoc
+// @Summary      Delete project file
+// @Description  Delete a file. index.html and style.css cannot be deleted. Owner and editors only.
+// @Tags         Files
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id    path      int     true  "Project ID"
+// @Param        path  path      string  true  "File path"
+// @Success      200   {object}  response.Response{data=service.SavedFiles}
+// @Failure      400   {object}  response.Response
+// @Failure      401   {object}  response.Response
+// @Failure      403   {object}  response.Response
+// @Failure      404   {object}  response.Response
+// @Router       /projects/{id}/files/{path} [delete]
+func (h *FileHandler) Delete(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	saved, err := h.fileService.Delete(ctx, id, userID, c.Param("path"))
+	if err != nil {
+		h.fail(ctx, c, err, "delete project file", id)
+		return
+	}
+
+	response.Success(c, saved)
+}
+
+// Save godoc
+// @Summary      Save several project files
+// @Description  Write and delete several files in one atomic save: either every change is applied or none. A path may appear only once across files and deleted. Owner and editors only.
+// @Tags         Files
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int               true  "Project ID"
+// @Param        request  body      SaveFilesRequest  true  "Files to write and paths to delete"
+// @Success      200      {object}  response.Response{data=service.SavedFiles}
+// @Failure      400      {object}  response.Response
+// @Failure      401      {object}  response.Response
+// @Failure      403      {object}  response.Response
+// @Failure      404      {object}  response.Response
+// @Failure      413      {object}  response.Response
+// @Router       /projects/{id}/files [put]
+func (h *FileHandler) Save(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	var req SaveFilesRequest
+	if err := c.BindJSON(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(validate.FirstError(err)))
+		return
+	}
+	if len(req.Files)+len(req.Deleted) == 0 {
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage("nothing to save"))
+		return
+	}
+
+	files := make([]model.ProjectFile, len(req.Files))
+	for i, f := range req.Files {
+		files[i] = model.ProjectFile{Path: f.Path, Content: f.Content}
+	}
+	saved, err := h.fileService.Save(ctx, id, userID, files, req.Deleted)
+	if err != nil {
+		h.fail(ctx, c, err, "save project files", id)
+		return
+	}
+
+	response.Success(c, saved)
+}
+
+// fail 将文件相关错误映射为响应
+func (h *FileHandler) fail(ctx context.Context, c *app.RequestContext, err error, action string, projectID uint64) {
+	switch {
+	case errors.Is(err, service.ErrFilePathInvalid):
+		response.Fail(c, errcode.ErrFilePathInvalid)
+	case errors.Is(err, service.ErrFileNotFound):
+		response.Fail(c, errcode.ErrNotFound.WithMessage("file not found"))
+	case errors.Is(err, service.ErrFileRequired):
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage("index.html and style.css cannot be deleted"))
+	case errors.Is(err, service.ErrFileDuplicate):
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage("a file can only be changed once per save"))
+	case errors.Is(err, service.ErrFileLimit):
+		response.Fail(c, errcode.ErrFileLimit)
+	default:
+		failProjectError(ctx, c, err, action, projectID)
+	}
+}
@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/test-tt/internal/middleware"
+	"github.com/test-tt/internal/service"
+	"github.com/test-tt/pkg/errcode"
+	"github.com/test-tt/pkg/logger"
+	"github.com/test-tt/pkg/response"
+	"github.com/test-tt/pkg/validate"
+)
+
+type FolderHandler struct {
+	folderService *service.FolderService
+}
+
+func NewFolderHandler() *FolderHandler {
+	return &FolderHandler{
+		folderService: service.NewFolderService(),
+	}
+}
+
+// FolderRequest create or update folder request
+type FolderRequest struct {
+	Name     string  `json:"name" validate:"required,max=100"`
+	ParentID *uint64 `json:"parent_id"`
+}
+
+// MoveProjectsRequest bulk move request
+type MoveProjectsRequest struct {
+	ProjectIDs []uint64 `json:"project_ids" validate:"required,min=1,max=100"`
+	FolderID   *uint64  `json:"folder_id"` // null moves the projects to the root
+}
+
+// List godoc
+// @Summary      List folders
+// @Description  Get all project folders of the authenticated user. Folders nest one level via parent_id.
+// @Tags         Folders
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {object}  response.Response{data=[]model.ProjectFolder}
+// @Failure      401  {object}  response.Response
+// @Router       /folders [get]
+func (h *FolderHandler) List(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	folders, err := h.folderService.List(ctx, userID)
+	if err != nil {
+		logger.ErrorCtxf(ctx, "failed to list folders", "error", err, "userID", userID)
+		response.Fail(c, errcode.ErrDatabase)
+		return
+	}
+
+	response.Success(c, folders)
+}
+
+// Create godoc
+// @Summary      Create folder
+// @Tags         Folders
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      FolderRequest  true  "Folder info"
+// @Success      200      {object}  response.Response{data=model.ProjectFolder}
+// @Failure      400      {object}  response.Response
+// @Failure      401      {object}  response.Response
+// @Router       /folders [post]
+func (h *FolderHandler) Create(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var req FolderRequest
+	if err := c.BindJSON(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(validate.FirstError(err)))
+		return
+	}
+
+	folder, err := h.folderService.Create(ctx, userID, req.Name, req.ParentID)
+	if err != nil {
+		h.fail(ctx, c, err, "create folder", 0)
+		return
+	}
+
+	response.Success(c, folder)
+}
+
+// Update godoc
+// @Summary      Update folder
+// @Description  Rename a folder and set its parent; parent_id null moves it to the root
+// @Tags         Folders
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int            true  "Folder ID"
+// @Param        request  body      FolderRequest  true  "Folder info"
+// @Success      200      {object}  response.Response{data=model.ProjectFolder}
+// @Failure      400      {object}  response.Response
+// @Failure      401      {object}  response.Response
+// @Failure      404      {object}  response.Response
+// @Router       /folders/{id} [put]
+func (h *FolderHandler) Update(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	var req FolderRequest
+	if err := c.BindJSON(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(validate.FirstError(err)))
+		return
+	}
+
+	folder, err := h.folderService.Update(ctx, id, userID, req.Name, req.ParentID)
+	if err != nil {
+		h.fail(ctx, c, err, "update folder", id)
+		return
+	}
+
+	response.Success(c, folder)
+}
+
+// Delete godoc
+// @Summary      Delete folder
+// @Description  Delete a folder and its subfolders. Their projects move to the root.
+// @Tags         Folders
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Folder ID"
+// @Success      200  {object}  response.Response
+// @Failure      401  {object}  response.Response
+// @Failure      404  {object}  response.Response
+// @Router       /folders/{id} [delete]
+func (h *FolderHandler) Delete(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	if err := h.folderService.Delete(ctx, id, userID); err != nil {
+		h.fail(ctx, c, err, "delete folder", id)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// MoveProjects godoc
+// @Summary      Move projects
+// @Description  Move several projects into a folder, or to the root when folder_id is null
+// @Tags         Projects
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      MoveProjectsRequest  true  "Projects and target folder"
+// @Success      200      {object}  response.Response
+// @Failure      400      {object}  response.Response
+// @Failure      401      {object}  response.Response
+// @Failure      404      {object}  response.Response
+// @Router       /projects/bulk/move [post]
+func (h *FolderHandler) MoveProjects(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var req MoveProjectsRequest
+	if err := c.BindJSON(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(validate.FirstError(err)))
+		return
+	}
+
+	if err := h.folderService.MoveProjects(ctx, userID, req.ProjectIDs, req.FolderID); err != nil {
+		h.fail(ctx, c, err, "move projects", 0)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// fail 将文件夹相关错误映射为响应
+func (h *FolderHandler) fail(ctx context.Context, c *app.RequestContext, err error, action string, folderID uint64) {
+	switch {
+	case errors.Is(err, service.ErrFolderNotFound):
+		response.Fail(c, errcode.ErrFolderNotFound)
+	case errors.Is(err, service.ErrFolderNotOwned):
+		response.Fail(c, errcode.ErrForbidden.WithMessage("folder does not belong to you"))
+	case errors.Is(err, service.ErrFolderNameEmpty):
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage("folder name cannot be empty"))
+	case errors.Is(err, service.ErrFolderTooDeep):
+		response.Fail(c, errcode.ErrFolderTooDeep)
+	case errors.Is(err, service.ErrFolderLimit):
+		response.Fail(c, errcode.ErrFolderLimit)
+	case errors.Is(err, service.ErrProjectNotFound):
+		response.Fail(c, errcode.ErrNotFound.WithMessage("project not found"))
+	default:
+		logger.ErrorCtxf(ctx, "failed to "+action, "error", err, "folderID", folderID)
+		response.Fail(c, errcode.ErrDatabase)
+	}
+}
@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/test-tt/internal/middleware"
+	"github.com/test-tt/internal/service"
+	"github.com/test-tt/pkg/errcode"
+	"github.com/test-tt/pkg/pagination"
+	"github.com/test-tt/pkg/response"
+	"github.com/test-tt/pkg/validate"
+)
+
+type GalleryHandler struct {
+	galleryService *service.GalleryService
+}
+
+func NewGalleryHandler() *GalleryHandler {
+	return &GalleryHandler{
+		galleryService: service.NewGalleryService(),
+	}
+}
+
+// GalleryListingRequest list in gallery request
+type GalleryListingRequest struct {
+	Category    string `json:"category" validate:"required"`
+	Description string `json:"description" validate:"max=500"`
+}
+
+// List godoc
+// @Summary      Browse gallery
+// @Description  Get published projects shared to the public gallery. Sort by trending (stars decayed over time, default), new or top (all-time stars). Signed-in visitors also get whether they starred each project.
+// @Tags         Gallery
+// @Produce      json
+// @Param        category  query     string  false  "Only this category"
+// @Param        sort      query     string  false  "trending, new or top"
+// @Param        cursor    query     string  false  "next_cursor of the previous page"
+// @Param        limit     query     int     false  "Page size"
+// @Success      200       {object}  response.Response{data=pagination.CursorResult{list=[]model.GalleryItem}}
+// @Failure      400       {object}  response.Response
+// @Router       /gallery [get]
+func (h *GalleryHandler) List(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+	page := pagination.GetCursorFromQuery(c)
+
+	items, nextCursor, err := h.galleryService.List(ctx, userID, &service.GalleryOptions{
+		Category: c.Query("category"),
+		Sort:     c.Query("sort"),
+		Cursor:   page.Cursor,
+		Limit:    page.Limit,
+	})
+	if err != nil {
+		h.fail(ctx, c, err, "list gallery", 0)
+		return
+	}
+
+	response.Success(c, pagination.NewCursorResult(items, nextCursor))
+}
+
+// Categories godoc
+// @Summary      List gallery categories
+// @Description  Get the categories projects can be listed under
+// @Tags         Gallery
+// @Produce      json
+// @Success      200  {object}  response.Response{data=[]string}
+// @Router       /gallery/categories [get]
+func (h *GalleryHandler) Categories(ctx context.Context, c *app.RequestContext) {
+	response.Success(c, h.galleryService.Categories())
+}
+
+// Add godoc
+// @Summary      List project in gallery
+// @Description  Share a published project to the public gallery, or change its category and description. Owner only.
+// @Tags         Gallery
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                    true  "Project ID"
+// @Param        request  body      GalleryListingRequest  true  "Listing"
+// @Success      200      {object}  response.Response{data=model.GalleryEntry}
+// @Failure      400      {object}  response.Response
+// @Failure      401      {object}  response.Response
+// @Failure      404      {object}  response.Response
+// @Router       /projects/{id}/gallery [put]
+func (h *GalleryHandler) Add(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	var req GalleryListingRequest
+	if err := c.BindJSON(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(validate.FirstError(err)))
+		return
+	}
+
+	entry, err := h.galleryService.Add(ctx, id, userID, req.Category, req.Description)
+	if err != nil {
+		h.fail(ctx, c, err, "list project in gallery", id)
+		return
+	}
+
+	response.Success(c, entry)
+}
+
+// Remove godoc
+// @Summary      Remove project from gallery
+// @Description  Take a project out of the public gallery. Its stars are kept if it is listed again. Owner only.
+// @Tags         Gallery
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "Project ID"
+// @Success      200  {object}  response.Response
+// @Failure      401  {object}  response.Response
+// @Failure      404  {object}  response.Response
+// @Router       /projects/{id}/gallery [delete]
+func (h *GalleryHandler) Remove(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	if err := h.galleryService.Remove(ctx, id, userID); err != nil {
+		h.fail(ctx, c, err, "remove project from gallery", id)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// Star godoc
+// @Summary      Star project
+// @Description  Star a gallery project. Starring a project twice has no effect.
+// @Tags         Gallery
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "Project ID"
+// @Success      200  {object}  response.Response{data=service.StarResult}
+// @Failure      401  {object}  response.Response
+// @Failure      404  {object}  response.Response
+// @Router       /gallery/{id}/star [post]
+func (h *GalleryHandler) Star(ctx context.Context, c *app.RequestContext) {
+	h.setStar(ctx, c, true)
+}
+
+// Unstar godoc
+// @Summary      Unstar project
+// @Description  Remove your star from a gallery project
+// @Tags         Gallery
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "Project ID"
+// @Success      200  {object}  response.Response{data=service.StarResult}
+// @Failure      401  {object}  response.Response
+// @Failure      404  {object}  response.Response
+// @Router       /gallery/{id}/star [delete]
+func (h *GalleryHandler) Unstar(ctx context.Context, c *app.RequestContext) {
+	h.setStar(ctx, c, false)
+}
+
+func (h *GalleryHandler) setStar(ctx context.Context, c *app.RequestContext, star bool) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	var result *service.StarResult
+	var err error
+	if star {
+		result, err = h.galleryService.Star(ctx, id, userID)
+	} else {
+		result, err = h.galleryService.Unstar(ctx, id, userID)
+	}
+	if err != nil {
+		h.fail(ctx, c, err, "star project", id)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+func (h *GalleryHandler) fail(ctx context.Context, c *app.RequestContext, err error, action string, projectID uint64) {
+	switch {
+	case errors.Is(err, service.ErrGalleryNotListed):
+		response.Fail(c, errcode.ErrGalleryNotListed)
+	case errors.Is(err, service.ErrNotPublished):
+		response.Fail(c, errcode.ErrProjectNotPublished)
+	case errors.Is(err, service.ErrGalleryCategoryInvalid), errors.Is(err, service.ErrGallerySortInvalid):
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(err.Error()))
+	case errors.Is(err, pagination.ErrInvalidCursor):
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage("invalid cursor"))
+	default:
+		failProjectError(ctx, c, err, action, projectID)
+	}
+}
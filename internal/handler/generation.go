@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/network"
+	"github.com/cloudwego/hertz/pkg/protocol/http1/resp"
+
+	"github.com/test-tt/internal/generation"
+	"github.com/test-tt/internal/middleware"
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/internal/service"
+	"github.com/test-tt/pkg/errcode"
+	"github.com/test-tt/pkg/logger"
+	"github.com/test-tt/pkg/response"
+	"github.com/test-tt/pkg/validate"
+)
+
+// sseHeartbeat 定期发送注释行保持连接，并及时发现断开的客户端
+const sseHeartbeat = 15 * time.Second
+
+type GenerationHandler struct {
+	manager        *generation.Manager
+	projectService *service.ProjectService
+}
+
+func NewGenerationHandler() *GenerationHandler {
+	return &GenerationHandler{
+		manager:        generation.GetManager(),
+		projectService: service.NewProjectService(),
+	}
+}
+
+// GenerateMessage earlier conversation turn
+type GenerateMessage struct {
+	Role    string `json:"role" validate:"required,oneof=user assistant"`
+	Content string `json:"content" validate:"max=20000"`
+}
+
+// GenerateRequest start generation request
+type GenerateRequest struct {
+	Prompt      string            `json:"prompt" validate:"required"`
+	ProjectID   *uint64           `json:"project_id"` // Project the page is saved into when it completes, owner or editor only
+	Messages    []GenerateMessage `json:"messages" validate:"max=100,dive"`
+	CurrentHTML string            `json:"current_html"`              // Page to modify, empty to create a new page
+	Target      string            `json:"target" validate:"max=500"` // CSS selector or element path of the only element of current_html to regenerate
+}
+
+// Generate godoc
+// @Summary      Generate page
+// @Description  Start generating a page from a prompt, or modifying current_html when it is set. With target, only the selected element of current_html is regenerated and put back into the page, and the job fails when the model does not return a single well-formed element. With project_id, the element is put into the project's current page when the job completes; the save fails if the element no longer matches. The job runs in the background and is kept after it ends: follow it with the stream endpoint from any server or poll it.
+// @Description  The current page and the prompt are always sent in full; earlier messages that do not fit the context budget are summarized or left out, and job.context reports how.
+// @Description  Jobs wait in a queue shared fairly between users when the servers are busy, and fail if they wait too long.
+// @Description  With project_id the completed page and the prompt and reply are saved into the project on the server, result.saved tells whether that succeeded.
+// @Description  Completed pages are checked for broken structure, missing alt text and viewport, low-contrast colors, external scripts and size; result.quality lists the problems and is saved with the project too.
+// @Description  Each generation is charged credits for the tokens it used. Returns 402 when the daily or monthly credits of the plan are used up.
+// @Tags         Generation
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      GenerateRequest  true  "Prompt and conversation"
+// @Success      200      {object}  response.Response{data=model.GenerationJob}
+// @Failure      400      {object}  response.Response
+// @Failure      401      {object}  response.Response
+// @Failure      402      {object}  response.Response
+// @Failure      403      {object}  response.Response
+// @Failure      404      {object}  response.Response
+// @Failure      429      {object}  response.Response
+// @Router       /generate [post]
+func (h *GenerationHandler) Generate(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var req GenerateRequest
+	if err := c.BindJSON(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(validate.FirstError(err)))
+		return
+	}
+
+	if req.ProjectID != nil {
+		_, role, err := h.projectService.Access(ctx, *req.ProjectID, userID)
+		if err == nil && role == model.MemberRoleViewer {
+			err = service.ErrProjectReadOnly
+		}
+		if err != nil {
+			failProjectError(ctx, c, err, "start generation", *req.ProjectID)
+			return
+		}
+	}
+
+	in := &generation.Input{Prompt: req.Prompt, CurrentHTML: req.CurrentHTML, Target: req.Target}
+	for _, msg := range req.Messages {
+		in.History = append(in.History, generation.Message{Role: msg.Role, Content: msg.Content})
+	}
+
+	job, err := h.manager.Start(ctx, userID, req.ProjectID, in)
+	if err != nil {
+		h.fail(ctx, c, err, "start generation")
+		return
+	}
+
+	logger.InfoCtxf(ctx, "generation started", "jobID", job.ID, "userID", userID, "provider", job.Provider)
+	response.Success(c, job)
+}
+
+// Get godoc
+// @Summary      Get generation job
+// @Description  Get the status, output so far and result of one of your generation jobs, and its place in the queue while pending
+// @Tags         Generation
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      string  true  "Job ID"
+// @Success      200  {object}  response.Response{data=model.GenerationJob}
+// @Failure      401  {object}  response.Response
+// @Failure      404  {object}  response.Response
+// @Router       /generate/{id} [get]
+func (h *GenerationHandler) Get(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	job, err := h.manager.Get(ctx, c.Param("id"), userID)
+	if err != nil {
+		h.fail(ctx, c, err, "get generation")
+		return
+	}
+
+	response.Success(c, job)
+}
+
+// Stream godoc
+// @Summary      Stream generation progress
+// @Description  Server-sent events of a generation job. Each event has id set to its seq and data set to a JSON generation.Event: status, queued (with the position in the queue whenever it changes), thinking and content deltas, then complete (with the result), error or canceled, after which the stream ends.
+// @Description  Any server can serve the stream of any job. To resume after a disconnect pass the last seq received as Last-Event-ID (EventSource does this automatically) or ?after=.
+// @Tags         Generation
+// @Security     BearerAuth
+// @Produce      text/event-stream
+// @Param        id             path    string  true   "Job ID"
+// @Param        after          query   int     false  "Only events after this seq"
+// @Param        Last-Event-ID  header  string  false  "Only events after this seq"
+// @Success      200  {object}  generation.Event
+// @Failure      401  {object}  response.Response
+// @Failure      404  {object}  response.Response
+// @Router       /generate/{id}/stream [get]
+func (h *GenerationHandler) Stream(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+	jobID := c.Param("id")
+
+	after := string(c.GetHeader("Last-Event-ID"))
+	if after == "" {
+		after = c.Query("after")
+	}
+	var seq int64
+	if after != "" {
+		var err error
+		if seq, err = strconv.ParseInt(after, 10, 64); err != nil || seq < 0 {
+			response.Fail(c, errcode.ErrInvalidParams.WithMessage("invalid event id"))
+			return
+		}
+	}
+
+	// 开始推流前确认任务存在，以便返回普通的错误响应
+	if _, err := h.manager.Get(ctx, jobID, userID); err != nil {
+		h.fail(ctx, c, err, "stream generation")
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 禁止反向代理缓冲
+	w := resp.NewChunkedBodyWriter(&c.Response, c.GetWriter())
+	c.Response.HijackWriter(w)
+
+	ctx, cancel := context.WithCancel(ctx)
+	stream := &sseWriter{w: w}
+	heartbeatDone := make(chan struct{})
+	// 返回后框架会结束响应，必须等心跳协程退出
+	defer func() {
+		cancel()
+		<-heartbeatDone
+	}()
+
+	go func() {
+		defer close(heartbeatDone)
+		ticker := time.NewTicker(sseHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := stream.send([]byte(": ping\n\n")); err != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	err := h.manager.Subscribe(ctx, jobID, userID, seq, func(event *generation.Event) error {
+		data, err := sonic.Marshal(event)
+		if err != nil {
+			return err
+		}
+		return stream.send([]byte(fmt.Sprintf("id: %d\ndata: %s\n\n", event.Seq, data)))
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		logger.WarnCtxf(ctx, "generation stream ended", "jobID", jobID, "error", err)
+	}
+}
+
+// Cancel godoc
+// @Summary      Cancel generation job
+// @Description  Stop one of your queued or running generation jobs. Its stream ends with a canceled event; a job running on another server stops within a few seconds. The tokens already generated are still charged.
+// @Tags         Generation
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Job ID"
+// @Success      200  {object}  response.Response
+// @Failure      401  {object}  response.Response
+// @Failure      404  {object}  response.Response
+// @Failure      409  {object}  response.Response
+// @Router       /generate/{id} [delete]
+func (h *GenerationHandler) Cancel(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+	jobID := c.Param("id")
+
+	if err := h.manager.Cancel(ctx, jobID, userID); err != nil {
+		h.fail(ctx, c, err, "cancel generation")
+		return
+	}
+
+	logger.InfoCtxf(ctx, "generation canceled", "jobID", jobID, "userID", userID)
+	response.Success(c, nil)
+}
+
+// sseWriter 串行化事件和心跳的写入
+type sseWriter struct {
+	mu sync.Mutex
+	w  network.ExtWriter
+}
+
+func (s *sseWriter) send(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(data); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+func (h *GenerationHandler) fail(ctx context.Context, c *app.RequestContext, err error, action string) {
+	switch {
+	case errors.Is(err, generation.ErrJobNotFound):
+		response.Fail(c, errcode.ErrNotFound.WithMessage("generation job not found"))
+	case errors.Is(err, generation.ErrJobFinished):
+		response.Fail(c, errcode.ErrGenerationFinished)
+	case errors.Is(err, generation.ErrTooManyJobs):
+		response.Fail(c, errcode.ErrTooManyRequests.WithMessage(err.Error()))
+	case errors.Is(err, service.ErrCreditsExhausted):
+		response.Fail(c, errcode.ErrCreditsExhausted)
+	case errors.Is(err, generation.ErrPromptEmpty), errors.Is(err, generation.ErrPromptTooLong),
+		errors.Is(err, generation.ErrTargetWithoutPage), errors.Is(err, generation.ErrTargetInvalid),
+		errors.Is(err, generation.ErrTargetNotFound), errors.Is(err, generation.ErrTargetAmbiguous),
+		errors.Is(err, generation.ErrTargetUnsupported):
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(err.Error()))
+	default:
+		logger.ErrorCtxf(ctx, "failed to "+action, "error", err)
+		response.Fail(c, errcode.ErrInternalServer)
+	}
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/test-tt/internal/middleware"
+	"github.com/test-tt/internal/service"
+	"github.com/test-tt/pkg/errcode"
+	"github.com/test-tt/pkg/response"
+	"github.com/test-tt/pkg/validate"
+)
+
+type MemberHandler struct {
+	memberService *service.MemberService
+}
+
+func NewMemberHandler() *MemberHandler {
+	return &MemberHandler{
+		memberService: service.NewMemberService(),
+	}
+}
+
+// AddMemberRequest invite member request
+type AddMemberRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=editor viewer"`
+}
+
+// List godoc
+// @Summary      List project members
+// @Description  Get the collaborators of a project. Available to the owner and members.
+// @Tags         Members
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "Project ID"
+// @Success      200  {object}  response.Response{data=[]model.ProjectMemberInfo}
+// @Failure      401  {object}  response.Response
+// @Failure      403  {object}  response.Response
+// @Failure      404  {object}  response.Response
+// @Router       /projects/{id}/members [get]
+func (h *MemberHandler) List(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	members, err := h.memberService.List(ctx, id, userID)
+	if err != nil {
+		h.fail(ctx, c, err, "list project members", id)
+		return
+	}
+
+	response.Success(c, members)
+}
+
+// Add godoc
+// @Summary      Add project member
+// @Description  Invite a registered user by email as editor or viewer, or change the role of an existing member. Owner only.
+// @Tags         Members
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int               true  "Project ID"
+// @Param        request  body      AddMemberRequest  true  "Member info"
+// @Success      200      {object}  response.Response{data=model.ProjectMemberInfo}
+// @Failure      400      {object}  response.Response
+// @Failure      401      {object}  response.Response
+// @Failure      403      {object}  response.Response
+// @Failure      404      {object}  response.Response
+// @Router       /projects/{id}/members [post]
+func (h *MemberHandler) Add(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	var req AddMemberRequest
+	if err := c.BindJSON(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(validate.FirstError(err)))
+		return
+	}
+
+	member, err := h.memberService.Add(ctx, id, userID, req.Email, req.Role)
+	if err != nil {
+		h.fail(ctx, c, err, "add project member", id)
+		return
+	}
+
+	response.Success(c, member)
+}
+
+// Remove godoc
+// @Summary      Remove project member
+// @Description  Remove a member from a project. The owner can remove anyone, members can remove themselves.
+// @Tags         Members
+// @Security     BearerAuth
+// @Param        id       path      int  true  "Project ID"
+// @Param        user_id  path      int  true  "Member user ID"
+// @Success      200      {object}  response.Response
+// @Failure      401      {object}  response.Response
+// @Failure      403      {object}  response.Response
+// @Failure      404      {object}  response.Response
+// @Router       /projects/{id}/members/{user_id} [delete]
+func (h *MemberHandler) Remove(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id, memberID uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+	if _, err := parseUint64(c.Param("user_id"), &memberID); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	if err := h.memberService.Remove(ctx, id, userID, memberID); err != nil {
+		h.fail(ctx, c, err, "remove project member", id)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// fail 将成员相关错误映射为响应
+func (h *MemberHandler) fail(ctx context.Context, c *app.RequestContext, err error, action string, projectID uint64) {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		response.Fail(c, errcode.ErrUserNotFound)
+	case errors.Is(err, service.ErrMemberNotFound):
+		response.Fail(c, errcode.ErrNotFound.WithMessage("member not found"))
+	case errors.Is(err, service.ErrMemberIsOwner):
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage("the project owner cannot be a member"))
+	case errors.Is(err, service.ErrMemberRoleInvalid):
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage("role must be editor or viewer"))
+	case errors.Is(err, service.ErrMemberLimit):
+		response.Fail(c, errcode.ErrMemberLimit)
+	default:
+		failProjectError(ctx, c, err, action, projectID)
+	}
+}
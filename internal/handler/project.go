@@ -10,21 +10,30 @@ import (
 	"github.com/test-tt/internal/service"
 	"github.com/test-tt/pkg/errcode"
 	"github.com/test-tt/pkg/logger"
+	"github.com/test-tt/pkg/pagination"
 	"github.com/test-tt/pkg/response"
 )
 
 type ProjectHandler struct {
-	projectService *service.ProjectService
+	projectService  *service.ProjectService
+	templateService *service.TemplateService
 }
 
 func NewProjectHandler() *ProjectHandler {
 	return &ProjectHandler{
-		projectService: service.NewProjectService(),
+		projectService:  service.NewProjectService(),
+		templateService: service.NewTemplateService(),
 	}
 }
 
 // CreateProjectRequest create project request
 type CreateProjectRequest struct {
+	Name       string `json:"name"`
+	TemplateID string `json:"template_id"`
+}
+
+// ForkProjectRequest fork project request
+type ForkProjectRequest struct {
 	Name string `json:"name"`
 }
 
@@ -38,24 +47,62 @@ type UpdateProjectRequest struct {
 
 // List godoc
 // @Summary      List user projects
-// @Description  Get all projects for the authenticated user
+// @Description  Get a page of project summaries for the authenticated user. Full content is only returned by GET /projects/{id}.
 // @Tags         Projects
 // @Security     BearerAuth
 // @Produce      json
-// @Success      200  {object}  response.Response{data=[]model.Project}
-// @Failure      401  {object}  response.Response
+// @Param        q          query     string  false  "Keyword in project name"
+// @Param        folder_id  query     string  false  "Folder ID, or root for projects outside any folder"
+// @Param        tag        query     string  false  "Tag"
+// @Param        sort    query     string  false  "Sort field: updated_at (default), created_at, name"
+// @Param        order   query     string  false  "Sort order: desc (default), asc"
+// @Param        cursor  query     string  false  "next_cursor from the previous page"
+// @Param        limit   query     int     false  "Page size (default 10, max 100)"
+// @Success      200     {object}  response.Response{data=pagination.CursorResult{list=[]model.ProjectSummary}}
+// @Failure      400     {object}  response.Response
+// @Failure      401     {object}  response.Response
 // @Router       /projects [get]
 func (h *ProjectHandler) List(ctx context.Context, c *app.RequestContext) {
 	userID := middleware.GetUserIDFromContext(c)
+	page := pagination.GetCursorFromQuery(c)
 
-	projects, err := h.projectService.GetByUserID(ctx, userID)
+	opts := &service.ProjectListOptions{
+		Keyword: c.Query("q"),
+		Tag:     c.Query("tag"),
+		SortBy:  c.Query("sort"),
+		Order:   c.Query("order"),
+		Cursor:  page.Cursor,
+		Limit:   page.Limit,
+	}
+	// folder_id=root（或 0）只列出不在任何文件夹中的项目
+	if folder := c.Query("folder_id"); folder != "" {
+		var folderID uint64
+		if folder != "root" {
+			if _, err := parseUint64(folder, &folderID); err != nil {
+				response.Fail(c, errcode.ErrInvalidParams.WithMessage("invalid folder_id"))
+				return
+			}
+		}
+		opts.FolderID = &folderID
+	}
+
+	summaries, nextCursor, err := h.projectService.List(ctx, userID, opts)
 	if err != nil {
-		logger.ErrorCtxf(ctx, "failed to list projects", "error", err, "userID", userID)
-		response.Fail(c, errcode.ErrDatabase)
+		switch {
+		case errors.Is(err, service.ErrTagInvalid):
+			response.Fail(c, errcode.ErrTagInvalid)
+		case errors.Is(err, service.ErrProjectSortInvalid):
+			response.Fail(c, errcode.ErrInvalidParams.WithMessage("sort must be updated_at, created_at or name and order must be asc or desc"))
+		case errors.Is(err, pagination.ErrInvalidCursor):
+			response.Fail(c, errcode.ErrInvalidParams.WithMessage("invalid cursor"))
+		default:
+			logger.ErrorCtxf(ctx, "failed to list projects", "error", err, "userID", userID)
+			response.Fail(c, errcode.ErrDatabase)
+		}
 		return
 	}
 
-	response.Success(c, projects)
+	response.Success(c, pagination.NewCursorResult(summaries, nextCursor))
 }
 
 // Get godoc
@@ -98,7 +145,7 @@ func (h *ProjectHandler) Get(ctx context.Context, c *app.RequestContext) {
 
 // Create godoc
 // @Summary      Create project
-// @Description  Create a new project
+// @Description  Create a new project, optionally starting from a template. Template content is sanitized like project saves and the response then includes warnings and policy_version.
 // @Tags         Projects
 // @Security     BearerAuth
 // @Accept       json
@@ -113,11 +160,19 @@ func (h *ProjectHandler) Create(ctx context.Context, c *app.RequestContext) {
 	var req CreateProjectRequest
 	if err := c.BindJSON(&req); err != nil {
 		// If no body, use default name
-		req.Name = ""
+		req = CreateProjectRequest{}
+	}
+
+	if req.TemplateID != "" {
+		h.createFromTemplate(ctx, c, userID, &req)
+		return
 	}
 
 	project, err := h.projectService.Create(ctx, userID, req.Name)
 	if err != nil {
+		if failQuotaError(c, err) {
+			return
+		}
 		logger.ErrorCtxf(ctx, "failed to create project", "error", err, "userID", userID)
 		response.Fail(c, errcode.ErrDatabase)
 		return
@@ -126,16 +181,78 @@ func (h *ProjectHandler) Create(ctx context.Context, c *app.RequestContext) {
 	response.Success(c, project)
 }
 
+// createFromTemplate creates a project from the catalog template named in the request
+func (h *ProjectHandler) createFromTemplate(ctx context.Context, c *app.RequestContext, userID uint64, req *CreateProjectRequest) {
+	template, err := h.templateService.Get(ctx, req.TemplateID)
+	if err != nil {
+		if errors.Is(err, service.ErrTemplateNotFound) {
+			response.Fail(c, errcode.ErrTemplateNotFound)
+			return
+		}
+		logger.ErrorCtxf(ctx, "failed to get template", "error", err, "templateID", req.TemplateID)
+		response.Fail(c, errcode.ErrDatabase)
+		return
+	}
+
+	project, err := h.projectService.CreateFromTemplate(ctx, userID, req.Name, template)
+	if err != nil {
+		if failQuotaError(c, err) {
+			return
+		}
+		logger.ErrorCtxf(ctx, "failed to create project from template", "error", err, "userID", userID)
+		response.Fail(c, errcode.ErrDatabase)
+		return
+	}
+
+	response.Success(c, project)
+}
+
+// Fork godoc
+// @Summary      Fork project
+// @Description  Copy a project into the caller's account. The owner and members copy the current content, chat history and files; other users' projects must be published and the published snapshot is copied.
+// @Tags         Projects
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                 true   "Source project ID"
+// @Param        request  body      ForkProjectRequest  false  "Fork name"
+// @Success      200      {object}  response.Response{data=model.Project}
+// @Failure      401      {object}  response.Response
+// @Failure      404      {object}  response.Response
+// @Router       /projects/{id}/fork [post]
+func (h *ProjectHandler) Fork(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	var req ForkProjectRequest
+	if err := c.BindJSON(&req); err != nil {
+		req.Name = ""
+	}
+
+	project, err := h.projectService.Fork(ctx, id, userID, req.Name)
+	if err != nil {
+		failProjectError(ctx, c, err, "fork project", id)
+		return
+	}
+
+	response.Success(c, project)
+}
+
 // Update godoc
 // @Summary      Update project
-// @Description  Update an existing project
+// @Description  Update an existing project. HTML and CSS are sanitized; <style> blocks move into css and changes are reported as warnings.
 // @Tags         Projects
 // @Security     BearerAuth
 // @Accept       json
 // @Produce      json
 // @Param        id       path      int                   true  "Project ID"
 // @Param        request  body      UpdateProjectRequest  true  "Project data"
-// @Success      200      {object}  response.Response{data=model.Project}
+// @Success      200      {object}  response.Response{data=service.SavedProject}
 // @Failure      401      {object}  response.Response
 // @Failure      404      {object}  response.Response
 // @Router       /projects/{id} [put]
@@ -157,15 +274,7 @@ func (h *ProjectHandler) Update(ctx context.Context, c *app.RequestContext) {
 
 	project, err := h.projectService.Update(ctx, id, userID, req.Name, req.HTML, req.CSS, req.Messages)
 	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrProjectNotFound):
-			response.Fail(c, errcode.ErrNotFound.WithMessage("project not found"))
-		case errors.Is(err, service.ErrProjectNotOwned):
-			response.Fail(c, errcode.ErrForbidden.WithMessage("project does not belong to you"))
-		default:
-			logger.ErrorCtxf(ctx, "failed to update project", "error", err, "projectID", id)
-			response.Fail(c, errcode.ErrDatabase)
-		}
+		failProjectError(ctx, c, err, "update project", id)
 		return
 	}
 
@@ -174,7 +283,7 @@ func (h *ProjectHandler) Update(ctx context.Context, c *app.RequestContext) {
 
 // Delete godoc
 // @Summary      Delete project
-// @Description  Delete a project
+// @Description  Move a project to the trash. Trashed projects are permanently deleted after the retention period.
 // @Tags         Projects
 // @Security     BearerAuth
 // @Param        id   path      int  true  "Project ID"
@@ -209,6 +318,84 @@ func (h *ProjectHandler) Delete(ctx context.Context, c *app.RequestContext) {
 	response.Success(c, nil)
 }
 
+// ListTrash godoc
+// @Summary      List trash
+// @Description  Get the trashed projects of the authenticated user, most recently deleted first
+// @Tags         Projects
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {object}  response.Response{data=[]model.Project}
+// @Failure      401  {object}  response.Response
+// @Router       /projects/trash [get]
+func (h *ProjectHandler) ListTrash(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	projects, err := h.projectService.ListTrash(ctx, userID)
+	if err != nil {
+		logger.ErrorCtxf(ctx, "failed to list trash", "error", err, "userID", userID)
+		response.Fail(c, errcode.ErrDatabase)
+		return
+	}
+
+	response.Success(c, projects)
+}
+
+// Restore godoc
+// @Summary      Restore project
+// @Description  Move a trashed project back to the project list
+// @Tags         Projects
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "Project ID"
+// @Success      200  {object}  response.Response{data=model.Project}
+// @Failure      401  {object}  response.Response
+// @Failure      404  {object}  response.Response
+// @Router       /projects/{id}/restore [post]
+func (h *ProjectHandler) Restore(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	project, err := h.projectService.Restore(ctx, id, userID)
+	if err != nil {
+		failProjectError(ctx, c, err, "restore project", id)
+		return
+	}
+
+	response.Success(c, project)
+}
+
+// DeleteForever godoc
+// @Summary      Delete project forever
+// @Description  Permanently delete a trashed project and its snapshots, publication and assets
+// @Tags         Projects
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Project ID"
+// @Success      200  {object}  response.Response
+// @Failure      401  {object}  response.Response
+// @Failure      404  {object}  response.Response
+// @Router       /projects/trash/{id} [delete]
+func (h *ProjectHandler) DeleteForever(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	if err := h.projectService.DeleteForever(ctx, id, userID); err != nil {
+		failProjectError(ctx, c, err, "delete project forever", id)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
 // Helper function to parse uint64
 func parseUint64(s string, result *uint64) (bool, error) {
 	var id uint64
@@ -221,3 +408,40 @@ func parseUint64(s string, result *uint64) (bool, error) {
 	*result = id
 	return true, nil
 }
+
+// failProjectError maps project ownership errors to responses, anything else is logged as a database error
+func failProjectError(ctx context.Context, c *app.RequestContext, err error, action string, projectID uint64) {
+	if failQuotaError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, service.ErrProjectNotFound):
+		response.Fail(c, errcode.ErrNotFound.WithMessage("project not found"))
+	case errors.Is(err, service.ErrProjectNotOwned):
+		response.Fail(c, errcode.ErrForbidden.WithMessage("project does not belong to you"))
+	case errors.Is(err, service.ErrProjectReadOnly):
+		response.Fail(c, errcode.ErrForbidden.WithMessage("viewers cannot edit this project"))
+	case errors.Is(err, service.ErrCollabBusy):
+		response.Fail(c, errcode.ErrTooManyRequests.WithMessage(service.ErrCollabBusy.Error()))
+	default:
+		logger.ErrorCtxf(ctx, "failed to "+action, "error", err, "projectID", projectID)
+		response.Fail(c, errcode.ErrDatabase)
+	}
+}
+
+// failQuotaError responds to plan limit errors, returns false if err is not one
+func failQuotaError(c *app.RequestContext, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrProjectLimitExceeded):
+		response.Fail(c, errcode.ErrProjectLimitExceeded)
+	case errors.Is(err, service.ErrProjectTooLarge):
+		response.Fail(c, errcode.ErrProjectTooLarge)
+	case errors.Is(err, service.ErrStorageLimitExceeded):
+		response.Fail(c, errcode.ErrStorageLimitExceeded)
+	case errors.Is(err, service.ErrAssetQuotaExceeded):
+		response.Fail(c, errcode.ErrAssetQuotaExceeded)
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/test-tt/internal/middleware"
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/internal/service"
+	"github.com/test-tt/pkg/errcode"
+	"github.com/test-tt/pkg/logger"
+	"github.com/test-tt/pkg/response"
+	"github.com/test-tt/pkg/validate"
+)
+
+const (
+	defaultPromptStatsDays = 30
+	maxPromptStatsDays     = 365
+)
+
+type PromptHandler struct {
+	promptService *service.PromptService
+}
+
+func NewPromptHandler() *PromptHandler {
+	return &PromptHandler{
+		promptService: service.NewPromptService(),
+	}
+}
+
+// CreatePromptRequest new prompt template version request
+type CreatePromptRequest struct {
+	Name        string            `json:"name" validate:"required,oneof=create modify fragment"`
+	Body        string            `json:"body" validate:"required"`        // text/template source, may use {{.prompt}}, {{.today}} and its own variables
+	Variables   map[string]string `json:"variables"`                       // Default values of the template's own variables
+	Weight      int               `json:"weight" validate:"min=0,max=100"` // Share of traffic, 0 saves the version without enabling it
+	Description string            `json:"description" validate:"max=255"`
+}
+
+// PromptWeightRequest traffic weight request
+type PromptWeightRequest struct {
+	Weight int `json:"weight" validate:"min=0,max=100"`
+}
+
+// RenderPromptRequest preview request
+type RenderPromptRequest struct {
+	Variables map[string]string `json:"variables"` // Values for {{.prompt}}, {{.today}} and overrides of the defaults
+}
+
+// List godoc
+// @Summary      List prompt templates (admin)
+// @Description  List the versions of the generation system prompts, newest first
+// @Tags         Admin
+// @Security     BearerAuth
+// @Produce      json
+// @Param        name  query     string  false  "create, modify or fragment, all when empty"
+// @Success      200   {object}  response.Response{data=[]model.PromptTemplate}
+// @Failure      400   {object}  response.Response
+// @Failure      403   {object}  response.Response
+// @Router       /admin/prompts [get]
+func (h *PromptHandler) List(ctx context.Context, c *app.RequestContext) {
+	prompts, err := h.promptService.List(ctx, c.Query("name"))
+	if err != nil {
+		h.fail(ctx, c, err, "list prompt templates")
+		return
+	}
+
+	response.Success(c, prompts)
+}
+
+// Create godoc
+// @Summary      Create prompt template version (admin)
+// @Description  Save a system prompt as the next version of its name. Versions cannot be edited afterwards; enabled versions of a name split users between them by weight, and without any the built-in prompt (version 0) is used.
+// @Tags         Admin
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      CreatePromptRequest  true  "Template"
+// @Success      200      {object}  response.Response{data=model.PromptTemplate}
+// @Failure      400      {object}  response.Response
+// @Failure      403      {object}  response.Response
+// @Router       /admin/prompts [post]
+func (h *PromptHandler) Create(ctx context.Context, c *app.RequestContext) {
+	var req CreatePromptRequest
+	if err := c.BindJSON(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(validate.FirstError(err)))
+		return
+	}
+
+	prompt := &model.PromptTemplate{
+		Name:        req.Name,
+		Body:        req.Body,
+		Variables:   req.Variables,
+		Weight:      req.Weight,
+		Description: req.Description,
+	}
+	if err := h.promptService.Create(ctx, middleware.GetUserIDFromContext(c), prompt); err != nil {
+		h.fail(ctx, c, err, "create prompt template")
+		return
+	}
+
+	response.Success(c, prompt)
+}
+
+// Get godoc
+// @Summary      Get prompt template version (admin)
+// @Tags         Admin
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "Template version ID"
+// @Success      200  {object}  response.Response{data=model.PromptTemplate}
+// @Failure      403  {object}  response.Response
+// @Failure      404  {object}  response.Response
+// @Router       /admin/prompts/{id} [get]
+func (h *PromptHandler) Get(ctx context.Context, c *app.RequestContext) {
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	prompt, err := h.promptService.Get(ctx, id)
+	if err != nil {
+		h.fail(ctx, c, err, "get prompt template")
+		return
+	}
+
+	response.Success(c, prompt)
+}
+
+// SetWeight godoc
+// @Summary      Set prompt template weight (admin)
+// @Description  Change the share of users a version is assigned to. Users keep their version as long as the enabled versions and weights stay the same.
+// @Tags         Admin
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                  true  "Template version ID"
+// @Param        request  body      PromptWeightRequest  true  "Weight"
+// @Success      200      {object}  response.Response{data=model.PromptTemplate}
+// @Failure      400      {object}  response.Response
+// @Failure      403      {object}  response.Response
+// @Failure      404      {object}  response.Response
+// @Router       /admin/prompts/{id}/weight [put]
+func (h *PromptHandler) SetWeight(ctx context.Context, c *app.RequestContext) {
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	var req PromptWeightRequest
+	if err := c.BindJSON(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(validate.FirstError(err)))
+		return
+	}
+
+	prompt, err := h.promptService.SetWeight(ctx, middleware.GetUserIDFromContext(c), id, req.Weight)
+	if err != nil {
+		h.fail(ctx, c, err, "set prompt template weight")
+		return
+	}
+
+	response.Success(c, prompt)
+}
+
+// Render godoc
+// @Summary      Preview prompt template (admin)
+// @Description  Render a version with the given variables over its defaults
+// @Tags         Admin
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                  true  "Template version ID"
+// @Param        request  body      RenderPromptRequest  true  "Variables"
+// @Success      200      {object}  response.Response{data=string}
+// @Failure      400      {object}  response.Response
+// @Failure      403      {object}  response.Response
+// @Failure      404      {object}  response.Response
+// @Router       /admin/prompts/{id}/render [post]
+func (h *PromptHandler) Render(ctx context.Context, c *app.RequestContext) {
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	var req RenderPromptRequest
+	if err := c.BindJSON(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	text, err := h.promptService.Render(ctx, id, req.Variables)
+	if err != nil {
+		h.fail(ctx, c, err, "render prompt template")
+		return
+	}
+
+	response.Success(c, text)
+}
+
+// Stats godoc
+// @Summary      Compare prompt template versions (admin)
+// @Description  Count the generations of each version of a prompt with their outcome, output tokens, time and credits
+// @Tags         Admin
+// @Security     BearerAuth
+// @Produce      json
+// @Param        name  query     string  true   "create, modify or fragment"
+// @Param        days  query     int     false  "Days to look back, default 30"
+// @Success      200   {object}  response.Response{data=[]model.PromptVariantStats}
+// @Failure      400   {object}  response.Response
+// @Failure      403   {object}  response.Response
+// @Router       /admin/prompts/stats [get]
+func (h *PromptHandler) Stats(ctx context.Context, c *app.RequestContext) {
+	days := defaultPromptStatsDays
+	if s := c.Query("days"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxPromptStatsDays {
+			response.Fail(c, errcode.ErrInvalidParams.WithMessage("days must be between 1 and 365"))
+			return
+		}
+		days = n
+	}
+
+	stats, err := h.promptService.Stats(ctx, c.Query("name"), days)
+	if err != nil {
+		h.fail(ctx, c, err, "get prompt template stats")
+		return
+	}
+
+	response.Success(c, stats)
+}
+
+func (h *PromptHandler) fail(ctx context.Context, c *app.RequestContext, err error, action string) {
+	switch {
+	case errors.Is(err, service.ErrPromptNotFound):
+		response.Fail(c, errcode.ErrNotFound.WithMessage(err.Error()))
+	case errors.Is(err, service.ErrPromptNameInvalid),
+		errors.Is(err, service.ErrPromptTemplateInvalid),
+		errors.Is(err, service.ErrPromptVariableInvalid):
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(err.Error()))
+	default:
+		logger.ErrorCtxf(ctx, "failed to "+action, "error", err)
+		response.Fail(c, errcode.ErrDatabase)
+	}
+}
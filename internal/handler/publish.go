@@ -0,0 +1,299 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/test-tt/internal/middleware"
+	"github.com/test-tt/internal/service"
+	"github.com/test-tt/pkg/errcode"
+	"github.com/test-tt/pkg/logger"
+	"github.com/test-tt/pkg/response"
+	"github.com/test-tt/pkg/validate"
+)
+
+type PublishHandler struct {
+	publishService   *service.PublishService
+	analyticsService *service.AnalyticsService
+}
+
+func NewPublishHandler() *PublishHandler {
+	return &PublishHandler{
+		publishService:   service.NewPublishService(),
+		analyticsService: service.NewAnalyticsService(),
+	}
+}
+
+// CreateSnapshotRequest create snapshot request
+type CreateSnapshotRequest struct {
+	Label string `json:"label" validate:"max=255"`
+}
+
+// PublishRequest publish request
+type PublishRequest struct {
+	SnapshotID uint64 `json:"snapshot_id"`
+	Slug       string `json:"slug"`
+}
+
+// CreateSnapshot godoc
+// @Summary      Create snapshot
+// @Description  Capture the current HTML, CSS and other files of a project. Owner and editors only.
+// @Tags         Publishing
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                    true   "Project ID"
+// @Param        request  body      CreateSnapshotRequest  false  "Snapshot label"
+// @Success      200      {object}  response.Response{data=model.ProjectSnapshot}
+// @Failure      401      {object}  response.Response
+// @Failure      404      {object}  response.Response
+// @Router       /projects/{id}/snapshots [post]
+func (h *PublishHandler) CreateSnapshot(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	var req CreateSnapshotRequest
+	if err := c.BindJSON(&req); err != nil {
+		// 无请求体时使用默认标签
+		req.Label = ""
+	}
+	if err := validate.Struct(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(validate.FirstError(err)))
+		return
+	}
+
+	snapshot, err := h.publishService.CreateSnapshot(ctx, id, userID, req.Label)
+	if err != nil {
+		failProjectError(ctx, c, err, "create snapshot", id)
+		return
+	}
+
+	response.Success(c, snapshot)
+}
+
+// ListSnapshots godoc
+// @Summary      List snapshots
+// @Description  List snapshots of a project (without content). Available to the owner and members.
+// @Tags         Publishing
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "Project ID"
+// @Success      200  {object}  response.Response{data=[]model.ProjectSnapshotSummary}
+// @Failure      401  {object}  response.Response
+// @Failure      404  {object}  response.Response
+// @Router       /projects/{id}/snapshots [get]
+func (h *PublishHandler) ListSnapshots(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	snapshots, err := h.publishService.ListSnapshots(ctx, id, userID)
+	if err != nil {
+		failProjectError(ctx, c, err, "list snapshots", id)
+		return
+	}
+
+	response.Success(c, snapshots)
+}
+
+// GetPublication godoc
+// @Summary      Get publication
+// @Description  Get the public slug and pinned snapshot of a project
+// @Tags         Publishing
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "Project ID"
+// @Success      200  {object}  response.Response{data=service.PublishInfo}
+// @Failure      401  {object}  response.Response
+// @Failure      404  {object}  response.Response
+// @Router       /projects/{id}/publish [get]
+func (h *PublishHandler) GetPublication(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	info, err := h.publishService.GetPublication(ctx, id, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrNotPublished) {
+			response.Fail(c, errcode.ErrProjectNotPublished)
+			return
+		}
+		failProjectError(ctx, c, err, "get publication", id)
+		return
+	}
+
+	response.Success(c, info)
+}
+
+// Publish godoc
+// @Summary      Publish project
+// @Description  Pin a snapshot of the project to a public URL (/p/{slug}). Without snapshot_id the current content is snapshotted. Other files of the snapshot are served under /p/{slug}/, e.g. /p/{slug}/about/index.html.
+// @Tags         Publishing
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int             true   "Project ID"
+// @Param        request  body      PublishRequest  false  "Snapshot and slug"
+// @Success      200      {object}  response.Response{data=service.PublishInfo}
+// @Failure      400      {object}  response.Response
+// @Failure      401      {object}  response.Response
+// @Failure      404      {object}  response.Response
+// @Failure      409      {object}  response.Response
+// @Router       /projects/{id}/publish [post]
+func (h *PublishHandler) Publish(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	var req PublishRequest
+	if err := c.BindJSON(&req); err != nil {
+		// 无请求体时发布当前内容
+		req = PublishRequest{}
+	}
+
+	info, err := h.publishService.Publish(ctx, id, userID, req.SnapshotID, req.Slug)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSnapshotNotFound):
+			response.Fail(c, errcode.ErrNotFound.WithMessage("snapshot not found"))
+		case errors.Is(err, service.ErrSlugInvalid):
+			response.Fail(c, errcode.ErrSlugInvalid)
+		case errors.Is(err, service.ErrSlugTaken):
+			response.Fail(c, errcode.ErrSlugTaken)
+		default:
+			failProjectError(ctx, c, err, "publish project", id)
+		}
+		return
+	}
+
+	response.Success(c, info)
+}
+
+// Unpublish godoc
+// @Summary      Unpublish project
+// @Description  Take a published project offline and release its slug
+// @Tags         Publishing
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Project ID"
+// @Success      200  {object}  response.Response
+// @Failure      401  {object}  response.Response
+// @Failure      404  {object}  response.Response
+// @Router       /projects/{id}/publish [delete]
+func (h *PublishHandler) Unpublish(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	if err := h.publishService.Unpublish(ctx, id, userID); err != nil {
+		if errors.Is(err, service.ErrNotPublished) {
+			response.Fail(c, errcode.ErrProjectNotPublished)
+			return
+		}
+		failProjectError(ctx, c, err, "unpublish project", id)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// ServePage 渲染已发布页面及其文件（公开访问，CSP 由 PublishedPageHeaders 设置）
+func (h *PublishHandler) ServePage(ctx context.Context, c *app.RequestContext) {
+	slug := c.Param("slug")
+	if !service.ValidSlug(slug) {
+		c.String(http.StatusNotFound, "Page not found")
+		return
+	}
+
+	filePath := c.Param("path")
+	page, err := h.publishService.RenderPublished(ctx, slug, filePath)
+	if err != nil {
+		if errors.Is(err, service.ErrNotPublished) {
+			c.String(http.StatusNotFound, "Page not found")
+			return
+		}
+		logger.ErrorCtxf(ctx, "failed to render published page", "error", err, "slug", slug)
+		c.String(http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// 多文件站点的首页改用 /p/:slug/ 访问，页面中的相对链接才会指向该站点下的文件
+	if page.HasFiles && filePath == "" && !strings.HasSuffix(string(c.URI().Path()), "/") {
+		c.Redirect(http.StatusFound, []byte(service.PublishedPath(slug)+"/"))
+		return
+	}
+
+	// 浏览器预取和页面引用的样式、脚本不计入访问
+	if page.IsHTML() && string(c.GetHeader("Purpose")) != "prefetch" && string(c.GetHeader("Sec-Purpose")) != "prefetch" {
+		h.analyticsService.Track(&service.PageView{
+			ProjectID:  page.ProjectID,
+			IP:         c.ClientIP(),
+			UserAgent:  string(c.UserAgent()),
+			Referer:    string(c.GetHeader("Referer")),
+			Host:       string(c.Host()),
+			DoNotTrack: string(c.GetHeader("DNT")) == "1" || string(c.GetHeader("Sec-GPC")) == "1",
+		})
+	}
+
+	c.Data(http.StatusOK, page.ContentType, []byte(page.Content))
+}
+
+// GetAnalytics godoc
+// @Summary      Get page analytics
+// @Description  Get views, unique visitors, referrers and device classes of a published project between two UTC dates (inclusive, default last 30 days). Visitors are counted per day without storing IPs, so the range total counts a returning visitor once per day. Today's numbers lag by the rollup interval.
+// @Tags         Publishing
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id    path      int     true   "Project ID"
+// @Param        from  query     string  false  "Start date, YYYY-MM-DD"
+// @Param        to    query     string  false  "End date, YYYY-MM-DD"
+// @Success      200   {object}  response.Response{data=service.AnalyticsReport}
+// @Failure      400   {object}  response.Response
+// @Failure      401   {object}  response.Response
+// @Failure      404   {object}  response.Response
+// @Router       /projects/{id}/analytics [get]
+func (h *PublishHandler) GetAnalytics(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	report, err := h.analyticsService.Report(ctx, id, userID, c.Query("from"), c.Query("to"))
+	if err != nil {
+		if errors.Is(err, service.ErrAnalyticsRangeInvalid) {
+			response.Fail(c, errcode.ErrInvalidParams.WithMessage("from and to must be YYYY-MM-DD dates in order and within the maximum range"))
+			return
+		}
+		failProjectError(ctx, c, err, "get page analytics", id)
+		return
+	}
+
+	response.Success(c, report)
+}
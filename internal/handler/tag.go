@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/test-tt/internal/middleware"
+	"github.com/test-tt/internal/service"
+	"github.com/test-tt/pkg/errcode"
+	"github.com/test-tt/pkg/logger"
+	"github.com/test-tt/pkg/response"
+	"github.com/test-tt/pkg/validate"
+)
+
+type TagHandler struct {
+	tagService *service.TagService
+}
+
+func NewTagHandler() *TagHandler {
+	return &TagHandler{
+		tagService: service.NewTagService(),
+	}
+}
+
+// SetTagsRequest replace project tags request
+type SetTagsRequest struct {
+	Tags []string `json:"tags" validate:"max=20"`
+}
+
+// BulkTagRequest bulk tag request
+type BulkTagRequest struct {
+	ProjectIDs []uint64 `json:"project_ids" validate:"required,min=1,max=100"`
+	Add        []string `json:"add" validate:"max=20"`
+	Remove     []string `json:"remove" validate:"max=20"`
+}
+
+// List godoc
+// @Summary      List tags
+// @Description  Get the tags used by the authenticated user's projects with usage counts
+// @Tags         Tags
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {object}  response.Response{data=[]model.TagCount}
+// @Failure      401  {object}  response.Response
+// @Router       /tags [get]
+func (h *TagHandler) List(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	tags, err := h.tagService.List(ctx, userID)
+	if err != nil {
+		logger.ErrorCtxf(ctx, "failed to list tags", "error", err, "userID", userID)
+		response.Fail(c, errcode.ErrDatabase)
+		return
+	}
+
+	response.Success(c, tags)
+}
+
+// SetProjectTags godoc
+// @Summary      Set project tags
+// @Description  Replace the tags of a project. Tags are case-insensitive and de-duplicated.
+// @Tags         Tags
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int             true  "Project ID"
+// @Param        request  body      SetTagsRequest  true  "Tags"
+// @Success      200      {object}  response.Response{data=[]string}
+// @Failure      400      {object}  response.Response
+// @Failure      401      {object}  response.Response
+// @Failure      404      {object}  response.Response
+// @Router       /projects/{id}/tags [put]
+func (h *TagHandler) SetProjectTags(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var id uint64
+	if _, err := parseUint64(c.Param("id"), &id); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+
+	var req SetTagsRequest
+	if err := c.BindJSON(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(validate.FirstError(err)))
+		return
+	}
+
+	tags, err := h.tagService.SetProjectTags(ctx, id, userID, req.Tags)
+	if err != nil {
+		h.fail(ctx, c, err, "set project tags", id)
+		return
+	}
+
+	response.Success(c, tags)
+}
+
+// BulkTag godoc
+// @Summary      Bulk tag projects
+// @Description  Add and remove tags on several projects at once
+// @Tags         Tags
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      BulkTagRequest  true  "Projects and tag changes"
+// @Success      200      {object}  response.Response
+// @Failure      400      {object}  response.Response
+// @Failure      401      {object}  response.Response
+// @Failure      404      {object}  response.Response
+// @Router       /projects/bulk/tags [post]
+func (h *TagHandler) BulkTag(ctx context.Context, c *app.RequestContext) {
+	userID := middleware.GetUserIDFromContext(c)
+
+	var req BulkTagRequest
+	if err := c.BindJSON(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(validate.FirstError(err)))
+		return
+	}
+
+	if err := h.tagService.BulkTag(ctx, userID, req.ProjectIDs, req.Add, req.Remove); err != nil {
+		h.fail(ctx, c, err, "bulk tag projects", 0)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// fail 将标签相关错误映射为响应
+func (h *TagHandler) fail(ctx context.Context, c *app.RequestContext, err error, action string, projectID uint64) {
+	switch {
+	case errors.Is(err, service.ErrTagInvalid):
+		response.Fail(c, errcode.ErrTagInvalid)
+	case errors.Is(err, service.ErrTooManyTags):
+		response.Fail(c, errcode.ErrTooManyTags)
+	default:
+		failProjectError(ctx, c, err, action, projectID)
+	}
+}
@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/test-tt/internal/middleware"
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/internal/service"
+	"github.com/test-tt/pkg/errcode"
+	"github.com/test-tt/pkg/logger"
+	"github.com/test-tt/pkg/response"
+	"github.com/test-tt/pkg/validate"
+)
+
+type TemplateHandler struct {
+	templateService *service.TemplateService
+}
+
+func NewTemplateHandler() *TemplateHandler {
+	return &TemplateHandler{
+		templateService: service.NewTemplateService(),
+	}
+}
+
+// TemplateRequest curated template request
+type TemplateRequest struct {
+	ID          string `json:"id" validate:"required,max=64"`
+	Name        string `json:"name" validate:"required,max=255"`
+	Description string `json:"description" validate:"max=1024"`
+	Category    string `json:"category" validate:"max=64"`
+	HTML        string `json:"html" validate:"required"`
+	CSS         string `json:"css"`
+	Sort        int    `json:"sort"`
+	Enabled     *bool  `json:"enabled"`
+}
+
+func (r *TemplateRequest) toModel() *model.ProjectTemplate {
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+	return &model.ProjectTemplate{
+		Key:         r.ID,
+		Name:        r.Name,
+		Description: r.Description,
+		Category:    r.Category,
+		HTML:        r.HTML,
+		CSS:         r.CSS,
+		Sort:        r.Sort,
+		Enabled:     enabled,
+	}
+}
+
+// List godoc
+// @Summary      List templates
+// @Description  Template gallery: builtin templates and admin-curated entries
+// @Tags         Templates
+// @Produce      json
+// @Param        category  query     string  false  "Filter by category"
+// @Success      200       {object}  response.Response{data=[]model.TemplateSummary}
+// @Router       /templates [get]
+func (h *TemplateHandler) List(ctx context.Context, c *app.RequestContext) {
+	templates, err := h.templateService.List(ctx, c.Query("category"))
+	if err != nil {
+		logger.ErrorCtxf(ctx, "failed to list templates", "error", err)
+		response.Fail(c, errcode.ErrDatabase)
+		return
+	}
+
+	response.Success(c, templates)
+}
+
+// Get godoc
+// @Summary      Get template
+// @Description  Get a template with its HTML and CSS
+// @Tags         Templates
+// @Produce      json
+// @Param        id   path      string  true  "Template ID"
+// @Success      200  {object}  response.Response{data=model.ProjectTemplate}
+// @Failure      404  {object}  response.Response
+// @Router       /templates/{id} [get]
+func (h *TemplateHandler) Get(ctx context.Context, c *app.RequestContext) {
+	template, err := h.templateService.Get(ctx, c.Param("id"))
+	if err != nil {
+		h.fail(ctx, c, err)
+		return
+	}
+
+	response.Success(c, template)
+}
+
+// ListCurated godoc
+// @Summary      List curated templates (admin)
+// @Description  List admin-curated templates including disabled ones
+// @Tags         Admin
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {object}  response.Response{data=[]model.ProjectTemplate}
+// @Failure      403  {object}  response.Response
+// @Router       /admin/templates [get]
+func (h *TemplateHandler) ListCurated(ctx context.Context, c *app.RequestContext) {
+	templates, err := h.templateService.ListCurated(ctx)
+	if err != nil {
+		logger.ErrorCtxf(ctx, "failed to list curated templates", "error", err)
+		response.Fail(c, errcode.ErrDatabase)
+		return
+	}
+
+	response.Success(c, templates)
+}
+
+// Create godoc
+// @Summary      Create curated template (admin)
+// @Tags         Admin
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      TemplateRequest  true  "Template"
+// @Success      200      {object}  response.Response{data=model.ProjectTemplate}
+// @Failure      400      {object}  response.Response
+// @Failure      403      {object}  response.Response
+// @Failure      409      {object}  response.Response
+// @Router       /admin/templates [post]
+func (h *TemplateHandler) Create(ctx context.Context, c *app.RequestContext) {
+	var req TemplateRequest
+	if err := c.BindJSON(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+	if err := validate.Struct(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(validate.FirstError(err)))
+		return
+	}
+
+	template := req.toModel()
+	if err := h.templateService.CreateCurated(ctx, middleware.GetUserIDFromContext(c), template); err != nil {
+		h.fail(ctx, c, err)
+		return
+	}
+
+	response.Success(c, template)
+}
+
+// Update godoc
+// @Summary      Update curated template (admin)
+// @Tags         Admin
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string           true  "Template ID"
+// @Param        request  body      TemplateRequest  true  "Template"
+// @Success      200      {object}  response.Response{data=model.ProjectTemplate}
+// @Failure      400      {object}  response.Response
+// @Failure      403      {object}  response.Response
+// @Failure      404      {object}  response.Response
+// @Router       /admin/templates/{id} [put]
+func (h *TemplateHandler) Update(ctx context.Context, c *app.RequestContext) {
+	var req TemplateRequest
+	if err := c.BindJSON(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams)
+		return
+	}
+	// 路径参数为准，请求体中的 id 可省略
+	req.ID = c.Param("id")
+	if err := validate.Struct(&req); err != nil {
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage(validate.FirstError(err)))
+		return
+	}
+
+	template, err := h.templateService.UpdateCurated(ctx, req.ID, req.toModel())
+	if err != nil {
+		h.fail(ctx, c, err)
+		return
+	}
+
+	response.Success(c, template)
+}
+
+// Delete godoc
+// @Summary      Delete curated template (admin)
+// @Tags         Admin
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Template ID"
+// @Success      200  {object}  response.Response
+// @Failure      403  {object}  response.Response
+// @Failure      404  {object}  response.Response
+// @Router       /admin/templates/{id} [delete]
+func (h *TemplateHandler) Delete(ctx context.Context, c *app.RequestContext) {
+	if err := h.templateService.DeleteCurated(ctx, c.Param("id")); err != nil {
+		h.fail(ctx, c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// fail 将模板相关错误映射为响应
+func (h *TemplateHandler) fail(ctx context.Context, c *app.RequestContext, err error) {
+	switch {
+	case errors.Is(err, service.ErrTemplateNotFound):
+		response.Fail(c, errcode.ErrTemplateNotFound)
+	case errors.Is(err, service.ErrTemplateKeyInvalid):
+		response.Fail(c, errcode.ErrInvalidParams.WithMessage("template id must be 2-64 lowercase letters, digits or hyphens"))
+	case errors.Is(err, service.ErrTemplateKeyExists):
+		response.Fail(c, errcode.ErrTemplateExists)
+	case errors.Is(err, service.ErrTemplateBuiltin):
+		response.Fail(c, errcode.ErrForbidden.WithMessage("builtin templates cannot be modified"))
+	default:
+		logger.ErrorCtxf(ctx, "template operation failed", "error", err)
+		response.Fail(c, errcode.ErrDatabase)
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// AdminOnly 管理员权限中间件
+// 必须在 JWTAuth 之后使用，isAdmin 判断当前用户是否拥有管理权限
+func AdminOnly(isAdmin func(userID uint64) bool) app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		userID := GetUserIDFromContext(c)
+		if userID == 0 || isAdmin == nil || !isAdmin(userID) {
+			c.AbortWithStatusJSON(http.StatusForbidden, map[string]interface{}{
+				"code":    1003,
+				"message": "admin permission required",
+			})
+			return
+		}
+
+		c.Next(ctx)
+	}
+}
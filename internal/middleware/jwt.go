@@ -21,6 +21,12 @@ func JWTAuth(jwtConfig *jwt.Config) app.HandlerFunc {
 	return func(ctx context.Context, c *app.RequestContext) {
 		// 从 Header 获取 token
 		authHeader := string(c.GetHeader("Authorization"))
+		// 浏览器无法为 WebSocket 握手设置请求头，升级请求允许通过 query 参数传递 token
+		if authHeader == "" && isWebSocketUpgrade(c) {
+			if token := c.Query("token"); token != "" {
+				authHeader = "Bearer " + token
+			}
+		}
 		if authHeader == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, map[string]interface{}{
 				"code":    1002,
@@ -60,6 +66,31 @@ func JWTAuth(jwtConfig *jwt.Config) app.HandlerFunc {
 	}
 }
 
+// OptionalJWTAuth 可选的 JWT 认证中间件
+// 携带有效 token 时与 JWTAuth 一样写入用户信息，未携带或无效时按匿名访问继续
+func OptionalJWTAuth(jwtConfig *jwt.Config) app.HandlerFunc {
+	j := jwt.New(jwtConfig)
+
+	return func(ctx context.Context, c *app.RequestContext) {
+		parts := strings.SplitN(string(c.GetHeader("Authorization")), " ", 2)
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			if claims, err := j.ParseToken(parts[1]); err == nil {
+				ctx = context.WithValue(ctx, userIDKey{}, claims.UserID)
+				ctx = context.WithValue(ctx, usernameKey{}, claims.Username)
+				c.Set("user_id", claims.UserID)
+				c.Set("username", claims.Username)
+			}
+		}
+
+		c.Next(ctx)
+	}
+}
+
+// isWebSocketUpgrade 判断是否为 WebSocket 握手请求
+func isWebSocketUpgrade(c *app.RequestContext) bool {
+	return strings.EqualFold(string(c.GetHeader("Upgrade")), "websocket")
+}
+
 // GetUserID 从 context 获取用户 ID
 func GetUserID(ctx context.Context) uint64 {
 	if id, ok := ctx.Value(userIDKey{}).(uint64); ok {
@@ -3,6 +3,7 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -240,3 +241,25 @@ func TestGetRequestIDEmpty(t *testing.T) {
 	assert.DeepEqual(t, http.StatusOK, w.Code)
 	assert.DeepEqual(t, "", w.Body.String())
 }
+
+// TestPublishedPageHeaders 测试已发布页面使用独立的沙箱 CSP
+func TestPublishedPageHeaders(t *testing.T) {
+	r := newTestEngine()
+	r.Use(SecurityHeaders())
+	r.GET("/api/test", func(ctx context.Context, c *app.RequestContext) {
+		c.String(http.StatusOK, "ok")
+	})
+	published := r.Group("/p", PublishedPageHeaders())
+	published.GET("/:slug", func(ctx context.Context, c *app.RequestContext) {
+		c.String(http.StatusOK, "page")
+	})
+
+	w := ut.PerformRequest(r, http.MethodGet, "/p/my-page", nil)
+	csp := w.Header().Get("Content-Security-Policy")
+	assert.True(t, strings.HasPrefix(csp, "sandbox "))
+	assert.False(t, strings.Contains(csp, "allow-same-origin"))
+	assert.DeepEqual(t, "SAMEORIGIN", w.Header().Get("X-Frame-Options"))
+
+	w = ut.PerformRequest(r, http.MethodGet, "/api/test", nil)
+	assert.DeepEqual(t, "default-src 'none'; frame-ancestors 'none'", w.Header().Get("Content-Security-Policy"))
+}
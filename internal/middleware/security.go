@@ -3,6 +3,7 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,12 +11,34 @@ import (
 	"golang.org/x/time/rate"
 )
 
+// PublishedPagePrefix 已发布页面的路径前缀，其安全策略由 PublishedPageHeaders 单独设置
+const PublishedPagePrefix = "/p/"
+
+// AssetPathPrefix 上传资源的路径前缀，其安全策略由 AssetHeaders 单独设置
+const AssetPathPrefix = "/assets/"
+
+// assetCSP 上传资源的 CSP，防止 SVG 中的脚本在本站源下执行
+const assetCSP = "default-src 'none'; style-src 'unsafe-inline'; sandbox"
+
+// publishedPageCSP 已发布页面的沙箱 CSP
+// sandbox 不含 allow-same-origin：页面运行在不透明源中，脚本无法读取应用的 Cookie 和 localStorage
+const publishedPageCSP = "sandbox allow-scripts allow-popups allow-popups-to-escape-sandbox; " +
+	"default-src 'none'; script-src 'unsafe-inline' https:; style-src 'unsafe-inline' https:; " +
+	"img-src data: https:; font-src data: https:; media-src https:; connect-src 'none'; " +
+	"form-action 'none'; base-uri 'none'; frame-ancestors 'self'"
+
 // SecurityHeaders 安全响应头中间件
 // 设置常见的安全相关 HTTP 响应头
 func SecurityHeaders() app.HandlerFunc {
 	return func(ctx context.Context, c *app.RequestContext) {
 		path := string(c.URI().Path())
 
+		// 已发布页面和上传资源是用户内容，不适用应用自身的 CSP
+		if strings.HasPrefix(path, PublishedPagePrefix) || strings.HasPrefix(path, AssetPathPrefix) {
+			c.Next(ctx)
+			return
+		}
+
 		// 静态文件和前端页面使用宽松的安全策略
 		// 自动识别：根路径、.html 结尾、/static 开头、/swagger 开头
 		isStaticOrPage := path == "/" || path == "/favicon.ico" ||
@@ -56,6 +79,42 @@ func SecurityHeaders() app.HandlerFunc {
 	}
 }
 
+// PublishedPageHeaders 已发布页面安全响应头中间件
+// 使用与应用页面隔离的沙箱 CSP，允许内联脚本和样式但禁止访问应用源和发起网络请求
+func PublishedPageHeaders() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		c.Response.Header.Set("Content-Security-Policy", publishedPageCSP)
+		c.Response.Header.Set("X-Content-Type-Options", "nosniff")
+		c.Response.Header.Set("X-Frame-Options", "SAMEORIGIN")
+		c.Response.Header.Set("Referrer-Policy", "no-referrer")
+		c.Response.Header.Set("Permissions-Policy", "geolocation=(), microphone=(), camera=()")
+		c.Response.Header.Set("Cache-Control", "public, max-age=60")
+
+		c.Next(ctx)
+	}
+}
+
+// AssetHeaders 上传资源安全响应头中间件
+// 资源 URL 由内容哈希生成，内容不变，可长期缓存；允许任意来源加载（沙箱页面的字体请求来源为 null）
+func AssetHeaders() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		c.Response.Header.Set("Content-Security-Policy", assetCSP)
+		c.Response.Header.Set("X-Content-Type-Options", "nosniff")
+		c.Response.Header.Set("Cross-Origin-Resource-Policy", "cross-origin")
+		c.Response.Header.Set("Access-Control-Allow-Origin", "*")
+		c.Response.Header.Del("Access-Control-Allow-Credentials")
+
+		c.Next(ctx)
+
+		// 仅成功响应可长期缓存，404 等错误不缓存
+		if c.Response.StatusCode() == http.StatusOK {
+			c.Response.Header.Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			c.Response.Header.Set("Cache-Control", "no-store")
+		}
+	}
+}
+
 // HSTSMiddleware HSTS 中间件（仅用于 HTTPS 生产环境）
 // maxAge: HSTS 有效期（秒），建议 31536000（1年）
 func HSTSMiddleware(maxAge int) app.HandlerFunc {
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// PageViewDaily is the daily view count of a published project, rolled up from Redis
+// 访客数按天去重（每天更换盐值），不保存 IP 或其他可识别信息
+type PageViewDaily struct {
+	ProjectID uint64    `json:"project_id" gorm:"primaryKey"`
+	Date      time.Time `json:"date" gorm:"primaryKey;type:date"`
+	Views     int64     `json:"views" gorm:"not null;default:0"`
+	Visitors  int64     `json:"visitors" gorm:"not null;default:0"`
+	Desktop   int64     `json:"desktop" gorm:"not null;default:0"`
+	Mobile    int64     `json:"mobile" gorm:"not null;default:0"`
+	Tablet    int64     `json:"tablet" gorm:"not null;default:0"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for PageViewDaily model
+func (PageViewDaily) TableName() string {
+	return "page_view_daily"
+}
+
+// PageViewReferrer is the daily view count of a published project from one referring site
+type PageViewReferrer struct {
+	ProjectID uint64    `json:"project_id" gorm:"primaryKey"`
+	Date      time.Time `json:"date" gorm:"primaryKey;type:date"`
+	Referrer  string    `json:"referrer" gorm:"primaryKey;type:varchar(255)"` // 来源站点域名，(direct) 表示直接访问
+	Views     int64     `json:"views" gorm:"not null;default:0"`
+}
+
+// TableName specifies the table name for PageViewReferrer model
+func (PageViewReferrer) TableName() string {
+	return "page_view_referrers"
+}
+
+// ReferrerCount is the number of views from a referring site
+type ReferrerCount struct {
+	Referrer string `json:"referrer"`
+	Views    int64  `json:"views"`
+}
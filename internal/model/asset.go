@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// ProjectAsset is an uploaded file (image, font) that generated pages can reference
+type ProjectAsset struct {
+	ID          uint64    `json:"id" gorm:"primaryKey;autoIncrement"`
+	ProjectID   uint64    `json:"project_id" gorm:"uniqueIndex:idx_asset_project_key,priority:1;not null"`
+	UserID      uint64    `json:"user_id" gorm:"index:idx_asset_user_id;not null"`
+	Filename    string    `json:"filename" gorm:"type:varchar(255);not null"`                                       // Original upload name
+	StorageKey  string    `json:"-" gorm:"type:varchar(255);uniqueIndex:idx_asset_project_key,priority:2;not null"` // Content-addressed key
+	ContentType string    `json:"content_type" gorm:"type:varchar(100);not null"`
+	Size        int64     `json:"size" gorm:"not null"`
+	SHA256      string    `json:"sha256" gorm:"type:char(64);not null"`
+	URL         string    `json:"url" gorm:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for ProjectAsset model
+func (ProjectAsset) TableName() string {
+	return "project_assets"
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// ContentBlob is a piece of html or css stored once by its SHA-256 and shared by projects, snapshots and forks
+type ContentBlob struct {
+	Hash      string    `json:"hash" gorm:"primaryKey;type:char(64)"`
+	Size      int64     `json:"size" gorm:"not null"`                                    // Bytes before encoding
+	Encoding  string    `json:"encoding" gorm:"type:varchar(16);not null;default:'raw'"` // raw or gzip
+	Data      []byte    `json:"-" gorm:"type:longblob"`
+	RefCount  int64     `json:"ref_count" gorm:"not null;default:0"` // Project and snapshot columns pointing at the blob
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for ContentBlob model
+func (ContentBlob) TableName() string {
+	return "content_blobs"
+}
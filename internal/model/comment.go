@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// ProjectComment is review feedback anchored to an element of a project revision
+// 根评论构成讨论串，回复的 ParentID 指向根评论（只有一层），锚点和快照沿用根评论
+type ProjectComment struct {
+	ID         uint64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	ProjectID  uint64     `json:"project_id" gorm:"index:idx_comment_project_id;not null"`
+	ParentID   *uint64    `json:"parent_id" gorm:"index:idx_comment_parent_id"`
+	UserID     uint64     `json:"user_id" gorm:"not null"`
+	SnapshotID uint64     `json:"snapshot_id" gorm:"not null"`
+	Anchor     string     `json:"anchor" gorm:"type:varchar(512);not null;default:''"` // CSS 选择器或元素路径
+	Body       string     `json:"body" gorm:"type:text;not null"`
+	Resolved   bool       `json:"resolved" gorm:"not null;default:false"`
+	ResolvedBy *uint64    `json:"resolved_by"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for ProjectComment model
+func (ProjectComment) TableName() string {
+	return "project_comments"
+}
+
+// CommentMention records a user mentioned in a comment
+type CommentMention struct {
+	CommentID uint64 `json:"comment_id" gorm:"primaryKey"`
+	UserID    uint64 `json:"user_id" gorm:"primaryKey;index:idx_mention_user_id"`
+}
+
+// TableName specifies the table name for CommentMention model
+func (CommentMention) TableName() string {
+	return "comment_mentions"
+}
+
+// CommentView is a comment with its author, mentions and, for threads, replies
+type CommentView struct {
+	ProjectComment
+	AuthorName string        `json:"author_name"`
+	Mentions   []uint64      `json:"mentions"`
+	Replies    []CommentView `json:"replies,omitempty"`
+}
@@ -0,0 +1,50 @@
+package model
+
+import "time"
+
+// 额度发放的周期
+const (
+	CreditPeriodDay   = "day"   // 只在发放当天有效
+	CreditPeriodMonth = "month" // 在发放当月有效
+)
+
+// GenerationUsage is the metered usage of one generation
+// 与任务记录分开保存，任务过期清理后用量仍然保留
+type GenerationUsage struct {
+	ID            uint64    `json:"id" gorm:"primaryKey"`
+	JobID         string    `json:"job_id" gorm:"type:char(36);uniqueIndex:uk_generation_usage_job;not null"`
+	UserID        uint64    `json:"user_id" gorm:"index:idx_generation_usage_user_created;not null"`
+	ProjectID     *uint64   `json:"project_id,omitempty"`
+	Provider      string    `json:"provider" gorm:"type:varchar(32);not null"`
+	Status        string    `json:"status" gorm:"type:varchar(16);not null"` // Final status of the job
+	PromptName    string    `json:"prompt_name" gorm:"type:varchar(32);index:idx_generation_usage_prompt;not null;default:''"`
+	PromptVersion int       `json:"prompt_version" gorm:"not null;default:0"` // 0 is the built-in prompt
+	InputTokens   int64     `json:"input_tokens" gorm:"not null;default:0"`
+	OutputTokens  int64     `json:"output_tokens" gorm:"not null;default:0"`
+	WallTimeMs    int64     `json:"wall_time_ms" gorm:"not null;default:0"`
+	CostMicros    int64     `json:"cost_micros" gorm:"not null;default:0"` // Cost in millionths of a dollar
+	Credits       int64     `json:"credits" gorm:"not null;default:0"`
+	CreatedAt     time.Time `json:"created_at" gorm:"index:idx_generation_usage_user_created;index:idx_generation_usage_prompt"`
+}
+
+// TableName specifies the table name for GenerationUsage model
+func (GenerationUsage) TableName() string {
+	return "generation_usage"
+}
+
+// CreditGrant is an extra credit budget given to a user by an admin
+// 发放的额度只在发放时所在的天或月内有效，负数表示扣减
+type CreditGrant struct {
+	ID        uint64    `json:"id" gorm:"primaryKey"`
+	UserID    uint64    `json:"user_id" gorm:"index:idx_credit_grant_user_period;not null"`
+	Period    string    `json:"period" gorm:"type:varchar(8);index:idx_credit_grant_user_period;not null"`
+	Credits   int64     `json:"credits" gorm:"not null"`
+	Reason    string    `json:"reason" gorm:"type:varchar(255);not null;default:''"`
+	GrantedBy uint64    `json:"granted_by" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"index:idx_credit_grant_user_period"`
+}
+
+// TableName specifies the table name for CreditGrant model
+func (CreditGrant) TableName() string {
+	return "credit_grants"
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// 项目入口文件，内容保存在项目的 html/css 中
+const (
+	ProjectIndexFile = "index.html"
+	ProjectStyleFile = "style.css"
+)
+
+// ProjectFile is a page, stylesheet or script of a multi-file project
+// index.html and style.css are the project's html and css; every other file is a row in project_files
+type ProjectFile struct {
+	ID        uint64    `json:"-" gorm:"primaryKey;autoIncrement"`
+	ProjectID uint64    `json:"-" gorm:"uniqueIndex:idx_file_project_path,priority:1;not null"`
+	Path      string    `json:"path" gorm:"type:varchar(255) COLLATE utf8mb4_bin;uniqueIndex:idx_file_project_path,priority:2;not null"` // Relative path such as about/index.html, case-sensitive
+	Content   string    `json:"content" gorm:"-"`                                                                                        // Loaded from content_blobs by the DAO
+	Hash      string    `json:"-" gorm:"type:char(64);not null;default:''"`                                                              // Blob holding the content, empty for an empty file
+	Size      int64     `json:"size" gorm:"not null;default:0"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for ProjectFile model
+func (ProjectFile) TableName() string {
+	return "project_files"
+}
+
+// ProjectFileInfo is a file without its content (used for the file tree)
+type ProjectFileInfo struct {
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
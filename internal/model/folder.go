@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// ProjectFolder groups a user's projects; folders nest at most one level (ParentID must be a root folder)
+type ProjectFolder struct {
+	ID        uint64    `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    uint64    `json:"user_id" gorm:"index:idx_folder_user_id;not null"`
+	ParentID  *uint64   `json:"parent_id" gorm:"index:idx_folder_parent_id"`
+	Name      string    `json:"name" gorm:"type:varchar(100);not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for ProjectFolder model
+func (ProjectFolder) TableName() string {
+	return "project_folders"
+}
+
+// ProjectTag is a free-form label on a project
+type ProjectTag struct {
+	ProjectID uint64 `json:"project_id" gorm:"primaryKey"`
+	Tag       string `json:"tag" gorm:"primaryKey;type:varchar(32);index:idx_tag_tag"`
+}
+
+// TableName specifies the table name for ProjectTag model
+func (ProjectTag) TableName() string {
+	return "project_tags"
+}
+
+// TagCount is a tag with the number of the user's projects using it
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// GalleryEntry lists a published project in the public gallery
+type GalleryEntry struct {
+	ProjectID     uint64    `json:"project_id" gorm:"primaryKey"`
+	UserID        uint64    `json:"user_id" gorm:"index:idx_gallery_user_id;not null"`
+	Category      string    `json:"category" gorm:"type:varchar(32);index:idx_gallery_category;not null"`
+	Description   string    `json:"description" gorm:"type:varchar(500);not null;default:''"`
+	Stars         int64     `json:"stars" gorm:"index:idx_gallery_stars;not null;default:0"`      // Denormalized count of project_stars
+	TrendingScore float64   `json:"-" gorm:"index:idx_gallery_trending_score;not null;default:0"` // Trending score as of the last reconcile
+	ListedAt      time.Time `json:"listed_at" gorm:"index:idx_gallery_listed_at;not null"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for GalleryEntry model
+func (GalleryEntry) TableName() string {
+	return "gallery_entries"
+}
+
+// ProjectStar is a user's star on a gallery project, one per user and project
+type ProjectStar struct {
+	ProjectID uint64    `json:"project_id" gorm:"primaryKey"`
+	UserID    uint64    `json:"user_id" gorm:"primaryKey;index:idx_star_user_id"`
+	CreatedAt time.Time `json:"created_at" gorm:"index:idx_star_created_at"`
+}
+
+// TableName specifies the table name for ProjectStar model
+func (ProjectStar) TableName() string {
+	return "project_stars"
+}
+
+// GalleryItem is a gallery entry with the project's public details
+type GalleryItem struct {
+	ProjectID   uint64    `json:"project_id"`
+	Name        string    `json:"name"`
+	Slug        string    `json:"slug"` // Served at /p/:slug
+	Description string    `json:"description"`
+	Category    string    `json:"category"`
+	Stars       int64     `json:"stars"`
+	AuthorName  string    `json:"author_name"`
+	ListedAt    time.Time `json:"listed_at"`
+	Starred     bool      `json:"starred" gorm:"-"` // Whether the signed-in viewer starred it
+}
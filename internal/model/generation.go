@@ -0,0 +1,75 @@
+package model
+
+import "time"
+
+// 生成任务状态
+const (
+	GenerationPending    = "pending"    // 已创建，排队等待开始
+	GenerationGenerating = "generating" // 模型正在输出
+	GenerationCompleted  = "completed"  // 已得到页面
+	GenerationError      = "error"      // 生成失败
+	GenerationCanceled   = "canceled"   // 被用户取消
+)
+
+// GenerationDone reports whether a generation status is final
+func GenerationDone(status string) bool {
+	return status == GenerationCompleted || status == GenerationError || status == GenerationCanceled
+}
+
+// GenerationJob is a page generation request and its outcome
+// 进度事件保存在 Redis Stream 中，这里只保存任务状态和最终输出
+type GenerationJob struct {
+	ID            string             `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID        uint64             `json:"user_id" gorm:"index:idx_generation_user_status;not null"`
+	ProjectID     *uint64            `json:"project_id,omitempty" gorm:"index:idx_generation_project_id"` // Project the page is generated for, if any
+	Provider      string             `json:"provider" gorm:"type:varchar(32);not null"`                   // Provider that generated the output, the router until one answers
+	PromptName    string             `json:"prompt_name" gorm:"type:varchar(32);not null;default:''"`     // System prompt template used
+	PromptVersion int                `json:"prompt_version" gorm:"not null;default:0"`                    // 0 is the built-in prompt
+	Status        string             `json:"status" gorm:"type:varchar(16);index:idx_generation_user_status;index:idx_generation_status_heartbeat;not null"`
+	Prompt        string             `json:"prompt" gorm:"type:text;not null"`
+	Input         string             `json:"-" gorm:"type:mediumtext;not null"`                       // JSON of the full input, including history and current html
+	Content       string             `json:"content" gorm:"type:mediumtext;not null"`                 // Model output, the output so far while generating
+	Result        *GenerationResult  `json:"result,omitempty" gorm:"type:mediumtext;serializer:json"` // Set when completed
+	Context       *GenerationContext `json:"context,omitempty" gorm:"type:text;serializer:json"`      // How the conversation was fitted into the request, set when the job ends
+	Error         string             `json:"error,omitempty" gorm:"type:varchar(255);not null;default:''"`
+	LastSeq       int64              `json:"last_seq" gorm:"not null;default:0"`             // Seq of the latest progress event
+	QueuePosition int                `json:"queue_position,omitempty" gorm:"-"`              // Estimated place in the queue while pending, 1 is next
+	Instance      string             `json:"-" gorm:"type:varchar(128);not null;default:''"` // API instance running the job
+	HeartbeatAt   time.Time          `json:"-" gorm:"index:idx_generation_status_heartbeat"`
+	CreatedAt     time.Time          `json:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
+	FinishedAt    *time.Time         `json:"finished_at,omitempty" gorm:"index:idx_generation_finished_at"`
+}
+
+// TableName specifies the table name for GenerationJob model
+func (GenerationJob) TableName() string {
+	return "generation_jobs"
+}
+
+// GenerationContext reports how the conversation of a job was fitted into the context budget
+// 当前页面和本次请求总是完整带入，只有历史消息会被截断、概括或舍弃
+type GenerationContext struct {
+	Tokenizer       string `json:"tokenizer"`        // Tokenizer used to count tokens
+	Budget          int64  `json:"budget"`           // Input tokens allowed, 0 is unlimited
+	Tokens          int64  `json:"tokens"`           // Input tokens of the request sent
+	PageTokens      int64  `json:"page_tokens"`      // Tokens of the current page
+	HistoryTurns    int    `json:"history_turns"`    // Earlier turns considered
+	LimitedTurns    int    `json:"limited_turns"`    // Oldest turns left out by the max_history limit
+	IncludedTurns   int    `json:"included_turns"`   // Recent turns included as they are
+	TruncatedTurns  int    `json:"truncated_turns"`  // Included turns cut to the message length limit
+	SummarizedTurns int    `json:"summarized_turns"` // Older turns replaced by summaries
+	CachedSummaries int    `json:"cached_summaries"` // Summaries reused from the cache
+	DroppedTurns    int    `json:"dropped_turns"`    // Older turns left out because summarizing failed or did not fit
+	OverBudget      bool   `json:"over_budget"`      // The page and the prompt alone exceed the budget
+}
+
+// GenerationResult is the page produced by a completed generation job
+type GenerationResult struct {
+	HTML    string `json:"html"`
+	CSS     string `json:"css"`
+	Message string `json:"message"` // Reply shown to the user
+	// Saved is set when the page and the conversation were written into the job's project
+	Saved     bool           `json:"saved"`
+	SaveError string         `json:"save_error,omitempty"` // Why the page could not be saved into the project
+	Quality   *QualityReport `json:"quality,omitempty"`    // Problems found in the generated page
+}
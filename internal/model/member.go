@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// 项目成员角色
+const (
+	MemberRoleOwner  = "owner" // 仅用于返回，所有者不在成员表中
+	MemberRoleEditor = "editor"
+	MemberRoleViewer = "viewer"
+)
+
+// ProjectMember grants another user access to a project
+type ProjectMember struct {
+	ProjectID uint64    `json:"project_id" gorm:"primaryKey"`
+	UserID    uint64    `json:"user_id" gorm:"primaryKey;index:idx_member_user_id"`
+	Role      string    `json:"role" gorm:"type:varchar(16);not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for ProjectMember model
+func (ProjectMember) TableName() string {
+	return "project_members"
+}
+
+// ProjectMemberInfo is a member with the user's name and email
+type ProjectMemberInfo struct {
+	UserID    uint64    `json:"user_id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -1,20 +1,57 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // Project represents a user's workspace project
 type Project struct {
-	ID        uint64    `json:"id" gorm:"primaryKey;autoIncrement"`
-	UserID    uint64    `json:"user_id" gorm:"index:idx_project_user_id;not null"`
-	Name      string    `json:"name" gorm:"type:varchar(255);not null;default:'New Project'"`
-	HTML      string    `json:"html" gorm:"type:longtext"`
-	CSS       string    `json:"css" gorm:"type:longtext"`
-	Messages  string    `json:"messages" gorm:"type:longtext"` // JSON format chat history
-	CreatedAt time.Time `json:"created_at" gorm:"index:idx_project_created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           uint64         `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID       uint64         `json:"user_id" gorm:"index:idx_project_user_id;not null"`
+	Name         string         `json:"name" gorm:"type:varchar(255);not null;default:'New Project'"`
+	HTML         string         `json:"html" gorm:"type:longtext"` // Filled from content_blobs by the DAO, the column only keeps rows not yet moved
+	CSS          string         `json:"css" gorm:"type:longtext"`
+	HTMLHash     string         `json:"-" gorm:"type:char(64);not null;default:''"` // Blob holding html, empty when stored inline or empty
+	CSSHash      string         `json:"-" gorm:"type:char(64);not null;default:''"`
+	HTMLHead     string         `json:"-" gorm:"type:text"`                                               // Leading part of html for list thumbnails
+	ContentSize  int64          `json:"-" gorm:"not null;default:0"`                                      // Bytes of html + css + other project files
+	Messages     string         `json:"messages" gorm:"type:longtext"`                                    // JSON format chat history
+	FolderID     *uint64        `json:"folder_id" gorm:"index:idx_project_folder_id"`                     // Folder containing the project, nil for the root
+	ForkedFromID *uint64        `json:"forked_from_id,omitempty" gorm:"index:idx_project_forked_from_id"` // Source project when forked
+	Quality      *QualityReport `json:"quality,omitempty" gorm:"type:text;serializer:json"`               // Report of the last generation saved into the project
+	CreatedAt    time.Time      `json:"created_at" gorm:"index:idx_project_created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index:idx_project_deleted_at"` // Set when moved to trash
 }
 
 // TableName specifies the table name for Project model
 func (Project) TableName() string {
 	return "projects"
 }
+
+// 对话记录中消息的发送方
+const (
+	ChatMessageUser = "user"
+	ChatMessageAI   = "ai"
+)
+
+// ChatMessage is one turn of a project's chat history, Project.Messages holds a JSON array of them
+type ChatMessage struct {
+	Type    string `json:"type"` // user or ai
+	Content string `json:"content"`
+}
+
+// ProjectSummary lightweight project info for the project list (no html, css or messages)
+type ProjectSummary struct {
+	ID        uint64    `json:"id"`
+	Name      string    `json:"name"`
+	FolderID  *uint64   `json:"folder_id"`
+	Tags      []string  `json:"tags" gorm:"-"`
+	Size      int64     `json:"size"`                      // Bytes of html + css + other project files
+	Thumbnail string    `json:"thumbnail"`                 // Plain-text excerpt of the page
+	HTMLHead  string    `json:"-" gorm:"column:html_head"` // Leading part of html, used to build Thumbnail
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
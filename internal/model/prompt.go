@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// 提示词模板名称，对应生成的不同场景
+const (
+	PromptCreate   = "create"   // 新建页面的系统提示词
+	PromptModify   = "modify"   // 修改现有页面的系统提示词
+	PromptFragment = "fragment" // 重新生成页面中选中元素的系统提示词
+)
+
+// PromptNames are the prompt templates generation uses
+var PromptNames = []string{PromptCreate, PromptModify, PromptFragment}
+
+// PromptTemplate is one version of a system prompt, rendered with text/template
+// 版本创建后内容不可修改，只能调整流量权重；同名的多个启用版本按权重分配给用户做 A/B 测试
+// 没有启用版本时使用代码内置的提示词（版本 0）
+type PromptTemplate struct {
+	ID          uint64            `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name        string            `json:"name" gorm:"type:varchar(32);uniqueIndex:uk_prompt_name_version;not null"`
+	Version     int               `json:"version" gorm:"uniqueIndex:uk_prompt_name_version;not null"`
+	Body        string            `json:"body" gorm:"type:mediumtext;not null"`
+	Variables   map[string]string `json:"variables" gorm:"type:text;serializer:json"` // Default values of the template's own variables
+	Weight      int               `json:"weight" gorm:"not null;default:0"`           // Share of traffic among the enabled versions, 0 disables the version
+	Description string            `json:"description" gorm:"type:varchar(255);not null;default:''"`
+	CreatedBy   uint64            `json:"created_by" gorm:"not null;default:0"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// TableName specifies the table name for PromptTemplate model
+func (PromptTemplate) TableName() string {
+	return "prompt_templates"
+}
+
+// PromptVariantStats is the outcome of generations that used one prompt version
+type PromptVariantStats struct {
+	Version         int     `json:"version"`
+	Generations     int64   `json:"generations"`
+	Completed       int64   `json:"completed"`
+	Failed          int64   `json:"failed"`
+	AvgOutputTokens float64 `json:"avg_output_tokens"`
+	AvgWallTimeMs   float64 `json:"avg_wall_time_ms"`
+	AvgCredits      float64 `json:"avg_credits"`
+}
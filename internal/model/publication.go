@@ -0,0 +1,62 @@
+package model
+
+import "time"
+
+// ProjectSnapshot is an immutable copy of a project's content at a point in time
+type ProjectSnapshot struct {
+	ID        uint64    `json:"id" gorm:"primaryKey;autoIncrement"`
+	ProjectID uint64    `json:"project_id" gorm:"index:idx_snapshot_project_id;not null"`
+	UserID    uint64    `json:"user_id" gorm:"not null"`
+	Label     string    `json:"label" gorm:"type:varchar(255);not null;default:''"`
+	HTML      string    `json:"html" gorm:"type:longtext"` // Filled from content_blobs by the DAO, the column only keeps rows not yet moved
+	CSS       string    `json:"css" gorm:"type:longtext"`
+	HTMLHash  string    `json:"-" gorm:"type:char(64);not null;default:''"`
+	CSSHash   string    `json:"-" gorm:"type:char(64);not null;default:''"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Files []ProjectFile `json:"-" gorm:"-"` // Files besides index.html and style.css to capture, only read by SnapshotDAO.Create
+}
+
+// TableName specifies the table name for ProjectSnapshot model
+func (ProjectSnapshot) TableName() string {
+	return "project_snapshots"
+}
+
+// ProjectSnapshotFile is a copy of a project file other than index.html and style.css kept by a snapshot
+type ProjectSnapshotFile struct {
+	ID         uint64 `json:"-" gorm:"primaryKey;autoIncrement"`
+	SnapshotID uint64 `json:"-" gorm:"uniqueIndex:idx_snapshot_file_path,priority:1;not null"`
+	Path       string `json:"path" gorm:"type:varchar(255) COLLATE utf8mb4_bin;uniqueIndex:idx_snapshot_file_path,priority:2;not null"`
+	Hash       string `json:"-" gorm:"type:char(64);not null;default:''"` // Blob holding the content, shared with the project file it was copied from
+	Size       int64  `json:"size" gorm:"not null;default:0"`
+}
+
+// TableName specifies the table name for ProjectSnapshotFile model
+func (ProjectSnapshotFile) TableName() string {
+	return "project_snapshot_files"
+}
+
+// ProjectSnapshotSummary is a snapshot without its content (used for listings)
+type ProjectSnapshotSummary struct {
+	ID        uint64    `json:"id"`
+	ProjectID uint64    `json:"project_id"`
+	Label     string    `json:"label"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ProjectPublication maps a public slug to the snapshot served at /p/:slug
+type ProjectPublication struct {
+	ID          uint64    `json:"id" gorm:"primaryKey;autoIncrement"`
+	ProjectID   uint64    `json:"project_id" gorm:"uniqueIndex:idx_publication_project_id;not null"`
+	UserID      uint64    `json:"user_id" gorm:"index:idx_publication_user_id;not null"`
+	Slug        string    `json:"slug" gorm:"type:varchar(64);uniqueIndex:idx_publication_slug;not null"`
+	SnapshotID  uint64    `json:"snapshot_id" gorm:"not null"`
+	PublishedAt time.Time `json:"published_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for ProjectPublication model
+func (ProjectPublication) TableName() string {
+	return "project_publications"
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// 质量问题的严重程度
+const (
+	QualityError   = "error"   // 页面可能显示错误或无法使用
+	QualityWarning = "warning" // 影响可访问性、性能或安全
+)
+
+// QualityIssue is one kind of problem found in a generated page
+type QualityIssue struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"`         // error or warning
+	Detail   string `json:"detail,omitempty"` // Tag, selector, host or size concerned
+	Count    int    `json:"count"`
+}
+
+// QualityReport is the result of checking a generated page
+type QualityReport struct {
+	Errors    int            `json:"errors"`   // Occurrences of error issues
+	Warnings  int            `json:"warnings"` // Occurrences of warning issues
+	Bytes     int            `json:"bytes"`    // Size of the page including inline CSS
+	Issues    []QualityIssue `json:"issues"`
+	CheckedAt time.Time      `json:"checked_at"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// ProjectTemplate is a starting point for new projects
+// 内置模板从嵌入文件加载（ID 为 0），管理员维护的模板存储在 project_templates 表
+type ProjectTemplate struct {
+	ID          uint64    `json:"-" gorm:"primaryKey;autoIncrement"`
+	Key         string    `json:"id" gorm:"type:varchar(64);uniqueIndex:idx_template_key;not null"`
+	Name        string    `json:"name" gorm:"type:varchar(255);not null"`
+	Description string    `json:"description" gorm:"type:varchar(1024);not null;default:''"`
+	Category    string    `json:"category" gorm:"type:varchar(64);index:idx_template_category;not null;default:''"`
+	HTML        string    `json:"html" gorm:"type:longtext"`
+	CSS         string    `json:"css" gorm:"type:longtext"`
+	Sort        int       `json:"sort" gorm:"not null;default:0"`
+	Enabled     bool      `json:"enabled" gorm:"not null;default:true"`
+	CreatedBy   uint64    `json:"-" gorm:"not null;default:0"`
+	Builtin     bool      `json:"builtin" gorm:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for ProjectTemplate model
+func (ProjectTemplate) TableName() string {
+	return "project_templates"
+}
+
+// TemplateSummary is a template without its content (used for the gallery)
+type TemplateSummary struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+	Builtin     bool   `json:"builtin"`
+}
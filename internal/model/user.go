@@ -12,7 +12,8 @@ type User struct {
 	Name      string    `json:"name" gorm:"type:varchar(100);not null;index:idx_name"`
 	Age       int       `json:"age" gorm:"default:0"`
 	Email     string    `json:"email" gorm:"type:varchar(255);not null;uniqueIndex:idx_email"`
-	Password  string    `json:"-" gorm:"type:varchar(255);not null"` // 密码不返回给前端
+	Password  string    `json:"-" gorm:"type:varchar(255);not null"`              // 密码不返回给前端
+	Plan      string    `json:"plan" gorm:"type:varchar(32);not null;default:''"` // 套餐，空表示默认套餐
 	CreatedAt time.Time `json:"created_at" gorm:"index:idx_created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -36,6 +36,11 @@ func getJWTConfig() *jwt.Config {
 	return jwtConfig
 }
 
+// isAdmin 判断用户是否为配置中的管理员
+func isAdmin(userID uint64) bool {
+	return config.Cfg != nil && config.Cfg.IsAdmin(userID)
+}
+
 func Register(h *server.Hertz) {
 	// 全局中间件
 	// CORS 配置：开发环境允许 localhost，生产环境需要显式配置允许的域名
@@ -66,6 +71,19 @@ func Register(h *server.Hertz) {
 	userHandler := handler.NewUserHandler()
 	authHandler := handler.NewAuthHandler()
 	projectHandler := handler.NewProjectHandler()
+	publishHandler := handler.NewPublishHandler()
+	templateHandler := handler.NewTemplateHandler()
+	assetHandler := handler.NewAssetHandler()
+	folderHandler := handler.NewFolderHandler()
+	tagHandler := handler.NewTagHandler()
+	memberHandler := handler.NewMemberHandler()
+	collabHandler := handler.NewCollabHandler()
+	commentHandler := handler.NewCommentHandler()
+	fileHandler := handler.NewFileHandler()
+	galleryHandler := handler.NewGalleryHandler()
+	generationHandler := handler.NewGenerationHandler()
+	creditHandler := handler.NewCreditHandler()
+	promptHandler := handler.NewPromptHandler()
 
 	// 静态文件服务 - 手动处理 JS 和 CSS
 	h.GET("/static/js/:file", func(ctx context.Context, c *app.RequestContext) {
@@ -111,6 +129,20 @@ func Register(h *server.Hertz) {
 		c.File("./web/workspace.html")
 	})
 
+	// 已发布项目页面（公开访问，使用独立的沙箱 CSP）
+	published := h.Group("/p", middleware.PublishedPageHeaders())
+	{
+		published.GET("/:slug", publishHandler.ServePage)
+		// 多文件项目的其他页面、样式和脚本
+		published.GET("/:slug/*path", publishHandler.ServePage)
+	}
+
+	// 上传资源（公开访问，内容寻址，长期缓存）
+	assets := h.Group("/assets", middleware.AssetHeaders())
+	{
+		assets.GET("/:project_id/:file", assetHandler.Serve)
+	}
+
 	// 健康检查
 	h.GET("/ping", pingHandler.Ping)
 	h.GET("/health", pingHandler.Health) // 详细健康检查
@@ -163,6 +195,7 @@ func Register(h *server.Hertz) {
 		{
 			authProtected.POST("/logout", authHandler.Logout)
 			authProtected.GET("/profile", authHandler.GetProfile)
+			authProtected.GET("/usage", authHandler.GetUsage)
 			authProtected.PUT("/profile", authHandler.UpdateProfile)
 			authProtected.PUT("/password", authHandler.ChangePassword)
 			authProtected.DELETE("/account", authHandler.DeleteAccount)
@@ -190,9 +223,129 @@ func Register(h *server.Hertz) {
 		{
 			projects.GET("", projectHandler.List)
 			projects.POST("", projectHandler.Create)
+			projects.POST("/import", projectHandler.Import)
+			projects.GET("/trash", projectHandler.ListTrash)
+			projects.DELETE("/trash/:id", projectHandler.DeleteForever)
+			projects.POST("/bulk/move", folderHandler.MoveProjects)
+			projects.POST("/bulk/tags", tagHandler.BulkTag)
 			projects.GET("/:id", projectHandler.Get)
 			projects.PUT("/:id", projectHandler.Update)
 			projects.DELETE("/:id", projectHandler.Delete)
+			projects.POST("/:id/fork", projectHandler.Fork)
+			projects.POST("/:id/restore", projectHandler.Restore)
+			projects.GET("/:id/export", projectHandler.Export)
+			projects.PUT("/:id/tags", tagHandler.SetProjectTags)
+
+			// 多文件项目：index.html 和 style.css 对应项目的 html 和 css
+			projects.GET("/:id/files", fileHandler.List)
+			projects.PUT("/:id/files", fileHandler.Save)
+			projects.GET("/:id/files/*path", fileHandler.Get)
+			projects.PUT("/:id/files/*path", fileHandler.Put)
+			projects.DELETE("/:id/files/*path", fileHandler.Delete)
+
+			// 成员与实时协作
+			projects.GET("/:id/members", memberHandler.List)
+			projects.POST("/:id/members", memberHandler.Add)
+			projects.DELETE("/:id/members/:user_id", memberHandler.Remove)
+			projects.GET("/:id/ws", collabHandler.Connect)
+
+			// 评审评论
+			projects.GET("/:id/comments", commentHandler.List)
+			projects.POST("/:id/comments", commentHandler.Create)
+			projects.PUT("/:id/comments/:comment_id", commentHandler.Update)
+			projects.DELETE("/:id/comments/:comment_id", commentHandler.Delete)
+			projects.POST("/:id/comments/:comment_id/replies", commentHandler.Reply)
+			projects.POST("/:id/comments/:comment_id/resolve", commentHandler.Resolve)
+			projects.DELETE("/:id/comments/:comment_id/resolve", commentHandler.Unresolve)
+
+			// 上传资源
+			projects.GET("/:id/assets", assetHandler.List)
+			projects.POST("/:id/assets", assetHandler.Upload)
+			projects.DELETE("/:id/assets/:asset_id", assetHandler.Delete)
+
+			// 快照与发布
+			projects.GET("/:id/snapshots", publishHandler.ListSnapshots)
+			projects.POST("/:id/snapshots", publishHandler.CreateSnapshot)
+			projects.GET("/:id/publish", publishHandler.GetPublication)
+			projects.POST("/:id/publish", publishHandler.Publish)
+			projects.DELETE("/:id/publish", publishHandler.Unpublish)
+			projects.GET("/:id/analytics", publishHandler.GetAnalytics)
+			projects.PUT("/:id/gallery", galleryHandler.Add)
+			projects.DELETE("/:id/gallery", galleryHandler.Remove)
+		}
+
+		// 文件夹与标签 - 需要认证
+		folders := v1.Group("/folders")
+		folders.Use(middleware.JWTAuth(getJWTConfig()))
+		{
+			folders.GET("", folderHandler.List)
+			folders.POST("", folderHandler.Create)
+			folders.PUT("/:id", folderHandler.Update)
+			folders.DELETE("/:id", folderHandler.Delete)
+		}
+
+		comments := v1.Group("/comments")
+		comments.Use(middleware.JWTAuth(getJWTConfig()))
+		{
+			comments.GET("/mentions", commentHandler.ListMentions)
+		}
+
+		tags := v1.Group("/tags")
+		tags.Use(middleware.JWTAuth(getJWTConfig()))
+		{
+			tags.GET("", tagHandler.List)
+		}
+
+		// AI 页面生成 - 需要认证
+		generate := v1.Group("/generate")
+		generate.Use(middleware.JWTAuth(getJWTConfig()))
+		{
+			generate.POST("", generationHandler.Generate)
+			generate.GET("/:id", generationHandler.Get)
+			generate.DELETE("/:id", generationHandler.Cancel)
+			generate.GET("/:id/stream", generationHandler.Stream)
+		}
+
+		// 生成额度和用量 - 需要认证
+		credits := v1.Group("/credits")
+		credits.Use(middleware.JWTAuth(getJWTConfig()))
+		{
+			credits.GET("", creditHandler.Balance)
+			credits.GET("/usage", creditHandler.History)
+		}
+
+		// 作品库 - 浏览公开，登录后可收藏
+		gallery := v1.Group("/gallery")
+		{
+			gallery.GET("", middleware.OptionalJWTAuth(getJWTConfig()), galleryHandler.List)
+			gallery.GET("/categories", galleryHandler.Categories)
+			gallery.POST("/:id/star", middleware.JWTAuth(getJWTConfig()), galleryHandler.Star)
+			gallery.DELETE("/:id/star", middleware.JWTAuth(getJWTConfig()), galleryHandler.Unstar)
+		}
+
+		// 模板库 - 公开接口
+		templates := v1.Group("/templates")
+		{
+			templates.GET("", templateHandler.List)
+			templates.GET("/:id", templateHandler.Get)
+		}
+
+		// 管理接口 - 需要管理员权限
+		admin := v1.Group("/admin")
+		admin.Use(middleware.JWTAuth(getJWTConfig()), middleware.AdminOnly(isAdmin))
+		{
+			admin.GET("/templates", templateHandler.ListCurated)
+			admin.POST("/templates", templateHandler.Create)
+			admin.PUT("/templates/:id", templateHandler.Update)
+			admin.DELETE("/templates/:id", templateHandler.Delete)
+			admin.GET("/users/:id/credits", creditHandler.UserBalance)
+			admin.POST("/users/:id/credits", creditHandler.Grant)
+			admin.GET("/prompts", promptHandler.List)
+			admin.POST("/prompts", promptHandler.Create)
+			admin.GET("/prompts/stats", promptHandler.Stats)
+			admin.GET("/prompts/:id", promptHandler.Get)
+			admin.PUT("/prompts/:id/weight", promptHandler.SetWeight)
+			admin.POST("/prompts/:id/render", promptHandler.Render)
 		}
 	}
 }
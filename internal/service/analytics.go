@@ -0,0 +1,445 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/test-tt/config"
+	"github.com/test-tt/internal/dao"
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/cache"
+	"github.com/test-tt/pkg/logger"
+)
+
+// 设备类型
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot" // 爬虫和脚本，不计入统计
+)
+
+const (
+	// Redis 中按天（UTC）保存的原始计数，汇总到 MySQL 后自然过期
+	analyticsViewsKey    = "analytics:%s:%d:views"
+	analyticsVisitorsKey = "analytics:%s:%d:uv" // HyperLogLog
+	analyticsReferrerKey = "analytics:%s:%d:ref"
+	analyticsDeviceKey   = "analytics:%s:%d:dev"
+	analyticsProjectsKey = "analytics:%s:projects"
+	analyticsSaltKey     = "analytics:%s:salt"
+	analyticsKeyTTL      = 72 * time.Hour
+
+	analyticsDayLayout     = "2006-01-02"
+	analyticsTrackTimeout  = 2 * time.Second
+	analyticsRollupTimeout = 5 * time.Minute
+	defaultAnalyticsDays   = 30
+	defaultMaxRangeDays    = 366
+	maxReferrerLen         = 255
+	maxReferrersPerDay     = 50
+	topReferrersLimit      = 20
+	directReferrer         = "(direct)"
+)
+
+var ErrAnalyticsRangeInvalid = errors.New("invalid date range")
+
+// PageView is one request for a published page
+type PageView struct {
+	ProjectID  uint64
+	IP         string
+	UserAgent  string
+	Referer    string
+	Host       string // 本站域名，站内跳转不算来源
+	DoNotTrack bool   // DNT: 1 或 Sec-GPC: 1
+}
+
+// AnalyticsReport is the traffic of a published project over a date range
+type AnalyticsReport struct {
+	From      string                `json:"from"`
+	To        string                `json:"to"`
+	Views     int64                 `json:"views"`
+	Visitors  int64                 `json:"visitors"` // 每日访客数之和，同一访客跨天会重复计数
+	Devices   map[string]int64      `json:"devices"`
+	Daily     []AnalyticsDay        `json:"daily"`
+	Referrers []model.ReferrerCount `json:"referrers"`
+}
+
+// AnalyticsDay is the traffic of one day
+type AnalyticsDay struct {
+	Date     string `json:"date"`
+	Views    int64  `json:"views"`
+	Visitors int64  `json:"visitors"`
+}
+
+type AnalyticsService struct {
+	rdb            *redis.Client
+	analyticsDAO   *dao.AnalyticsDAO
+	projectService *ProjectService
+
+	saltMu  sync.Mutex
+	saltDay string
+	salt    string
+}
+
+func NewAnalyticsService() *AnalyticsService {
+	return &AnalyticsService{
+		rdb:            cache.RDB,
+		analyticsDAO:   dao.NewAnalyticsDAO(),
+		projectService: NewProjectService(),
+	}
+}
+
+// Track records a page view in the background; bots and opted-out visitors are skipped
+func (s *AnalyticsService) Track(view *PageView) {
+	if s.rdb == nil || !analyticsEnabled() {
+		return
+	}
+	if view.DoNotTrack && config.Cfg.Analytics.RespectDNT {
+		return
+	}
+	device := DeviceClass(view.UserAgent)
+	if device == DeviceBot {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), analyticsTrackTimeout)
+		defer cancel()
+		if err := s.record(ctx, view, device, time.Now().UTC()); err != nil {
+			logger.Warnf("failed to record page view", "projectID", view.ProjectID, "error", err)
+		}
+	}()
+}
+
+// record 在一个 pipeline 中更新当天的计数
+func (s *AnalyticsService) record(ctx context.Context, view *PageView, device string, now time.Time) error {
+	day := now.Format(analyticsDayLayout)
+	salt, err := s.dailySalt(ctx, day)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{
+		fmt.Sprintf(analyticsViewsKey, day, view.ProjectID),
+		fmt.Sprintf(analyticsVisitorsKey, day, view.ProjectID),
+		fmt.Sprintf(analyticsReferrerKey, day, view.ProjectID),
+		fmt.Sprintf(analyticsDeviceKey, day, view.ProjectID),
+		fmt.Sprintf(analyticsProjectsKey, day),
+	}
+	pipe := s.rdb.Pipeline()
+	pipe.Incr(ctx, keys[0])
+	pipe.PFAdd(ctx, keys[1], visitorID(salt, view))
+	pipe.HIncrBy(ctx, keys[2], ReferrerHost(view.Referer, view.Host), 1)
+	pipe.HIncrBy(ctx, keys[3], device, 1)
+	pipe.SAdd(ctx, keys[4], view.ProjectID)
+	for _, key := range keys {
+		pipe.Expire(ctx, key, analyticsKeyTTL)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// dailySalt 返回当天的随机盐值，所有实例共享；盐值过期后访客标识无法还原或跨天关联
+func (s *AnalyticsService) dailySalt(ctx context.Context, day string) (string, error) {
+	s.saltMu.Lock()
+	defer s.saltMu.Unlock()
+	if s.saltDay == day {
+		return s.salt, nil
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	key := fmt.Sprintf(analyticsSaltKey, day)
+	if err := s.rdb.SetNX(ctx, key, hex.EncodeToString(buf), analyticsKeyTTL).Err(); err != nil {
+		return "", err
+	}
+	salt, err := s.rdb.Get(ctx, key).Result()
+	if err != nil {
+		return "", err
+	}
+	s.saltDay, s.salt = day, salt
+	return salt, nil
+}
+
+// Rollup copies one day's counts of every viewed project from Redis to MySQL
+// 写入的是当天累计值，重复执行或多实例同时执行结果一致
+func (s *AnalyticsService) Rollup(ctx context.Context, day string) (int, error) {
+	if s.rdb == nil {
+		return 0, nil
+	}
+	date, err := parseAnalyticsDay(day)
+	if err != nil {
+		return 0, err
+	}
+
+	members, err := s.rdb.SMembers(ctx, fmt.Sprintf(analyticsProjectsKey, day)).Result()
+	if err != nil {
+		return 0, err
+	}
+	rolled := 0
+	for _, member := range members {
+		projectID, err := strconv.ParseUint(member, 10, 64)
+		if err != nil {
+			continue
+		}
+		if err := s.rollupProject(ctx, day, date, projectID); err != nil {
+			// 项目可能已被永久删除，跳过即可
+			logger.WarnCtxf(ctx, "failed to roll up page views", "projectID", projectID, "day", day, "error", err)
+			continue
+		}
+		rolled++
+	}
+	return rolled, nil
+}
+
+func (s *AnalyticsService) rollupProject(ctx context.Context, day string, date time.Time, projectID uint64) error {
+	pipe := s.rdb.Pipeline()
+	views := pipe.Get(ctx, fmt.Sprintf(analyticsViewsKey, day, projectID))
+	visitors := pipe.PFCount(ctx, fmt.Sprintf(analyticsVisitorsKey, day, projectID))
+	referrers := pipe.HGetAll(ctx, fmt.Sprintf(analyticsReferrerKey, day, projectID))
+	devices := pipe.HGetAll(ctx, fmt.Sprintf(analyticsDeviceKey, day, projectID))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return err
+	}
+
+	viewCount, _ := views.Int64()
+	deviceCounts := parseCounts(devices.Val())
+	daily := &model.PageViewDaily{
+		ProjectID: projectID,
+		Date:      date,
+		Views:     viewCount,
+		Visitors:  visitors.Val(),
+		Desktop:   deviceCounts[DeviceDesktop],
+		Mobile:    deviceCounts[DeviceMobile],
+		Tablet:    deviceCounts[DeviceTablet],
+	}
+
+	top := topCounts(parseCounts(referrers.Val()), maxReferrersPerDay)
+	rows := make([]model.PageViewReferrer, len(top))
+	for i, r := range top {
+		rows[i] = model.PageViewReferrer{ProjectID: projectID, Date: date, Referrer: r.Referrer, Views: r.Views}
+	}
+	return s.analyticsDAO.SaveDay(ctx, daily, rows)
+}
+
+// Report returns the traffic of a project the user owns between two dates (YYYY-MM-DD, inclusive, UTC)
+// 默认最近 30 天；数据按 rollup_interval 汇总，当天的数字会有延迟
+func (s *AnalyticsService) Report(ctx context.Context, projectID, userID uint64, from, to string) (*AnalyticsReport, error) {
+	if _, err := s.projectService.GetByID(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+	start, end, err := analyticsRange(from, to, time.Now().UTC(), maxAnalyticsRangeDays())
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.analyticsDAO.ListDaily(ctx, projectID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	referrers, err := s.analyticsDAO.TopReferrers(ctx, projectID, start, end, topReferrersLimit)
+	if err != nil {
+		return nil, err
+	}
+	if referrers == nil {
+		referrers = []model.ReferrerCount{}
+	}
+
+	report := &AnalyticsReport{
+		From:      start.Format(analyticsDayLayout),
+		To:        end.Format(analyticsDayLayout),
+		Devices:   map[string]int64{DeviceDesktop: 0, DeviceMobile: 0, DeviceTablet: 0},
+		Referrers: referrers,
+	}
+	byDay := make(map[string]*model.PageViewDaily, len(rows))
+	for i := range rows {
+		byDay[rows[i].Date.Format(analyticsDayLayout)] = &rows[i]
+	}
+	// 没有访问的日期补 0，方便前端直接绘图
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		day := AnalyticsDay{Date: d.Format(analyticsDayLayout)}
+		if row := byDay[day.Date]; row != nil {
+			day.Views, day.Visitors = row.Views, row.Visitors
+			report.Devices[DeviceDesktop] += row.Desktop
+			report.Devices[DeviceMobile] += row.Mobile
+			report.Devices[DeviceTablet] += row.Tablet
+		}
+		report.Views += day.Views
+		report.Visitors += day.Visitors
+		report.Daily = append(report.Daily, day)
+	}
+	return report, nil
+}
+
+// StartAnalyticsRollup 启动访问统计汇总任务，每次汇总昨天和今天（UTC）
+// 返回停止函数
+func StartAnalyticsRollup(interval time.Duration) func() {
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	stopChan := make(chan struct{})
+	analyticsService := NewAnalyticsService()
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				rollupRecentViews(analyticsService)
+			case <-stopChan:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(stopChan)
+	}
+}
+
+func rollupRecentViews(analyticsService *AnalyticsService) {
+	ctx, cancel := context.WithTimeout(context.Background(), analyticsRollupTimeout)
+	defer cancel()
+
+	now := time.Now().UTC()
+	for _, day := range []time.Time{now.AddDate(0, 0, -1), now} {
+		if _, err := analyticsService.Rollup(ctx, day.Format(analyticsDayLayout)); err != nil {
+			logger.Errorf("failed to roll up page views", "day", day.Format(analyticsDayLayout), "error", err)
+		}
+	}
+}
+
+// DeviceClass classifies a user agent as desktop, mobile, tablet or bot
+func DeviceClass(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case ua == "" || containsAny(ua, "bot", "crawler", "spider", "slurp", "curl/", "wget/", "python-", "go-http-client", "headless", "preview", "monitor"):
+		return DeviceBot
+	case containsAny(ua, "ipad", "tablet", "kindle", "silk/", "playbook") ||
+		(strings.Contains(ua, "android") && !strings.Contains(ua, "mobile")):
+		return DeviceTablet
+	case containsAny(ua, "mobi", "iphone", "ipod", "android", "windows phone"):
+		return DeviceMobile
+	default:
+		return DeviceDesktop
+	}
+}
+
+// ReferrerHost reduces a Referer header to the referring site, only the host is kept
+func ReferrerHost(referer, selfHost string) string {
+	if referer == "" {
+		return directReferrer
+	}
+	u, err := url.Parse(referer)
+	if err != nil || u.Hostname() == "" {
+		return directReferrer
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	self := strings.ToLower(selfHost)
+	if h, _, ok := strings.Cut(self, ":"); ok {
+		self = h
+	}
+	if host == strings.TrimPrefix(self, "www.") {
+		return directReferrer
+	}
+	if len(host) > maxReferrerLen {
+		host = host[:maxReferrerLen]
+	}
+	return host
+}
+
+// visitorID 当天的匿名访客标识，只用于 HyperLogLog 计数，不落盘
+func visitorID(salt string, view *PageView) string {
+	sum := sha256.Sum256([]byte(salt + "|" + strconv.FormatUint(view.ProjectID, 10) + "|" + view.IP + "|" + view.UserAgent))
+	return hex.EncodeToString(sum[:16])
+}
+
+// analyticsRange 解析查询日期范围，缺省为截至今天的最近 30 天
+func analyticsRange(from, to string, now time.Time, maxDays int) (time.Time, time.Time, error) {
+	end, err := parseAnalyticsDay(now.Format(analyticsDayLayout))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if to != "" {
+		if end, err = parseAnalyticsDay(to); err != nil {
+			return time.Time{}, time.Time{}, ErrAnalyticsRangeInvalid
+		}
+	}
+	start := end.AddDate(0, 0, -(defaultAnalyticsDays - 1))
+	if from != "" {
+		if start, err = parseAnalyticsDay(from); err != nil {
+			return time.Time{}, time.Time{}, ErrAnalyticsRangeInvalid
+		}
+	}
+	if start.After(end) || !end.Before(start.AddDate(0, 0, maxDays)) {
+		return time.Time{}, time.Time{}, ErrAnalyticsRangeInvalid
+	}
+	return start, end, nil
+}
+
+// parseAnalyticsDay 解析 YYYY-MM-DD；MySQL 连接使用本地时区，DATE 列按本地零点读写
+func parseAnalyticsDay(day string) (time.Time, error) {
+	return time.ParseInLocation(analyticsDayLayout, day, time.Local)
+}
+
+func analyticsEnabled() bool {
+	return config.Cfg != nil && config.Cfg.Analytics != nil && config.Cfg.Analytics.Enabled
+}
+
+func maxAnalyticsRangeDays() int {
+	if config.Cfg != nil && config.Cfg.Analytics != nil && config.Cfg.Analytics.MaxRangeDays > 0 {
+		return config.Cfg.Analytics.MaxRangeDays
+	}
+	return defaultMaxRangeDays
+}
+
+func parseCounts(values map[string]string) map[string]int64 {
+	counts := make(map[string]int64, len(values))
+	for k, v := range values {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			counts[k] = n
+		}
+	}
+	return counts
+}
+
+// topCounts 按次数降序取前 limit 个
+func topCounts(counts map[string]int64, limit int) []model.ReferrerCount {
+	top := make([]model.ReferrerCount, 0, len(counts))
+	for referrer, views := range counts {
+		top = append(top, model.ReferrerCount{Referrer: referrer, Views: views})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Views != top[j].Views {
+			return top[i].Views > top[j].Views
+		}
+		return top[i].Referrer < top[j].Referrer
+	})
+	if len(top) > limit {
+		top = top[:limit]
+	}
+	return top
+}
+
+func containsAny(s string, subs ...string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+// TestDeviceClass tests user agent classification
+func TestDeviceClass(t *testing.T) {
+	tests := []struct {
+		ua   string
+		want string
+	}{
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36", DeviceDesktop},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148", DeviceMobile},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36", DeviceMobile},
+		{"Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 Chrome/120.0 Safari/537.36", DeviceTablet},
+		{"Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X) AppleWebKit/605.1.15", DeviceTablet},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", DeviceBot},
+		{"curl/8.4.0", DeviceBot},
+		{"", DeviceBot},
+	}
+	for _, tt := range tests {
+		if got := DeviceClass(tt.ua); got != tt.want {
+			t.Errorf("DeviceClass(%q) = %s, want %s", tt.ua, got, tt.want)
+		}
+	}
+}
+
+// TestReferrerHost tests that only the referring host is kept
+func TestReferrerHost(t *testing.T) {
+	tests := []struct {
+		referer string
+		want    string
+	}{
+		{"", directReferrer},
+		{"https://www.Google.com/search?q=secret", "google.com"},
+		{"https://news.ycombinator.com/item?id=1", "news.ycombinator.com"},
+		{"https://example.com:8080/p/other", directReferrer},
+		{"not a url", directReferrer},
+	}
+	for _, tt := range tests {
+		if got := ReferrerHost(tt.referer, "www.example.com:8080"); got != tt.want {
+			t.Errorf("ReferrerHost(%q) = %s, want %s", tt.referer, got, tt.want)
+		}
+	}
+}
+
+// TestAnalyticsRange tests date range defaults and limits
+func TestAnalyticsRange(t *testing.T) {
+	now := time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
+
+	start, end, err := analyticsRange("", "", now, 366)
+	if err != nil || start.Format(analyticsDayLayout) != "2026-03-02" || end.Format(analyticsDayLayout) != "2026-03-31" {
+		t.Errorf("default range = %v..%v, %v", start, end, err)
+	}
+
+	start, end, err = analyticsRange("2026-01-01", "2026-01-01", now, 366)
+	if err != nil || !start.Equal(end) {
+		t.Errorf("single day range = %v..%v, %v", start, end, err)
+	}
+
+	invalid := [][2]string{
+		{"2026-02-01", "2026-01-01"}, // 倒序
+		{"2026/01/01", ""},           // 格式错误
+		{"2025-01-01", "2026-01-01"}, // 超过最大天数
+	}
+	for _, r := range invalid {
+		if _, _, err := analyticsRange(r[0], r[1], now, 365); err != ErrAnalyticsRangeInvalid {
+			t.Errorf("analyticsRange(%s, %s) error = %v", r[0], r[1], err)
+		}
+	}
+	if _, _, err := analyticsRange("2025-01-02", "2026-01-01", now, 365); err != nil {
+		t.Errorf("range of exactly max days error = %v", err)
+	}
+}
+
+// TestVisitorID tests that visitor IDs depend on the daily salt
+func TestVisitorID(t *testing.T) {
+	view := &PageView{ProjectID: 1, IP: "203.0.113.7", UserAgent: "Mozilla/5.0"}
+	if visitorID("a", view) != visitorID("a", view) {
+		t.Error("visitorID() is not stable within a day")
+	}
+	if visitorID("a", view) == visitorID("b", view) {
+		t.Error("visitorID() does not change with the salt")
+	}
+	other := *view
+	other.ProjectID = 2
+	if visitorID("a", view) == visitorID("a", &other) {
+		t.Error("visitorID() is shared across projects")
+	}
+}
+
+// TestTopCounts tests referrer ranking
+func TestTopCounts(t *testing.T) {
+	top := topCounts(map[string]int64{"b.com": 5, "a.com": 5, "c.com": 9, "d.com": 1}, 3)
+	want := []string{"c.com", "a.com", "b.com"}
+	if len(top) != len(want) {
+		t.Fatalf("topCounts() = %v", top)
+	}
+	for i, r := range top {
+		if r.Referrer != want[i] {
+			t.Errorf("topCounts()[%d] = %s, want %s", i, r.Referrer, want[i])
+		}
+	}
+}
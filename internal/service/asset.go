@@ -0,0 +1,256 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/test-tt/config"
+	"github.com/test-tt/internal/dao"
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/logger"
+	"github.com/test-tt/pkg/storage"
+)
+
+const (
+	// assetPathPrefix 资源公开访问路径前缀（与路由 /assets/:project_id/:file 对应）
+	assetPathPrefix = "/assets/"
+
+	defaultAssetMaxFileSize = 2 * 1024 * 1024 // 单文件 2MB
+	assetSniffLen           = 512
+)
+
+var (
+	ErrAssetNotFound       = errors.New("asset not found")
+	ErrAssetTooLarge       = errors.New("asset exceeds size limit")
+	ErrAssetTypeNotAllowed = errors.New("asset type not allowed")
+	ErrAssetQuotaExceeded  = errors.New("asset storage quota exceeded")
+)
+
+// allowedAssetTypes 允许上传的内容类型（以嗅探结果为准，不信任客户端声明）及其规范扩展名
+var allowedAssetTypes = map[string]string{
+	"image/png":                     ".png",
+	"image/jpeg":                    ".jpg",
+	"image/gif":                     ".gif",
+	"image/webp":                    ".webp",
+	"image/x-icon":                  ".ico",
+	"image/svg+xml":                 ".svg",
+	"font/woff":                     ".woff",
+	"font/woff2":                    ".woff2",
+	"font/ttf":                      ".ttf",
+	"font/otf":                      ".otf",
+	"application/vnd.ms-fontobject": ".eot",
+}
+
+// SniffAssetType detects the content type of an upload, returns "" if the type is not allowed
+// SVG 无法通过魔数识别，需同时满足 .svg 扩展名和包含 <svg 根元素
+func SniffAssetType(filename string, data []byte) string {
+	head := data
+	if len(head) > assetSniffLen {
+		head = head[:assetSniffLen]
+	}
+	contentType := http.DetectContentType(head)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+
+	if strings.EqualFold(path.Ext(filename), ".svg") &&
+		(contentType == "text/xml" || contentType == "text/plain") &&
+		bytes.Contains(bytes.ToLower(data), []byte("<svg")) {
+		return "image/svg+xml"
+	}
+
+	if _, ok := allowedAssetTypes[contentType]; ok {
+		return contentType
+	}
+	return ""
+}
+
+// AssetURL returns the stable public URL of an asset
+func AssetURL(asset *model.ProjectAsset) string {
+	return assetPathPrefix + asset.StorageKey
+}
+
+type AssetService struct {
+	projectService *ProjectService
+	assetDAO       *dao.AssetDAO
+	quotaService   *QuotaService
+	maxFileSize    int64
+}
+
+func NewAssetService() *AssetService {
+	s := &AssetService{
+		projectService: NewProjectService(),
+		assetDAO:       dao.NewAssetDAO(),
+		quotaService:   NewQuotaService(),
+		maxFileSize:    defaultAssetMaxFileSize,
+	}
+	if config.Cfg != nil && config.Cfg.Storage != nil && config.Cfg.Storage.MaxFileSize > 0 {
+		s.maxFileSize = config.Cfg.Storage.MaxFileSize
+	}
+	return s
+}
+
+// MaxFileSize returns the per-file upload limit in bytes
+func (s *AssetService) MaxFileSize() int64 {
+	return s.maxFileSize
+}
+
+// Upload stores an asset for a project
+// 存储 key 由内容哈希生成，同一项目重复上传相同内容直接返回已有记录
+func (s *AssetService) Upload(ctx context.Context, projectID, userID uint64, filename string, r io.Reader) (*model.ProjectAsset, error) {
+	if _, err := s.projectService.GetByID(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, s.maxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.maxFileSize {
+		return nil, ErrAssetTooLarge
+	}
+
+	contentType := SniffAssetType(filename, data)
+	if contentType == "" {
+		return nil, ErrAssetTypeNotAllowed
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	key := fmt.Sprintf("%d/%s%s", projectID, hash[:32], allowedAssetTypes[contentType])
+
+	existing, err := s.assetDAO.GetByStorageKey(ctx, projectID, key)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		existing.URL = AssetURL(existing)
+		return existing, nil
+	}
+
+	if err := s.quotaService.CheckAssets(ctx, userID, int64(len(data))); err != nil {
+		return nil, err
+	}
+
+	if err := storage.Default().Put(ctx, key, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	asset := &model.ProjectAsset{
+		ProjectID:   projectID,
+		UserID:      userID,
+		Filename:    sanitizeAssetFilename(filename),
+		StorageKey:  key,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		SHA256:      hash,
+	}
+	if err := s.assetDAO.Create(ctx, asset); err != nil {
+		// 记录写入失败时回收已存储的对象
+		if delErr := storage.Default().Delete(ctx, key); delErr != nil {
+			logger.WarnCtxf(ctx, "failed to remove orphaned asset object", "key", key, "error", delErr)
+		}
+		return nil, err
+	}
+
+	asset.URL = AssetURL(asset)
+	return asset, nil
+}
+
+// List lists the assets of a project with ownership check
+func (s *AssetService) List(ctx context.Context, projectID, userID uint64) ([]model.ProjectAsset, error) {
+	if _, err := s.projectService.GetByID(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+	assets, err := s.assetDAO.ListByProjectID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range assets {
+		assets[i].URL = AssetURL(&assets[i])
+	}
+	return assets, nil
+}
+
+// Delete removes an asset with ownership check
+func (s *AssetService) Delete(ctx context.Context, projectID, assetID, userID uint64) error {
+	if _, err := s.projectService.GetByID(ctx, projectID, userID); err != nil {
+		return err
+	}
+
+	asset, err := s.assetDAO.GetByID(ctx, assetID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrAssetNotFound
+		}
+		return err
+	}
+	if asset.ProjectID != projectID {
+		return ErrAssetNotFound
+	}
+
+	if err := s.assetDAO.Delete(ctx, assetID); err != nil {
+		return err
+	}
+	if err := storage.Default().Delete(ctx, asset.StorageKey); err != nil {
+		logger.WarnCtxf(ctx, "failed to delete asset object", "key", asset.StorageKey, "error", err)
+	}
+	return nil
+}
+
+// Open returns a public asset by project ID and file name for serving
+func (s *AssetService) Open(ctx context.Context, projectID uint64, file string) (*model.ProjectAsset, io.ReadCloser, error) {
+	key := fmt.Sprintf("%d/%s", projectID, file)
+	asset, err := s.assetDAO.GetByStorageKey(ctx, projectID, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if asset == nil {
+		return nil, nil, ErrAssetNotFound
+	}
+
+	rc, err := storage.Default().Open(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, ErrAssetNotFound
+		}
+		return nil, nil, err
+	}
+	return asset, rc, nil
+}
+
+// purgeProjectAssets 删除项目的全部资源（存储对象和记录）
+func purgeProjectAssets(ctx context.Context, assetDAO *dao.AssetDAO, projectID uint64) error {
+	assets, err := assetDAO.ListByProjectID(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	for i := range assets {
+		if err := storage.Default().Delete(ctx, assets[i].StorageKey); err != nil {
+			logger.WarnCtxf(ctx, "failed to delete asset object", "key", assets[i].StorageKey, "error", err)
+		}
+	}
+	return assetDAO.DeleteByProjectID(ctx, projectID)
+}
+
+// sanitizeAssetFilename 只保留文件名部分并限制长度
+func sanitizeAssetFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		name = "upload"
+	}
+	if runes := []rune(name); len(runes) > 255 {
+		name = string(runes[:255])
+	}
+	return name
+}
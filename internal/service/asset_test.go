@@ -0,0 +1,60 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/test-tt/internal/model"
+)
+
+// TestSniffAssetType tests content-based type detection
+func TestSniffAssetType(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	woff2 := []byte("wOF2\x00\x01\x00\x00")
+	svg := []byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"></svg>`)
+
+	tests := []struct {
+		name     string
+		filename string
+		data     []byte
+		want     string
+	}{
+		{"png", "logo.png", png, "image/png"},
+		{"png with wrong extension", "logo.txt", png, "image/png"},
+		{"woff2", "font.woff2", woff2, "font/woff2"},
+		{"svg", "icon.svg", svg, "image/svg+xml"},
+		{"svg content without extension", "icon.xml", svg, ""},
+		{"html disguised as image", "evil.png", []byte("<html><script>alert(1)</script></html>"), ""},
+		{"plain text", "notes.svg", []byte("just text"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SniffAssetType(tt.filename, tt.data); got != tt.want {
+				t.Errorf("SniffAssetType(%q) = %q, want %q", tt.filename, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSanitizeAssetFilename tests that only the base name is kept
+func TestSanitizeAssetFilename(t *testing.T) {
+	tests := map[string]string{
+		"logo.png":              "logo.png",
+		"../../etc/passwd":      "passwd",
+		`C:\Users\me\photo.jpg`: "photo.jpg",
+		"":                      "upload",
+	}
+	for in, want := range tests {
+		if got := sanitizeAssetFilename(in); got != want {
+			t.Errorf("sanitizeAssetFilename(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// TestAssetURL tests stable asset URLs
+func TestAssetURL(t *testing.T) {
+	asset := &model.ProjectAsset{StorageKey: "12/abcdef.png"}
+	if got := AssetURL(asset); got != "/assets/12/abcdef.png" {
+		t.Errorf("AssetURL() = %q", got)
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/test-tt/internal/dao"
+	"github.com/test-tt/pkg/logger"
+)
+
+const (
+	contentMigrationBatchSize = 100
+	contentMigrationPause     = time.Second
+)
+
+// StartContentMigration 在后台把仍内联存储 html/css 的项目和快照迁入 content_blobs
+// 分批执行直到没有剩余行，批次之间暂停以免占满数据库
+// 返回停止函数；多实例同时运行是安全的（逐行加锁）
+func StartContentMigration() func() {
+	stopChan := make(chan struct{})
+	projectDAO := dao.NewProjectDAO()
+	snapshotDAO := dao.NewSnapshotDAO()
+
+	go func() {
+		steps := []struct {
+			name    string
+			migrate func(ctx context.Context, limit int) (int, error)
+		}{
+			{"projects", projectDAO.MigrateInlineContent},
+			{"snapshots", snapshotDAO.MigrateInlineContent},
+		}
+		for _, step := range steps {
+			total := 0
+			for {
+				migrated, err := step.migrate(context.Background(), contentMigrationBatchSize)
+				total += migrated
+				if err != nil {
+					logger.Errorf("failed to migrate inline content", "table", step.name, "migrated", total, "error", err)
+					return
+				}
+				if migrated < contentMigrationBatchSize {
+					break
+				}
+				select {
+				case <-time.After(contentMigrationPause):
+				case <-stopChan:
+					return
+				}
+			}
+			if total > 0 {
+				logger.Infof("migrated inline content to blobs", "table", step.name, "count", total)
+			}
+		}
+	}()
+
+	return func() {
+		close(stopChan)
+	}
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/test-tt/internal/model"
+)
+
+// 项目导出包格式
+// index.html、style.css、messages.json 为项目内容，manifest.json 记录元数据
+// 版本 2 起包中还可以有项目的其余文件，按原路径存放
+const (
+	BundleFormatVersion = 2
+
+	bundleManifestFile = "manifest.json"
+	bundleHTMLFile     = "index.html"
+	bundleCSSFile      = "style.css"
+	bundleMessagesFile = "messages.json"
+
+	// MaxBundleSize 导入包（压缩后）的最大体积，与服务器请求体限制一致
+	MaxBundleSize = 4 * 1024 * 1024
+	// maxBundleEntrySize 单个文件解压后的最大体积，防止 zip 炸弹
+	maxBundleEntrySize = 32 * 1024 * 1024
+	// maxBundleTotalSize 所有文件解压后的总体积上限
+	maxBundleTotalSize = 64 * 1024 * 1024
+)
+
+var (
+	ErrBundleInvalid  = errors.New("invalid project bundle")
+	ErrBundleTooLarge = errors.New("project bundle too large")
+)
+
+// BundleManifest is the manifest.json of a project bundle
+type BundleManifest struct {
+	FormatVersion int       `json:"format_version"`
+	Name          string    `json:"name"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	ExportedAt    time.Time `json:"exported_at"`
+}
+
+// Bundle is the parsed content of a project bundle
+type Bundle struct {
+	Manifest BundleManifest
+	HTML     string
+	CSS      string
+	Messages string
+	Files    []model.ProjectFile // Files other than index.html and style.css
+}
+
+// WriteBundle streams a project and its extra files as a ZIP bundle to w
+// 逐个文件写入 zip.Writer，不在内存中拼装整个压缩包
+func WriteBundle(w io.Writer, project *model.Project, projectFiles []model.ProjectFile) error {
+	zw := zip.NewWriter(w)
+
+	manifest, err := json.MarshalIndent(BundleManifest{
+		FormatVersion: BundleFormatVersion,
+		Name:          project.Name,
+		CreatedAt:     project.CreatedAt,
+		UpdatedAt:     project.UpdatedAt,
+		ExportedAt:    time.Now(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	messages := project.Messages
+	if strings.TrimSpace(messages) == "" {
+		messages = "[]"
+	}
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{bundleManifestFile, string(manifest)},
+		{bundleHTMLFile, project.HTML},
+		{bundleCSSFile, project.CSS},
+		{bundleMessagesFile, messages},
+	}
+	for _, f := range projectFiles {
+		files = append(files, struct {
+			name    string
+			content string
+		}{f.Path, f.Content})
+	}
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     f.name,
+			Method:   zip.Deflate,
+			Modified: project.UpdatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.content); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// ReadBundle parses and validates a ZIP bundle
+// 只接受约定的文件名和合法的项目文件路径，校验解压后体积、manifest 版本和 messages 的 JSON 格式
+func ReadBundle(r io.ReaderAt, size int64) (*Bundle, error) {
+	if size > MaxBundleSize {
+		return nil, ErrBundleTooLarge
+	}
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBundleInvalid, err)
+	}
+
+	contents := make(map[string]string, 4)
+	var projectFiles []model.ProjectFile
+	var total int64
+	for _, f := range zr.File {
+		extra := false
+		switch f.Name {
+		case bundleManifestFile, bundleHTMLFile, bundleCSSFile, bundleMessagesFile:
+		default:
+			if p, err := CleanFilePath(f.Name); err != nil || p != f.Name {
+				return nil, fmt.Errorf("%w: unexpected file %q", ErrBundleInvalid, f.Name)
+			}
+			if len(projectFiles) == maxProjectFiles {
+				return nil, fmt.Errorf("%w: more than %d files", ErrBundleInvalid, maxProjectFiles)
+			}
+			extra = true
+		}
+		if _, dup := contents[f.Name]; dup {
+			return nil, fmt.Errorf("%w: duplicate file %q", ErrBundleInvalid, f.Name)
+		}
+
+		data, err := readBundleEntry(f)
+		if err != nil {
+			return nil, err
+		}
+		total += int64(len(data))
+		if total > maxBundleTotalSize {
+			return nil, ErrBundleTooLarge
+		}
+		contents[f.Name] = data
+		if extra {
+			projectFiles = append(projectFiles, model.ProjectFile{Path: f.Name, Content: data})
+		}
+	}
+
+	manifestData, ok := contents[bundleManifestFile]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s", ErrBundleInvalid, bundleManifestFile)
+	}
+	var manifest BundleManifest
+	if err := json.Unmarshal([]byte(manifestData), &manifest); err != nil {
+		return nil, fmt.Errorf("%w: malformed %s", ErrBundleInvalid, bundleManifestFile)
+	}
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > BundleFormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format version %d", ErrBundleInvalid, manifest.FormatVersion)
+	}
+
+	html, ok := contents[bundleHTMLFile]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s", ErrBundleInvalid, bundleHTMLFile)
+	}
+
+	messages := strings.TrimSpace(contents[bundleMessagesFile])
+	if messages == "" {
+		messages = "[]"
+	}
+	var turns []json.RawMessage
+	if err := json.Unmarshal([]byte(messages), &turns); err != nil {
+		return nil, fmt.Errorf("%w: %s must be a JSON array", ErrBundleInvalid, bundleMessagesFile)
+	}
+
+	return &Bundle{
+		Manifest: manifest,
+		HTML:     html,
+		CSS:      contents[bundleCSSFile],
+		Messages: messages,
+		Files:    projectFiles,
+	}, nil
+}
+
+// readBundleEntry 读取单个文件，超过大小限制即中止（不信任头部声明的大小）
+func readBundleEntry(f *zip.File) (string, error) {
+	if f.UncompressedSize64 > maxBundleEntrySize {
+		return "", ErrBundleTooLarge
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrBundleInvalid, err)
+	}
+	defer rc.Close()
+
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, io.LimitReader(rc, maxBundleEntrySize+1))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrBundleInvalid, err)
+	}
+	if n > maxBundleEntrySize {
+		return "", ErrBundleTooLarge
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/test-tt/internal/model"
+)
+
+// buildZip 构造测试用 ZIP
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestBundleRoundTrip tests that an exported bundle can be imported
+func TestBundleRoundTrip(t *testing.T) {
+	project := &model.Project{
+		Name:      "My Site",
+		HTML:      "<html><body>Hi</body></html>",
+		CSS:       "body{color:red}",
+		Messages:  `[{"role":"user","content":"make it red"}]`,
+		CreatedAt: time.Now().Add(-time.Hour),
+		UpdatedAt: time.Now(),
+	}
+
+	files := []model.ProjectFile{
+		{Path: "about.html", Content: "<p>About</p>"},
+		{Path: "js/app.js", Content: "console.log(1)"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteBundle(&buf, project, files); err != nil {
+		t.Fatalf("WriteBundle() error: %v", err)
+	}
+
+	bundle, err := ReadBundle(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("ReadBundle() error: %v", err)
+	}
+	if bundle.HTML != project.HTML || bundle.CSS != project.CSS || bundle.Messages != project.Messages {
+		t.Errorf("content mismatch: %+v", bundle)
+	}
+	if bundle.Manifest.Name != project.Name || bundle.Manifest.FormatVersion != BundleFormatVersion {
+		t.Errorf("manifest mismatch: %+v", bundle.Manifest)
+	}
+	if len(bundle.Files) != len(files) {
+		t.Fatalf("files = %+v, want %d", bundle.Files, len(files))
+	}
+	for i, f := range bundle.Files {
+		if f.Path != files[i].Path || f.Content != files[i].Content {
+			t.Errorf("file %d = %+v, want %+v", i, f, files[i])
+		}
+	}
+}
+
+// TestReadBundleValidation tests rejection of malformed bundles
+func TestReadBundleValidation(t *testing.T) {
+	manifest := `{"format_version":1,"name":"x"}`
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"missing manifest", map[string]string{"index.html": "<p>"}},
+		{"missing html", map[string]string{"manifest.json": manifest}},
+		{"unknown file", map[string]string{"manifest.json": manifest, "index.html": "<p>", "../evil.sh": "x"}},
+		{"unsupported type", map[string]string{"manifest.json": manifest, "index.html": "<p>", "pages/a.php": "x"}},
+		{"hidden file", map[string]string{"manifest.json": manifest, "index.html": "<p>", ".env/a.js": "x"}},
+		{"future version", map[string]string{"manifest.json": `{"format_version":99}`, "index.html": "<p>"}},
+		{"messages not array", map[string]string{"manifest.json": manifest, "index.html": "<p>", "messages.json": `{}`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := buildZip(t, tt.files)
+			_, err := ReadBundle(bytes.NewReader(data), int64(len(data)))
+			if !errors.Is(err, ErrBundleInvalid) {
+				t.Errorf("ReadBundle() error = %v, want ErrBundleInvalid", err)
+			}
+		})
+	}
+
+	t.Run("not a zip", func(t *testing.T) {
+		data := []byte("plain text")
+		if _, err := ReadBundle(bytes.NewReader(data), int64(len(data))); !errors.Is(err, ErrBundleInvalid) {
+			t.Errorf("ReadBundle() error = %v, want ErrBundleInvalid", err)
+		}
+	})
+
+	t.Run("oversized entry", func(t *testing.T) {
+		data := buildZip(t, map[string]string{
+			"manifest.json": manifest,
+			"index.html":    strings.Repeat("a", maxBundleEntrySize+1),
+		})
+		if _, err := ReadBundle(bytes.NewReader(data), int64(len(data))); !errors.Is(err, ErrBundleTooLarge) {
+			t.Errorf("ReadBundle() error = %v, want ErrBundleTooLarge", err)
+		}
+	})
+
+	t.Run("defaults optional files", func(t *testing.T) {
+		data := buildZip(t, map[string]string{"manifest.json": manifest, "index.html": "<p>"})
+		bundle, err := ReadBundle(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("ReadBundle() error: %v", err)
+		}
+		if bundle.CSS != "" || bundle.Messages != "[]" {
+			t.Errorf("unexpected defaults: %+v", bundle)
+		}
+	})
+}
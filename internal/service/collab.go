@@ -0,0 +1,769 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bytedance/sonic"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/cache"
+	"github.com/test-tt/pkg/logger"
+	"github.com/test-tt/pkg/sanitize"
+)
+
+// 协作编辑消息类型
+const (
+	CollabTypeWelcome  = "welcome"  // 服务端 -> 客户端：连接成功，携带当前内容、版本和在线成员
+	CollabTypeEdit     = "edit"     // 双向：提交或广播某个区域的新内容
+	CollabTypeAck      = "ack"      // 服务端 -> 客户端：编辑已保存
+	CollabTypeConflict = "conflict" // 服务端 -> 客户端：base_version 过期，携带最新内容
+	CollabTypePresence = "presence" // 双向：在线状态与光标/选区
+	CollabTypeLeave    = "leave"    // 服务端 -> 客户端：有人离开
+	CollabTypeChat     = "chat"     // 双向：聊天消息
+	CollabTypePing     = "ping"     // 客户端 -> 服务端：应用层心跳
+	CollabTypePong     = "pong"     // 服务端 -> 客户端：心跳响应
+	CollabTypeError    = "error"    // 服务端 -> 客户端：请求失败
+)
+
+const (
+	// MaxCollabMessageSize 单条消息上限，编辑消息携带整个区域的内容
+	MaxCollabMessageSize = 4 << 20
+
+	collabChannelKey  = "collab:project:%d"
+	collabVersionKey  = "collab:project:%d:versions"
+	collabPresenceKey = "collab:project:%d:presence"
+	collabLockKey     = "collab:project:%d:lock"
+
+	collabVersionTTL  = 24 * time.Hour
+	collabPresenceTTL = 2 * time.Minute
+	collabLockTTL     = 10 * time.Second
+	collabLockWait    = 5 * time.Second
+	collabLockRetry   = 20 * time.Millisecond
+	collabSendBuffer  = 64
+	collabLocalLocks  = 64
+	maxCollabChatLen  = 2000
+)
+
+var (
+	ErrCollabMessageInvalid = errors.New("invalid collaboration message")
+	ErrCollabBusy           = errors.New("project is busy, try again")
+)
+
+// collabUnlockScript 仅释放自己持有的锁
+var collabUnlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// CollabMessage is a message exchanged over the collaboration socket
+// 编辑采用按区域的最后写入者胜出：base_version 与服务端版本一致才会保存，否则返回 conflict
+type CollabMessage struct {
+	Type        string             `json:"type"`
+	Ref         string             `json:"ref,omitempty"` // 客户端请求标识，原样带回 ack/conflict/error
+	Session     string             `json:"session,omitempty"`
+	UserID      uint64             `json:"user_id,omitempty"`
+	Name        string             `json:"name,omitempty"`
+	Region      string             `json:"region,omitempty"`
+	BaseVersion int64              `json:"base_version,omitempty"`
+	Version     int64              `json:"version,omitempty"`
+	Content     *string            `json:"content,omitempty"`
+	Warnings    []sanitize.Warning `json:"warnings,omitempty"`
+	Document    *CollabDocument    `json:"document,omitempty"`
+	Presence    *CollabPresence    `json:"presence,omitempty"`
+	Peers       []*CollabPresence  `json:"peers,omitempty"`
+	Text        string             `json:"text,omitempty"`
+	Error       string             `json:"error,omitempty"`
+	Time        int64              `json:"time,omitempty"` // 毫秒时间戳
+}
+
+// CollabDocument is the project content sent to a client when it joins
+type CollabDocument struct {
+	Name     string           `json:"name"`
+	HTML     string           `json:"html"`
+	CSS      string           `json:"css"`
+	Versions map[string]int64 `json:"versions"`
+}
+
+// CollabPresence is who is viewing a project and where their cursor or selection is
+type CollabPresence struct {
+	Session string `json:"session"`
+	UserID  uint64 `json:"user_id"`
+	Name    string `json:"name"`
+	Role    string `json:"role"`
+	Region  string `json:"region,omitempty"`
+	Start   int    `json:"start,omitempty"` // 选区起止偏移，相等时为光标
+	End     int    `json:"end,omitempty"`
+	Path    string `json:"path,omitempty"` // 可选的元素路径，如预览中选中的节点
+	At      int64  `json:"at"`
+}
+
+// collabEnvelope 房间内广播的消息，Skip 为不需要接收的发送方会话
+type collabEnvelope struct {
+	Skip string          `json:"skip,omitempty"`
+	Msg  json.RawMessage `json:"msg"`
+}
+
+// CollabClient is one socket connected to a project room
+type CollabClient struct {
+	Session   string
+	UserID    uint64
+	Name      string
+	Role      string
+	ProjectID uint64
+
+	send      chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewCollabClient creates a client with a new session ID
+func NewCollabClient(projectID, userID uint64, name, role string) *CollabClient {
+	return &CollabClient{
+		Session:   uuid.NewString(),
+		UserID:    userID,
+		Name:      name,
+		Role:      role,
+		ProjectID: projectID,
+		send:      make(chan []byte, collabSendBuffer),
+		done:      make(chan struct{}),
+	}
+}
+
+// Send returns the messages to write to the socket
+func (c *CollabClient) Send() <-chan []byte {
+	return c.send
+}
+
+// Done is closed when the client should disconnect
+func (c *CollabClient) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close asks the connection to shut down
+func (c *CollabClient) Close() {
+	c.closeOnce.Do(func() { close(c.done) })
+}
+
+// enqueue 非阻塞写入发送队列，队列满说明客户端过慢，直接断开
+func (c *CollabClient) enqueue(data []byte) {
+	select {
+	case <-c.done:
+	case c.send <- data:
+	default:
+		c.Close()
+	}
+}
+
+// CollabHub routes collaboration messages between the clients of each project
+// 本实例只持有连接，跨实例的广播、版本号、在线状态和编辑锁都放在 Redis；未配置 Redis 时退化为单实例内存实现
+type CollabHub struct {
+	rdb            *redis.Client
+	projectService collabProjects
+
+	mu    sync.Mutex
+	rooms map[uint64]*collabRoom
+
+	// 以下仅在未配置 Redis 时使用
+	versions map[uint64]map[string]int64
+	locks    [collabLocalLocks]sync.Mutex
+}
+
+// collabProjects 协作编辑读写项目内容的方法，由 ProjectService 实现
+type collabProjects interface {
+	Access(ctx context.Context, id, userID uint64) (*model.Project, string, error)
+	SaveRegion(ctx context.Context, id, userID uint64, region, content string) (*SavedProject, error)
+}
+
+type collabRoom struct {
+	clients  map[*CollabClient]struct{}
+	presence map[string]*CollabPresence // 仅未配置 Redis 时使用
+	pubsub   *redis.PubSub
+}
+
+var (
+	collabHubOnce sync.Once
+	collabHub     *CollabHub
+)
+
+// GetCollabHub returns the process-wide hub
+func GetCollabHub() *CollabHub {
+	collabHubOnce.Do(func() {
+		collabHub = NewCollabHub(cache.RDB, NewProjectService())
+	})
+	return collabHub
+}
+
+// NewCollabHub creates a hub, rdb may be nil for a single instance
+func NewCollabHub(rdb *redis.Client, projectService collabProjects) *CollabHub {
+	return &CollabHub{
+		rdb:            rdb,
+		projectService: projectService,
+		rooms:          make(map[uint64]*collabRoom),
+		versions:       make(map[uint64]map[string]int64),
+	}
+}
+
+// Join adds a client to its project room and returns the welcome message
+func (h *CollabHub) Join(ctx context.Context, client *CollabClient) (*CollabMessage, error) {
+	project, versions, err := h.document(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.register(ctx, client); err != nil {
+		return nil, err
+	}
+
+	peers, err := h.peers(ctx, client.ProjectID)
+	if err != nil {
+		logger.WarnCtxf(ctx, "failed to load collab presence", "projectID", client.ProjectID, "error", err)
+	}
+
+	presence := h.presenceOf(client, nil)
+	h.storePresence(ctx, client.ProjectID, presence)
+	h.publish(ctx, client.ProjectID, client.Session, &CollabMessage{Type: CollabTypePresence, Presence: presence})
+
+	return &CollabMessage{
+		Type:    CollabTypeWelcome,
+		Session: client.Session,
+		UserID:  client.UserID,
+		Name:    client.Name,
+		Document: &CollabDocument{
+			Name:     project.Name,
+			HTML:     project.HTML,
+			CSS:      project.CSS,
+			Versions: versions,
+		},
+		Presence: presence,
+		Peers:    peers,
+		Time:     nowMillis(),
+	}, nil
+}
+
+// Leave removes a client from its room and tells the others
+func (h *CollabHub) Leave(ctx context.Context, client *CollabClient) {
+	client.Close()
+	h.removePresence(ctx, client.ProjectID, client.Session)
+	h.publish(ctx, client.ProjectID, client.Session, &CollabMessage{
+		Type:    CollabTypeLeave,
+		Session: client.Session,
+		UserID:  client.UserID,
+		Name:    client.Name,
+		Time:    nowMillis(),
+	})
+	h.unregister(client)
+}
+
+// Touch refreshes the client's presence so it does not expire while connected
+func (h *CollabHub) Touch(ctx context.Context, client *CollabClient) {
+	if h.rdb == nil {
+		return
+	}
+	key := fmt.Sprintf(collabPresenceKey, client.ProjectID)
+	data, err := h.rdb.HGet(ctx, key, client.Session).Result()
+	if err != nil {
+		return
+	}
+	var presence CollabPresence
+	if err := sonic.UnmarshalString(data, &presence); err != nil {
+		return
+	}
+	presence.At = nowMillis()
+	h.storePresence(ctx, client.ProjectID, &presence)
+}
+
+// Handle processes one message received from a client
+func (h *CollabHub) Handle(ctx context.Context, client *CollabClient, data []byte) {
+	msg, err := ParseCollabMessage(data)
+	if err != nil {
+		h.reply(client, &CollabMessage{Type: CollabTypeError, Error: err.Error()})
+		return
+	}
+
+	switch msg.Type {
+	case CollabTypeEdit:
+		h.edit(ctx, client, msg)
+	case CollabTypePresence:
+		presence := h.presenceOf(client, msg.Presence)
+		h.storePresence(ctx, client.ProjectID, presence)
+		h.publish(ctx, client.ProjectID, client.Session, &CollabMessage{Type: CollabTypePresence, Presence: presence})
+	case CollabTypeChat:
+		h.publish(ctx, client.ProjectID, "", &CollabMessage{
+			Type:    CollabTypeChat,
+			Ref:     msg.Ref,
+			Session: client.Session,
+			UserID:  client.UserID,
+			Name:    client.Name,
+			Text:    msg.Text,
+			Time:    nowMillis(),
+		})
+	case CollabTypePing:
+		h.reply(client, &CollabMessage{Type: CollabTypePong, Ref: msg.Ref, Time: nowMillis()})
+	}
+}
+
+// edit 保存一个区域的新内容：加锁后比较版本，一致则清洗保存、版本加一并广播
+func (h *CollabHub) edit(ctx context.Context, client *CollabClient, msg *CollabMessage) {
+	fail := func(err error) {
+		h.reply(client, &CollabMessage{Type: CollabTypeError, Ref: msg.Ref, Region: msg.Region, Error: err.Error()})
+	}
+	if client.Role == model.MemberRoleViewer {
+		fail(ErrProjectReadOnly)
+		return
+	}
+
+	unlock, err := h.lock(ctx, client.ProjectID)
+	if err != nil {
+		fail(err)
+		return
+	}
+	defer unlock()
+
+	versions, err := h.versionsOf(ctx, client.ProjectID)
+	if err != nil {
+		logger.ErrorCtxf(ctx, "failed to get collab versions", "projectID", client.ProjectID, "error", err)
+		fail(ErrCollabBusy)
+		return
+	}
+	current := versions[msg.Region]
+	if msg.BaseVersion != current {
+		project, _, err := h.projectService.Access(ctx, client.ProjectID, client.UserID)
+		if err != nil {
+			fail(err)
+			return
+		}
+		content := regionContent(project, msg.Region)
+		h.reply(client, &CollabMessage{
+			Type:    CollabTypeConflict,
+			Ref:     msg.Ref,
+			Region:  msg.Region,
+			Version: current,
+			Content: &content,
+		})
+		return
+	}
+
+	saved, err := h.projectService.SaveRegion(ctx, client.ProjectID, client.UserID, msg.Region, *msg.Content)
+	if err != nil {
+		if !isCollabClientError(err) {
+			logger.ErrorCtxf(ctx, "failed to save collab edit", "projectID", client.ProjectID, "userID", client.UserID, "error", err)
+		}
+		fail(err)
+		return
+	}
+	version, err := h.bumpVersion(ctx, client.ProjectID, msg.Region)
+	if err != nil {
+		logger.ErrorCtxf(ctx, "failed to bump collab version", "projectID", client.ProjectID, "error", err)
+		fail(ErrCollabBusy)
+		return
+	}
+
+	content := regionContent(saved.Project, msg.Region)
+	ack := &CollabMessage{Type: CollabTypeAck, Ref: msg.Ref, Region: msg.Region, Version: version, Warnings: saved.Warnings}
+	// 清洗改动了内容时发送方需要同步
+	if content != *msg.Content {
+		ack.Content = &content
+	}
+	h.reply(client, ack)
+	h.publish(ctx, client.ProjectID, client.Session, &CollabMessage{
+		Type:    CollabTypeEdit,
+		Session: client.Session,
+		UserID:  client.UserID,
+		Name:    client.Name,
+		Region:  msg.Region,
+		Version: version,
+		Content: &content,
+		Time:    nowMillis(),
+	})
+}
+
+// Apply runs a save made outside the collaboration socket under the project's edit lock,
+// then bumps the versions of both regions and sends the saved content to the connected clients
+// 协作者基于旧版本的编辑随后会收到 conflict，而不是覆盖服务端保存的内容
+func (h *CollabHub) Apply(ctx context.Context, projectID, userID uint64, save func() (*model.Project, error)) error {
+	unlock, err := h.lock(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	project, err := save()
+	if err != nil {
+		return err
+	}
+	for _, region := range []string{RegionHTML, RegionCSS} {
+		version, err := h.bumpVersion(ctx, projectID, region)
+		if err != nil {
+			logger.ErrorCtxf(ctx, "failed to bump collab version", "projectID", projectID, "error", err)
+			continue
+		}
+		content := regionContent(project, region)
+		h.publish(ctx, projectID, "", &CollabMessage{
+			Type:    CollabTypeEdit,
+			UserID:  userID,
+			Region:  region,
+			Version: version,
+			Content: &content,
+			Time:    nowMillis(),
+		})
+	}
+	return nil
+}
+
+// document 在编辑锁内读取项目内容和版本，保证两者一致
+func (h *CollabHub) document(ctx context.Context, client *CollabClient) (*model.Project, map[string]int64, error) {
+	unlock, err := h.lock(ctx, client.ProjectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer unlock()
+
+	project, _, err := h.projectService.Access(ctx, client.ProjectID, client.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	versions, err := h.versionsOf(ctx, client.ProjectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return project, versions, nil
+}
+
+// ParseCollabMessage decodes and validates a message sent by a client
+func ParseCollabMessage(data []byte) (*CollabMessage, error) {
+	var msg CollabMessage
+	if err := sonic.Unmarshal(data, &msg); err != nil {
+		return nil, ErrCollabMessageInvalid
+	}
+
+	switch msg.Type {
+	case CollabTypeEdit:
+		if msg.Region != RegionHTML && msg.Region != RegionCSS {
+			return nil, ErrRegionInvalid
+		}
+		if msg.Content == nil || msg.BaseVersion < 0 {
+			return nil, ErrCollabMessageInvalid
+		}
+	case CollabTypePresence:
+		if msg.Presence == nil {
+			return nil, ErrCollabMessageInvalid
+		}
+		if msg.Presence.Region != "" && msg.Presence.Region != RegionHTML && msg.Presence.Region != RegionCSS {
+			return nil, ErrRegionInvalid
+		}
+	case CollabTypeChat:
+		msg.Text = strings.TrimSpace(msg.Text)
+		if msg.Text == "" || utf8.RuneCountInString(msg.Text) > maxCollabChatLen {
+			return nil, ErrCollabMessageInvalid
+		}
+	case CollabTypePing:
+	default:
+		return nil, ErrCollabMessageInvalid
+	}
+	return &msg, nil
+}
+
+// presenceOf 用连接身份覆盖客户端上报的在线状态，防止冒充
+func (h *CollabHub) presenceOf(client *CollabClient, reported *CollabPresence) *CollabPresence {
+	presence := &CollabPresence{}
+	if reported != nil {
+		presence.Region = reported.Region
+		presence.Start = reported.Start
+		presence.End = reported.End
+		presence.Path = reported.Path
+	}
+	presence.Session = client.Session
+	presence.UserID = client.UserID
+	presence.Name = client.Name
+	presence.Role = client.Role
+	presence.At = nowMillis()
+	return presence
+}
+
+// reply 只发给当前客户端
+func (h *CollabHub) reply(client *CollabClient, msg *CollabMessage) {
+	data, err := sonic.Marshal(msg)
+	if err != nil {
+		return
+	}
+	client.enqueue(data)
+}
+
+// publish 广播到项目房间（跨实例），skip 会话不接收
+func (h *CollabHub) publish(ctx context.Context, projectID uint64, skip string, msg *CollabMessage) {
+	data, err := sonic.Marshal(msg)
+	if err != nil {
+		return
+	}
+	envelope, err := sonic.Marshal(&collabEnvelope{Skip: skip, Msg: data})
+	if err != nil {
+		return
+	}
+	if h.rdb == nil {
+		h.deliver(projectID, envelope)
+		return
+	}
+	if err := h.rdb.Publish(ctx, fmt.Sprintf(collabChannelKey, projectID), envelope).Err(); err != nil {
+		logger.WarnCtxf(ctx, "failed to publish collab message", "projectID", projectID, "error", err)
+	}
+}
+
+// deliver 把广播消息发给本实例中该项目的连接
+func (h *CollabHub) deliver(projectID uint64, envelope []byte) {
+	var e collabEnvelope
+	if err := sonic.Unmarshal(envelope, &e); err != nil {
+		return
+	}
+
+	h.mu.Lock()
+	room := h.rooms[projectID]
+	var clients []*CollabClient
+	if room != nil {
+		clients = make([]*CollabClient, 0, len(room.clients))
+		for c := range room.clients {
+			if c.Session != e.Skip {
+				clients = append(clients, c)
+			}
+		}
+	}
+	h.mu.Unlock()
+
+	for _, c := range clients {
+		c.enqueue(e.Msg)
+	}
+}
+
+// register 加入房间，房间的第一个连接负责订阅 Redis 频道
+func (h *CollabHub) register(ctx context.Context, client *CollabClient) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room := h.rooms[client.ProjectID]
+	if room == nil {
+		room = &collabRoom{
+			clients:  make(map[*CollabClient]struct{}),
+			presence: make(map[string]*CollabPresence),
+		}
+		if h.rdb != nil {
+			pubsub := h.rdb.Subscribe(context.Background(), fmt.Sprintf(collabChannelKey, client.ProjectID))
+			// 等待订阅确认，避免错过加入后立即发生的广播
+			if _, err := pubsub.Receive(ctx); err != nil {
+				_ = pubsub.Close()
+				return err
+			}
+			room.pubsub = pubsub
+			go h.relay(client.ProjectID, pubsub)
+		}
+		h.rooms[client.ProjectID] = room
+	}
+	room.clients[client] = struct{}{}
+	return nil
+}
+
+// unregister 离开房间，最后一个连接离开时取消订阅
+func (h *CollabHub) unregister(client *CollabClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room := h.rooms[client.ProjectID]
+	if room == nil {
+		return
+	}
+	delete(room.clients, client)
+	if len(room.clients) > 0 {
+		return
+	}
+	if room.pubsub != nil {
+		_ = room.pubsub.Close()
+	}
+	delete(h.rooms, client.ProjectID)
+}
+
+// relay 把 Redis 频道中的消息转发给本实例的连接，直到取消订阅
+func (h *CollabHub) relay(projectID uint64, pubsub *redis.PubSub) {
+	for m := range pubsub.Channel() {
+		h.deliver(projectID, []byte(m.Payload))
+	}
+}
+
+// versionsOf 返回各区域的当前版本
+func (h *CollabHub) versionsOf(ctx context.Context, projectID uint64) (map[string]int64, error) {
+	versions := map[string]int64{RegionHTML: 0, RegionCSS: 0}
+	if h.rdb == nil {
+		h.mu.Lock()
+		for region, v := range h.versions[projectID] {
+			versions[region] = v
+		}
+		h.mu.Unlock()
+		return versions, nil
+	}
+
+	values, err := h.rdb.HGetAll(ctx, fmt.Sprintf(collabVersionKey, projectID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	for region := range versions {
+		if v, err := strconv.ParseInt(values[region], 10, 64); err == nil {
+			versions[region] = v
+		}
+	}
+	return versions, nil
+}
+
+// bumpVersion 区域版本加一；版本只用于并发校验，过期后从 0 重新开始
+func (h *CollabHub) bumpVersion(ctx context.Context, projectID uint64, region string) (int64, error) {
+	if h.rdb == nil {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.versions[projectID] == nil {
+			h.versions[projectID] = make(map[string]int64)
+		}
+		h.versions[projectID][region]++
+		return h.versions[projectID][region], nil
+	}
+
+	key := fmt.Sprintf(collabVersionKey, projectID)
+	pipe := h.rdb.TxPipeline()
+	incr := pipe.HIncrBy(ctx, key, region, 1)
+	pipe.Expire(ctx, key, collabVersionTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// lock 串行化同一项目的编辑，返回释放函数
+func (h *CollabHub) lock(ctx context.Context, projectID uint64) (func(), error) {
+	if h.rdb == nil {
+		mu := &h.locks[projectID%collabLocalLocks]
+		mu.Lock()
+		return mu.Unlock, nil
+	}
+
+	key := fmt.Sprintf(collabLockKey, projectID)
+	token := uuid.NewString()
+	deadline := time.Now().Add(collabLockWait)
+	for {
+		ok, err := h.rdb.SetNX(ctx, key, token, collabLockTTL).Result()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return func() {
+				if err := collabUnlockScript.Run(context.Background(), h.rdb, []string{key}, token).Err(); err != nil {
+					logger.WarnCtxf(ctx, "failed to release collab lock", "projectID", projectID, "error", err)
+				}
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, ErrCollabBusy
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(collabLockRetry):
+		}
+	}
+}
+
+// peers 返回项目当前的在线成员，过期的记录会被忽略
+func (h *CollabHub) peers(ctx context.Context, projectID uint64) ([]*CollabPresence, error) {
+	peers := make([]*CollabPresence, 0)
+	if h.rdb == nil {
+		h.mu.Lock()
+		if room := h.rooms[projectID]; room != nil {
+			for _, p := range room.presence {
+				peers = append(peers, p)
+			}
+		}
+		h.mu.Unlock()
+		return peers, nil
+	}
+
+	key := fmt.Sprintf(collabPresenceKey, projectID)
+	values, err := h.rdb.HGetAll(ctx, key).Result()
+	if err != nil {
+		return peers, err
+	}
+	cutoff := nowMillis() - collabPresenceTTL.Milliseconds()
+	var stale []string
+	for session, data := range values {
+		var p CollabPresence
+		if err := sonic.UnmarshalString(data, &p); err != nil || p.At < cutoff {
+			stale = append(stale, session)
+			continue
+		}
+		peers = append(peers, &p)
+	}
+	// 顺带清理异常退出的实例留下的记录
+	if len(stale) > 0 {
+		_ = h.rdb.HDel(ctx, key, stale...).Err()
+	}
+	return peers, nil
+}
+
+func (h *CollabHub) storePresence(ctx context.Context, projectID uint64, presence *CollabPresence) {
+	if h.rdb == nil {
+		h.mu.Lock()
+		if room := h.rooms[projectID]; room != nil {
+			room.presence[presence.Session] = presence
+		}
+		h.mu.Unlock()
+		return
+	}
+
+	data, err := sonic.MarshalString(presence)
+	if err != nil {
+		return
+	}
+	key := fmt.Sprintf(collabPresenceKey, projectID)
+	pipe := h.rdb.TxPipeline()
+	pipe.HSet(ctx, key, presence.Session, data)
+	pipe.Expire(ctx, key, collabPresenceTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.WarnCtxf(ctx, "failed to store collab presence", "projectID", projectID, "error", err)
+	}
+}
+
+func (h *CollabHub) removePresence(ctx context.Context, projectID uint64, session string) {
+	if h.rdb == nil {
+		h.mu.Lock()
+		if room := h.rooms[projectID]; room != nil {
+			delete(room.presence, session)
+		}
+		h.mu.Unlock()
+		return
+	}
+	if err := h.rdb.HDel(ctx, fmt.Sprintf(collabPresenceKey, projectID), session).Err(); err != nil {
+		logger.WarnCtxf(ctx, "failed to remove collab presence", "projectID", projectID, "error", err)
+	}
+}
+
+// regionContent 返回项目某个区域的内容
+func regionContent(project *model.Project, region string) string {
+	if region == RegionCSS {
+		return project.CSS
+	}
+	return project.HTML
+}
+
+// isCollabClientError 是否为客户端可处理的业务错误（无需记录错误日志）
+func isCollabClientError(err error) bool {
+	return errors.Is(err, ErrProjectNotFound) || errors.Is(err, ErrProjectNotOwned) ||
+		errors.Is(err, ErrProjectReadOnly) || errors.Is(err, ErrProjectTooLarge) ||
+		errors.Is(err, ErrStorageLimitExceeded)
+}
+
+func nowMillis() int64 {
+	return time.Now().UnixMilli()
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bytedance/sonic"
+
+	"github.com/test-tt/internal/model"
+)
+
+// TestParseCollabMessage tests validation of client messages
+func TestParseCollabMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{"edit", `{"type":"edit","region":"html","base_version":3,"content":"<p>hi</p>"}`, nil},
+		{"empty edit", `{"type":"edit","region":"css","content":""}`, nil},
+		{"edit without content", `{"type":"edit","region":"html"}`, ErrCollabMessageInvalid},
+		{"edit bad region", `{"type":"edit","region":"js","content":"x"}`, ErrRegionInvalid},
+		{"negative version", `{"type":"edit","region":"html","base_version":-1,"content":"x"}`, ErrCollabMessageInvalid},
+		{"presence", `{"type":"presence","presence":{"region":"css","start":4,"end":9}}`, nil},
+		{"presence missing", `{"type":"presence"}`, ErrCollabMessageInvalid},
+		{"chat", `{"type":"chat","text":" hello "}`, nil},
+		{"blank chat", `{"type":"chat","text":"   "}`, ErrCollabMessageInvalid},
+		{"long chat", `{"type":"chat","text":"` + strings.Repeat("a", maxCollabChatLen+1) + `"}`, ErrCollabMessageInvalid},
+		{"ping", `{"type":"ping","ref":"1"}`, nil},
+		{"server type", `{"type":"welcome"}`, ErrCollabMessageInvalid},
+		{"not json", `hello`, ErrCollabMessageInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCollabMessage([]byte(tt.data))
+			if err != tt.wantErr {
+				t.Errorf("ParseCollabMessage() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	msg, _ := ParseCollabMessage([]byte(`{"type":"chat","text":" hello "}`))
+	if msg.Text != "hello" {
+		t.Errorf("chat text = %q, want trimmed", msg.Text)
+	}
+}
+
+// TestCollabHub_Broadcast tests local fan-out without Redis
+func TestCollabHub_Broadcast(t *testing.T) {
+	ctx := context.Background()
+	hub := NewCollabHub(nil, nil)
+	alice := NewCollabClient(1, 10, "alice", model.MemberRoleOwner)
+	bob := NewCollabClient(1, 20, "bob", model.MemberRoleViewer)
+	other := NewCollabClient(2, 30, "carol", model.MemberRoleOwner)
+	for _, c := range []*CollabClient{alice, bob, other} {
+		if err := hub.register(ctx, c); err != nil {
+			t.Fatalf("register() error = %v", err)
+		}
+	}
+
+	hub.Handle(ctx, alice, []byte(`{"type":"chat","text":"hi"}`))
+	for _, c := range []*CollabClient{alice, bob} {
+		msg := receive(t, c)
+		if msg.Type != CollabTypeChat || msg.Text != "hi" || msg.UserID != 10 || msg.Name != "alice" {
+			t.Errorf("%s received %+v", c.Name, msg)
+		}
+	}
+
+	// 在线状态不回发给自己，且身份由服务端填写
+	hub.Handle(ctx, bob, []byte(`{"type":"presence","presence":{"user_id":10,"name":"mallory","region":"html","start":1,"end":5}}`))
+	msg := receive(t, alice)
+	if msg.Type != CollabTypePresence || msg.Presence.UserID != 20 || msg.Presence.Name != "bob" || msg.Presence.End != 5 {
+		t.Errorf("alice received %+v", msg.Presence)
+	}
+	peers, _ := hub.peers(ctx, 1)
+	if len(peers) != 1 || peers[0].Session != bob.Session {
+		t.Errorf("peers = %+v, want bob", peers)
+	}
+
+	// 只读成员不能编辑
+	hub.Handle(ctx, bob, []byte(`{"type":"edit","region":"html","content":"x"}`))
+	if msg := receive(t, bob); msg.Type != CollabTypeError || msg.Error != ErrProjectReadOnly.Error() {
+		t.Errorf("viewer edit reply = %+v", msg)
+	}
+
+	for _, c := range []*CollabClient{alice, bob, other} {
+		if n := len(c.send); n != 0 {
+			t.Errorf("%s has %d unexpected messages", c.Name, n)
+		}
+	}
+
+	hub.Leave(ctx, bob)
+	if msg := receive(t, alice); msg.Type != CollabTypeLeave || msg.Session != bob.Session {
+		t.Errorf("alice received %+v, want leave", msg)
+	}
+	if peers, _ := hub.peers(ctx, 1); len(peers) != 0 {
+		t.Errorf("peers after leave = %d, want 0", len(peers))
+	}
+	hub.Leave(ctx, alice)
+	if _, ok := hub.rooms[1]; ok {
+		t.Error("empty room was not removed")
+	}
+}
+
+// TestCollabHub_Versions tests local region versions
+func TestCollabHub_Versions(t *testing.T) {
+	ctx := context.Background()
+	hub := NewCollabHub(nil, nil)
+
+	versions, _ := hub.versionsOf(ctx, 1)
+	if versions[RegionHTML] != 0 || versions[RegionCSS] != 0 {
+		t.Errorf("initial versions = %v", versions)
+	}
+	for i := int64(1); i <= 3; i++ {
+		if v, _ := hub.bumpVersion(ctx, 1, RegionHTML); v != i {
+			t.Errorf("bumpVersion() = %d, want %d", v, i)
+		}
+	}
+	versions, _ = hub.versionsOf(ctx, 1)
+	if versions[RegionHTML] != 3 || versions[RegionCSS] != 0 {
+		t.Errorf("versions = %v, want html 3 css 0", versions)
+	}
+	if versions, _ := hub.versionsOf(ctx, 2); versions[RegionHTML] != 0 {
+		t.Errorf("other project versions = %v", versions)
+	}
+}
+
+// collabTestProjects 内存中的项目，记录协作编辑的保存次数
+type collabTestProjects struct {
+	project *model.Project
+	saves   int
+}
+
+func (p *collabTestProjects) Access(_ context.Context, _, _ uint64) (*model.Project, string, error) {
+	project := *p.project
+	return &project, model.MemberRoleEditor, nil
+}
+
+func (p *collabTestProjects) SaveRegion(_ context.Context, _, _ uint64, region, content string) (*SavedProject, error) {
+	p.saves++
+	if region == RegionHTML {
+		p.project.HTML = content
+	} else {
+		p.project.CSS = content
+	}
+	project := *p.project
+	return &SavedProject{Project: &project}, nil
+}
+
+// TestCollabHub_Apply tests that a server-side save reaches connected clients and stale edits get a conflict
+func TestCollabHub_Apply(t *testing.T) {
+	ctx := context.Background()
+	projects := &collabTestProjects{project: &model.Project{ID: 1, HTML: "<p>old</p>", CSS: "p{}"}}
+	hub := NewCollabHub(nil, projects)
+	bob := NewCollabClient(1, 20, "bob", model.MemberRoleEditor)
+	if err := hub.register(ctx, bob); err != nil {
+		t.Fatalf("register() error = %v", err)
+	}
+
+	// 模拟 ApplyGeneration 的保存
+	err := hub.Apply(ctx, 1, 10, func() (*model.Project, error) {
+		projects.project.HTML, projects.project.CSS = "<p>generated</p>", "p{color:red}"
+		return projects.project, nil
+	})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	for _, region := range []string{RegionHTML, RegionCSS} {
+		msg := receive(t, bob)
+		if msg.Type != CollabTypeEdit || msg.Region != region || msg.Version != 1 || msg.UserID != 10 || *msg.Content != regionContent(projects.project, region) {
+			t.Errorf("bob received %+v", msg)
+		}
+	}
+
+	// bob 基于 Apply 之前的版本编辑
+	hub.Handle(ctx, bob, []byte(`{"type":"edit","ref":"1","region":"html","base_version":0,"content":"<p>bob</p>"}`))
+	msg := receive(t, bob)
+	if msg.Type != CollabTypeConflict || msg.Version != 1 || *msg.Content != "<p>generated</p>" {
+		t.Errorf("stale edit reply = %+v", msg)
+	}
+	if projects.saves != 0 || projects.project.HTML != "<p>generated</p>" {
+		t.Errorf("stale edit was saved: %q", projects.project.HTML)
+	}
+
+	hub.Handle(ctx, bob, []byte(`{"type":"edit","ref":"2","region":"html","base_version":1,"content":"<p>bob</p>"}`))
+	if msg := receive(t, bob); msg.Type != CollabTypeAck || msg.Version != 2 || projects.saves != 1 {
+		t.Errorf("edit on the current version reply = %+v", msg)
+	}
+
+	failed := hub.Apply(ctx, 1, 10, func() (*model.Project, error) { return nil, ErrProjectNotFound })
+	if failed != ErrProjectNotFound {
+		t.Errorf("Apply() error = %v, want the save error", failed)
+	}
+	if versions, _ := hub.versionsOf(ctx, 1); versions[RegionHTML] != 2 || versions[RegionCSS] != 1 {
+		t.Errorf("versions after a failed save = %v", versions)
+	}
+}
+
+// TestCollabClient_SlowConsumer tests that a full send queue disconnects the client
+func TestCollabClient_SlowConsumer(t *testing.T) {
+	c := NewCollabClient(1, 1, "slow", model.MemberRoleOwner)
+	for i := 0; i <= collabSendBuffer; i++ {
+		c.enqueue([]byte("{}"))
+	}
+	select {
+	case <-c.Done():
+	default:
+		t.Error("client was not closed when its queue overflowed")
+	}
+}
+
+func receive(t *testing.T, c *CollabClient) *CollabMessage {
+	t.Helper()
+	select {
+	case data := <-c.Send():
+		var msg CollabMessage
+		if err := sonic.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("invalid message %s: %v", data, err)
+		}
+		return &msg
+	default:
+		t.Fatalf("%s received nothing", c.Name)
+		return nil
+	}
+}
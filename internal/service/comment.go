@@ -0,0 +1,425 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/test-tt/internal/dao"
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/pagination"
+)
+
+const (
+	maxCommentAnchorLen = 512
+	maxCommentMentions  = 20
+)
+
+var (
+	ErrCommentNotFound  = errors.New("comment not found")
+	ErrCommentEmpty     = errors.New("comment body cannot be empty")
+	ErrCommentForbidden = errors.New("not allowed to change this comment")
+	ErrCommentNotThread = errors.New("only threads can be resolved")
+	ErrAnchorInvalid    = errors.New("anchor must be 1-512 characters")
+	ErrMentionInvalid   = errors.New("mentioned users must have access to the project")
+)
+
+type CommentService struct {
+	commentDAO     *dao.CommentDAO
+	memberDAO      *dao.MemberDAO
+	snapshotDAO    *dao.SnapshotDAO
+	userDAO        *dao.UserDAO
+	projectService *ProjectService
+}
+
+func NewCommentService() *CommentService {
+	return &CommentService{
+		commentDAO:     dao.NewCommentDAO(),
+		memberDAO:      dao.NewMemberDAO(),
+		snapshotDAO:    dao.NewSnapshotDAO(),
+		userDAO:        dao.NewUserDAO(),
+		projectService: NewProjectService(),
+	}
+}
+
+// CommentListOptions comment thread list parameters from the request
+type CommentListOptions struct {
+	Resolved   *bool
+	SnapshotID uint64
+	Anchor     string
+	Cursor     string
+	Limit      int
+}
+
+// commentCursor 评论列表游标，记录上一页最后一条的 ID
+type commentCursor struct {
+	ID uint64 `json:"id"`
+}
+
+// List retrieves the comment threads of a project with their replies
+func (s *CommentService) List(ctx context.Context, projectID, userID uint64, opts *CommentListOptions) ([]model.CommentView, string, error) {
+	if _, _, err := s.projectService.Access(ctx, projectID, userID); err != nil {
+		return nil, "", err
+	}
+
+	q := &dao.CommentListQuery{
+		ProjectID:  projectID,
+		Resolved:   opts.Resolved,
+		SnapshotID: opts.SnapshotID,
+		Anchor:     strings.TrimSpace(opts.Anchor),
+		Limit:      opts.Limit + 1, // 多取一条判断是否还有下一页
+	}
+	afterID, err := decodeCommentCursor(opts.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	q.AfterID = afterID
+
+	threads, err := s.commentDAO.ListThreads(ctx, q)
+	if err != nil {
+		return nil, "", err
+	}
+	threads, nextCursor, err := commentPage(threads, opts.Limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ids := make([]uint64, len(threads))
+	for i := range threads {
+		ids[i] = threads[i].ID
+	}
+	replies, err := s.commentDAO.ListReplies(ctx, ids)
+	if err != nil {
+		return nil, "", err
+	}
+
+	views, err := s.views(ctx, append(threads, replies...))
+	if err != nil {
+		return nil, "", err
+	}
+	// 前 len(threads) 个是讨论串，其余按 ID 升序挂到所属讨论串下
+	result := views[:len(threads)]
+	index := make(map[uint64]int, len(threads))
+	for i := range result {
+		index[result[i].ID] = i
+	}
+	for _, reply := range views[len(threads):] {
+		i := index[*reply.ParentID]
+		result[i].Replies = append(result[i].Replies, reply)
+	}
+	for i := range result {
+		if result[i].Replies == nil {
+			result[i].Replies = []model.CommentView{}
+		}
+	}
+	return result, nextCursor, nil
+}
+
+// Create starts a thread anchored to an element of a project snapshot
+func (s *CommentService) Create(ctx context.Context, projectID, userID, snapshotID uint64, anchor, body string, mentions []uint64) (*model.CommentView, error) {
+	project, _, err := s.projectService.Access(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	anchor = strings.TrimSpace(anchor)
+	if anchor == "" || len(anchor) > maxCommentAnchorLen {
+		return nil, ErrAnchorInvalid
+	}
+	body, err = normalizeCommentBody(body)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkSnapshot(ctx, projectID, snapshotID); err != nil {
+		return nil, err
+	}
+	mentions, err = s.checkMentions(ctx, project, mentions)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &model.ProjectComment{
+		ProjectID:  projectID,
+		UserID:     userID,
+		SnapshotID: snapshotID,
+		Anchor:     anchor,
+		Body:       body,
+	}
+	if err := s.commentDAO.Create(ctx, comment, mentions); err != nil {
+		return nil, err
+	}
+	return s.view(ctx, comment)
+}
+
+// Reply adds a reply to a thread; replying to a reply adds it to the same thread
+func (s *CommentService) Reply(ctx context.Context, projectID, commentID, userID uint64, body string, mentions []uint64) (*model.CommentView, error) {
+	project, _, err := s.projectService.Access(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.get(ctx, projectID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	body, err = normalizeCommentBody(body)
+	if err != nil {
+		return nil, err
+	}
+	mentions, err = s.checkMentions(ctx, project, mentions)
+	if err != nil {
+		return nil, err
+	}
+
+	rootID := target.ID
+	if target.ParentID != nil {
+		rootID = *target.ParentID
+	}
+	reply := &model.ProjectComment{
+		ProjectID:  projectID,
+		ParentID:   &rootID,
+		UserID:     userID,
+		SnapshotID: target.SnapshotID,
+		Anchor:     target.Anchor,
+		Body:       body,
+	}
+	if err := s.commentDAO.Create(ctx, reply, mentions); err != nil {
+		return nil, err
+	}
+	return s.view(ctx, reply)
+}
+
+// Update edits the body and mentions of a comment, only its author can edit it
+func (s *CommentService) Update(ctx context.Context, projectID, commentID, userID uint64, body string, mentions []uint64) (*model.CommentView, error) {
+	project, _, err := s.projectService.Access(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := s.get(ctx, projectID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.UserID != userID {
+		return nil, ErrCommentForbidden
+	}
+	comment.Body, err = normalizeCommentBody(body)
+	if err != nil {
+		return nil, err
+	}
+	mentions, err = s.checkMentions(ctx, project, mentions)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.commentDAO.UpdateBody(ctx, comment, mentions); err != nil {
+		return nil, err
+	}
+	return s.view(ctx, comment)
+}
+
+// Delete deletes a comment and, for a thread, its replies; allowed for the author and the project owner
+func (s *CommentService) Delete(ctx context.Context, projectID, commentID, userID uint64) error {
+	_, role, err := s.projectService.Access(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+	comment, err := s.get(ctx, projectID, commentID)
+	if err != nil {
+		return err
+	}
+	if comment.UserID != userID && role != model.MemberRoleOwner {
+		return ErrCommentForbidden
+	}
+	return s.commentDAO.Delete(ctx, commentID)
+}
+
+// SetResolved resolves or reopens a thread; allowed for the thread author, the owner and editors
+func (s *CommentService) SetResolved(ctx context.Context, projectID, commentID, userID uint64, resolved bool) (*model.CommentView, error) {
+	_, role, err := s.projectService.Access(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := s.get(ctx, projectID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.ParentID != nil {
+		return nil, ErrCommentNotThread
+	}
+	if comment.UserID != userID && role == model.MemberRoleViewer {
+		return nil, ErrCommentForbidden
+	}
+
+	comment.Resolved = resolved
+	comment.ResolvedBy, comment.ResolvedAt = nil, nil
+	if resolved {
+		now := time.Now()
+		comment.ResolvedBy, comment.ResolvedAt = &userID, &now
+	}
+	if err := s.commentDAO.SetResolved(ctx, commentID, resolved, comment.ResolvedBy, comment.ResolvedAt); err != nil {
+		return nil, err
+	}
+	return s.view(ctx, comment)
+}
+
+// ListMentions retrieves the comments mentioning a user, newest first
+func (s *CommentService) ListMentions(ctx context.Context, userID uint64, cursor string, limit int) ([]model.CommentView, string, error) {
+	afterID, err := decodeCommentCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	comments, err := s.commentDAO.ListMentioning(ctx, userID, afterID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	comments, nextCursor, err := commentPage(comments, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	views, err := s.views(ctx, comments)
+	if err != nil {
+		return nil, "", err
+	}
+	return views, nextCursor, nil
+}
+
+// get retrieves a comment of a project
+func (s *CommentService) get(ctx context.Context, projectID, commentID uint64) (*model.ProjectComment, error) {
+	comment, err := s.commentDAO.GetByID(ctx, commentID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrCommentNotFound
+		}
+		return nil, err
+	}
+	if comment.ProjectID != projectID {
+		return nil, ErrCommentNotFound
+	}
+	return comment, nil
+}
+
+// checkSnapshot 校验快照属于项目
+func (s *CommentService) checkSnapshot(ctx context.Context, projectID, snapshotID uint64) error {
+	snapshot, err := s.snapshotDAO.GetByID(ctx, snapshotID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrSnapshotNotFound
+		}
+		return err
+	}
+	if snapshot.ProjectID != projectID {
+		return ErrSnapshotNotFound
+	}
+	return nil
+}
+
+// checkMentions 去重并校验被提及的用户是项目所有者或成员
+func (s *CommentService) checkMentions(ctx context.Context, project *model.Project, mentions []uint64) ([]uint64, error) {
+	mentions = uniqueIDs(mentions)
+	if len(mentions) == 0 {
+		return mentions, nil
+	}
+	if len(mentions) > maxCommentMentions {
+		return nil, ErrMentionInvalid
+	}
+
+	members, err := s.memberDAO.ListByProjectID(ctx, project.ID)
+	if err != nil {
+		return nil, err
+	}
+	allowed := make(map[uint64]struct{}, len(members)+1)
+	allowed[project.UserID] = struct{}{}
+	for _, m := range members {
+		allowed[m.UserID] = struct{}{}
+	}
+	for _, id := range mentions {
+		if _, ok := allowed[id]; !ok {
+			return nil, ErrMentionInvalid
+		}
+	}
+	return mentions, nil
+}
+
+func (s *CommentService) view(ctx context.Context, comment *model.ProjectComment) (*model.CommentView, error) {
+	views, err := s.views(ctx, []model.ProjectComment{*comment})
+	if err != nil {
+		return nil, err
+	}
+	return &views[0], nil
+}
+
+// views 补充作者名和提及的用户，保持原有顺序
+func (s *CommentService) views(ctx context.Context, comments []model.ProjectComment) ([]model.CommentView, error) {
+	views := make([]model.CommentView, len(comments))
+	if len(comments) == 0 {
+		return views, nil
+	}
+
+	ids := make([]uint64, len(comments))
+	authorIDs := make([]uint64, len(comments))
+	for i := range comments {
+		ids[i] = comments[i].ID
+		authorIDs[i] = comments[i].UserID
+	}
+	mentions, err := s.commentDAO.ListMentions(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	users, err := s.userDAO.GetByIDs(ctx, uniqueIDs(authorIDs))
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[uint64]string, len(users))
+	for _, u := range users {
+		names[u.ID] = u.Name
+	}
+
+	for i := range comments {
+		views[i] = model.CommentView{
+			ProjectComment: comments[i],
+			AuthorName:     names[comments[i].UserID],
+			Mentions:       mentions[comments[i].ID],
+		}
+		if views[i].Mentions == nil {
+			views[i].Mentions = []uint64{}
+		}
+	}
+	return views, nil
+}
+
+// normalizeCommentBody 去除首尾空白并校验非空
+func normalizeCommentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", ErrCommentEmpty
+	}
+	return body, nil
+}
+
+// commentPage 截取一页评论，多取的一条表示还有下一页
+func commentPage(comments []model.ProjectComment, limit int) ([]model.ProjectComment, string, error) {
+	if len(comments) <= limit {
+		return comments, "", nil
+	}
+	comments = comments[:limit]
+	cursor, err := pagination.EncodeCursor(commentCursor{ID: comments[len(comments)-1].ID})
+	if err != nil {
+		return nil, "", err
+	}
+	return comments, cursor, nil
+}
+
+func decodeCommentCursor(cursor string) (uint64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	var c commentCursor
+	if err := pagination.DecodeCursor(cursor, &c); err != nil {
+		return 0, err
+	}
+	if c.ID == 0 {
+		return 0, pagination.ErrInvalidCursor
+	}
+	return c.ID, nil
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/pagination"
+)
+
+// TestCommentPage tests page trimming and cursor round trip
+func TestCommentPage(t *testing.T) {
+	comments := []model.ProjectComment{{ID: 9}, {ID: 7}, {ID: 4}}
+
+	page, cursor, err := commentPage(comments, 2)
+	if err != nil || len(page) != 2 || cursor == "" {
+		t.Fatalf("commentPage() = %d items, cursor %q, err %v", len(page), cursor, err)
+	}
+	if afterID, err := decodeCommentCursor(cursor); err != nil || afterID != 7 {
+		t.Errorf("decodeCommentCursor() = %d, %v, want 7", afterID, err)
+	}
+
+	page, cursor, _ = commentPage(comments, 3)
+	if len(page) != 3 || cursor != "" {
+		t.Errorf("last page = %d items, cursor %q", len(page), cursor)
+	}
+}
+
+// TestDecodeCommentCursor tests invalid cursors
+func TestDecodeCommentCursor(t *testing.T) {
+	if afterID, err := decodeCommentCursor(""); err != nil || afterID != 0 {
+		t.Errorf("decodeCommentCursor(\"\") = %d, %v", afterID, err)
+	}
+	if _, err := decodeCommentCursor("not-a-cursor"); err != pagination.ErrInvalidCursor {
+		t.Errorf("decodeCommentCursor(garbage) error = %v", err)
+	}
+	zero, _ := pagination.EncodeCursor(commentCursor{})
+	if _, err := decodeCommentCursor(zero); err != pagination.ErrInvalidCursor {
+		t.Errorf("decodeCommentCursor(zero id) error = %v", err)
+	}
+}
+
+// TestNormalizeCommentBody tests comment body trimming
+func TestNormalizeCommentBody(t *testing.T) {
+	if body, err := normalizeCommentBody("  looks off on mobile \n"); err != nil || body != "looks off on mobile" {
+		t.Errorf("normalizeCommentBody() = %q, %v", body, err)
+	}
+	if _, err := normalizeCommentBody(" \n\t "); err != ErrCommentEmpty {
+		t.Errorf("normalizeCommentBody(blank) error = %v", err)
+	}
+}
+
+// TestUniqueIDs tests de-duplication keeps the first occurrence order
+func TestUniqueIDs(t *testing.T) {
+	got := uniqueIDs([]uint64{3, 1, 3, 2, 1})
+	want := []uint64{3, 1, 2}
+	if len(got) != len(want) {
+		t.Fatalf("uniqueIDs() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("uniqueIDs() = %v, want %v", got, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/test-tt/config"
+	"github.com/test-tt/internal/dao"
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/logger"
+	"github.com/test-tt/pkg/pagination"
+)
+
+var ErrCreditsExhausted = errors.New("generation credits exhausted")
+
+// CreditBudget credits used in the current period against the budget
+type CreditBudget struct {
+	Used      int64     `json:"used"`
+	Limit     int64     `json:"limit"`     // Plan budget plus grants
+	Granted   int64     `json:"granted"`   // Credits granted by admins for this period
+	Remaining int64     `json:"remaining"` // 0 when unlimited
+	Unlimited bool      `json:"unlimited"`
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+// Exhausted reports whether no credits are left in the period
+func (b *CreditBudget) Exhausted() bool {
+	return !b.Unlimited && b.Used >= b.Limit
+}
+
+// CreditBalance a user's plan and credit budgets
+type CreditBalance struct {
+	Plan    string              `json:"plan"`
+	Daily   CreditBudget        `json:"daily"`
+	Monthly CreditBudget        `json:"monthly"`
+	Grants  []model.CreditGrant `json:"grants"` // Grants given this month
+}
+
+// usageCursor 用量记录游标，记录上一页最后一条的 ID
+type usageCursor struct {
+	ID uint64 `json:"id"`
+}
+
+// CreditService meters generations and enforces the daily and monthly credit budgets
+// 和 QuotaService 一样，检查与记录不在同一事务中，并发的生成可能略微超出预算
+type CreditService struct {
+	creditDAO    *dao.CreditDAO
+	userDAO      *dao.UserDAO
+	quotaService *QuotaService
+}
+
+func NewCreditService() *CreditService {
+	return &CreditService{
+		creditDAO:    dao.NewCreditDAO(),
+		userDAO:      dao.NewUserDAO(),
+		quotaService: NewQuotaService(),
+	}
+}
+
+func meteringConfig() *config.MeteringConfig {
+	if config.Cfg != nil && config.Cfg.Metering != nil {
+		return config.Cfg.Metering
+	}
+	return &config.MeteringConfig{InputPrice: 5, OutputPrice: 25, CreditValue: 0.01}
+}
+
+// Price returns the cost in millionths of a dollar and the credits charged for the tokens
+// 单价按每百万 token 计，正好等于每个 token 的微美元数；额度向上取整，有成本就至少扣 1
+func Price(cfg *config.MeteringConfig, inputTokens, outputTokens int64) (costMicros, credits int64) {
+	cost := float64(inputTokens)*cfg.InputPrice + float64(outputTokens)*cfg.OutputPrice
+	costMicros = int64(math.Round(cost))
+	if costMicros <= 0 || cfg.CreditValue <= 0 {
+		return costMicros, 0
+	}
+	return costMicros, int64(math.Ceil(float64(costMicros) / (cfg.CreditValue * 1e6)))
+}
+
+// creditPeriods 返回 now 所在的天和月的起止时间，按服务器时区计算
+func creditPeriods(now time.Time) (dayStart, dayEnd, monthStart, monthEnd time.Time) {
+	y, m, d := now.Date()
+	dayStart = time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	monthStart = time.Date(y, m, 1, 0, 0, 0, 0, now.Location())
+	return dayStart, dayStart.AddDate(0, 0, 1), monthStart, monthStart.AddDate(0, 1, 0)
+}
+
+// newCreditBudget 计算周期预算，套餐额度为 0 表示不限制，发放的额度只在有限预算上累加
+func newCreditBudget(planLimit, granted, used int64, resetsAt time.Time) CreditBudget {
+	b := CreditBudget{Used: used, Granted: granted, ResetsAt: resetsAt}
+	if planLimit == 0 {
+		b.Unlimited = true
+		return b
+	}
+	b.Limit = max(planLimit+granted, 0)
+	b.Remaining = max(b.Limit-used, 0)
+	return b
+}
+
+// Balance reports a user's credit budgets for the current day and month
+func (s *CreditService) Balance(ctx context.Context, userID uint64) (*CreditBalance, error) {
+	plan, limits, err := s.quotaService.planOf(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	dayStart, dayEnd, monthStart, monthEnd := creditPeriods(time.Now())
+	usedToday, err := s.creditDAO.SumCredits(ctx, userID, dayStart)
+	if err != nil {
+		return nil, err
+	}
+	usedMonth, err := s.creditDAO.SumCredits(ctx, userID, monthStart)
+	if err != nil {
+		return nil, err
+	}
+	grants, err := s.creditDAO.ListGrants(ctx, userID, monthStart)
+	if err != nil {
+		return nil, err
+	}
+
+	var grantedToday, grantedMonth int64
+	for _, g := range grants {
+		switch g.Period {
+		case model.CreditPeriodDay:
+			if !g.CreatedAt.Before(dayStart) {
+				grantedToday += g.Credits
+			}
+		case model.CreditPeriodMonth:
+			grantedMonth += g.Credits
+		}
+	}
+
+	return &CreditBalance{
+		Plan:    plan,
+		Daily:   newCreditBudget(limits.DailyCredits, grantedToday, usedToday, dayEnd),
+		Monthly: newCreditBudget(limits.MonthlyCredits, grantedMonth, usedMonth, monthEnd),
+		Grants:  grants,
+	}, nil
+}
+
+// Check returns ErrCreditsExhausted when the user has no credits left today or this month
+func (s *CreditService) Check(ctx context.Context, userID uint64) error {
+	balance, err := s.Balance(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if balance.Daily.Exhausted() || balance.Monthly.Exhausted() {
+		return ErrCreditsExhausted
+	}
+	return nil
+}
+
+// Record prices and saves the usage of a finished generation
+func (s *CreditService) Record(ctx context.Context, usage *model.GenerationUsage) error {
+	usage.CostMicros, usage.Credits = Price(meteringConfig(), usage.InputTokens, usage.OutputTokens)
+	if err := s.creditDAO.RecordUsage(ctx, usage); err != nil {
+		return err
+	}
+	logger.InfoCtxf(ctx, "generation metered",
+		"jobID", usage.JobID, "userID", usage.UserID,
+		"inputTokens", usage.InputTokens, "outputTokens", usage.OutputTokens,
+		"wallTimeMs", usage.WallTimeMs, "credits", usage.Credits)
+	return nil
+}
+
+// History lists a user's generation usage, newest first
+func (s *CreditService) History(ctx context.Context, userID uint64, cursor string, limit int) ([]model.GenerationUsage, string, error) {
+	var beforeID uint64
+	if cursor != "" {
+		var c usageCursor
+		if err := pagination.DecodeCursor(cursor, &c); err != nil {
+			return nil, "", err
+		}
+		if c.ID == 0 {
+			return nil, "", pagination.ErrInvalidCursor
+		}
+		beforeID = c.ID
+	}
+
+	usage, err := s.creditDAO.ListUsage(ctx, userID, beforeID, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(usage) <= limit {
+		return usage, "", nil
+	}
+	usage = usage[:limit]
+	next, err := pagination.EncodeCursor(usageCursor{ID: usage[len(usage)-1].ID})
+	if err != nil {
+		return nil, "", err
+	}
+	return usage, next, nil
+}
+
+// Grant gives a user extra credits for the current day or month
+// 负数表示扣减
+func (s *CreditService) Grant(ctx context.Context, adminID, userID uint64, period string, credits int64, reason string) (*model.CreditGrant, error) {
+	if _, err := s.userDAO.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	grant := &model.CreditGrant{
+		UserID:    userID,
+		Period:    period,
+		Credits:   credits,
+		Reason:    reason,
+		GrantedBy: adminID,
+	}
+	if err := s.creditDAO.CreateGrant(ctx, grant); err != nil {
+		return nil, err
+	}
+	logger.InfoCtxf(ctx, "credits granted", "adminID", adminID, "userID", userID, "period", period, "credits", credits)
+	return grant, nil
+}
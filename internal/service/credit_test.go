@@ -0,0 +1,73 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/test-tt/config"
+)
+
+// TestPrice tests cost and credit calculation
+func TestPrice(t *testing.T) {
+	cfg := &config.MeteringConfig{InputPrice: 5, OutputPrice: 25, CreditValue: 0.01}
+	tests := []struct {
+		name        string
+		in, out     int64
+		wantCost    int64
+		wantCredits int64
+	}{
+		{"no tokens", 0, 0, 0, 0},
+		{"rounds up to one credit", 10, 10, 300, 1},
+		{"typical page", 3000, 8000, 215000, 22},
+		{"exact credits", 0, 400, 10000, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cost, credits := Price(cfg, tt.in, tt.out)
+			if cost != tt.wantCost || credits != tt.wantCredits {
+				t.Errorf("Price(%d, %d) = %d, %d, want %d, %d", tt.in, tt.out, cost, credits, tt.wantCost, tt.wantCredits)
+			}
+		})
+	}
+}
+
+// TestCreditPeriods tests day and month boundaries
+func TestCreditPeriods(t *testing.T) {
+	now := time.Date(2024, 12, 31, 15, 4, 5, 0, time.UTC)
+	dayStart, dayEnd, monthStart, monthEnd := creditPeriods(now)
+	if !dayStart.Equal(time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)) || !dayEnd.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("day = %v - %v", dayStart, dayEnd)
+	}
+	if !monthStart.Equal(time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)) || !monthEnd.Equal(dayEnd) {
+		t.Errorf("month = %v - %v", monthStart, monthEnd)
+	}
+}
+
+// TestNewCreditBudget tests budgets with grants and unlimited plans
+func TestNewCreditBudget(t *testing.T) {
+	tests := []struct {
+		name                     string
+		plan, granted, used      int64
+		wantLimit, wantRemaining int64
+		wantExhausted            bool
+	}{
+		{"within budget", 100, 0, 40, 100, 60, false},
+		{"used up", 100, 0, 100, 100, 0, true},
+		{"grant adds credits", 100, 50, 120, 150, 30, false},
+		{"negative grant", 100, -150, 0, 0, 0, true},
+		{"over budget", 100, 0, 130, 100, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newCreditBudget(tt.plan, tt.granted, tt.used, time.Time{})
+			if b.Unlimited || b.Limit != tt.wantLimit || b.Remaining != tt.wantRemaining || b.Exhausted() != tt.wantExhausted {
+				t.Errorf("newCreditBudget() = %+v, exhausted %v", b, b.Exhausted())
+			}
+		})
+	}
+
+	// 套餐不限额度时发放的额度不影响
+	if b := newCreditBudget(0, -10, 1e6, time.Time{}); !b.Unlimited || b.Exhausted() {
+		t.Errorf("unlimited budget = %+v", b)
+	}
+}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"path"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/test-tt/internal/dao"
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/logger"
+	"github.com/test-tt/pkg/sanitize"
+)
+
+const (
+	// maxProjectFiles 除 index.html 和 style.css 外的文件数上限
+	maxProjectFiles  = 100
+	maxFilePathLen   = 255
+	maxFilePathDepth = 8
+)
+
+var (
+	ErrFilePathInvalid = errors.New("invalid file path")
+	ErrFileNotFound    = errors.New("file not found")
+	ErrFileRequired    = errors.New("index.html and style.css cannot be deleted")
+	ErrFileDuplicate   = errors.New("a file can only be changed once per save")
+	ErrFileLimit       = errors.New("too many project files")
+)
+
+// filePathSegment 路径的每一段，不能以 . 开头（排除 .. 和隐藏文件）
+var filePathSegment = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._-]*$`)
+
+// projectFileTypes 允许的文件扩展名
+var projectFileTypes = map[string]struct{}{
+	".html": {},
+	".css":  {},
+	".js":   {},
+}
+
+// CleanFilePath validates a project file path and returns it without a leading slash
+// 路径为 / 分隔的相对路径，最多 8 层，扩展名必须是 .html、.css 或 .js
+func CleanFilePath(p string) (string, error) {
+	p = strings.TrimPrefix(p, "/")
+	if p == "" || len(p) > maxFilePathLen {
+		return "", ErrFilePathInvalid
+	}
+	segments := strings.Split(p, "/")
+	if len(segments) > maxFilePathDepth {
+		return "", ErrFilePathInvalid
+	}
+	for _, seg := range segments {
+		if !filePathSegment.MatchString(seg) {
+			return "", ErrFilePathInvalid
+		}
+	}
+	if _, ok := projectFileTypes[path.Ext(p)]; !ok {
+		return "", ErrFilePathInvalid
+	}
+	return p, nil
+}
+
+// isEntryFile 判断是否为保存在项目 html/css 中的入口文件
+func isEntryFile(p string) bool {
+	return p == model.ProjectIndexFile || p == model.ProjectStyleFile
+}
+
+// SavedFiles is the file tree after a save, with the sanitization warnings
+type SavedFiles struct {
+	Files         []model.ProjectFileInfo `json:"files"`
+	Warnings      []sanitize.Warning      `json:"warnings"`
+	PolicyVersion string                  `json:"policy_version"`
+}
+
+type FileService struct {
+	fileDAO        *dao.FileDAO
+	projectService *ProjectService
+	quotaService   *QuotaService
+	sanitizePolicy *sanitize.Policy
+}
+
+func NewFileService() *FileService {
+	return &FileService{
+		fileDAO:        dao.NewFileDAO(),
+		projectService: NewProjectService(),
+		quotaService:   NewQuotaService(),
+		sanitizePolicy: projectSanitizePolicy(),
+	}
+}
+
+// List retrieves the file tree of a project the user owns or is a member of
+// index.html 和 style.css 总是排在最前
+func (s *FileService) List(ctx context.Context, projectID, userID uint64) ([]model.ProjectFileInfo, error) {
+	project, _, err := s.projectService.Access(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.tree(ctx, project)
+}
+
+func (s *FileService) tree(ctx context.Context, project *model.Project) ([]model.ProjectFileInfo, error) {
+	files, err := s.fileDAO.ListByProjectID(ctx, project.ID)
+	if err != nil {
+		return nil, err
+	}
+	return append([]model.ProjectFileInfo{
+		{Path: model.ProjectIndexFile, Size: int64(len(project.HTML)), UpdatedAt: project.UpdatedAt},
+		{Path: model.ProjectStyleFile, Size: int64(len(project.CSS)), UpdatedAt: project.UpdatedAt},
+	}, files...), nil
+}
+
+// Get retrieves a file of a project the user owns or is a member of
+func (s *FileService) Get(ctx context.Context, projectID, userID uint64, filePath string) (*model.ProjectFile, error) {
+	filePath, err := CleanFilePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+	project, _, err := s.projectService.Access(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch filePath {
+	case model.ProjectIndexFile:
+		return entryFile(project, filePath, project.HTML), nil
+	case model.ProjectStyleFile:
+		return entryFile(project, filePath, project.CSS), nil
+	}
+	file, err := s.fileDAO.Get(ctx, projectID, filePath)
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, ErrFileNotFound
+	}
+	return file, nil
+}
+
+func entryFile(project *model.Project, filePath, content string) *model.ProjectFile {
+	return &model.ProjectFile{
+		ProjectID: project.ID,
+		Path:      filePath,
+		Content:   content,
+		Size:      int64(len(content)),
+		CreatedAt: project.CreatedAt,
+		UpdatedAt: project.UpdatedAt,
+	}
+}
+
+// Put creates or replaces a single file
+func (s *FileService) Put(ctx context.Context, projectID, userID uint64, filePath, content string) (*SavedFiles, error) {
+	return s.Save(ctx, projectID, userID, []model.ProjectFile{{Path: filePath, Content: content}}, nil)
+}
+
+// Delete removes a single file, index.html and style.css cannot be deleted
+func (s *FileService) Delete(ctx context.Context, projectID, userID uint64, filePath string) (*SavedFiles, error) {
+	return s.Save(ctx, projectID, userID, nil, []string{filePath})
+}
+
+// Save writes and deletes several files of a project at once, all or nothing
+// 所有者和编辑者可用；内容先清洗，限额按项目所有者的套餐计算整个项目的大小
+func (s *FileService) Save(ctx context.Context, projectID, userID uint64, files []model.ProjectFile, deleted []string) (*SavedFiles, error) {
+	seen := make(map[string]struct{}, len(files)+len(deleted))
+	for i := range files {
+		p, err := CleanFilePath(files[i].Path)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := seen[p]; dup {
+			return nil, ErrFileDuplicate
+		}
+		seen[p] = struct{}{}
+		files[i].Path = p
+	}
+	for i := range deleted {
+		p, err := CleanFilePath(deleted[i])
+		if err != nil {
+			return nil, err
+		}
+		if isEntryFile(p) {
+			return nil, ErrFileRequired
+		}
+		if _, dup := seen[p]; dup {
+			return nil, ErrFileDuplicate
+		}
+		seen[p] = struct{}{}
+		deleted[i] = p
+	}
+
+	_, role, err := s.projectService.Access(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role == model.MemberRoleViewer {
+		return nil, ErrProjectReadOnly
+	}
+
+	existing, err := s.fileDAO.ListByProjectID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[string]int64, len(existing))
+	var filesSize int64
+	for _, f := range existing {
+		sizes[f.Path] = f.Size
+		filesSize += f.Size
+	}
+	for _, p := range deleted {
+		if _, ok := sizes[p]; !ok {
+			return nil, ErrFileNotFound
+		}
+		delete(sizes, p)
+	}
+
+	var entries []model.ProjectFile
+	others := make([]model.ProjectFile, 0, len(files))
+	for _, f := range files {
+		if isEntryFile(f.Path) {
+			entries = append(entries, f)
+		} else {
+			others = append(others, f)
+		}
+	}
+	for _, f := range others {
+		sizes[f.Path] = int64(len(f.Content))
+	}
+	if len(sizes) > maxProjectFiles {
+		return nil, ErrFileLimit
+	}
+
+	// 入口文件基于加锁后的最新内容：请求中没有的入口文件保持原样，其余页面的 <style> 并入最新的 style.css
+	var cleaned *sanitize.Result
+	var project *model.Project
+	err = GetCollabHub().Apply(ctx, projectID, userID, func() (*model.Project, error) {
+		var err error
+		project, err = s.fileDAO.Save(ctx, projectID, others, deleted, func(project *model.Project) error {
+			html, css := project.HTML, project.CSS
+			for _, f := range entries {
+				if f.Path == model.ProjectIndexFile {
+					html = f.Content
+				} else {
+					css = f.Content
+				}
+			}
+			cleaned = sanitizeProjectFiles(s.sanitizePolicy, html, css, others)
+			for _, f := range others {
+				sizes[f.Path] = int64(len(f.Content))
+			}
+			newSize := contentSize(cleaned.HTML, cleaned.CSS)
+			for _, size := range sizes {
+				newSize += size
+			}
+			oldSize := contentSize(project.HTML, project.CSS) + filesSize
+			if err := s.quotaService.CheckUpdate(ctx, project.UserID, oldSize, newSize); err != nil {
+				return err
+			}
+			project.HTML = cleaned.HTML
+			project.CSS = cleaned.CSS
+			return nil
+		})
+		return project, err
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, err
+	}
+	logger.InfoCtxf(ctx, "project files saved", "projectID", project.ID, "userID", userID,
+		"written", len(files), "deleted", len(deleted), "policyVersion", cleaned.PolicyVersion, "warnings", len(cleaned.Warnings))
+
+	tree, err := s.tree(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	return &SavedFiles{
+		Files:         tree,
+		Warnings:      cleaned.Warnings,
+		PolicyVersion: cleaned.PolicyVersion,
+	}, nil
+}
+
+// sanitizeProjectFiles 清洗入口 html/css 并就地清洗其余文件，返回入口文件的结果和全部警告
+// 其余页面中的 <style> 与 index.html 一样移入 style.css；脚本文件原样保存并给出提示
+func sanitizeProjectFiles(policy *sanitize.Policy, html, css string, files []model.ProjectFile) *sanitize.Result {
+	var warnings []sanitize.Warning
+	styles := make([]string, 0)
+	for i := range files {
+		switch path.Ext(files[i].Path) {
+		case ".html":
+			result := policy.Sanitize(files[i].Content, "")
+			files[i].Content = result.HTML
+			if result.CSS != "" {
+				styles = append(styles, strings.TrimRight(result.CSS, "\n"))
+			}
+			warnings = append(warnings, result.Warnings...)
+		case ".css":
+			result := policy.Sanitize("", files[i].Content)
+			files[i].Content = result.CSS
+			warnings = append(warnings, result.Warnings...)
+		case ".js":
+			warnings = append(warnings, sanitize.Warning{Code: sanitize.WarnScriptFlagged, Detail: files[i].Path, Count: 1})
+		}
+	}
+
+	if len(styles) > 0 {
+		if strings.TrimSpace(css) != "" {
+			styles = append([]string{strings.TrimRight(css, "\n")}, styles...)
+		}
+		css = strings.Join(styles, "\n\n") + "\n"
+	}
+	cleaned := policy.Sanitize(html, css)
+	cleaned.Warnings = append(cleaned.Warnings, warnings...)
+	return cleaned
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/sanitize"
+)
+
+// TestCleanFilePath tests project file path validation
+func TestCleanFilePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"index.html", "index.html", false},
+		{"/about/index.html", "about/index.html", false},
+		{"css/theme-dark.css", "css/theme-dark.css", false},
+		{"js/app.min.js", "js/app.min.js", false},
+		{"_partials/nav.html", "_partials/nav.html", false},
+		{"", "", true},
+		{"/", "", true},
+		{"about/", "", true},
+		{"../index.html", "", true},
+		{"a/./b.html", "", true},
+		{".hidden.css", "", true},
+		{"a//b.html", "", true},
+		{`a\b.html`, "", true},
+		{"page one.html", "", true},
+		{"logo.png", "", true},
+		{"README", "", true},
+		{"PAGE.HTML", "", true},
+		{strings.Repeat("a/", maxFilePathDepth) + "x.html", "", true},
+		{strings.Repeat("a", maxFilePathLen) + ".html", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := CleanFilePath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CleanFilePath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CleanFilePath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSanitizeProjectFiles tests that extra files are sanitized like the entry files
+func TestSanitizeProjectFiles(t *testing.T) {
+	files := []model.ProjectFile{
+		{Path: "about.html", Content: `<style>h1{color:red}</style><h1 onclick="x()">About</h1><script>alert(1)</script>`},
+		{Path: "theme.css", Content: `p{background:url(javascript:alert(1))}`},
+		{Path: "app.js", Content: "console.log(1)"},
+	}
+	cleaned := sanitizeProjectFiles(sanitize.DefaultPolicy(), "<p>Home</p>", "body{margin:0}\n", files)
+
+	if strings.Contains(files[0].Content, "script") || strings.Contains(files[0].Content, "onclick") || strings.Contains(files[0].Content, "<style") {
+		t.Errorf("page not sanitized: %s", files[0].Content)
+	}
+	if strings.Contains(files[1].Content, "javascript:") {
+		t.Errorf("stylesheet not sanitized: %s", files[1].Content)
+	}
+	if files[2].Content != "console.log(1)" {
+		t.Errorf("script changed: %s", files[2].Content)
+	}
+	// 其余页面的 <style> 并入 style.css
+	if !strings.HasPrefix(cleaned.CSS, "body{margin:0}") || !strings.Contains(cleaned.CSS, "h1{color:red}") {
+		t.Errorf("style.css = %q", cleaned.CSS)
+	}
+	if cleaned.HTML != "<p>Home</p>" {
+		t.Errorf("index.html = %q", cleaned.HTML)
+	}
+
+	codes := make(map[string]string)
+	for _, w := range cleaned.Warnings {
+		codes[w.Code] = w.Detail
+	}
+	for _, code := range []string{sanitize.WarnScriptRemoved, sanitize.WarnStyleExtracted, sanitize.WarnScriptFlagged} {
+		if _, ok := codes[code]; !ok {
+			t.Errorf("missing warning %s in %+v", code, cleaned.Warnings)
+		}
+	}
+	if codes[sanitize.WarnScriptFlagged] != "app.js" {
+		t.Errorf("script warning detail = %q, want app.js", codes[sanitize.WarnScriptFlagged])
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/test-tt/internal/dao"
+	"github.com/test-tt/internal/model"
+)
+
+const (
+	maxFolderNameLen  = 100
+	maxFoldersPerUser = 500
+)
+
+var (
+	ErrFolderNotFound  = errors.New("folder not found")
+	ErrFolderNotOwned  = errors.New("folder does not belong to user")
+	ErrFolderNameEmpty = errors.New("folder name cannot be empty")
+	ErrFolderTooDeep   = errors.New("folders can only be nested one level")
+	ErrFolderLimit     = errors.New("too many folders")
+)
+
+type FolderService struct {
+	folderDAO      *dao.FolderDAO
+	projectDAO     *dao.ProjectDAO
+	projectService *ProjectService
+}
+
+func NewFolderService() *FolderService {
+	return &FolderService{
+		folderDAO:      dao.NewFolderDAO(),
+		projectDAO:     dao.NewProjectDAO(),
+		projectService: NewProjectService(),
+	}
+}
+
+// List retrieves all folders of a user; clients build the tree from parent_id
+func (s *FolderService) List(ctx context.Context, userID uint64) ([]model.ProjectFolder, error) {
+	return s.folderDAO.ListByUserID(ctx, userID)
+}
+
+// Create creates a folder, optionally inside a root folder
+func (s *FolderService) Create(ctx context.Context, userID uint64, name string, parentID *uint64) (*model.ProjectFolder, error) {
+	name, err := normalizeFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkParent(ctx, userID, 0, parentID); err != nil {
+		return nil, err
+	}
+
+	count, err := s.folderDAO.CountByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxFoldersPerUser {
+		return nil, ErrFolderLimit
+	}
+
+	folder := &model.ProjectFolder{
+		UserID:   userID,
+		ParentID: parentID,
+		Name:     name,
+	}
+	if err := s.folderDAO.Create(ctx, folder); err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+// Update renames a folder and sets its parent (nil moves it to the root)
+func (s *FolderService) Update(ctx context.Context, id, userID uint64, name string, parentID *uint64) (*model.ProjectFolder, error) {
+	folder, err := s.getOwned(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	name, err = normalizeFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkParent(ctx, userID, id, parentID); err != nil {
+		return nil, err
+	}
+	// 有子文件夹的文件夹不能再放入其他文件夹
+	if parentID != nil {
+		hasChildren, err := s.folderDAO.HasChildren(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if hasChildren {
+			return nil, ErrFolderTooDeep
+		}
+	}
+
+	folder.Name = name
+	folder.ParentID = parentID
+	if err := s.folderDAO.Update(ctx, folder); err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+// Delete deletes a folder and its subfolders; their projects move to the root
+func (s *FolderService) Delete(ctx context.Context, id, userID uint64) error {
+	if _, err := s.getOwned(ctx, id, userID); err != nil {
+		return err
+	}
+	return s.folderDAO.Delete(ctx, id)
+}
+
+// MoveProjects moves projects into a folder, nil moves them to the root
+func (s *FolderService) MoveProjects(ctx context.Context, userID uint64, projectIDs []uint64, folderID *uint64) error {
+	if folderID != nil {
+		if _, err := s.getOwned(ctx, *folderID, userID); err != nil {
+			return err
+		}
+	}
+	ids, err := s.projectService.ensureOwned(ctx, userID, projectIDs)
+	if err != nil {
+		return err
+	}
+	return s.projectDAO.MoveToFolder(ctx, ids, folderID)
+}
+
+// getOwned retrieves a folder with ownership check
+func (s *FolderService) getOwned(ctx context.Context, id, userID uint64) (*model.ProjectFolder, error) {
+	folder, err := s.folderDAO.GetByID(ctx, id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrFolderNotFound
+		}
+		return nil, err
+	}
+	if folder.UserID != userID {
+		return nil, ErrFolderNotOwned
+	}
+	return folder, nil
+}
+
+// checkParent 校验父文件夹属于用户且本身位于根目录（只允许一层嵌套）
+func (s *FolderService) checkParent(ctx context.Context, userID, folderID uint64, parentID *uint64) error {
+	if parentID == nil {
+		return nil
+	}
+	if *parentID == folderID {
+		return ErrFolderTooDeep
+	}
+	parent, err := s.getOwned(ctx, *parentID, userID)
+	if err != nil {
+		return err
+	}
+	if parent.ParentID != nil {
+		return ErrFolderTooDeep
+	}
+	return nil
+}
+
+// normalizeFolderName 去除首尾空白并校验长度
+func normalizeFolderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", ErrFolderNameEmpty
+	}
+	if runes := []rune(name); len(runes) > maxFolderNameLen {
+		name = string(runes[:maxFolderNameLen])
+	}
+	return name, nil
+}
@@ -0,0 +1,19 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestNormalizeFolderName tests folder name trimming
+func TestNormalizeFolderName(t *testing.T) {
+	if name, err := normalizeFolderName("  Clients "); err != nil || name != "Clients" {
+		t.Errorf("normalizeFolderName() = %q, %v", name, err)
+	}
+	if _, err := normalizeFolderName("   "); err != ErrFolderNameEmpty {
+		t.Errorf("normalizeFolderName(blank) error = %v", err)
+	}
+	if name, _ := normalizeFolderName(strings.Repeat("é", maxFolderNameLen+5)); len([]rune(name)) != maxFolderNameLen {
+		t.Errorf("normalizeFolderName() did not truncate, got %d runes", len([]rune(name)))
+	}
+}
@@ -0,0 +1,597 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/test-tt/config"
+	"github.com/test-tt/internal/dao"
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/cache"
+	"github.com/test-tt/pkg/logger"
+	"github.com/test-tt/pkg/pagination"
+)
+
+const (
+	maxGalleryDescriptionLen = 500
+	// trendingWindowHalfLives 对账时只统计最近若干个半衰期内的收藏，更早的贡献可忽略
+	trendingWindowHalfLives = 10
+	galleryReconcileTimeout = 5 * time.Minute
+	galleryReconcileLockTTL = 5 * time.Minute
+
+	galleryTrendingKey      = "gallery:trending"
+	galleryCategoryKey      = "gallery:trending:c:%s"
+	galleryEpochKey         = "gallery:trending:epoch"
+	galleryVersionKey       = "gallery:version"
+	galleryPageCacheKey     = "gallery:page:%d:%s:%s:%d:%s"
+	galleryReconcileLockKey = "gallery:reconcile:lock"
+)
+
+var (
+	ErrGalleryCategoryInvalid = errors.New("unknown gallery category")
+	ErrGallerySortInvalid     = errors.New("gallery sort must be trending, new or top")
+	ErrGalleryNotListed       = errors.New("project is not in the gallery")
+)
+
+// trendingScript 按收藏时间给排行加减权重，基准时间与对账重建原子地切换
+// 权重 2^((t-epoch)/halfLife) 随时间指数增长，等价于让所有旧分数按半衰期衰减
+var trendingScript = redis.NewScript(`
+local epoch = tonumber(redis.call('GET', KEYS[3]))
+if not epoch then
+	epoch = tonumber(ARGV[1])
+	redis.call('SET', KEYS[3], ARGV[1])
+end
+local weight = ARGV[4] * math.pow(2, (ARGV[1] - epoch) / ARGV[2])
+redis.call('ZINCRBY', KEYS[1], weight, ARGV[3])
+redis.call('ZINCRBY', KEYS[2], weight, ARGV[3])
+return 1
+`)
+
+// GalleryOptions gallery listing parameters from the request
+type GalleryOptions struct {
+	Category string
+	Sort     string // trending (default), new or top
+	Cursor   string // next_cursor of the previous page
+	Limit    int
+}
+
+// galleryCursor 列表游标：new/top 记录上一页最后一项的排序值和 ID，trending 记录位置
+type galleryCursor struct {
+	Sort     string `json:"s"`
+	Category string `json:"c,omitempty"`
+	Value    string `json:"v,omitempty"`
+	ID       uint64 `json:"id,omitempty"`
+	Offset   int    `json:"o,omitempty"`
+}
+
+// galleryPage 缓存的一页列表（不含查看者的收藏状态）
+type galleryPage struct {
+	Items      []model.GalleryItem `json:"items"`
+	NextCursor string              `json:"next_cursor"`
+}
+
+// StarResult is the star state of a project after starring or unstarring
+type StarResult struct {
+	ProjectID uint64 `json:"project_id"`
+	Stars     int64  `json:"stars"`
+	Starred   bool   `json:"starred"`
+}
+
+type GalleryService struct {
+	rdb            *redis.Client
+	galleryDAO     *dao.GalleryDAO
+	publicationDAO *dao.PublicationDAO
+	projectService *ProjectService
+}
+
+func NewGalleryService() *GalleryService {
+	return &GalleryService{
+		rdb:            cache.RDB,
+		galleryDAO:     dao.NewGalleryDAO(),
+		publicationDAO: dao.NewPublicationDAO(),
+		projectService: NewProjectService(),
+	}
+}
+
+// galleryConfig 返回作品库配置，未配置时使用默认值
+func galleryConfig() *config.GalleryConfig {
+	if config.Cfg != nil && config.Cfg.Gallery != nil {
+		return config.Cfg.Gallery
+	}
+	return &config.GalleryConfig{
+		Categories:        []string{"landing", "portfolio", "blog", "shop", "game", "tool", "other"},
+		TrendingHalfLife:  48 * time.Hour,
+		ReconcileInterval: 10 * time.Minute,
+		CacheTTL:          time.Minute,
+	}
+}
+
+// Categories returns the gallery categories
+func (s *GalleryService) Categories() []string {
+	return galleryConfig().Categories
+}
+
+// ValidGalleryCategory reports whether a category is configured
+func ValidGalleryCategory(category string) bool {
+	for _, c := range galleryConfig().Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// Add lists a published project in the gallery or updates its category and description, owner only
+func (s *GalleryService) Add(ctx context.Context, projectID, userID uint64, category, description string) (*model.GalleryEntry, error) {
+	if !ValidGalleryCategory(category) {
+		return nil, ErrGalleryCategoryInvalid
+	}
+	description = strings.TrimSpace(description)
+	if runes := []rune(description); len(runes) > maxGalleryDescriptionLen {
+		description = string(runes[:maxGalleryDescriptionLen])
+	}
+
+	if _, err := s.projectService.GetByID(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+	publication, err := s.publicationDAO.GetByProjectID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if publication == nil {
+		return nil, ErrNotPublished
+	}
+
+	previous, err := s.galleryDAO.Get(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	entry := &model.GalleryEntry{
+		ProjectID:   projectID,
+		UserID:      userID,
+		Category:    category,
+		Description: description,
+		ListedAt:    now,
+		UpdatedAt:   now,
+	}
+	if err := s.galleryDAO.Save(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	oldCategory := ""
+	if previous != nil {
+		oldCategory = previous.Category
+	}
+	if err := s.placeInTrending(ctx, projectID, oldCategory, category); err != nil {
+		logger.WarnCtxf(ctx, "failed to update trending ranking", "projectID", projectID, "error", err)
+	}
+	s.invalidate(ctx)
+	return entry, nil
+}
+
+// Remove takes a project out of the gallery, owner only; its stars are kept for a later relisting
+func (s *GalleryService) Remove(ctx context.Context, projectID, userID uint64) error {
+	if _, err := s.projectService.GetByID(ctx, projectID, userID); err != nil {
+		return err
+	}
+	entry, err := s.galleryDAO.Get(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return ErrGalleryNotListed
+	}
+	if err := s.galleryDAO.Delete(ctx, projectID); err != nil {
+		return err
+	}
+
+	if s.rdb != nil {
+		member := strconv.FormatUint(projectID, 10)
+		pipe := s.rdb.TxPipeline()
+		pipe.ZRem(ctx, galleryTrendingKey, member)
+		pipe.ZRem(ctx, fmt.Sprintf(galleryCategoryKey, entry.Category), member)
+		if _, err := pipe.Exec(ctx); err != nil {
+			logger.WarnCtxf(ctx, "failed to update trending ranking", "projectID", projectID, "error", err)
+		}
+	}
+	s.invalidate(ctx)
+	return nil
+}
+
+// placeInTrending 新条目以 0 分进入排行，更换分类时带着当前分数移到新分类
+func (s *GalleryService) placeInTrending(ctx context.Context, projectID uint64, oldCategory, category string) error {
+	if s.rdb == nil {
+		return nil
+	}
+	member := strconv.FormatUint(projectID, 10)
+	score, err := s.rdb.ZScore(ctx, galleryTrendingKey, member).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	pipe := s.rdb.TxPipeline()
+	if oldCategory != "" && oldCategory != category {
+		pipe.ZRem(ctx, fmt.Sprintf(galleryCategoryKey, oldCategory), member)
+	}
+	pipe.ZAddNX(ctx, galleryTrendingKey, redis.Z{Score: 0, Member: member})
+	pipe.ZAdd(ctx, fmt.Sprintf(galleryCategoryKey, category), redis.Z{Score: score, Member: member})
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// List retrieves a page of the gallery; viewerID 0 means an anonymous visitor
+// 列表按 (版本, 排序, 分类, 游标) 缓存，查看者的收藏状态在缓存之外补充
+func (s *GalleryService) List(ctx context.Context, viewerID uint64, opts *GalleryOptions) ([]model.GalleryItem, string, error) {
+	if opts.Sort == "" {
+		opts.Sort = dao.GallerySortTrending
+	}
+	switch opts.Sort {
+	case dao.GallerySortTrending, dao.GallerySortNew, dao.GallerySortTop:
+	default:
+		return nil, "", ErrGallerySortInvalid
+	}
+	if opts.Category != "" && !ValidGalleryCategory(opts.Category) {
+		return nil, "", ErrGalleryCategoryInvalid
+	}
+
+	page, err := s.cachedPage(ctx, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	items := page.Items
+	if viewerID > 0 && len(items) > 0 {
+		ids := make([]uint64, len(items))
+		for i := range items {
+			ids[i] = items[i].ProjectID
+		}
+		starred, err := s.galleryDAO.StarredAmong(ctx, viewerID, ids)
+		if err != nil {
+			return nil, "", err
+		}
+		for i := range items {
+			items[i].Starred = starred[items[i].ProjectID]
+		}
+	}
+	return items, page.NextCursor, nil
+}
+
+func (s *GalleryService) cachedPage(ctx context.Context, opts *GalleryOptions) (*galleryPage, error) {
+	ttl := galleryConfig().CacheTTL
+	if s.rdb == nil || ttl <= 0 {
+		return s.page(ctx, opts)
+	}
+
+	version, err := s.rdb.Get(ctx, galleryVersionKey).Int64()
+	if err != nil && err != redis.Nil {
+		logger.WarnCtxf(ctx, "failed to read gallery cache version", "error", err)
+		return s.page(ctx, opts)
+	}
+	key := fmt.Sprintf(galleryPageCacheKey, version, opts.Sort, opts.Category, opts.Limit, opts.Cursor)
+	if cached, err := s.rdb.Get(ctx, key).Result(); err == nil {
+		var page galleryPage
+		if err := sonic.UnmarshalString(cached, &page); err == nil {
+			return &page, nil
+		}
+	}
+
+	page, err := s.page(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	data, _ := sonic.MarshalString(page)
+	if err := s.rdb.Set(ctx, key, data, ttl).Err(); err != nil {
+		logger.WarnCtxf(ctx, "failed to cache gallery page", "key", key, "error", err)
+	}
+	return page, nil
+}
+
+// invalidate 作品库内容变化时使所有缓存的列表失效
+func (s *GalleryService) invalidate(ctx context.Context) {
+	if s.rdb == nil {
+		return
+	}
+	if err := s.rdb.Incr(ctx, galleryVersionKey).Err(); err != nil {
+		logger.WarnCtxf(ctx, "failed to invalidate gallery cache", "error", err)
+	}
+}
+
+// page 读取一页列表；trending 优先使用 Redis 排行，排行尚未建立时退回 MySQL 中对账的分数
+func (s *GalleryService) page(ctx context.Context, opts *GalleryOptions) (*galleryPage, error) {
+	cursor := galleryCursor{Sort: opts.Sort, Category: opts.Category}
+	if opts.Cursor != "" {
+		if err := pagination.DecodeCursor(opts.Cursor, &cursor); err != nil {
+			return nil, err
+		}
+		// 游标与当前排序和分类不一致时无法续页
+		if cursor.Sort != opts.Sort || cursor.Category != opts.Category || cursor.Offset < 0 {
+			return nil, pagination.ErrInvalidCursor
+		}
+	}
+
+	var items []model.GalleryItem
+	var err error
+	hasMore := false
+	if opts.Sort == dao.GallerySortTrending {
+		items, hasMore, err = s.trendingPage(ctx, opts.Category, cursor.Offset, opts.Limit)
+	} else {
+		q := &dao.GalleryListQuery{Category: opts.Category, Sort: opts.Sort, Limit: opts.Limit + 1}
+		if cursor.ID > 0 {
+			if q.AfterValue, err = gallerySortValue(opts.Sort, cursor.Value); err != nil {
+				return nil, pagination.ErrInvalidCursor
+			}
+			q.AfterID = cursor.ID
+		}
+		items, err = s.galleryDAO.List(ctx, q)
+		if len(items) > opts.Limit {
+			items, hasMore = items[:opts.Limit], true
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	page := &galleryPage{Items: items}
+	if hasMore && len(items) > 0 {
+		last := items[len(items)-1]
+		next := galleryCursor{Sort: opts.Sort, Category: opts.Category}
+		switch opts.Sort {
+		case dao.GallerySortTrending:
+			next.Offset = cursor.Offset + opts.Limit
+		case dao.GallerySortNew:
+			next.Value, next.ID = last.ListedAt.Format(time.RFC3339Nano), last.ProjectID
+		default:
+			next.Value, next.ID = strconv.FormatInt(last.Stars, 10), last.ProjectID
+		}
+		if page.NextCursor, err = pagination.EncodeCursor(next); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// trendingPage 按热度读取 offset 开始的 limit 项，并返回之后是否还有数据
+// Redis 排行中已下线的项目会被过滤，此时该页可能少于 limit 项
+func (s *GalleryService) trendingPage(ctx context.Context, category string, offset, limit int) ([]model.GalleryItem, bool, error) {
+	if s.rdb != nil {
+		key := galleryTrendingKey
+		if category != "" {
+			key = fmt.Sprintf(galleryCategoryKey, category)
+		}
+		built, err := s.rdb.Exists(ctx, galleryEpochKey).Result()
+		if err != nil {
+			return nil, false, err
+		}
+		if built > 0 {
+			// 多取一项用于判断是否还有下一页
+			members, err := s.rdb.ZRevRange(ctx, key, int64(offset), int64(offset+limit)).Result()
+			if err != nil {
+				return nil, false, err
+			}
+			hasMore := len(members) > limit
+			if hasMore {
+				members = members[:limit]
+			}
+			ids := make([]uint64, 0, len(members))
+			for _, m := range members {
+				if id, err := strconv.ParseUint(m, 10, 64); err == nil {
+					ids = append(ids, id)
+				}
+			}
+			items, err := s.galleryDAO.ListByProjectIDs(ctx, ids)
+			return items, hasMore, err
+		}
+	}
+	items, err := s.galleryDAO.List(ctx, &dao.GalleryListQuery{
+		Category: category,
+		Sort:     dao.GallerySortTrending,
+		Offset:   offset,
+		Limit:    limit + 1,
+	})
+	if len(items) > limit {
+		return items[:limit], true, err
+	}
+	return items, false, err
+}
+
+// gallerySortValue 将游标中的字符串还原为查询参数
+func gallerySortValue(sort, value string) (interface{}, error) {
+	if sort == dao.GallerySortNew {
+		return time.Parse(time.RFC3339Nano, value)
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// Star stars a gallery project for a user, starring twice has no effect
+func (s *GalleryService) Star(ctx context.Context, projectID, userID uint64) (*StarResult, error) {
+	entry, err := s.visibleEntry(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	star, err := s.galleryDAO.Star(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if star != nil {
+		s.scoreStar(ctx, entry, star.CreatedAt, 1)
+	}
+	return s.starResult(ctx, projectID, true)
+}
+
+// Unstar removes a user's star, unstarring twice has no effect
+func (s *GalleryService) Unstar(ctx context.Context, projectID, userID uint64) (*StarResult, error) {
+	entry, err := s.visibleEntry(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	star, err := s.galleryDAO.Unstar(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if star != nil {
+		// 减去该收藏当初加上的权重
+		s.scoreStar(ctx, entry, star.CreatedAt, -1)
+	}
+	return s.starResult(ctx, projectID, false)
+}
+
+// visibleEntry 返回作品库中仍可见的条目
+func (s *GalleryService) visibleEntry(ctx context.Context, projectID uint64) (*model.GalleryEntry, error) {
+	entry, err := s.galleryDAO.Get(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, ErrGalleryNotListed
+	}
+	items, err := s.galleryDAO.ListByProjectIDs(ctx, []uint64{projectID})
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, ErrGalleryNotListed
+	}
+	return entry, nil
+}
+
+func (s *GalleryService) starResult(ctx context.Context, projectID uint64, starred bool) (*StarResult, error) {
+	stars, err := s.galleryDAO.CountStars(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return &StarResult{ProjectID: projectID, Stars: stars, Starred: starred}, nil
+}
+
+// scoreStar 更新 Redis 热度排行，失败时等待下次对账修正
+func (s *GalleryService) scoreStar(ctx context.Context, entry *model.GalleryEntry, starredAt time.Time, sign int) {
+	if s.rdb == nil {
+		return
+	}
+	halfLife := galleryConfig().TrendingHalfLife.Seconds()
+	err := trendingScript.Run(ctx, s.rdb,
+		[]string{galleryTrendingKey, fmt.Sprintf(galleryCategoryKey, entry.Category), galleryEpochKey},
+		unixSeconds(starredAt), halfLife, strconv.FormatUint(entry.ProjectID, 10), sign,
+	).Err()
+	if err != nil {
+		logger.WarnCtxf(ctx, "failed to update trending ranking", "projectID", entry.ProjectID, "error", err)
+	}
+}
+
+// TrendingWeight is the contribution of a star given at starredAt to the trending score at now
+func TrendingWeight(starredAt, now time.Time, halfLife time.Duration) float64 {
+	return math.Pow(2, (unixSeconds(starredAt)-unixSeconds(now))/halfLife.Seconds())
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixMilli()) / 1000
+}
+
+// Reconcile recounts stars in MySQL and rebuilds the Redis trending rankings from them
+// 以当前时间为新的基准重算分数，同时消除 Redis 中累积的误差和已下线的项目
+func (s *GalleryService) Reconcile(ctx context.Context) error {
+	cfg := galleryConfig()
+	now := time.Now()
+	stars, err := s.galleryDAO.ListStarsSince(ctx, now.Add(-cfg.TrendingHalfLife*trendingWindowHalfLives))
+	if err != nil {
+		return err
+	}
+	categories, err := s.galleryDAO.ListVisibleCategories(ctx)
+	if err != nil {
+		return err
+	}
+
+	scores := make(map[uint64]float64)
+	for _, star := range stars {
+		if _, ok := categories[star.ProjectID]; ok {
+			scores[star.ProjectID] += TrendingWeight(star.CreatedAt, now, cfg.TrendingHalfLife)
+		}
+	}
+	if err := s.galleryDAO.Reconcile(ctx, scores); err != nil {
+		return err
+	}
+	if s.rdb == nil {
+		return nil
+	}
+
+	rankings := map[string][]redis.Z{galleryTrendingKey: {}}
+	for _, c := range cfg.Categories {
+		rankings[fmt.Sprintf(galleryCategoryKey, c)] = []redis.Z{}
+	}
+	for projectID, category := range categories {
+		z := redis.Z{Score: scores[projectID], Member: strconv.FormatUint(projectID, 10)}
+		rankings[galleryTrendingKey] = append(rankings[galleryTrendingKey], z)
+		key := fmt.Sprintf(galleryCategoryKey, category)
+		rankings[key] = append(rankings[key], z)
+	}
+
+	// 在一个 MULTI 中替换所有排行和基准时间，读者不会看到重建到一半的排行
+	pipe := s.rdb.TxPipeline()
+	for key, members := range rankings {
+		pipe.Del(ctx, key)
+		if len(members) > 0 {
+			pipe.ZAdd(ctx, key, members...)
+		}
+	}
+	pipe.Set(ctx, galleryEpochKey, strconv.FormatFloat(unixSeconds(now), 'f', 3, 64), 0)
+	pipe.Incr(ctx, galleryVersionKey)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// StartGalleryReconciler 启动作品库对账任务，启动时立即执行一次
+// 返回停止函数；多实例部署时通过 Redis 锁避免重复执行
+func StartGalleryReconciler(interval time.Duration) func() {
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	stopChan := make(chan struct{})
+	galleryService := NewGalleryService()
+
+	go func() {
+		reconcileGallery(galleryService)
+		for {
+			select {
+			case <-ticker.C:
+				reconcileGallery(galleryService)
+			case <-stopChan:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(stopChan)
+	}
+}
+
+func reconcileGallery(galleryService *GalleryService) {
+	ctx, cancel := context.WithTimeout(context.Background(), galleryReconcileTimeout)
+	defer cancel()
+
+	if galleryService.rdb != nil {
+		ok, err := galleryService.rdb.SetNX(ctx, galleryReconcileLockKey, "1", galleryReconcileLockTTL).Result()
+		if err != nil {
+			logger.Errorf("failed to lock gallery reconcile", "error", err)
+			return
+		}
+		if !ok {
+			return
+		}
+		defer galleryService.rdb.Del(context.Background(), galleryReconcileLockKey)
+	}
+
+	if err := galleryService.Reconcile(ctx); err != nil {
+		logger.Errorf("failed to reconcile gallery", "error", err)
+	}
+}
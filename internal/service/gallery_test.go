@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/test-tt/internal/dao"
+	"github.com/test-tt/pkg/pagination"
+)
+
+// TestTrendingWeight tests that a star's weight halves every half-life
+func TestTrendingWeight(t *testing.T) {
+	now := time.Now()
+	halfLife := 48 * time.Hour
+	tests := []struct {
+		age  time.Duration
+		want float64
+	}{
+		{0, 1},
+		{halfLife, 0.5},
+		{2 * halfLife, 0.25},
+		{halfLife / 2, math.Sqrt(0.5)},
+	}
+	for _, tt := range tests {
+		got := TrendingWeight(now.Add(-tt.age), now, halfLife)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("TrendingWeight(age %v) = %v, want %v", tt.age, got, tt.want)
+		}
+	}
+
+	// 以任意基准计算的分数之比不变，排序与基准无关
+	epoch := now.Add(-100 * time.Hour)
+	a := TrendingWeight(now.Add(-time.Hour), epoch, halfLife)
+	b := TrendingWeight(now.Add(-10*time.Hour), epoch, halfLife)
+	want := TrendingWeight(now.Add(-time.Hour), now, halfLife) / TrendingWeight(now.Add(-10*time.Hour), now, halfLife)
+	if math.Abs(a/b-want) > 1e-9 {
+		t.Errorf("weight ratio = %v, want %v", a/b, want)
+	}
+}
+
+// TestValidGalleryCategory tests the default categories
+func TestValidGalleryCategory(t *testing.T) {
+	for _, c := range []string{"landing", "game", "other"} {
+		if !ValidGalleryCategory(c) {
+			t.Errorf("ValidGalleryCategory(%q) = false", c)
+		}
+	}
+	for _, c := range []string{"", "Landing", "unknown"} {
+		if ValidGalleryCategory(c) {
+			t.Errorf("ValidGalleryCategory(%q) = true", c)
+		}
+	}
+}
+
+// TestGalleryService_ListValidation tests rejected options before any query
+func TestGalleryService_ListValidation(t *testing.T) {
+	ctx := context.Background()
+	s := &GalleryService{}
+
+	if _, _, err := s.List(ctx, 0, &GalleryOptions{Sort: "hot", Limit: 10}); err != ErrGallerySortInvalid {
+		t.Errorf("List(sort=hot) error = %v, want %v", err, ErrGallerySortInvalid)
+	}
+	if _, _, err := s.List(ctx, 0, &GalleryOptions{Category: "nope", Limit: 10}); err != ErrGalleryCategoryInvalid {
+		t.Errorf("List(category=nope) error = %v, want %v", err, ErrGalleryCategoryInvalid)
+	}
+
+	// 游标只能用于生成它的排序和分类
+	cursor, _ := pagination.EncodeCursor(galleryCursor{Sort: dao.GallerySortNew, Category: "game", Value: time.Now().Format(time.RFC3339Nano), ID: 1})
+	tests := []*GalleryOptions{
+		{Sort: dao.GallerySortTop, Category: "game", Cursor: cursor, Limit: 10},
+		{Sort: dao.GallerySortNew, Category: "blog", Cursor: cursor, Limit: 10},
+		{Sort: dao.GallerySortNew, Cursor: "not-a-cursor", Limit: 10},
+	}
+	for _, opts := range tests {
+		if _, _, err := s.List(ctx, 0, opts); err != pagination.ErrInvalidCursor {
+			t.Errorf("List(%+v) error = %v, want %v", opts, err, pagination.ErrInvalidCursor)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/test-tt/internal/dao"
+	"github.com/test-tt/internal/model"
+)
+
+const maxMembersPerProject = 50
+
+var (
+	ErrMemberNotFound    = errors.New("member not found")
+	ErrMemberRoleInvalid = errors.New("member role must be editor or viewer")
+	ErrMemberIsOwner     = errors.New("project owner cannot be a member")
+	ErrMemberLimit       = errors.New("too many project members")
+)
+
+type MemberService struct {
+	memberDAO      *dao.MemberDAO
+	userDAO        *dao.UserDAO
+	projectService *ProjectService
+}
+
+func NewMemberService() *MemberService {
+	return &MemberService{
+		memberDAO:      dao.NewMemberDAO(),
+		userDAO:        dao.NewUserDAO(),
+		projectService: NewProjectService(),
+	}
+}
+
+// List retrieves the members of a project the user owns or is a member of
+func (s *MemberService) List(ctx context.Context, projectID, userID uint64) ([]model.ProjectMemberInfo, error) {
+	if _, _, err := s.projectService.Access(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+	return s.memberDAO.ListByProjectID(ctx, projectID)
+}
+
+// Add invites a user by email or changes their role, only the owner can manage members
+func (s *MemberService) Add(ctx context.Context, projectID, ownerID uint64, email, role string) (*model.ProjectMemberInfo, error) {
+	if !ValidMemberRole(role) {
+		return nil, ErrMemberRoleInvalid
+	}
+	if _, err := s.projectService.GetByID(ctx, projectID, ownerID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userDAO.GetByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if user.ID == ownerID {
+		return nil, ErrMemberIsOwner
+	}
+
+	existing, err := s.memberDAO.Get(ctx, projectID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		count, err := s.memberDAO.CountByProjectID(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		if count >= maxMembersPerProject {
+			return nil, ErrMemberLimit
+		}
+	}
+
+	member := &model.ProjectMember{ProjectID: projectID, UserID: user.ID, Role: role}
+	if existing != nil {
+		member.CreatedAt = existing.CreatedAt
+	}
+	if err := s.memberDAO.Upsert(ctx, member); err != nil {
+		return nil, err
+	}
+	return &model.ProjectMemberInfo{
+		UserID:    user.ID,
+		Name:      user.Name,
+		Email:     user.Email,
+		Role:      role,
+		CreatedAt: member.CreatedAt,
+	}, nil
+}
+
+// Remove removes a member; the owner can remove anyone and members can leave
+func (s *MemberService) Remove(ctx context.Context, projectID, userID, memberID uint64) error {
+	project, role, err := s.projectService.Access(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if role != model.MemberRoleOwner && memberID != userID {
+		return ErrProjectNotOwned
+	}
+	if memberID == project.UserID {
+		return ErrMemberIsOwner
+	}
+
+	existing, err := s.memberDAO.Get(ctx, projectID, memberID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return ErrMemberNotFound
+	}
+	return s.memberDAO.Delete(ctx, projectID, memberID)
+}
+
+// ValidMemberRole reports whether a role can be granted to a member
+func ValidMemberRole(role string) bool {
+	return role == model.MemberRoleEditor || role == model.MemberRoleViewer
+}
@@ -2,30 +2,110 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	stdhtml "html"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/bytedance/sonic"
 	"gorm.io/gorm"
 
+	"github.com/test-tt/config"
 	"github.com/test-tt/internal/dao"
 	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/logger"
+	"github.com/test-tt/pkg/pagination"
+	"github.com/test-tt/pkg/sanitize"
+)
+
+const (
+	defaultProjectName = "New Project"
+	maxProjectNameLen  = 255
+	forkNameSuffix     = " (fork)"
+	thumbnailMaxLen    = 140
 )
 
 var (
 	ErrProjectNotFound    = errors.New("project not found")
 	ErrProjectNotOwned    = errors.New("project does not belong to user")
 	ErrProjectNameEmpty   = errors.New("project name cannot be empty")
+	ErrProjectSortInvalid = errors.New("invalid project sort")
+	ErrProjectReadOnly    = errors.New("project is read-only for viewers")
+	ErrRegionInvalid      = errors.New("region must be html or css")
+	ErrChatHistoryInvalid = errors.New("project chat history is not valid JSON")
+)
+
+// 可单独编辑的项目内容区域
+const (
+	RegionHTML = "html"
+	RegionCSS  = "css"
 )
 
 type ProjectService struct {
-	projectDAO *dao.ProjectDAO
+	projectDAO     *dao.ProjectDAO
+	snapshotDAO    *dao.SnapshotDAO
+	publicationDAO *dao.PublicationDAO
+	assetDAO       *dao.AssetDAO
+	tagDAO         *dao.TagDAO
+	memberDAO      *dao.MemberDAO
+	fileDAO        *dao.FileDAO
+	quotaService   *QuotaService
+	sanitizePolicy *sanitize.Policy
+}
+
+// SavedProject is a project returned by a save, with the sanitization warnings
+type SavedProject struct {
+	*model.Project
+	Warnings      []sanitize.Warning `json:"warnings"`
+	PolicyVersion string             `json:"policy_version"`
 }
 
 func NewProjectService() *ProjectService {
 	return &ProjectService{
-		projectDAO: dao.NewProjectDAO(),
+		projectDAO:     dao.NewProjectDAO(),
+		snapshotDAO:    dao.NewSnapshotDAO(),
+		publicationDAO: dao.NewPublicationDAO(),
+		assetDAO:       dao.NewAssetDAO(),
+		tagDAO:         dao.NewTagDAO(),
+		memberDAO:      dao.NewMemberDAO(),
+		fileDAO:        dao.NewFileDAO(),
+		quotaService:   NewQuotaService(),
+		sanitizePolicy: projectSanitizePolicy(),
 	}
 }
 
+var (
+	sanitizePolicyOnce sync.Once
+	sanitizePolicy     *sanitize.Policy
+)
+
+// projectSanitizePolicy 根据配置构建清洗策略，配置无效时使用默认策略
+func projectSanitizePolicy() *sanitize.Policy {
+	sanitizePolicyOnce.Do(func() {
+		sanitizePolicy = sanitize.DefaultPolicy()
+		if config.Cfg == nil || config.Cfg.Sanitize == nil {
+			return
+		}
+		cfg := config.Cfg.Sanitize
+		policy, err := sanitize.NewPolicy(&sanitize.Options{
+			ScriptMode:    cfg.ScriptMode,
+			ExternalURLs:  cfg.ExternalURLs,
+			RewritePrefix: cfg.RewritePrefix,
+			ExtraTags:     cfg.ExtraTags,
+			ExtraAttrs:    cfg.ExtraAttrs,
+		})
+		if err != nil {
+			logger.Warnf("invalid sanitize config, using default policy", "error", err)
+			return
+		}
+		sanitizePolicy = policy
+	})
+	return sanitizePolicy
+}
+
 // GetByID retrieves a project by ID with ownership check
 func (s *ProjectService) GetByID(ctx context.Context, id, userID uint64) (*model.Project, error) {
 	project, err := s.projectDAO.GetByID(ctx, id)
@@ -44,9 +124,167 @@ func (s *ProjectService) GetByID(ctx context.Context, id, userID uint64) (*model
 	return project, nil
 }
 
-// GetByUserID retrieves all projects for a user
-func (s *ProjectService) GetByUserID(ctx context.Context, userID uint64) ([]model.Project, error) {
-	return s.projectDAO.GetByUserID(ctx, userID)
+// Access retrieves a project the user owns or is a member of, with the user's role
+func (s *ProjectService) Access(ctx context.Context, id, userID uint64) (*model.Project, string, error) {
+	project, err := s.projectDAO.GetByID(ctx, id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, "", ErrProjectNotFound
+		}
+		return nil, "", err
+	}
+	if project.UserID == userID {
+		return project, model.MemberRoleOwner, nil
+	}
+
+	member, err := s.memberDAO.Get(ctx, id, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if member == nil {
+		return nil, "", ErrProjectNotOwned
+	}
+	return project, member.Role, nil
+}
+
+// ProjectListOptions project list parameters from the request
+type ProjectListOptions struct {
+	Keyword  string
+	FolderID *uint64 // Only projects in this folder, 0 for projects outside any folder
+	Tag      string
+	SortBy   string // updated_at (default), created_at or name
+	Order    string // desc (default) or asc
+	Cursor   string // next_cursor of the previous page
+	Limit    int
+}
+
+// projectCursor 列表游标，记录上一页最后一项的排序值和 ID
+type projectCursor struct {
+	SortBy string `json:"s"`
+	Order  string `json:"o"`
+	Value  string `json:"v"`
+	ID     uint64 `json:"id"`
+}
+
+// List retrieves a page of project summaries for a user
+// 返回 nextCursor，为空表示没有更多数据
+func (s *ProjectService) List(ctx context.Context, userID uint64, opts *ProjectListOptions) ([]model.ProjectSummary, string, error) {
+	q := &dao.ProjectListQuery{
+		UserID:   userID,
+		Keyword:  strings.TrimSpace(opts.Keyword),
+		FolderID: opts.FolderID,
+		SortBy:   opts.SortBy,
+		Asc:      opts.Order == "asc",
+		Limit:    opts.Limit + 1, // 多取一条判断是否还有下一页
+	}
+	if q.SortBy == "" {
+		q.SortBy = "updated_at"
+	}
+	if !dao.ValidProjectSort(q.SortBy) || (opts.Order != "" && opts.Order != "asc" && opts.Order != "desc") {
+		return nil, "", ErrProjectSortInvalid
+	}
+	if opts.Tag != "" {
+		tag, err := normalizeTag(opts.Tag)
+		if err != nil {
+			return nil, "", err
+		}
+		q.Tag = tag
+	}
+	order := "desc"
+	if q.Asc {
+		order = "asc"
+	}
+
+	if opts.Cursor != "" {
+		var cursor projectCursor
+		if err := pagination.DecodeCursor(opts.Cursor, &cursor); err != nil {
+			return nil, "", err
+		}
+		// 游标与当前排序方式不一致时无法续页
+		if cursor.SortBy != q.SortBy || cursor.Order != order || cursor.ID == 0 {
+			return nil, "", pagination.ErrInvalidCursor
+		}
+		value, err := cursorSortValue(q.SortBy, cursor.Value)
+		if err != nil {
+			return nil, "", pagination.ErrInvalidCursor
+		}
+		q.AfterValue, q.AfterID = value, cursor.ID
+	}
+
+	summaries, err := s.projectDAO.ListSummaries(ctx, q)
+	if err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(summaries) > opts.Limit {
+		summaries = summaries[:opts.Limit]
+		last := summaries[len(summaries)-1]
+		nextCursor, err = pagination.EncodeCursor(projectCursor{
+			SortBy: q.SortBy,
+			Order:  order,
+			Value:  summarySortValue(q.SortBy, &last),
+			ID:     last.ID,
+		})
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	ids := make([]uint64, len(summaries))
+	for i := range summaries {
+		ids[i] = summaries[i].ID
+	}
+	tags, err := s.tagDAO.ListByProjectIDs(ctx, ids)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for i := range summaries {
+		summaries[i].Thumbnail = ProjectThumbnail(summaries[i].HTMLHead)
+		summaries[i].HTMLHead = ""
+		summaries[i].Tags = tags[summaries[i].ID]
+		if summaries[i].Tags == nil {
+			summaries[i].Tags = []string{}
+		}
+	}
+	return summaries, nextCursor, nil
+}
+
+// summarySortValue 将排序字段值编码为游标中的字符串
+func summarySortValue(sortBy string, summary *model.ProjectSummary) string {
+	switch sortBy {
+	case "name":
+		return summary.Name
+	case "created_at":
+		return summary.CreatedAt.Format(time.RFC3339Nano)
+	default:
+		return summary.UpdatedAt.Format(time.RFC3339Nano)
+	}
+}
+
+// cursorSortValue 将游标中的字符串还原为查询参数
+func cursorSortValue(sortBy, value string) (interface{}, error) {
+	if sortBy == "name" {
+		return value, nil
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+var (
+	thumbnailStripPattern = regexp.MustCompile(`(?is)<script\b.*?(</script\s*>|\z)|<style\b.*?(</style\s*>|\z)|<head\b.*?(</head\s*>|\z)|<!--.*?(-->|\z)`)
+	thumbnailTagPattern   = regexp.MustCompile(`(?s)<[^>]*(>|\z)`)
+)
+
+// ProjectThumbnail extracts a short plain-text excerpt from page HTML for list display
+func ProjectThumbnail(html string) string {
+	text := thumbnailStripPattern.ReplaceAllString(html, " ")
+	text = thumbnailTagPattern.ReplaceAllString(text, " ")
+	text = strings.Join(strings.Fields(stdhtml.UnescapeString(text)), " ")
+	if runes := []rune(text); len(runes) > thumbnailMaxLen {
+		text = strings.TrimSpace(string(runes[:thumbnailMaxLen])) + "…"
+	}
+	return text
 }
 
 // GetLatestByUserID retrieves the most recent project for a user
@@ -57,7 +295,10 @@ func (s *ProjectService) GetLatestByUserID(ctx context.Context, userID uint64) (
 // Create creates a new project
 func (s *ProjectService) Create(ctx context.Context, userID uint64, name string) (*model.Project, error) {
 	if name == "" {
-		name = "New Project"
+		name = defaultProjectName
+	}
+	if err := s.quotaService.CheckCreate(ctx, userID, 0); err != nil {
+		return nil, err
 	}
 
 	project := &model.Project{
@@ -75,8 +316,186 @@ func (s *ProjectService) Create(ctx context.Context, userID uint64, name string)
 	return project, nil
 }
 
+// CreateFromTemplate creates a new project starting from a template's HTML and CSS
+// 模板内容与保存一样经过清洗，管理员维护的模板同样不能带入策略不允许的内容
+func (s *ProjectService) CreateFromTemplate(ctx context.Context, userID uint64, name string, template *model.ProjectTemplate) (*SavedProject, error) {
+	if name == "" {
+		name = template.Name
+	}
+	cleaned := s.sanitizePolicy.Sanitize(template.HTML, template.CSS)
+	if err := s.quotaService.CheckCreate(ctx, userID, contentSize(cleaned.HTML, cleaned.CSS)); err != nil {
+		return nil, err
+	}
+
+	project := &model.Project{
+		UserID:   userID,
+		Name:     name,
+		HTML:     cleaned.HTML,
+		CSS:      cleaned.CSS,
+		Messages: "[]",
+	}
+
+	if err := s.projectDAO.Create(ctx, project); err != nil {
+		return nil, err
+	}
+
+	return s.saved(ctx, project, cleaned), nil
+}
+
+// Fork copies a project into the caller's account
+// 所有者和成员复制当前内容、对话记录和全部文件；其他用户的项目必须已发布，且只复制发布的快照（未发布的修改不会泄露）
+func (s *ProjectService) Fork(ctx context.Context, sourceID, userID uint64, name string) (*model.Project, error) {
+	source, _, err := s.Access(ctx, sourceID, userID)
+	published, err := forkReadsSnapshot(err)
+	if err != nil {
+		return nil, err
+	}
+
+	var html, css, messages string
+	var files []model.ProjectFile
+	if !published {
+		sourceFiles, err := s.fileDAO.ListWithContent(ctx, sourceID)
+		if err != nil {
+			return nil, err
+		}
+		html, css, messages, files = source.HTML, source.CSS, source.Messages, copyFiles(sourceFiles)
+	} else {
+		if source, err = s.projectDAO.GetByID(ctx, sourceID); err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, ErrProjectNotFound
+			}
+			return nil, err
+		}
+		publication, err := s.publicationDAO.GetByProjectID(ctx, sourceID)
+		if err != nil {
+			return nil, err
+		}
+		// 未发布的项目对其他用户不可见，与不存在一致
+		if publication == nil {
+			return nil, ErrProjectNotFound
+		}
+		snapshot, err := s.snapshotDAO.GetByID(ctx, publication.SnapshotID)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, ErrProjectNotFound
+			}
+			return nil, err
+		}
+		snapshotFiles, err := s.snapshotDAO.ListFiles(ctx, snapshot.ID)
+		if err != nil {
+			return nil, err
+		}
+		html, css, messages, files = snapshot.HTML, snapshot.CSS, "[]", copyFiles(snapshotFiles)
+	}
+
+	if name == "" {
+		name = forkName(source.Name)
+	}
+	if err := s.quotaService.CheckCreate(ctx, userID, contentSize(html, css)+filesSize(files)); err != nil {
+		return nil, err
+	}
+
+	project := &model.Project{
+		UserID:       userID,
+		Name:         name,
+		HTML:         html,
+		CSS:          css,
+		Messages:     messages,
+		ForkedFromID: &source.ID,
+	}
+
+	if err := s.projectDAO.Create(ctx, project, files...); err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+// forkReadsSnapshot 根据 Access 的结果判断复刻的来源：所有者和成员（包括只读成员）复制当前内容，
+// 不是成员时返回 true，只能复制发布的快照
+func forkReadsSnapshot(accessErr error) (bool, error) {
+	switch {
+	case accessErr == nil:
+		return false, nil
+	case errors.Is(accessErr, ErrProjectNotOwned):
+		return true, nil
+	default:
+		return false, accessErr
+	}
+}
+
+// Import creates a project from a parsed bundle
+func (s *ProjectService) Import(ctx context.Context, userID uint64, name string, bundle *Bundle) (*SavedProject, error) {
+	if name == "" {
+		name = bundle.Manifest.Name
+	}
+	if name == "" {
+		name = defaultProjectName
+	}
+	if runes := []rune(name); len(runes) > maxProjectNameLen {
+		name = string(runes[:maxProjectNameLen])
+	}
+	files := copyFiles(bundle.Files)
+	cleaned := sanitizeProjectFiles(s.sanitizePolicy, bundle.HTML, bundle.CSS, files)
+	if err := s.quotaService.CheckCreate(ctx, userID, contentSize(cleaned.HTML, cleaned.CSS)+filesSize(files)); err != nil {
+		return nil, err
+	}
+
+	project := &model.Project{
+		UserID:   userID,
+		Name:     name,
+		HTML:     cleaned.HTML,
+		CSS:      cleaned.CSS,
+		Messages: bundle.Messages,
+	}
+
+	if err := s.projectDAO.Create(ctx, project, files...); err != nil {
+		return nil, err
+	}
+
+	return s.saved(ctx, project, cleaned), nil
+}
+
+// ListFiles retrieves the extra files of a project with their content, the caller checks access
+func (s *ProjectService) ListFiles(ctx context.Context, projectID uint64) ([]model.ProjectFile, error) {
+	return s.fileDAO.ListWithContent(ctx, projectID)
+}
+
+// copyFiles 只复制路径和内容，用于在新项目中创建文件
+func copyFiles(files []model.ProjectFile) []model.ProjectFile {
+	copies := make([]model.ProjectFile, len(files))
+	for i, f := range files {
+		copies[i] = model.ProjectFile{Path: f.Path, Content: f.Content}
+	}
+	return copies
+}
+
+// filesSize 文件内容的总字节数
+func filesSize(files []model.ProjectFile) int64 {
+	var total int64
+	for _, f := range files {
+		total += int64(len(f.Content))
+	}
+	return total
+}
+
+// otherFilesSize index.html 和 style.css 以外文件的总字节数，由保存时计入 ContentSize 的总大小得出
+func otherFilesSize(project *model.Project) int64 {
+	return max(0, project.ContentSize-contentSize(project.HTML, project.CSS))
+}
+
+// forkName 为 fork 生成默认名称，超长时截断原名称
+func forkName(sourceName string) string {
+	runes := []rune(sourceName)
+	if max := maxProjectNameLen - len(forkNameSuffix); len(runes) > max {
+		runes = runes[:max]
+	}
+	return string(runes) + forkNameSuffix
+}
+
 // Update updates a project with ownership check
-func (s *ProjectService) Update(ctx context.Context, id, userID uint64, name, html, css, messages string) (*model.Project, error) {
+// HTML 和 CSS 先按清洗策略处理，再检查限额并保存
+func (s *ProjectService) Update(ctx context.Context, id, userID uint64, name, html, css, messages string) (*SavedProject, error) {
 	// Check ownership
 	project, err := s.projectDAO.GetByID(ctx, id)
 	if err != nil {
@@ -90,22 +509,170 @@ func (s *ProjectService) Update(ctx context.Context, id, userID uint64, name, ht
 		return nil, ErrProjectNotOwned
 	}
 
+	cleaned := s.sanitizePolicy.Sanitize(html, css)
+	others := otherFilesSize(project)
+	if err := s.quotaService.CheckUpdate(ctx, userID, contentSize(project.HTML, project.CSS)+others, contentSize(cleaned.HTML, cleaned.CSS)+others); err != nil {
+		return nil, err
+	}
+
 	// Update fields
 	if name != "" {
 		project.Name = name
 	}
-	project.HTML = html
-	project.CSS = css
+	project.HTML = cleaned.HTML
+	project.CSS = cleaned.CSS
 	project.Messages = messages
 
+	err = GetCollabHub().Apply(ctx, id, userID, func() (*model.Project, error) {
+		return project, s.projectDAO.Update(ctx, project)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.saved(ctx, project, cleaned), nil
+}
+
+// SaveRegion replaces the HTML or CSS of a project the user owns or can edit
+// 只清洗并保存一个区域，另一区域保持不变；限额按项目所有者的套餐计算
+func (s *ProjectService) SaveRegion(ctx context.Context, id, userID uint64, region, content string) (*SavedProject, error) {
+	project, role, err := s.Access(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role == model.MemberRoleViewer {
+		return nil, ErrProjectReadOnly
+	}
+
+	html, css := project.HTML, project.CSS
+	switch region {
+	case RegionHTML:
+		html = content
+	case RegionCSS:
+		css = content
+	default:
+		return nil, ErrRegionInvalid
+	}
+
+	cleaned := s.sanitizePolicy.Sanitize(html, css)
+	others := otherFilesSize(project)
+	if err := s.quotaService.CheckUpdate(ctx, project.UserID, contentSize(project.HTML, project.CSS)+others, contentSize(cleaned.HTML, cleaned.CSS)+others); err != nil {
+		return nil, err
+	}
+
+	project.HTML = cleaned.HTML
+	project.CSS = cleaned.CSS
 	if err := s.projectDAO.Update(ctx, project); err != nil {
 		return nil, err
 	}
+	return s.saved(ctx, project, cleaned), nil
+}
 
-	return project, nil
+// GeneratedPage is a completed generation to save into a project
+type GeneratedPage struct {
+	HTML    string              // Full page, its <style> blocks become the project's style.css
+	Turns   []model.ChatMessage // Prompt and reply appended to the chat history
+	Quality *model.QualityReport
+	// Splice 重新生成单个元素时不为空：在加锁读取的最新项目 HTML 中替换该元素并返回整页，HTML 字段不再使用
+	Splice func(current string) (string, error)
+}
+
+// ApplyGeneration saves a generated page into a project the user owns or can edit and appends the chat turns
+// 内容和对话记录在同一事务中基于最新的项目写入，不会覆盖期间其他人追加的消息；限额按项目所有者的套餐计算
+func (s *ProjectService) ApplyGeneration(ctx context.Context, id, userID uint64, page *GeneratedPage) (*SavedProject, error) {
+	_, role, err := s.Access(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role == model.MemberRoleViewer {
+		return nil, ErrProjectReadOnly
+	}
+
+	// 页面中的 <style> 由清洗时移入 CSS，不再另外传入，避免样式重复
+	var cleaned *sanitize.Result
+	if page.Splice == nil {
+		cleaned = s.sanitizePolicy.Sanitize(page.HTML, "")
+	}
+	var project *model.Project
+	err = GetCollabHub().Apply(ctx, id, userID, func() (*model.Project, error) {
+		var err error
+		project, err = s.projectDAO.UpdateLocked(ctx, id, func(project *model.Project) error {
+			// 元素替换到项目当前的 HTML 中，不覆盖请求发出后其他人对页面的修改；元素已不存在时返回替换的错误
+			if page.Splice != nil {
+				spliced, err := page.Splice(project.HTML)
+				if err != nil {
+					return err
+				}
+				cleaned = s.sanitizePolicy.Sanitize(spliced, "")
+			}
+			css := generatedCSS(cleaned, project.CSS)
+			others := otherFilesSize(project)
+			if err := s.quotaService.CheckUpdate(ctx, project.UserID, contentSize(project.HTML, project.CSS)+others, contentSize(cleaned.HTML, css)+others); err != nil {
+				return err
+			}
+			messages, err := appendChatMessages(project.Messages, page.Turns)
+			if err != nil {
+				return err
+			}
+			project.HTML = cleaned.HTML
+			project.CSS = css
+			project.Messages = messages
+			project.Quality = page.Quality
+			return nil
+		})
+		return project, err
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, err
+	}
+	return s.saved(ctx, project, cleaned), nil
 }
 
-// Delete deletes a project with ownership check
+// generatedCSS 生成的页面带有样式块时以其为准；没有样式块（如只修改了结构）时保留项目原有的 CSS
+func generatedCSS(cleaned *sanitize.Result, current string) string {
+	if strings.TrimSpace(cleaned.CSS) == "" {
+		return current
+	}
+	return cleaned.CSS
+}
+
+// appendChatMessages 在 JSON 对话记录后追加消息，保留已有消息的原始内容；无法解析的记录返回错误，不覆盖原有记录
+func appendChatMessages(messages string, turns []model.ChatMessage) (string, error) {
+	var list []json.RawMessage
+	if strings.TrimSpace(messages) != "" {
+		if err := sonic.UnmarshalString(messages, &list); err != nil {
+			return "", ErrChatHistoryInvalid
+		}
+	}
+	for i := range turns {
+		data, err := sonic.Marshal(&turns[i])
+		if err != nil {
+			return "", err
+		}
+		list = append(list, data)
+	}
+	if list == nil {
+		return "[]", nil
+	}
+	return sonic.MarshalString(list)
+}
+
+// saved 记录本次保存使用的清洗策略版本
+func (s *ProjectService) saved(ctx context.Context, project *model.Project, cleaned *sanitize.Result) *SavedProject {
+	logger.InfoCtxf(ctx, "project saved", "projectID", project.ID, "userID", project.UserID,
+		"policyVersion", cleaned.PolicyVersion, "warnings", len(cleaned.Warnings))
+	return &SavedProject{
+		Project:       project,
+		Warnings:      cleaned.Warnings,
+		PolicyVersion: cleaned.PolicyVersion,
+	}
+}
+
+// Delete moves a project to the trash with ownership check
+// 已发布的页面随之下线（slug 保留），恢复后重新上线
 func (s *ProjectService) Delete(ctx context.Context, id, userID uint64) error {
 	// Check ownership
 	exists, err := s.projectDAO.ExistsByIDAndUserID(ctx, id, userID)
@@ -116,7 +683,140 @@ func (s *ProjectService) Delete(ctx context.Context, id, userID uint64) error {
 		return ErrProjectNotFound
 	}
 
-	return s.projectDAO.Delete(ctx, id)
+	if err := s.projectDAO.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.invalidatePublishedPage(ctx, id)
+	return nil
+}
+
+// ListTrash retrieves the trashed projects of a user
+func (s *ProjectService) ListTrash(ctx context.Context, userID uint64) ([]model.Project, error) {
+	return s.projectDAO.GetTrashedByUserID(ctx, userID)
+}
+
+// Restore moves a trashed project back to the user's project list
+func (s *ProjectService) Restore(ctx context.Context, id, userID uint64) (*model.Project, error) {
+	project, err := s.getTrashed(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	// 回收站中的项目不计入限额，恢复时按新建检查
+	if err := s.quotaService.CheckCreate(ctx, userID, contentSize(project.HTML, project.CSS)+otherFilesSize(project)); err != nil {
+		return nil, err
+	}
+
+	if err := s.projectDAO.Restore(ctx, id); err != nil {
+		return nil, err
+	}
+	s.invalidatePublishedPage(ctx, id)
+
+	project.DeletedAt = gorm.DeletedAt{}
+	return project, nil
+}
+
+// DeleteForever permanently deletes a trashed project
+func (s *ProjectService) DeleteForever(ctx context.Context, id, userID uint64) error {
+	if _, err := s.getTrashed(ctx, id, userID); err != nil {
+		return err
+	}
+	return s.purge(ctx, id)
+}
+
+// PurgeTrash permanently deletes projects that have been in the trash longer than retention
+// 每批最多处理 batchSize 个，返回删除的数量
+func (s *ProjectService) PurgeTrash(ctx context.Context, retention time.Duration, batchSize int) (int, error) {
+	ids, err := s.projectDAO.ListTrashedIDsBefore(ctx, time.Now().Add(-retention), batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		if err := s.purge(ctx, id); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// ensureOwned 校验所有项目都属于用户（回收站中的项目除外），返回去重后的 ID
+func (s *ProjectService) ensureOwned(ctx context.Context, userID uint64, ids []uint64) ([]uint64, error) {
+	unique := uniqueIDs(ids)
+	if len(unique) == 0 {
+		return unique, nil
+	}
+
+	count, err := s.projectDAO.CountByIDsAndUserID(ctx, unique, userID)
+	if err != nil {
+		return nil, err
+	}
+	if count != int64(len(unique)) {
+		return nil, ErrProjectNotFound
+	}
+	return unique, nil
+}
+
+// uniqueIDs 去重并保持原有顺序
+func uniqueIDs(ids []uint64) []uint64 {
+	unique := make([]uint64, 0, len(ids))
+	seen := make(map[uint64]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// getTrashed retrieves a trashed project with ownership check
+func (s *ProjectService) getTrashed(ctx context.Context, id, userID uint64) (*model.Project, error) {
+	project, err := s.projectDAO.GetTrashedByID(ctx, id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrProjectNotFound
+		}
+		return nil, err
+	}
+	if project.UserID != userID {
+		return nil, ErrProjectNotOwned
+	}
+	return project, nil
+}
+
+// purge 永久删除项目；快照和发布记录由外键级联删除，上传资源需单独清理
+func (s *ProjectService) purge(ctx context.Context, id uint64) error {
+	publication, err := s.publicationDAO.GetByProjectID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.projectDAO.HardDelete(ctx, id); err != nil {
+		return err
+	}
+
+	if publication != nil {
+		invalidatePublishedPages(ctx, publication.Slug)
+	}
+	// 资源清理失败不影响删除结果
+	if err := purgeProjectAssets(ctx, s.assetDAO, id); err != nil {
+		logger.WarnCtxf(ctx, "failed to purge project assets", "projectID", id, "error", err)
+	}
+	return nil
+}
+
+// invalidatePublishedPage 项目进出回收站时清除其发布页面缓存
+func (s *ProjectService) invalidatePublishedPage(ctx context.Context, id uint64) {
+	publication, err := s.publicationDAO.GetByProjectID(ctx, id)
+	if err != nil {
+		logger.WarnCtxf(ctx, "failed to get publication", "projectID", id, "error", err)
+		return
+	}
+	if publication != nil {
+		invalidatePublishedPages(ctx, publication.Slug)
+	}
 }
 
 // DeleteAllByUserID deletes all projects for a user (used when user deletes account)
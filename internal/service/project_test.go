@@ -0,0 +1,139 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/sanitize"
+)
+
+// TestProjectThumbnail tests plain-text excerpts for the project list
+func TestProjectThumbnail(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"empty", "", ""},
+		{"tags stripped", "<div class=\"hero\"><h1>Hello</h1>\n<p>World &amp; friends</p></div>", "Hello World & friends"},
+		{"script and style removed", "<style>body{color:red}</style><script>alert(1)</script><p>Visible</p>", "Visible"},
+		{"head removed, header kept", "<head><title>T</title></head><header>Top</header>", "Top"},
+		{"comment removed", "<!-- note --><span>Text</span>", "Text"},
+		{"truncated tag at end", "<p>Text</p><img src=\"a", "Text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ProjectThumbnail(tt.html); got != tt.want {
+				t.Errorf("ProjectThumbnail() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	long := "<p>" + strings.Repeat("word ", 100) + "</p>"
+	got := ProjectThumbnail(long)
+	if n := len([]rune(got)); n > thumbnailMaxLen+1 || !strings.HasSuffix(got, "…") {
+		t.Errorf("ProjectThumbnail() long text = %d runes, %q", n, got)
+	}
+}
+
+// TestProjectCursorSortValue tests that cursor values round-trip for every sort field
+func TestProjectCursorSortValue(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 30, 0, 123000000, time.UTC)
+	summary := &model.ProjectSummary{Name: "Landing", CreatedAt: ts, UpdatedAt: ts.Add(time.Hour)}
+
+	for _, sortBy := range []string{"updated_at", "created_at", "name"} {
+		value, err := cursorSortValue(sortBy, summarySortValue(sortBy, summary))
+		if err != nil {
+			t.Fatalf("cursorSortValue(%s) error = %v", sortBy, err)
+		}
+		var want interface{}
+		switch sortBy {
+		case "name":
+			want = summary.Name
+		case "created_at":
+			want = summary.CreatedAt
+		default:
+			want = summary.UpdatedAt
+		}
+		if tv, ok := value.(time.Time); ok {
+			if !tv.Equal(want.(time.Time)) {
+				t.Errorf("%s: got %v, want %v", sortBy, tv, want)
+			}
+		} else if value != want {
+			t.Errorf("%s: got %v, want %v", sortBy, value, want)
+		}
+	}
+
+	if _, err := cursorSortValue("updated_at", "not-a-time"); err == nil {
+		t.Error("expected error for malformed time")
+	}
+}
+
+// TestAppendChatMessages tests that turns are appended after the stored conversation as is
+func TestAppendChatMessages(t *testing.T) {
+	turns := []model.ChatMessage{
+		{Type: model.ChatMessageUser, Content: "a bakery"},
+		{Type: model.ChatMessageAI, Content: "Done"},
+	}
+
+	tests := []struct {
+		name     string
+		messages string
+		want     string
+		wantErr  error
+	}{
+		{"empty", "", `[{"type":"user","content":"a bakery"},{"type":"ai","content":"Done"}]`, nil},
+		{"existing", `[{"type":"user","content":"hi","extra":1}]`, `[{"type":"user","content":"hi","extra":1},{"type":"user","content":"a bakery"},{"type":"ai","content":"Done"}]`, nil},
+		{"invalid", `not json`, "", ErrChatHistoryInvalid},
+	}
+	for _, tt := range tests {
+		got, err := appendChatMessages(tt.messages, turns)
+		if !errors.Is(err, tt.wantErr) {
+			t.Fatalf("%s: appendChatMessages() error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("%s: appendChatMessages() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	if got, _ := appendChatMessages("", nil); got != "[]" {
+		t.Errorf("appendChatMessages() without turns = %s, want []", got)
+	}
+}
+
+// TestOtherFilesSize tests the size of project files other than index.html and style.css
+func TestOtherFilesSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		project model.Project
+		want    int64
+	}{
+		{"entry files only", model.Project{HTML: "<p>hi</p>", CSS: "p{}", ContentSize: 12}, 0},
+		{"other files", model.Project{HTML: "<p>hi</p>", CSS: "p{}", ContentSize: 112}, 100},
+		{"stale size", model.Project{HTML: "<p>hi</p>", ContentSize: 3}, 0},
+	}
+	for _, tt := range tests {
+		if got := otherFilesSize(&tt.project); got != tt.want {
+			t.Errorf("%s: otherFilesSize() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestGeneratedCSS tests that a generated page replaces the stylesheet once and a page without styles keeps it
+func TestGeneratedCSS(t *testing.T) {
+	policy := sanitize.DefaultPolicy()
+
+	cleaned := policy.Sanitize(`<style>body { color: red; }</style><p>Hi</p>`, "")
+	if got := generatedCSS(cleaned, "p { margin: 0; }"); strings.Count(got, "color: red") != 1 || strings.Contains(got, "margin") {
+		t.Errorf("generatedCSS() with a style block = %q", got)
+	}
+
+	cleaned = policy.Sanitize(`<p>Hi</p>`, "")
+	if got := generatedCSS(cleaned, "p { margin: 0; }"); got != "p { margin: 0; }" {
+		t.Errorf("generatedCSS() without a style block = %q, want the current css", got)
+	}
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/test-tt/internal/dao"
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/logger"
+)
+
+const (
+	// promptCacheTTL 启用版本在内存中的缓存时间，其他实例上的权重调整最多延迟这么久生效
+	promptCacheTTL    = 30 * time.Second
+	maxPromptBodyLen  = 100000
+	maxPromptVarCount = 20
+	maxPromptVarLen   = 2000
+)
+
+var (
+	ErrPromptNotFound        = errors.New("prompt template not found")
+	ErrPromptNameInvalid     = errors.New("unknown prompt template name")
+	ErrPromptTemplateInvalid = errors.New("prompt template is invalid")
+	ErrPromptVariableInvalid = errors.New("prompt variables must be 1-32 lowercase letters, digits or underscores and not built-in")
+)
+
+// PromptBuiltinVariables are filled in by generation and cannot be declared by templates
+// prompt: 用户的请求; today: 生成当天的日期
+var PromptBuiltinVariables = []string{"prompt", "today"}
+
+var promptVariablePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// PromptChoice is the system prompt picked for a generation
+type PromptChoice struct {
+	Name    string
+	Version int
+	System  string
+}
+
+// promptCache 各模板名称启用的版本，所有 PromptService 共享
+var promptCache = struct {
+	sync.Mutex
+	entries map[string]promptCacheEntry
+}{entries: make(map[string]promptCacheEntry)}
+
+type promptCacheEntry struct {
+	variants []model.PromptTemplate
+	expires  time.Time
+}
+
+// PromptService manages versioned system prompts and assigns them to users
+type PromptService struct {
+	promptDAO *dao.PromptDAO
+}
+
+func NewPromptService() *PromptService {
+	return &PromptService{
+		promptDAO: dao.NewPromptDAO(),
+	}
+}
+
+// RenderPrompt renders a template body, every variable it uses must be given
+func RenderPrompt(body string, vars map[string]string) (string, error) {
+	tmpl, err := template.New("prompt").Option("missingkey=error").Parse(body)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrPromptTemplateInvalid, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, vars); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrPromptTemplateInvalid, err)
+	}
+	return b.String(), nil
+}
+
+// promptVars 合并模板变量的默认值和生成时的变量
+func promptVars(defaults, vars map[string]string) map[string]string {
+	merged := make(map[string]string, len(defaults)+len(vars))
+	for k, v := range defaults {
+		merged[k] = v
+	}
+	for k, v := range vars {
+		merged[k] = v
+	}
+	return merged
+}
+
+// AssignPromptVariant picks a user's variant among the enabled versions in proportion to their weights
+// 同一用户在版本集合不变时总是得到同一版本
+func AssignPromptVariant(variants []model.PromptTemplate, name string, userID uint64) *model.PromptTemplate {
+	var total uint32
+	for _, v := range variants {
+		total += uint32(max(v.Weight, 0))
+	}
+	if total == 0 {
+		return nil
+	}
+
+	h := fnv.New32a()
+	_, _ = fmt.Fprintf(h, "%s:%d", name, userID)
+	bucket := h.Sum32() % total
+	for i := range variants {
+		w := uint32(max(variants[i].Weight, 0))
+		if bucket < w {
+			return &variants[i]
+		}
+		bucket -= w
+	}
+	return nil
+}
+
+// Choose picks and renders the system prompt of a user, nil when no version is enabled
+func (s *PromptService) Choose(ctx context.Context, name string, userID uint64, vars map[string]string) (*PromptChoice, error) {
+	variants, err := s.enabled(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	variant := AssignPromptVariant(variants, name, userID)
+	if variant == nil {
+		return nil, nil
+	}
+
+	system, err := RenderPrompt(variant.Body, promptVars(variant.Variables, vars))
+	if err != nil {
+		return nil, err
+	}
+	return &PromptChoice{Name: name, Version: variant.Version, System: system}, nil
+}
+
+// enabled 返回启用的版本，优先使用缓存
+func (s *PromptService) enabled(ctx context.Context, name string) ([]model.PromptTemplate, error) {
+	promptCache.Lock()
+	entry, ok := promptCache.entries[name]
+	promptCache.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.variants, nil
+	}
+
+	variants, err := s.promptDAO.ListEnabled(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	promptCache.Lock()
+	promptCache.entries[name] = promptCacheEntry{variants: variants, expires: time.Now().Add(promptCacheTTL)}
+	promptCache.Unlock()
+	return variants, nil
+}
+
+// invalidatePrompts 清除本实例的缓存，其他实例在缓存过期后生效
+func invalidatePrompts(name string) {
+	promptCache.Lock()
+	delete(promptCache.entries, name)
+	promptCache.Unlock()
+}
+
+// validatePromptTemplate 检查变量名并用默认值和示例内置变量试渲染
+func validatePromptTemplate(body string, vars map[string]string) error {
+	if strings.TrimSpace(body) == "" || len(body) > maxPromptBodyLen {
+		return fmt.Errorf("%w: body must be 1-%d bytes", ErrPromptTemplateInvalid, maxPromptBodyLen)
+	}
+	if len(vars) > maxPromptVarCount {
+		return fmt.Errorf("%w: at most %d variables", ErrPromptTemplateInvalid, maxPromptVarCount)
+	}
+	for k, v := range vars {
+		if !promptVariablePattern.MatchString(k) || slices.Contains(PromptBuiltinVariables, k) {
+			return ErrPromptVariableInvalid
+		}
+		if len(v) > maxPromptVarLen {
+			return fmt.Errorf("%w: variable %s is longer than %d bytes", ErrPromptTemplateInvalid, k, maxPromptVarLen)
+		}
+	}
+
+	sample := map[string]string{"prompt": "A landing page for a bakery", "today": time.Now().Format(time.DateOnly)}
+	_, err := RenderPrompt(body, promptVars(vars, sample))
+	return err
+}
+
+// Create adds a template as the next version of its name
+// 新版本的权重为 0 时只保存不启用，可以先预览再分配流量
+func (s *PromptService) Create(ctx context.Context, adminID uint64, prompt *model.PromptTemplate) error {
+	if !slices.Contains(model.PromptNames, prompt.Name) {
+		return ErrPromptNameInvalid
+	}
+	if err := validatePromptTemplate(prompt.Body, prompt.Variables); err != nil {
+		return err
+	}
+
+	prompt.ID = 0
+	prompt.CreatedBy = adminID
+	if err := s.promptDAO.CreateVersion(ctx, prompt); err != nil {
+		return err
+	}
+	invalidatePrompts(prompt.Name)
+	logger.InfoCtxf(ctx, "prompt template created", "name", prompt.Name, "version", prompt.Version, "weight", prompt.Weight, "adminID", adminID)
+	return nil
+}
+
+// List lists the versions of a template newest first, all templates when name is empty
+func (s *PromptService) List(ctx context.Context, name string) ([]model.PromptTemplate, error) {
+	if name != "" && !slices.Contains(model.PromptNames, name) {
+		return nil, ErrPromptNameInvalid
+	}
+	return s.promptDAO.List(ctx, name)
+}
+
+// Get retrieves a template version
+func (s *PromptService) Get(ctx context.Context, id uint64) (*model.PromptTemplate, error) {
+	prompt, err := s.promptDAO.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if prompt == nil {
+		return nil, ErrPromptNotFound
+	}
+	return prompt, nil
+}
+
+// SetWeight changes the share of traffic a version gets, 0 disables it
+func (s *PromptService) SetWeight(ctx context.Context, adminID, id uint64, weight int) (*model.PromptTemplate, error) {
+	prompt, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.promptDAO.UpdateWeight(ctx, id, weight); err != nil {
+		return nil, err
+	}
+	prompt.Weight = weight
+	invalidatePrompts(prompt.Name)
+	logger.InfoCtxf(ctx, "prompt template weight changed", "name", prompt.Name, "version", prompt.Version, "weight", weight, "adminID", adminID)
+	return prompt, nil
+}
+
+// Render previews a version with the given variables over its defaults
+func (s *PromptService) Render(ctx context.Context, id uint64, vars map[string]string) (string, error) {
+	prompt, err := s.Get(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return RenderPrompt(prompt.Body, promptVars(prompt.Variables, vars))
+}
+
+// Stats compares the generations of each version of a prompt over the last days
+func (s *PromptService) Stats(ctx context.Context, name string, days int) ([]model.PromptVariantStats, error) {
+	if !slices.Contains(model.PromptNames, name) {
+		return nil, ErrPromptNameInvalid
+	}
+	return s.promptDAO.Stats(ctx, name, time.Now().AddDate(0, 0, -days))
+}
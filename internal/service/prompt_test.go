@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/test-tt/internal/model"
+)
+
+// TestRenderPrompt tests template rendering and missing variables
+func TestRenderPrompt(t *testing.T) {
+	got, err := RenderPrompt("Tone: {{.tone}}. Request: {{.prompt}}", map[string]string{"tone": "playful", "prompt": "a blog"})
+	if err != nil || got != "Tone: playful. Request: a blog" {
+		t.Errorf("RenderPrompt() = %q, %v", got, err)
+	}
+	if _, err := RenderPrompt("{{.missing}}", map[string]string{}); !errors.Is(err, ErrPromptTemplateInvalid) {
+		t.Errorf("RenderPrompt() with a missing variable error = %v, want %v", err, ErrPromptTemplateInvalid)
+	}
+	if _, err := RenderPrompt("{{.tone", nil); !errors.Is(err, ErrPromptTemplateInvalid) {
+		t.Errorf("RenderPrompt() with bad syntax error = %v, want %v", err, ErrPromptTemplateInvalid)
+	}
+}
+
+// TestValidatePromptTemplate tests variable names and trial rendering
+func TestValidatePromptTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		vars    map[string]string
+		wantErr error
+	}{
+		{"builtin variables", "Today is {{.today}}, build {{.prompt}}", nil, nil},
+		{"declared variable", "Tone: {{.tone}}", map[string]string{"tone": "calm"}, nil},
+		{"undeclared variable", "Tone: {{.tone}}", nil, ErrPromptTemplateInvalid},
+		{"builtin redeclared", "x", map[string]string{"prompt": "y"}, ErrPromptVariableInvalid},
+		{"bad variable name", "x", map[string]string{"Tone": "y"}, ErrPromptVariableInvalid},
+		{"empty body", "  ", nil, ErrPromptTemplateInvalid},
+		{"too long", strings.Repeat("a", maxPromptBodyLen+1), nil, ErrPromptTemplateInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePromptTemplate(tt.body, tt.vars)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("validatePromptTemplate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestAssignPromptVariant tests deterministic weighted assignment
+func TestAssignPromptVariant(t *testing.T) {
+	variants := []model.PromptTemplate{{Version: 1, Weight: 75}, {Version: 2, Weight: 25}, {Version: 3, Weight: 0}}
+
+	counts := make(map[int]int)
+	for userID := uint64(1); userID <= 4000; userID++ {
+		v := AssignPromptVariant(variants, model.PromptCreate, userID)
+		if again := AssignPromptVariant(variants, model.PromptCreate, userID); again.Version != v.Version {
+			t.Fatalf("user %d got versions %d and %d", userID, v.Version, again.Version)
+		}
+		counts[v.Version]++
+	}
+	if counts[3] != 0 {
+		t.Errorf("disabled version got %d users", counts[3])
+	}
+	if counts[1] < 2800 || counts[1] > 3200 || counts[2] < 800 || counts[2] > 1200 {
+		t.Errorf("assignment = %v, want about 3000/1000", counts)
+	}
+
+	if v := AssignPromptVariant(variants[2:], model.PromptCreate, 1); v != nil {
+		t.Errorf("AssignPromptVariant() without weights = %+v, want nil", v)
+	}
+	if v := AssignPromptVariant(nil, model.PromptCreate, 1); v != nil {
+		t.Errorf("AssignPromptVariant() without variants = %+v, want nil", v)
+	}
+}
@@ -0,0 +1,434 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"gorm.io/gorm"
+
+	"github.com/test-tt/internal/dao"
+	"github.com/test-tt/internal/model"
+	"github.com/test-tt/pkg/cache"
+	"github.com/test-tt/pkg/logger"
+)
+
+const (
+	publishedPageCacheKey = "published:site:%s"
+	publishedPageCacheTTL = 1 * time.Minute
+	slugLength            = 10
+	slugMaxRetries        = 5
+	slugAlphabet          = "abcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+var (
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+	ErrSlugInvalid      = errors.New("slug is invalid")
+	ErrSlugTaken        = errors.New("slug is already taken")
+	ErrNotPublished     = errors.New("project is not published")
+)
+
+// slugPattern 自定义 slug：小写字母、数字和连字符，不能以连字符开头或结尾
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,62}[a-z0-9]$`)
+
+// reservedSlugs 保留的 slug，避免与路由或常见路径混淆
+var reservedSlugs = map[string]struct{}{
+	"api": {}, "static": {}, "admin": {}, "assets": {}, "swagger": {}, "debug": {}, "metrics": {},
+}
+
+// PublishInfo publication state returned to the owner
+type PublishInfo struct {
+	*model.ProjectPublication
+	URL string `json:"url"`
+}
+
+type PublishService struct {
+	projectService *ProjectService
+	projectDAO     *dao.ProjectDAO
+	snapshotDAO    *dao.SnapshotDAO
+	publicationDAO *dao.PublicationDAO
+	fileDAO        *dao.FileDAO
+}
+
+func NewPublishService() *PublishService {
+	return &PublishService{
+		projectService: NewProjectService(),
+		projectDAO:     dao.NewProjectDAO(),
+		snapshotDAO:    dao.NewSnapshotDAO(),
+		publicationDAO: dao.NewPublicationDAO(),
+		fileDAO:        dao.NewFileDAO(),
+	}
+}
+
+// CreateSnapshot captures the current HTML, CSS and files of a project the user owns or can edit
+func (s *PublishService) CreateSnapshot(ctx context.Context, projectID, userID uint64, label string) (*model.ProjectSnapshot, error) {
+	project, role, err := s.projectService.Access(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role == model.MemberRoleViewer {
+		return nil, ErrProjectReadOnly
+	}
+	return s.createSnapshot(ctx, project, label)
+}
+
+func (s *PublishService) createSnapshot(ctx context.Context, project *model.Project, label string) (*model.ProjectSnapshot, error) {
+	if label == "" {
+		label = "Snapshot " + time.Now().Format("2006-01-02 15:04:05")
+	}
+	files, err := s.fileDAO.ListWithContent(ctx, project.ID)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &model.ProjectSnapshot{
+		ProjectID: project.ID,
+		UserID:    project.UserID,
+		Label:     label,
+		HTML:      project.HTML,
+		CSS:       project.CSS,
+		Files:     files,
+	}
+	if err := s.snapshotDAO.Create(ctx, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// ListSnapshots lists snapshots of a project the user owns or is a member of
+// 成员需要快照 ID 才能发表评审评论
+func (s *PublishService) ListSnapshots(ctx context.Context, projectID, userID uint64) ([]model.ProjectSnapshotSummary, error) {
+	if _, _, err := s.projectService.Access(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+	return s.snapshotDAO.ListByProjectID(ctx, projectID)
+}
+
+// GetPublication returns the publication state of a project
+func (s *PublishService) GetPublication(ctx context.Context, projectID, userID uint64) (*PublishInfo, error) {
+	if _, err := s.projectService.GetByID(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+	publication, err := s.publicationDAO.GetByProjectID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if publication == nil {
+		return nil, ErrNotPublished
+	}
+	return newPublishInfo(publication), nil
+}
+
+// Publish pins a snapshot of the project to a public slug
+// snapshotID 为 0 时使用项目当前内容创建新快照；slug 为空时沿用已有 slug 或随机生成
+func (s *PublishService) Publish(ctx context.Context, projectID, userID, snapshotID uint64, slug string) (*PublishInfo, error) {
+	project, err := s.projectService.GetByID(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, err := s.resolveSnapshot(ctx, project, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	publication, err := s.publicationDAO.GetByProjectID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if publication == nil {
+		publication = &model.ProjectPublication{ProjectID: projectID, UserID: userID}
+	}
+	oldSlug := publication.Slug
+
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	switch {
+	case slug != "":
+		if err := s.checkSlugAvailable(ctx, slug, projectID); err != nil {
+			return nil, err
+		}
+		publication.Slug = slug
+	case publication.Slug == "":
+		generated, err := s.generateUniqueSlug(ctx)
+		if err != nil {
+			return nil, err
+		}
+		publication.Slug = generated
+	}
+
+	publication.SnapshotID = snapshot.ID
+	publication.PublishedAt = time.Now()
+	if err := s.publicationDAO.Save(ctx, publication); err != nil {
+		// 检查与保存之间 slug 被其他项目抢先使用
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrSlugTaken
+		}
+		return nil, err
+	}
+
+	invalidatePublishedPages(ctx, oldSlug, publication.Slug)
+	return newPublishInfo(publication), nil
+}
+
+// Unpublish takes a project offline and releases its slug
+func (s *PublishService) Unpublish(ctx context.Context, projectID, userID uint64) error {
+	if _, err := s.projectService.GetByID(ctx, projectID, userID); err != nil {
+		return err
+	}
+	publication, err := s.publicationDAO.GetByProjectID(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if publication == nil {
+		return ErrNotPublished
+	}
+	if err := s.publicationDAO.DeleteByProjectID(ctx, projectID); err != nil {
+		return err
+	}
+	invalidatePublishedPages(ctx, publication.Slug)
+	return nil
+}
+
+// PublishedPage is a rendered public file, cached by slug and path
+type PublishedPage struct {
+	ProjectID   uint64 `json:"project_id"`
+	ContentType string `json:"content_type"`
+	Content     string `json:"content"`
+	// HasFiles 快照中有 index.html 和 style.css 以外的文件，首页需要以 /p/:slug/ 访问，页面中的相对链接才能找到它们
+	HasFiles bool `json:"has_files"`
+}
+
+// IsHTML reports whether the file is a page (counted as a page view)
+func (p *PublishedPage) IsHTML() bool {
+	return strings.HasPrefix(p.ContentType, "text/html")
+}
+
+// RenderPublished returns the file of a published project served at /p/:slug/*path
+// 路径为空或以 / 结尾时返回该目录的 index.html；页面与首页一样内联 style.css
+func (s *PublishService) RenderPublished(ctx context.Context, slug, filePath string) (*PublishedPage, error) {
+	filePath, err := publishedFilePath(filePath)
+	if err != nil {
+		return nil, ErrNotPublished
+	}
+	// 同一 slug 的所有文件放在一个哈希中，重新发布或下线时一次删除
+	cacheKey := fmt.Sprintf(publishedPageCacheKey, slug)
+	if cache.RDB != nil {
+		if cached, err := cache.RDB.HGet(ctx, cacheKey, filePath).Result(); err == nil && cached != "" {
+			var page PublishedPage
+			if err := sonic.UnmarshalString(cached, &page); err == nil {
+				return &page, nil
+			}
+		}
+	}
+
+	publication, err := s.publicationDAO.GetBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	if publication == nil {
+		return nil, ErrNotPublished
+	}
+	// 回收站中的项目保留 slug，但页面下线
+	live, err := s.projectDAO.ExistsByID(ctx, publication.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if !live {
+		return nil, ErrNotPublished
+	}
+	snapshot, err := s.snapshotDAO.GetByID(ctx, publication.SnapshotID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotPublished
+		}
+		return nil, err
+	}
+	page, err := s.renderSnapshotFile(ctx, snapshot, filePath)
+	if err != nil {
+		return nil, err
+	}
+	page.ProjectID = publication.ProjectID
+
+	if cache.RDB != nil {
+		data, _ := sonic.MarshalString(page)
+		pipe := cache.Pipeline()
+		pipe.HSet(ctx, cacheKey, filePath, data)
+		pipe.Expire(ctx, cacheKey, publishedPageCacheTTL)
+		if _, err := pipe.Exec(ctx); err != nil {
+			logger.WarnCtxf(ctx, "failed to cache published page", "key", cacheKey, "path", filePath, "error", err)
+		}
+	}
+	return page, nil
+}
+
+// renderSnapshotFile 渲染快照中的一个文件，文件不存在时返回 ErrNotPublished
+func (s *PublishService) renderSnapshotFile(ctx context.Context, snapshot *model.ProjectSnapshot, filePath string) (*PublishedPage, error) {
+	switch filePath {
+	case model.ProjectIndexFile:
+		hasFiles, err := s.snapshotDAO.HasFiles(ctx, snapshot.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &PublishedPage{ContentType: publishedContentType(filePath), Content: RenderPage(snapshot.HTML, snapshot.CSS), HasFiles: hasFiles}, nil
+	case model.ProjectStyleFile:
+		return &PublishedPage{ContentType: publishedContentType(filePath), Content: snapshot.CSS}, nil
+	}
+
+	file, err := s.snapshotDAO.GetFile(ctx, snapshot.ID, filePath)
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, ErrNotPublished
+	}
+	content := file.Content
+	if path.Ext(filePath) == ".html" {
+		content = RenderPage(content, snapshot.CSS)
+	}
+	return &PublishedPage{ContentType: publishedContentType(filePath), Content: content}, nil
+}
+
+// publishedFilePath 把 /p/:slug 之后的路径转为快照中的文件路径，目录对应其 index.html
+func publishedFilePath(p string) (string, error) {
+	p = strings.TrimPrefix(p, "/")
+	if p == "" || strings.HasSuffix(p, "/") {
+		p += model.ProjectIndexFile
+	}
+	return CleanFilePath(p)
+}
+
+// publishedContentType 按扩展名返回文件的 Content-Type，路径已经过 CleanFilePath 校验
+func publishedContentType(p string) string {
+	switch path.Ext(p) {
+	case ".css":
+		return "text/css; charset=utf-8"
+	case ".js":
+		return "text/javascript; charset=utf-8"
+	default:
+		return "text/html; charset=utf-8"
+	}
+}
+
+// resolveSnapshot 返回指定快照（校验归属），或基于当前内容新建快照
+func (s *PublishService) resolveSnapshot(ctx context.Context, project *model.Project, snapshotID uint64) (*model.ProjectSnapshot, error) {
+	if snapshotID == 0 {
+		return s.createSnapshot(ctx, project, "")
+	}
+	snapshot, err := s.snapshotDAO.GetByID(ctx, snapshotID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrSnapshotNotFound
+		}
+		return nil, err
+	}
+	if snapshot.ProjectID != project.ID {
+		return nil, ErrSnapshotNotFound
+	}
+	return snapshot, nil
+}
+
+// checkSlugAvailable 校验 slug 格式并确认未被其他项目占用
+func (s *PublishService) checkSlugAvailable(ctx context.Context, slug string, projectID uint64) error {
+	if !ValidSlug(slug) {
+		return ErrSlugInvalid
+	}
+	existing, err := s.publicationDAO.GetBySlug(ctx, slug)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ProjectID != projectID {
+		return ErrSlugTaken
+	}
+	return nil
+}
+
+// generateUniqueSlug 随机生成 slug，冲突时重试
+func (s *PublishService) generateUniqueSlug(ctx context.Context) (string, error) {
+	for i := 0; i < slugMaxRetries; i++ {
+		slug, err := GenerateSlug()
+		if err != nil {
+			return "", err
+		}
+		existing, err := s.publicationDAO.GetBySlug(ctx, slug)
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return slug, nil
+		}
+	}
+	return "", ErrSlugTaken
+}
+
+// invalidatePublishedPages 清除已发布页面缓存
+func invalidatePublishedPages(ctx context.Context, slugs ...string) {
+	if cache.RDB == nil {
+		return
+	}
+	keys := make([]string, 0, len(slugs))
+	for _, slug := range slugs {
+		if slug != "" {
+			keys = append(keys, fmt.Sprintf(publishedPageCacheKey, slug))
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	if err := cache.Del(ctx, keys...); err != nil {
+		logger.WarnCtxf(ctx, "failed to delete published page cache", "keys", keys, "error", err)
+	}
+}
+
+func newPublishInfo(publication *model.ProjectPublication) *PublishInfo {
+	return &PublishInfo{
+		ProjectPublication: publication,
+		URL:                PublishedPath(publication.Slug),
+	}
+}
+
+// PublishedPath returns the public path of a slug
+func PublishedPath(slug string) string {
+	return "/p/" + slug
+}
+
+// ValidSlug reports whether a user-chosen slug is acceptable
+func ValidSlug(slug string) bool {
+	if !slugPattern.MatchString(slug) || strings.Contains(slug, "--") {
+		return false
+	}
+	_, reserved := reservedSlugs[slug]
+	return !reserved
+}
+
+// GenerateSlug returns a random lowercase alphanumeric slug
+func GenerateSlug() (string, error) {
+	buf := make([]byte, slugLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	for i, b := range buf {
+		buf[i] = slugAlphabet[int(b)%len(slugAlphabet)]
+	}
+	return string(buf), nil
+}
+
+// RenderPage inlines CSS into the HTML document
+// CSS 放在 </head> 之前；没有 head 时包装成完整文档
+func RenderPage(html, css string) string {
+	if css == "" {
+		return html
+	}
+	// 防止 CSS 内容提前闭合 style 标签
+	style := "<style>\n" + strings.ReplaceAll(css, "</", `<\/`) + "\n</style>\n"
+
+	lower := strings.ToLower(html)
+	if idx := strings.Index(lower, "</head>"); idx >= 0 {
+		return html[:idx] + style + html[idx:]
+	}
+	return "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"UTF-8\">\n" + style + "</head>\n<body>\n" + html + "\n</body>\n</html>"
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestValidSlug tests custom slug validation
+func TestValidSlug(t *testing.T) {
+	tests := []struct {
+		slug  string
+		valid bool
+	}{
+		{"my-page", true},
+		{"abc", true},
+		{"page2024", true},
+		{"ab", false},
+		{"-page", false},
+		{"page-", false},
+		{"my--page", false},
+		{"My-Page", false},
+		{"my_page", false},
+		{"api", false},
+		{strings.Repeat("a", 64), true},
+		{strings.Repeat("a", 65), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.slug, func(t *testing.T) {
+			if got := ValidSlug(tt.slug); got != tt.valid {
+				t.Errorf("ValidSlug(%q) = %v, want %v", tt.slug, got, tt.valid)
+			}
+		})
+	}
+}
+
+// TestGenerateSlug tests random slug generation
+func TestGenerateSlug(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		slug, err := GenerateSlug()
+		if err != nil {
+			t.Fatalf("GenerateSlug() error: %v", err)
+		}
+		if len(slug) != slugLength {
+			t.Errorf("len(slug) = %d, want %d", len(slug), slugLength)
+		}
+		if !ValidSlug(slug) {
+			t.Errorf("generated slug %q is not valid", slug)
+		}
+		seen[slug] = true
+	}
+	if len(seen) < 95 {
+		t.Errorf("generated slugs are not random enough: %d unique of 100", len(seen))
+	}
+}
+
+// TestRenderPage tests CSS inlining into published pages
+func TestRenderPage(t *testing.T) {
+	t.Run("inserts style before head close", func(t *testing.T) {
+		page := RenderPage("<html><head><title>x</title></head><body></body></html>", "body{color:red}")
+		if !strings.Contains(page, "<style>\nbody{color:red}\n</style>\n</head>") {
+			t.Errorf("style not inserted before </head>: %s", page)
+		}
+	})
+
+	t.Run("wraps fragment without head", func(t *testing.T) {
+		page := RenderPage("<h1>Hi</h1>", "h1{}")
+		if !strings.HasPrefix(page, "<!DOCTYPE html>") || !strings.Contains(page, "<body>\n<h1>Hi</h1>") {
+			t.Errorf("fragment not wrapped: %s", page)
+		}
+	})
+
+	t.Run("escapes closing style tag", func(t *testing.T) {
+		page := RenderPage("<head></head>", "a{}</style><script>alert(1)</script>")
+		if strings.Contains(page, "</style><script>") {
+			t.Errorf("css was able to close style tag: %s", page)
+		}
+	})
+
+	t.Run("no css keeps html", func(t *testing.T) {
+		if got := RenderPage("<p>x</p>", ""); got != "<p>x</p>" {
+			t.Errorf("RenderPage without css = %q", got)
+		}
+	})
+}
+
+// TestPublishedFilePath tests mapping /p/:slug paths to snapshot files
+func TestPublishedFilePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{"", "index.html", true},
+		{"/", "index.html", true},
+		{"index.html", "index.html", true},
+		{"about/", "about/index.html", true},
+		{"/blog/post.html", "blog/post.html", true},
+		{"style.css", "style.css", true},
+		{"js/app.js", "js/app.js", true},
+		{"about", "", false},
+		{"../secret.html", "", false},
+		{"logo.png", "", false},
+	}
+
+	for _, tt := range tests {
+		got, err := publishedFilePath(tt.path)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("publishedFilePath(%q) = %q, %v", tt.path, got, err)
+		}
+		if tt.ok && !strings.HasPrefix(publishedContentType(got), "text/") {
+			t.Errorf("publishedContentType(%q) = %q", got, publishedContentType(got))
+		}
+	}
+	if got := publishedContentType("a/b.js"); got != "text/javascript; charset=utf-8" {
+		t.Errorf("publishedContentType(js) = %q", got)
+	}
+	page := &PublishedPage{ContentType: publishedContentType("about/index.html")}
+	if !page.IsHTML() || (&PublishedPage{ContentType: publishedContentType("style.css")}).IsHTML() {
+		t.Error("only pages should count as HTML")
+	}
+}
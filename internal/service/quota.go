@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/test-tt/config"
+	"github.com/test-tt/internal/dao"
+)
+
+var (
+	ErrProjectLimitExceeded = errors.New("project limit exceeded")
+	ErrProjectTooLarge      = errors.New("project content exceeds size limit")
+	ErrStorageLimitExceeded = errors.New("storage limit exceeded")
+)
+
+// UsageItem current usage against a limit; Limit 0 means unlimited
+type UsageItem struct {
+	Used  int64 `json:"used"`
+	Limit int64 `json:"limit"`
+}
+
+// Usage a user's plan and current usage
+type Usage struct {
+	Plan           string    `json:"plan"`
+	Projects       UsageItem `json:"projects"`
+	Storage        UsageItem `json:"storage"` // Bytes of html + css across projects
+	Assets         UsageItem `json:"assets"`  // Bytes of uploaded assets
+	MaxProjectSize int64     `json:"max_project_size"`
+}
+
+// QuotaService enforces plan limits
+// 检查与写入不在同一事务中，并发请求可能略微超出限额，这是可以接受的
+type QuotaService struct {
+	userDAO    *dao.UserDAO
+	projectDAO *dao.ProjectDAO
+	assetDAO   *dao.AssetDAO
+}
+
+func NewQuotaService() *QuotaService {
+	return &QuotaService{
+		userDAO:    dao.NewUserDAO(),
+		projectDAO: dao.NewProjectDAO(),
+		assetDAO:   dao.NewAssetDAO(),
+	}
+}
+
+// Usage reports a user's current usage against the plan limits
+func (s *QuotaService) Usage(ctx context.Context, userID uint64) (*Usage, error) {
+	plan, limits, err := s.planOf(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	projects, err := s.projectDAO.CountByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	storage, err := s.projectDAO.SumContentSizeByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	assets, err := s.assetDAO.SumSizeByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Usage{
+		Plan:           plan,
+		Projects:       UsageItem{Used: projects, Limit: limits.MaxProjects},
+		Storage:        UsageItem{Used: storage, Limit: limits.MaxStorage},
+		Assets:         UsageItem{Used: assets, Limit: limits.MaxAssetBytes},
+		MaxProjectSize: limits.MaxProjectSize,
+	}, nil
+}
+
+// CheckCreate checks that a new project of the given content size fits the plan
+func (s *QuotaService) CheckCreate(ctx context.Context, userID uint64, size int64) error {
+	_, limits, err := s.planOf(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if exceeds(size, limits.MaxProjectSize) {
+		return ErrProjectTooLarge
+	}
+
+	if limits.MaxProjects > 0 {
+		count, err := s.projectDAO.CountByUserID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if count >= limits.MaxProjects {
+			return ErrProjectLimitExceeded
+		}
+	}
+	return s.checkStorage(ctx, userID, size, limits)
+}
+
+// CheckUpdate checks that changing a project's content size from oldSize to newSize fits the plan
+func (s *QuotaService) CheckUpdate(ctx context.Context, userID uint64, oldSize, newSize int64) error {
+	_, limits, err := s.planOf(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if exceeds(newSize, limits.MaxProjectSize) {
+		return ErrProjectTooLarge
+	}
+	// 内容变小时总是允许，便于超额用户清理
+	if newSize <= oldSize {
+		return nil
+	}
+	return s.checkStorage(ctx, userID, newSize-oldSize, limits)
+}
+
+// CheckAssets checks that uploading size more asset bytes fits the plan
+func (s *QuotaService) CheckAssets(ctx context.Context, userID uint64, size int64) error {
+	_, limits, err := s.planOf(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if limits.MaxAssetBytes <= 0 {
+		return nil
+	}
+	used, err := s.assetDAO.SumSizeByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if exceeds(used+size, limits.MaxAssetBytes) {
+		return ErrAssetQuotaExceeded
+	}
+	return nil
+}
+
+func (s *QuotaService) checkStorage(ctx context.Context, userID uint64, delta int64, limits *config.PlanLimits) error {
+	if limits.MaxStorage <= 0 {
+		return nil
+	}
+	used, err := s.projectDAO.SumContentSizeByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if exceeds(used+delta, limits.MaxStorage) {
+		return ErrStorageLimitExceeded
+	}
+	return nil
+}
+
+// planOf 返回用户的套餐名和限额；未配置套餐时不限制
+func (s *QuotaService) planOf(ctx context.Context, userID uint64) (string, *config.PlanLimits, error) {
+	if config.Cfg == nil || config.Cfg.Plans == nil {
+		return "", &config.PlanLimits{}, nil
+	}
+
+	plan := ""
+	user, err := s.userDAO.GetByID(ctx, userID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return "", nil, err
+	}
+	if user != nil {
+		plan = user.Plan
+	}
+	if _, ok := config.Cfg.Plans.Limits[plan]; !ok {
+		plan = config.Cfg.Plans.Default
+	}
+
+	limits := config.Cfg.Plan(plan)
+	if limits == nil {
+		limits = &config.PlanLimits{}
+	}
+	return plan, limits, nil
+}
+
+// exceeds 判断 value 是否超过限额，limit 为 0 表示不限制
+func exceeds(value, limit int64) bool {
+	return limit > 0 && value > limit
+}
+
+// contentSize 项目内容大小（HTML + CSS 字节数）
+func contentSize(html, css string) int64 {
+	return int64(len(html) + len(css))
+}
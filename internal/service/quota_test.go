@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"testing"
+)
+
+// TestExceeds tests limit comparison where 0 means unlimited
+func TestExceeds(t *testing.T) {
+	tests := []struct {
+		value, limit int64
+		want         bool
+	}{
+		{100, 0, false},
+		{100, 100, false},
+		{101, 100, true},
+		{0, 1, false},
+	}
+	for _, tt := range tests {
+		if got := exceeds(tt.value, tt.limit); got != tt.want {
+			t.Errorf("exceeds(%d, %d) = %v, want %v", tt.value, tt.limit, got, tt.want)
+		}
+	}
+}
+
+// TestQuotaWithoutPlans tests that nothing is limited when no plans are configured
+func TestQuotaWithoutPlans(t *testing.T) {
+	s := NewQuotaService()
+	ctx := context.Background()
+
+	if err := s.CheckCreate(ctx, 1, 1<<30); err != nil {
+		t.Errorf("CheckCreate() error = %v", err)
+	}
+	if err := s.CheckUpdate(ctx, 1, 0, 1<<30); err != nil {
+		t.Errorf("CheckUpdate() error = %v", err)
+	}
+	if err := s.CheckAssets(ctx, 1, 1<<30); err != nil {
+		t.Errorf("CheckAssets() error = %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/test-tt/internal/dao"
+	"github.com/test-tt/internal/model"
+)
+
+const (
+	maxTagLen         = 32
+	maxTagsPerProject = 20
+)
+
+var (
+	ErrTagInvalid  = errors.New("tag is invalid")
+	ErrTooManyTags = errors.New("too many tags on project")
+)
+
+type TagService struct {
+	tagDAO         *dao.TagDAO
+	projectService *ProjectService
+}
+
+func NewTagService() *TagService {
+	return &TagService{
+		tagDAO:         dao.NewTagDAO(),
+		projectService: NewProjectService(),
+	}
+}
+
+// List retrieves the tags used by a user's projects with usage counts
+func (s *TagService) List(ctx context.Context, userID uint64) ([]model.TagCount, error) {
+	return s.tagDAO.CountByUserID(ctx, userID)
+}
+
+// SetProjectTags replaces the tags of a project and returns the normalized list
+func (s *TagService) SetProjectTags(ctx context.Context, projectID, userID uint64, tags []string) ([]string, error) {
+	if _, err := s.projectService.GetByID(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+	normalized, err := NormalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	if len(normalized) > maxTagsPerProject {
+		return nil, ErrTooManyTags
+	}
+	if err := s.tagDAO.Replace(ctx, projectID, normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// BulkTag adds and removes tags on several projects
+func (s *TagService) BulkTag(ctx context.Context, userID uint64, projectIDs []uint64, add, remove []string) error {
+	add, err := NormalizeTags(add)
+	if err != nil {
+		return err
+	}
+	remove, err = NormalizeTags(remove)
+	if err != nil {
+		return err
+	}
+	ids, err := s.projectService.ensureOwned(ctx, userID, projectIDs)
+	if err != nil {
+		return err
+	}
+
+	// 先检查添加后每个项目的标签数不超过上限
+	if len(add) > 0 {
+		current, err := s.tagDAO.ListByProjectIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if len(mergeTags(current[id], add, remove)) > maxTagsPerProject {
+				return ErrTooManyTags
+			}
+		}
+	}
+
+	if err := s.tagDAO.Remove(ctx, ids, remove); err != nil {
+		return err
+	}
+	return s.tagDAO.Add(ctx, ids, add)
+}
+
+// NormalizeTags trims, lowercases and de-duplicates tags, keeping their order
+func NormalizeTags(tags []string) ([]string, error) {
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		tag, err := normalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		result = append(result, tag)
+	}
+	return result, nil
+}
+
+// normalizeTag 标签不区分大小写，内部空白折叠为单个空格，不允许控制字符
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+	if tag == "" || len([]rune(tag)) > maxTagLen {
+		return "", ErrTagInvalid
+	}
+	for _, r := range tag {
+		if unicode.IsControl(r) {
+			return "", ErrTagInvalid
+		}
+	}
+	return tag, nil
+}
+
+// mergeTags 计算 current 删除 remove 再加入 add 后的标签集合
+func mergeTags(current, add, remove []string) map[string]struct{} {
+	result := make(map[string]struct{}, len(current)+len(add))
+	for _, tag := range current {
+		result[tag] = struct{}{}
+	}
+	for _, tag := range remove {
+		delete(result, tag)
+	}
+	for _, tag := range add {
+		result[tag] = struct{}{}
+	}
+	return result
+}
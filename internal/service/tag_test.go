@@ -0,0 +1,39 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestNormalizeTags tests tag normalization and de-duplication
+func TestNormalizeTags(t *testing.T) {
+	got, err := NormalizeTags([]string{" Landing ", "landing", "Client  Work", "dark"})
+	if err != nil {
+		t.Fatalf("NormalizeTags() error = %v", err)
+	}
+	want := []string{"landing", "client work", "dark"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeTags() = %v, want %v", got, want)
+	}
+
+	invalid := []string{"", "   ", strings.Repeat("x", maxTagLen+1), "bad\x00tag"}
+	for _, tag := range invalid {
+		if _, err := NormalizeTags([]string{tag}); err != ErrTagInvalid {
+			t.Errorf("NormalizeTags(%q) error = %v, want ErrTagInvalid", tag, err)
+		}
+	}
+}
+
+// TestMergeTags tests the tag set after a bulk change
+func TestMergeTags(t *testing.T) {
+	got := mergeTags([]string{"a", "b"}, []string{"c", "a"}, []string{"b"})
+	if len(got) != 2 {
+		t.Errorf("mergeTags() = %v, want a and c", got)
+	}
+	for _, tag := range []string{"a", "c"} {
+		if _, ok := got[tag]; !ok {
+			t.Errorf("mergeTags() missing %q", tag)
+		}
+	}
+}
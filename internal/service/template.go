@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/test-tt/internal/dao"
+	"github.com/test-tt/internal/model"
+)
+
+//go:embed templates
+var builtinTemplateFS embed.FS
+
+var (
+	ErrTemplateNotFound   = errors.New("template not found")
+	ErrTemplateKeyInvalid = errors.New("template key is invalid")
+	ErrTemplateKeyExists  = errors.New("template key already exists")
+	ErrTemplateBuiltin    = errors.New("builtin templates cannot be modified")
+)
+
+// templateKeyPattern 模板 key：小写字母、数字和连字符
+var templateKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}[a-z0-9]$`)
+
+// builtinTemplateMeta 内置模板目录下 template.yaml 的内容
+type builtinTemplateMeta struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Category    string `yaml:"category"`
+	Sort        int    `yaml:"sort"`
+}
+
+var (
+	builtinTemplates     map[string]*model.ProjectTemplate
+	builtinTemplatesErr  error
+	builtinTemplatesOnce sync.Once
+)
+
+// loadBuiltinTemplates 从嵌入文件加载内置模板
+// 每个模板一个目录：template.yaml（元数据）、index.html、style.css
+func loadBuiltinTemplates() (map[string]*model.ProjectTemplate, error) {
+	builtinTemplatesOnce.Do(func() {
+		builtinTemplates, builtinTemplatesErr = parseTemplateFS(builtinTemplateFS, "templates")
+	})
+	return builtinTemplates, builtinTemplatesErr
+}
+
+func parseTemplateFS(fsys fs.FS, root string) (map[string]*model.ProjectTemplate, error) {
+	entries, err := fs.ReadDir(fsys, root)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]*model.ProjectTemplate, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		key := entry.Name()
+		dir := path.Join(root, key)
+
+		metaData, err := fs.ReadFile(fsys, path.Join(dir, "template.yaml"))
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", key, err)
+		}
+		var meta builtinTemplateMeta
+		if err := yaml.Unmarshal(metaData, &meta); err != nil {
+			return nil, fmt.Errorf("template %s: %w", key, err)
+		}
+		html, err := fs.ReadFile(fsys, path.Join(dir, "index.html"))
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", key, err)
+		}
+		// style.css 可选
+		css, _ := fs.ReadFile(fsys, path.Join(dir, "style.css"))
+
+		templates[key] = &model.ProjectTemplate{
+			Key:         key,
+			Name:        meta.Name,
+			Description: meta.Description,
+			Category:    meta.Category,
+			Sort:        meta.Sort,
+			HTML:        string(html),
+			CSS:         string(css),
+			Enabled:     true,
+			Builtin:     true,
+		}
+	}
+	return templates, nil
+}
+
+type TemplateService struct {
+	templateDAO *dao.TemplateDAO
+}
+
+func NewTemplateService() *TemplateService {
+	return &TemplateService{
+		templateDAO: dao.NewTemplateDAO(),
+	}
+}
+
+// List returns the template catalog: builtin templates followed by enabled curated ones
+// category 为空时返回全部分类
+func (s *TemplateService) List(ctx context.Context, category string) ([]model.TemplateSummary, error) {
+	builtin, err := loadBuiltinTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	all := make([]*model.ProjectTemplate, 0, len(builtin))
+	for _, t := range builtin {
+		all = append(all, t)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Sort != all[j].Sort {
+			return all[i].Sort < all[j].Sort
+		}
+		return all[i].Key < all[j].Key
+	})
+
+	curated, err := s.templateDAO.ListSummaries(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	for i := range curated {
+		all = append(all, &curated[i])
+	}
+
+	summaries := make([]model.TemplateSummary, 0, len(all))
+	for _, t := range all {
+		if category != "" && t.Category != category {
+			continue
+		}
+		summaries = append(summaries, model.TemplateSummary{
+			ID:          t.Key,
+			Name:        t.Name,
+			Description: t.Description,
+			Category:    t.Category,
+			Builtin:     t.Builtin,
+		})
+	}
+	return summaries, nil
+}
+
+// Get returns a template with content, builtin templates take precedence
+func (s *TemplateService) Get(ctx context.Context, key string) (*model.ProjectTemplate, error) {
+	builtin, err := loadBuiltinTemplates()
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := builtin[key]; ok {
+		return t, nil
+	}
+
+	t, err := s.templateDAO.GetByKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil || !t.Enabled {
+		return nil, ErrTemplateNotFound
+	}
+	return t, nil
+}
+
+// ListCurated lists all curated templates including disabled ones (admin)
+func (s *TemplateService) ListCurated(ctx context.Context) ([]model.ProjectTemplate, error) {
+	return s.templateDAO.ListSummaries(ctx, true)
+}
+
+// CreateCurated adds a curated template (admin)
+func (s *TemplateService) CreateCurated(ctx context.Context, adminID uint64, t *model.ProjectTemplate) error {
+	if err := s.checkKeyAvailable(ctx, t.Key); err != nil {
+		return err
+	}
+	t.ID = 0
+	t.CreatedBy = adminID
+	return s.templateDAO.Create(ctx, t)
+}
+
+// UpdateCurated updates a curated template (admin)
+func (s *TemplateService) UpdateCurated(ctx context.Context, key string, update *model.ProjectTemplate) (*model.ProjectTemplate, error) {
+	builtin, err := loadBuiltinTemplates()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := builtin[key]; ok {
+		return nil, ErrTemplateBuiltin
+	}
+
+	t, err := s.templateDAO.GetByKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, ErrTemplateNotFound
+	}
+
+	t.Name = update.Name
+	t.Description = update.Description
+	t.Category = update.Category
+	t.HTML = update.HTML
+	t.CSS = update.CSS
+	t.Sort = update.Sort
+	t.Enabled = update.Enabled
+
+	if err := s.templateDAO.Update(ctx, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// DeleteCurated removes a curated template (admin)
+func (s *TemplateService) DeleteCurated(ctx context.Context, key string) error {
+	builtin, err := loadBuiltinTemplates()
+	if err != nil {
+		return err
+	}
+	if _, ok := builtin[key]; ok {
+		return ErrTemplateBuiltin
+	}
+
+	deleted, err := s.templateDAO.DeleteByKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+// checkKeyAvailable 校验 key 格式且不与内置或已有模板冲突
+func (s *TemplateService) checkKeyAvailable(ctx context.Context, key string) error {
+	if !templateKeyPattern.MatchString(key) {
+		return ErrTemplateKeyInvalid
+	}
+	builtin, err := loadBuiltinTemplates()
+	if err != nil {
+		return err
+	}
+	if _, ok := builtin[key]; ok {
+		return ErrTemplateKeyExists
+	}
+	existing, err := s.templateDAO.GetByKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return ErrTemplateKeyExists
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+	"unicode/utf8"
+
+	"github.com/test-tt/pkg/sanitize"
+)
+
+// TestBuiltinTemplates tests that every embedded template is complete
+func TestBuiltinTemplates(t *testing.T) {
+	templates, err := loadBuiltinTemplates()
+	if err != nil {
+		t.Fatalf("loadBuiltinTemplates() error: %v", err)
+	}
+	if len(templates) == 0 {
+		t.Fatal("expected builtin templates")
+	}
+
+	for key, tpl := range templates {
+		if !templateKeyPattern.MatchString(key) {
+			t.Errorf("template key %q is invalid", key)
+		}
+		if tpl.Name == "" || tpl.Category == "" {
+			t.Errorf("template %q missing name or category", key)
+		}
+		if !strings.Contains(tpl.HTML, "<html") {
+			t.Errorf("template %q html is not a document", key)
+		}
+		if !tpl.Builtin {
+			t.Errorf("template %q should be marked builtin", key)
+		}
+		// 从模板创建项目时会清洗内容，内置模板不应触发任何警告
+		if cleaned := sanitize.DefaultPolicy().Sanitize(tpl.HTML, tpl.CSS); len(cleaned.Warnings) > 0 {
+			t.Errorf("template %q sanitize warnings = %+v", key, cleaned.Warnings)
+		}
+	}
+}
+
+// TestParseTemplateFS tests template directory parsing
+func TestParseTemplateFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"tpl/demo/template.yaml": {Data: []byte("name: Demo\ncategory: test\nsort: 5\n")},
+		"tpl/demo/index.html":    {Data: []byte("<html></html>")},
+		"tpl/README.md":          {Data: []byte("ignored")},
+	}
+
+	templates, err := parseTemplateFS(fsys, "tpl")
+	if err != nil {
+		t.Fatalf("parseTemplateFS() error: %v", err)
+	}
+	demo, ok := templates["demo"]
+	if !ok || len(templates) != 1 {
+		t.Fatalf("expected only the demo template, got %v", templates)
+	}
+	if demo.Name != "Demo" || demo.Sort != 5 || demo.CSS != "" {
+		t.Errorf("unexpected template: %+v", demo)
+	}
+
+	t.Run("missing index.html", func(t *testing.T) {
+		broken := fstest.MapFS{"tpl/x/template.yaml": {Data: []byte("name: X\n")}}
+		if _, err := parseTemplateFS(broken, "tpl"); err == nil {
+			t.Error("expected error for template without index.html")
+		}
+	})
+}
+
+// TestForkName tests default fork names
+func TestForkName(t *testing.T) {
+	if got := forkName("My Site"); got != "My Site (fork)" {
+		t.Errorf("forkName() = %q", got)
+	}
+
+	long := forkName(strings.Repeat("页", 300))
+	if n := utf8.RuneCountInString(long); n != maxProjectNameLen {
+		t.Errorf("forkName() length = %d, want %d", n, maxProjectNameLen)
+	}
+	if !strings.HasSuffix(long, forkNameSuffix) {
+		t.Errorf("forkName() lost suffix: %q", long)
+	}
+}
+
+// TestForkReadsSnapshot tests that owners and members fork the current content and other users the published snapshot
+func TestForkReadsSnapshot(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		snapshot bool
+		wantErr  error
+	}{
+		{"owner or member", nil, false, nil},
+		{"not a member", ErrProjectNotOwned, true, nil},
+		{"missing", ErrProjectNotFound, false, ErrProjectNotFound},
+	}
+	for _, tt := range tests {
+		snapshot, err := forkReadsSnapshot(tt.err)
+		if snapshot != tt.snapshot || err != tt.wantErr {
+			t.Errorf("%s: forkReadsSnapshot() = %v, %v", tt.name, snapshot, err)
+		}
+	}
+}
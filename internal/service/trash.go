@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/test-tt/pkg/logger"
+)
+
+const (
+	trashPurgeBatchSize = 100
+	trashPurgeTimeout   = 5 * time.Minute
+)
+
+// StartTrashPurger 启动回收站清理任务，定期永久删除超过保留期的项目
+// 返回停止函数；多实例同时运行是安全的（删除是幂等的）
+func StartTrashPurger(retention, interval time.Duration) func() {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	stopChan := make(chan struct{})
+	projectService := NewProjectService()
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				purgeExpiredTrash(projectService, retention)
+			case <-stopChan:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(stopChan)
+	}
+}
+
+// purgeExpiredTrash 分批清理，直到没有过期项目或出错
+func purgeExpiredTrash(projectService *ProjectService, retention time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), trashPurgeTimeout)
+	defer cancel()
+
+	total := 0
+	for {
+		purged, err := projectService.PurgeTrash(ctx, retention, trashPurgeBatchSize)
+		total += purged
+		if err != nil {
+			logger.Errorf("failed to purge trash", "error", err, "purged", total)
+			return
+		}
+		if purged < trashPurgeBatchSize {
+			break
+		}
+	}
+	if total > 0 {
+		logger.Infof("purged expired projects from trash", "count", total)
+	}
+}
@@ -0,0 +1,72 @@
+package blob
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+)
+
+// 存储编码
+const (
+	EncodingRaw  = "raw"
+	EncodingGzip = "gzip"
+)
+
+// CompressThreshold 超过该字节数的内容压缩后存储
+const CompressThreshold = 4 << 10
+
+var ErrUnknownEncoding = errors.New("unknown blob encoding")
+
+// Hash returns the hex SHA-256 of content, the address a blob is stored under
+func Hash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// Encode prepares content for storage, gzipping it when it is above the threshold
+// 压缩后没有变小时按原样存储
+func Encode(content string) ([]byte, string, error) {
+	if len(content) <= CompressThreshold {
+		return []byte(content), EncodingRaw, nil
+	}
+
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestSpeed)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := io.WriteString(zw, content); err != nil {
+		return nil, "", err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, "", err
+	}
+	if buf.Len() >= len(content) {
+		return []byte(content), EncodingRaw, nil
+	}
+	return buf.Bytes(), EncodingGzip, nil
+}
+
+// Decode restores content stored by Encode
+func Decode(data []byte, encoding string) (string, error) {
+	switch encoding {
+	case EncodingRaw, "":
+		return string(data), nil
+	case EncodingGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return "", err
+		}
+		defer zr.Close()
+		content, err := io.ReadAll(zr)
+		if err != nil {
+			return "", err
+		}
+		return string(content), nil
+	default:
+		return "", ErrUnknownEncoding
+	}
+}
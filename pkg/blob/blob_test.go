@@ -0,0 +1,64 @@
+package blob
+
+import (
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	// sha256("abc")
+	want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if got := Hash("abc"); got != want {
+		t.Errorf("Hash() = %s, want %s", got, want)
+	}
+	if Hash("a") == Hash("b") {
+		t.Error("different content has the same hash")
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	random := make([]byte, 2*CompressThreshold)
+	_, _ = rand.Read(random)
+
+	tests := []struct {
+		name     string
+		content  string
+		encoding string
+	}{
+		{"empty", "", EncodingRaw},
+		{"small", "<p>hello</p>", EncodingRaw},
+		{"at threshold", strings.Repeat("a", CompressThreshold), EncodingRaw},
+		{"large", strings.Repeat("<div class=\"card\">你好</div>\n", 1000), EncodingGzip},
+		{"incompressible", string(random), EncodingRaw},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, encoding, err := Encode(tt.content)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if encoding != tt.encoding {
+				t.Errorf("Encode() encoding = %s, want %s", encoding, tt.encoding)
+			}
+			if encoding == EncodingGzip && len(data) >= len(tt.content) {
+				t.Errorf("compressed %d bytes to %d", len(tt.content), len(data))
+			}
+			got, err := Decode(data, encoding)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if got != tt.content {
+				t.Error("Decode() did not round-trip the content")
+			}
+		})
+	}
+
+	if _, err := Decode([]byte("x"), "zstd"); err != ErrUnknownEncoding {
+		t.Errorf("Decode() unknown encoding error = %v", err)
+	}
+	if _, err := Decode([]byte("not gzip"), EncodingGzip); err == nil {
+		t.Error("Decode() accepted corrupt gzip data")
+	}
+}
@@ -41,6 +41,34 @@ var (
 
 	// 缓存相关 4xxx
 	ErrCache = &ErrCode{Code: 4001, Message: "cache error", HTTPStatus: http.StatusInternalServerError}
+
+	// 项目相关 6xxx
+	ErrProjectNotPublished  = &ErrCode{Code: 6001, Message: "project is not published", HTTPStatus: http.StatusNotFound}
+	ErrSlugInvalid          = &ErrCode{Code: 6002, Message: "slug must be 3-64 lowercase letters, digits or single hyphens", HTTPStatus: http.StatusBadRequest}
+	ErrSlugTaken            = &ErrCode{Code: 6003, Message: "slug is already taken", HTTPStatus: http.StatusConflict}
+	ErrTemplateNotFound     = &ErrCode{Code: 6004, Message: "template not found", HTTPStatus: http.StatusNotFound}
+	ErrTemplateExists       = &ErrCode{Code: 6005, Message: "template already exists", HTTPStatus: http.StatusConflict}
+	ErrBundleInvalid        = &ErrCode{Code: 6006, Message: "invalid project bundle", HTTPStatus: http.StatusBadRequest}
+	ErrBundleTooLarge       = &ErrCode{Code: 6007, Message: "project bundle too large", HTTPStatus: http.StatusRequestEntityTooLarge}
+	ErrAssetTooLarge        = &ErrCode{Code: 6008, Message: "asset exceeds size limit", HTTPStatus: http.StatusRequestEntityTooLarge}
+	ErrAssetTypeNotAllowed  = &ErrCode{Code: 6009, Message: "only images and fonts can be uploaded", HTTPStatus: http.StatusUnsupportedMediaType}
+	ErrAssetQuotaExceeded   = &ErrCode{Code: 6010, Message: "asset storage quota exceeded", HTTPStatus: http.StatusForbidden}
+	ErrFolderNotFound       = &ErrCode{Code: 6011, Message: "folder not found", HTTPStatus: http.StatusNotFound}
+	ErrFolderTooDeep        = &ErrCode{Code: 6012, Message: "folders can only be nested one level", HTTPStatus: http.StatusBadRequest}
+	ErrFolderLimit          = &ErrCode{Code: 6013, Message: "folder limit reached", HTTPStatus: http.StatusBadRequest}
+	ErrTagInvalid           = &ErrCode{Code: 6014, Message: "tags must be 1-32 characters", HTTPStatus: http.StatusBadRequest}
+	ErrTooManyTags          = &ErrCode{Code: 6015, Message: "a project can have at most 20 tags", HTTPStatus: http.StatusBadRequest}
+	ErrProjectLimitExceeded = &ErrCode{Code: 6016, Message: "project limit of your plan reached", HTTPStatus: http.StatusForbidden}
+	ErrProjectTooLarge      = &ErrCode{Code: 6017, Message: "project content exceeds the size limit of your plan", HTTPStatus: http.StatusRequestEntityTooLarge}
+	ErrStorageLimitExceeded = &ErrCode{Code: 6018, Message: "storage limit of your plan reached", HTTPStatus: http.StatusForbidden}
+	ErrMemberLimit          = &ErrCode{Code: 6019, Message: "a project can have at most 50 members", HTTPStatus: http.StatusBadRequest}
+	ErrFilePathInvalid      = &ErrCode{Code: 6020, Message: "file path must be a relative path of letters, digits, '.', '_' and '-' ending in .html, .css or .js", HTTPStatus: http.StatusBadRequest}
+	ErrFileLimit            = &ErrCode{Code: 6021, Message: "a project can have at most 100 files besides index.html and style.css", HTTPStatus: http.StatusBadRequest}
+	ErrGalleryNotListed     = &ErrCode{Code: 6022, Message: "project is not in the gallery", HTTPStatus: http.StatusNotFound}
+
+	// 生成相关 7xxx
+	ErrCreditsExhausted   = &ErrCode{Code: 7001, Message: "generation credits of your plan are used up", HTTPStatus: http.StatusPaymentRequired}
+	ErrGenerationFinished = &ErrCode{Code: 7002, Message: "generation job has already ended", HTTPStatus: http.StatusConflict}
 )
 
 // WithMessage 返回带自定义消息的错误码
@@ -0,0 +1,80 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// ErrInvalidCursor 游标无法解析
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// maxCursorLen 游标最大长度，避免解析超大输入
+const maxCursorLen = 1024
+
+// CursorPagination 游标分页参数
+type CursorPagination struct {
+	Cursor string `json:"cursor"`
+	Limit  int    `json:"limit"`
+}
+
+// CursorResult 游标分页结果
+type CursorResult struct {
+	List       interface{} `json:"list"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	HasMore    bool        `json:"has_more"`
+}
+
+// NewCursorResult 创建游标分页结果，nextCursor 为空表示没有更多数据
+func NewCursorResult(list interface{}, nextCursor string) *CursorResult {
+	return &CursorResult{
+		List:       list,
+		NextCursor: nextCursor,
+		HasMore:    nextCursor != "",
+	}
+}
+
+// EncodeCursor 将游标位置编码为不透明字符串
+func EncodeCursor(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCursor 解析 EncodeCursor 生成的游标
+func DecodeCursor(cursor string, v interface{}) error {
+	if len(cursor) > maxCursorLen {
+		return ErrInvalidCursor
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+// GetCursorFromQuery 从请求参数中获取游标分页信息（cursor, limit）
+func GetCursorFromQuery(c *app.RequestContext) *CursorPagination {
+	limit := DefaultPageSize
+	if l := c.Query("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 {
+			limit = v
+			if limit > MaxPageSize {
+				limit = MaxPageSize
+			}
+		}
+	}
+
+	return &CursorPagination{
+		Cursor: c.Query("cursor"),
+		Limit:  limit,
+	}
+}
@@ -3,6 +3,7 @@ package pagination
 import (
 	"context"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/cloudwego/hertz/pkg/app"
@@ -151,3 +152,87 @@ func TestNewPageResultWithData(t *testing.T) {
 		t.Errorf("Pages = %v, want 10", result.Pages)
 	}
 }
+
+func TestCursorRoundTrip(t *testing.T) {
+	type position struct {
+		Name string `json:"n"`
+		ID   uint64 `json:"id"`
+	}
+
+	cursor, err := EncodeCursor(position{Name: "My Project", ID: 42})
+	if err != nil {
+		t.Fatalf("EncodeCursor() error = %v", err)
+	}
+
+	var got position
+	if err := DecodeCursor(cursor, &got); err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+	if got.Name != "My Project" || got.ID != 42 {
+		t.Errorf("DecodeCursor() = %+v", got)
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "!!!"},
+		{"not json", "bm90LWpzb24"},
+		{"too long", strings.Repeat("a", maxCursorLen+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v map[string]interface{}
+			if err := DecodeCursor(tt.cursor, &v); err != ErrInvalidCursor {
+				t.Errorf("DecodeCursor() error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestNewCursorResult(t *testing.T) {
+	if r := NewCursorResult([]int{1}, "abc"); !r.HasMore || r.NextCursor != "abc" {
+		t.Errorf("NewCursorResult() with cursor = %+v", r)
+	}
+	if r := NewCursorResult([]int{}, ""); r.HasMore {
+		t.Error("HasMore should be false without next cursor")
+	}
+}
+
+func TestGetCursorFromQuery(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantCursor string
+		wantLimit  int
+	}{
+		{"defaults", "", "", DefaultPageSize},
+		{"custom", "cursor=abc&limit=30", "abc", 30},
+		{"exceeds max limit", "limit=500", "", MaxPageSize},
+		{"invalid limit", "limit=x", "", DefaultPageSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestEngine()
+			var got *CursorPagination
+
+			r.GET("/test", func(ctx context.Context, c *app.RequestContext) {
+				got = GetCursorFromQuery(c)
+				c.String(http.StatusOK, "ok")
+			})
+
+			ut.PerformRequest(r, http.MethodGet, "/test?"+tt.query, nil)
+
+			if got.Cursor != tt.wantCursor {
+				t.Errorf("Cursor = %q, want %q", got.Cursor, tt.wantCursor)
+			}
+			if got.Limit != tt.wantLimit {
+				t.Errorf("Limit = %v, want %v", got.Limit, tt.wantLimit)
+			}
+		})
+	}
+}
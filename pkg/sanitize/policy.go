@@ -0,0 +1,174 @@
+package sanitize
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Revision 清洗规则的版本号，修改默认白名单或处理逻辑时递增
+const Revision = 1
+
+// 脚本处理方式
+const (
+	ScriptStrip = "strip" // 删除脚本、事件属性和 javascript: 链接
+	ScriptFlag  = "flag"  // 保留但返回警告
+)
+
+// 外部资源 URL 处理方式
+const (
+	ExternalKeep    = "keep"    // 保留原 URL
+	ExternalRewrite = "rewrite" // 改写为 RewritePrefix + 转义后的 URL
+	ExternalStrip   = "strip"   // 删除
+)
+
+var ErrInvalidOptions = errors.New("invalid sanitize options")
+
+// Options configures a Policy
+type Options struct {
+	ScriptMode    string   // strip (default) or flag
+	ExternalURLs  string   // keep (default), rewrite or strip
+	RewritePrefix string   // Required when ExternalURLs is rewrite, e.g. "/proxy?url="
+	ExtraTags     []string // Tags allowed in addition to the defaults
+	ExtraAttrs    []string // Attributes allowed in addition to the defaults
+}
+
+// Policy is an immutable sanitization policy
+type Policy struct {
+	scriptMode    string
+	externalURLs  string
+	rewritePrefix string
+	tags          map[string]struct{}
+	attrs         map[string]struct{}
+	version       string
+}
+
+// defaultTags 默认允许的标签（小写）
+var defaultTags = []string{
+	// 文档结构
+	"html", "head", "body", "title", "meta", "link",
+	// 区块与文本
+	"div", "span", "p", "br", "hr", "h1", "h2", "h3", "h4", "h5", "h6",
+	"header", "footer", "main", "nav", "section", "article", "aside", "address",
+	"a", "strong", "b", "em", "i", "u", "s", "small", "mark", "sub", "sup", "code", "pre",
+	"kbd", "samp", "var", "abbr", "cite", "q", "blockquote", "del", "ins", "time", "wbr",
+	"ul", "ol", "li", "dl", "dt", "dd", "figure", "figcaption", "details", "summary",
+	// 表格
+	"table", "caption", "thead", "tbody", "tfoot", "tr", "th", "td", "colgroup", "col",
+	// 媒体
+	"img", "picture", "source", "video", "audio", "track",
+	// 表单
+	"form", "label", "input", "button", "select", "option", "optgroup", "textarea", "fieldset", "legend",
+	// SVG 图标
+	"svg", "g", "path", "circle", "ellipse", "rect", "line", "polyline", "polygon", "defs",
+	"lineargradient", "radialgradient", "stop", "clippath", "mask", "use", "symbol", "text", "tspan",
+}
+
+// defaultAttrs 默认允许的属性（小写），data-* 和 aria-* 始终允许
+var defaultAttrs = []string{
+	"id", "class", "style", "title", "lang", "dir", "role", "hidden", "tabindex",
+	"href", "target", "rel", "src", "srcset", "sizes", "alt", "width", "height", "loading", "decoding",
+	"name", "content", "charset", "type", "value", "placeholder", "for", "disabled", "checked",
+	"selected", "required", "readonly", "multiple", "min", "max", "step", "maxlength", "pattern",
+	"action", "method", "autocomplete", "rows", "cols", "colspan", "rowspan", "scope", "span",
+	"controls", "autoplay", "loop", "muted", "playsinline", "poster", "preload", "open", "datetime", "cite",
+	"media", "kind", "srclang", "label", "start", "reversed",
+	// SVG
+	"xmlns", "viewbox", "fill", "stroke", "stroke-width", "stroke-linecap", "stroke-linejoin",
+	"fill-rule", "clip-rule", "d", "cx", "cy", "r", "rx", "ry", "x", "y", "x1", "y1", "x2", "y2",
+	"points", "transform", "opacity", "fill-opacity", "stroke-opacity", "offset", "stop-color",
+	"stop-opacity", "gradientunits", "gradienttransform", "preserveaspectratio", "clip-path",
+	"xlink:href", "text-anchor", "font-size", "font-family", "font-weight",
+}
+
+// DefaultPolicy returns the policy with default options
+func DefaultPolicy() *Policy {
+	p, _ := NewPolicy(&Options{})
+	return p
+}
+
+// NewPolicy builds a policy from options
+func NewPolicy(opts *Options) (*Policy, error) {
+	p := &Policy{
+		scriptMode:    opts.ScriptMode,
+		externalURLs:  opts.ExternalURLs,
+		rewritePrefix: opts.RewritePrefix,
+		tags:          make(map[string]struct{}, len(defaultTags)+len(opts.ExtraTags)),
+		attrs:         make(map[string]struct{}, len(defaultAttrs)+len(opts.ExtraAttrs)),
+	}
+	if p.scriptMode == "" {
+		p.scriptMode = ScriptStrip
+	}
+	if p.externalURLs == "" {
+		p.externalURLs = ExternalKeep
+	}
+	if p.scriptMode != ScriptStrip && p.scriptMode != ScriptFlag {
+		return nil, fmt.Errorf("%w: script mode %q", ErrInvalidOptions, p.scriptMode)
+	}
+	switch p.externalURLs {
+	case ExternalKeep, ExternalStrip:
+	case ExternalRewrite:
+		if p.rewritePrefix == "" {
+			return nil, fmt.Errorf("%w: rewrite requires a prefix", ErrInvalidOptions)
+		}
+	default:
+		return nil, fmt.Errorf("%w: external urls %q", ErrInvalidOptions, p.externalURLs)
+	}
+
+	for _, t := range append(append([]string{}, defaultTags...), opts.ExtraTags...) {
+		t = strings.ToLower(strings.TrimSpace(t))
+		// 危险标签不能通过配置放行
+		if _, dangerous := dangerousTags[t]; t != "" && !dangerous {
+			p.tags[t] = struct{}{}
+		}
+	}
+	for _, a := range append(append([]string{}, defaultAttrs...), opts.ExtraAttrs...) {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a != "" && !strings.HasPrefix(a, "on") {
+			p.attrs[a] = struct{}{}
+		}
+	}
+
+	p.version = p.computeVersion()
+	return p, nil
+}
+
+// Version identifies the rules and options of the policy, e.g. "v1-3fa2b1c0"
+func (p *Policy) Version() string {
+	return p.version
+}
+
+// computeVersion 由规则版本号和选项摘要组成，配置变化时版本随之变化
+func (p *Policy) computeVersion() string {
+	tags := make([]string, 0, len(p.tags))
+	for t := range p.tags {
+		tags = append(tags, t)
+	}
+	attrs := make([]string, 0, len(p.attrs))
+	for a := range p.attrs {
+		attrs = append(attrs, a)
+	}
+	sort.Strings(tags)
+	sort.Strings(attrs)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s|%s|%s|%s", p.scriptMode, p.externalURLs, p.rewritePrefix,
+		strings.Join(tags, ","), strings.Join(attrs, ","))
+	return fmt.Sprintf("v%d-%s", Revision, hex.EncodeToString(h.Sum(nil))[:8])
+}
+
+func (p *Policy) allowTag(tag string) bool {
+	_, ok := p.tags[tag]
+	return ok
+}
+
+func (p *Policy) allowAttr(attr string) bool {
+	if strings.HasPrefix(attr, "data-") || strings.HasPrefix(attr, "aria-") {
+		return true
+	}
+	_, ok := p.attrs[attr]
+	return ok
+}
@@ -0,0 +1,437 @@
+package sanitize
+
+import (
+	"bytes"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 警告代码
+const (
+	WarnScriptRemoved        = "script_removed"
+	WarnScriptFlagged        = "script_flagged"
+	WarnTagRemoved           = "tag_removed"
+	WarnAttributeRemoved     = "attribute_removed"
+	WarnStyleExtracted       = "style_extracted"
+	WarnExternalURLRewritten = "external_url_rewritten"
+	WarnExternalURLRemoved   = "external_url_removed"
+	WarnUnsafeURLRemoved     = "unsafe_url_removed"
+	WarnCSSExpressionRemoved = "css_expression_removed"
+)
+
+// Warning describes a change made (or a risk found) while sanitizing
+type Warning struct {
+	Code   string `json:"code"`
+	Detail string `json:"detail,omitempty"` // Tag, attribute or URL concerned
+	Count  int    `json:"count"`
+}
+
+// Result is the output of Sanitize
+type Result struct {
+	HTML          string    `json:"-"`
+	CSS           string    `json:"-"`
+	Warnings      []Warning `json:"warnings"`
+	PolicyVersion string    `json:"policy_version"`
+}
+
+// dangerousTags 连同内容一起删除的标签，不能通过配置放行
+var dangerousTags = map[string]struct{}{
+	"script": {}, "iframe": {}, "frame": {}, "frameset": {}, "object": {}, "embed": {}, "applet": {},
+	"base": {}, "noscript": {}, "template": {}, "math": {}, "portal": {}, "style": {},
+}
+
+// urlAttrs 值为 URL 的属性
+var urlAttrs = map[string]struct{}{
+	"href": {}, "src": {}, "action": {}, "poster": {}, "cite": {}, "xlink:href": {},
+}
+
+// documentPattern 内容是完整文档（而不是片段）时按文档解析，保留 head
+var documentPattern = regexp.MustCompile(`(?i)<!doctype|<html[\s>]|<head[\s>]|<body[\s>]`)
+
+// Sanitize cleans project HTML and CSS according to the policy
+// <style> 块中的 CSS 被移到 CSS 字段末尾；输出 HTML 经过重新序列化（规范化）
+func (p *Policy) Sanitize(htmlText, css string) *Result {
+	s := &sanitizer{policy: p, warnings: newWarningSet()}
+
+	outHTML := htmlText
+	if strings.TrimSpace(htmlText) != "" {
+		outHTML = s.sanitizeHTML(htmlText)
+	}
+
+	parts := make([]string, 0, len(s.styles)+1)
+	if strings.TrimSpace(css) != "" {
+		parts = append(parts, strings.TrimRight(css, "\n"))
+	}
+	parts = append(parts, s.styles...)
+	outCSS := s.sanitizeCSS(strings.Join(parts, "\n\n"))
+	if outCSS != "" && strings.HasSuffix(css, "\n") {
+		outCSS += "\n"
+	}
+
+	return &Result{
+		HTML:          outHTML,
+		CSS:           outCSS,
+		Warnings:      s.warnings.list(),
+		PolicyVersion: p.version,
+	}
+}
+
+type sanitizer struct {
+	policy   *Policy
+	warnings *warningSet
+	styles   []string
+}
+
+func (s *sanitizer) sanitizeHTML(text string) string {
+	var buf bytes.Buffer
+	if documentPattern.MatchString(text) {
+		doc, err := html.Parse(strings.NewReader(text))
+		if err != nil {
+			return ""
+		}
+		s.walk(doc)
+		if err := html.Render(&buf, doc); err != nil {
+			return ""
+		}
+		return buf.String()
+	}
+
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(text), context)
+	if err != nil {
+		return ""
+	}
+	for _, n := range nodes {
+		context.AppendChild(n)
+	}
+	s.walk(context)
+	for c := context.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&buf, c); err != nil {
+			return ""
+		}
+	}
+	return buf.String()
+}
+
+// walk 清洗 n 的所有子节点
+func (s *sanitizer) walk(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch c.Type {
+		case html.ElementNode:
+			next = s.element(n, c, next)
+		case html.CommentNode:
+			// 注释可能包含 IE 条件注释，直接删除
+			n.RemoveChild(c)
+		}
+		c = next
+	}
+}
+
+// element 清洗单个元素，返回下一个要处理的节点
+func (s *sanitizer) element(parent, n *html.Node, next *html.Node) *html.Node {
+	tag := strings.ToLower(n.Data)
+
+	switch {
+	case tag == "style":
+		if text := textContent(n); strings.TrimSpace(text) != "" {
+			s.styles = append(s.styles, strings.TrimSpace(text))
+			s.warnings.add(WarnStyleExtracted, "")
+		}
+		parent.RemoveChild(n)
+		return next
+	case tag == "script":
+		if s.policy.scriptMode == ScriptFlag {
+			s.warnings.add(WarnScriptFlagged, "script")
+			s.attributes(n, tag)
+			return next
+		}
+		s.warnings.add(WarnScriptRemoved, "script")
+		parent.RemoveChild(n)
+		return next
+	case isDangerous(tag):
+		s.warnings.add(WarnTagRemoved, tag)
+		parent.RemoveChild(n)
+		return next
+	case !s.policy.allowTag(tag):
+		// 未知标签去掉外壳，保留内容
+		s.warnings.add(WarnTagRemoved, tag)
+		first := n.FirstChild
+		for c := n.FirstChild; c != nil; {
+			cn := c.NextSibling
+			n.RemoveChild(c)
+			parent.InsertBefore(c, n)
+			c = cn
+		}
+		parent.RemoveChild(n)
+		if first != nil {
+			return first
+		}
+		return next
+	}
+
+	s.attributes(n, tag)
+	s.walk(n)
+	return next
+}
+
+// attributes 清洗元素属性
+func (s *sanitizer) attributes(n *html.Node, tag string) {
+	kept := n.Attr[:0]
+	for _, a := range n.Attr {
+		name := strings.ToLower(a.Key)
+		if a.Namespace != "" {
+			name = a.Namespace + ":" + name
+		}
+
+		if strings.HasPrefix(name, "on") {
+			if s.policy.scriptMode == ScriptFlag {
+				s.warnings.add(WarnScriptFlagged, name)
+				kept = append(kept, a)
+			} else {
+				s.warnings.add(WarnScriptRemoved, name)
+			}
+			continue
+		}
+		if !s.policy.allowAttr(name) || (tag == "meta" && name == "http-equiv") {
+			s.warnings.add(WarnAttributeRemoved, name)
+			continue
+		}
+
+		value, ok := a.Val, true
+		switch {
+		case name == "style":
+			value = s.sanitizeCSS(a.Val)
+		case name == "srcset":
+			value, ok = s.srcset(a.Val)
+		case isURLAttr(name):
+			value, ok = s.url(a.Val, tag, name)
+		}
+		if !ok {
+			continue
+		}
+		a.Val = value
+		kept = append(kept, a)
+	}
+	n.Attr = kept
+}
+
+// url 处理 URL 属性，返回 false 表示删除该属性
+func (s *sanitizer) url(raw, tag, attr string) (string, bool) {
+	scheme := urlScheme(raw)
+	switch scheme {
+	case "javascript", "vbscript":
+		if s.policy.scriptMode == ScriptFlag {
+			s.warnings.add(WarnScriptFlagged, scheme+":")
+			return raw, true
+		}
+		s.warnings.add(WarnScriptRemoved, scheme+":")
+		return "", false
+	case "data":
+		// 只允许图片类 data URL 作为资源
+		if attr == "src" && strings.HasPrefix(strings.ToLower(strings.TrimSpace(raw)), "data:image/") &&
+			!strings.HasPrefix(strings.ToLower(strings.TrimSpace(raw)), "data:image/svg") {
+			return raw, true
+		}
+		s.warnings.add(WarnUnsafeURLRemoved, "data:")
+		return "", false
+	}
+
+	if !isExternal(raw) || !isResource(tag, attr) {
+		return raw, true
+	}
+	return s.external(raw)
+}
+
+// srcset 逐个处理候选 URL
+func (s *sanitizer) srcset(raw string) (string, bool) {
+	candidates := strings.Split(raw, ",")
+	out := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		fields := strings.Fields(c)
+		if len(fields) == 0 {
+			continue
+		}
+		u, ok := s.url(fields[0], "img", "src")
+		if !ok {
+			continue
+		}
+		fields[0] = u
+		out = append(out, strings.Join(fields, " "))
+	}
+	if len(out) == 0 {
+		return "", false
+	}
+	return strings.Join(out, ", "), true
+}
+
+// external 按策略处理外部资源 URL
+func (s *sanitizer) external(raw string) (string, bool) {
+	switch s.policy.externalURLs {
+	case ExternalRewrite:
+		s.warnings.add(WarnExternalURLRewritten, hostOf(raw))
+		return s.policy.rewritePrefix + url.QueryEscape(strings.TrimSpace(raw)), true
+	case ExternalStrip:
+		s.warnings.add(WarnExternalURLRemoved, hostOf(raw))
+		return "", false
+	default:
+		return raw, true
+	}
+}
+
+var (
+	cssURLPattern        = regexp.MustCompile(`(?i)url\(\s*("[^"]*"|'[^']*'|[^)'"]*?)\s*\)`)
+	cssImportPattern     = regexp.MustCompile(`(?i)@import\s+("[^"]*"|'[^']*')`)
+	cssExpressionPattern = regexp.MustCompile(`(?i)expression\s*\(|-moz-binding\s*:|behavior\s*:`)
+)
+
+// sanitizeCSS 删除 CSS 中的脚本表达式，并按策略处理 url() 和 @import
+func (s *sanitizer) sanitizeCSS(css string) string {
+	if css == "" {
+		return css
+	}
+	css = cssExpressionPattern.ReplaceAllStringFunc(css, func(string) string {
+		s.warnings.add(WarnCSSExpressionRemoved, "")
+		return "removed:"
+	})
+	css = cssURLPattern.ReplaceAllStringFunc(css, func(m string) string {
+		raw := unquote(cssURLPattern.FindStringSubmatch(m)[1])
+		if u, ok := s.cssURL(raw); ok {
+			if u == raw {
+				return m
+			}
+			return `url("` + u + `")`
+		}
+		return `url("")`
+	})
+	css = cssImportPattern.ReplaceAllStringFunc(css, func(m string) string {
+		raw := unquote(cssImportPattern.FindStringSubmatch(m)[1])
+		if u, ok := s.cssURL(raw); ok {
+			if u == raw {
+				return m
+			}
+			return `@import "` + u + `"`
+		}
+		return `@import ""`
+	})
+	return css
+}
+
+func (s *sanitizer) cssURL(raw string) (string, bool) {
+	switch scheme := urlScheme(raw); scheme {
+	case "javascript", "vbscript":
+		s.warnings.add(WarnScriptRemoved, scheme+":")
+		return "", false
+	}
+	if !isExternal(raw) {
+		return raw, true
+	}
+	return s.external(raw)
+}
+
+// urlScheme 返回小写的 scheme，浏览器会忽略 URL 中的空白和控制字符，这里同样处理
+func urlScheme(raw string) string {
+	var b strings.Builder
+	for _, r := range raw {
+		if r <= ' ' {
+			continue
+		}
+		if r == ':' {
+			return strings.ToLower(b.String())
+		}
+		if r == '/' || r == '?' || r == '#' {
+			return ""
+		}
+		b.WriteRune(r)
+	}
+	return ""
+}
+
+func isExternal(raw string) bool {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "//") {
+		return true
+	}
+	scheme := urlScheme(raw)
+	return scheme == "http" || scheme == "https"
+}
+
+// isResource 是否为页面加载的资源（而不是用户点击的链接）
+func isResource(tag, attr string) bool {
+	switch attr {
+	case "src", "poster", "xlink:href":
+		return true
+	case "href":
+		return tag == "link" || tag == "use"
+	}
+	return false
+}
+
+func isURLAttr(name string) bool {
+	_, ok := urlAttrs[name]
+	return ok
+}
+
+func isDangerous(tag string) bool {
+	_, ok := dangerousTags[tag]
+	return ok
+}
+
+func hostOf(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "//") {
+		raw = "https:" + raw
+	}
+	if u, err := url.Parse(raw); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return ""
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+	}
+	return b.String()
+}
+
+// warningSet 按 code + detail 合并警告，保持首次出现的顺序
+type warningSet struct {
+	index map[string]int
+	items []Warning
+}
+
+func newWarningSet() *warningSet {
+	return &warningSet{index: make(map[string]int)}
+}
+
+func (w *warningSet) add(code, detail string) {
+	key := code + "|" + detail
+	if i, ok := w.index[key]; ok {
+		w.items[i].Count++
+		return
+	}
+	w.index[key] = len(w.items)
+	w.items = append(w.items, Warning{Code: code, Detail: detail, Count: 1})
+}
+
+func (w *warningSet) list() []Warning {
+	if w.items == nil {
+		return []Warning{}
+	}
+	return w.items
+}
@@ -0,0 +1,162 @@
+package sanitize
+
+import (
+	"strings"
+	"testing"
+)
+
+func hasWarning(r *Result, code, detail string) bool {
+	for _, w := range r.Warnings {
+		if w.Code == code && (detail == "" || w.Detail == detail) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSanitize_Scripts(t *testing.T) {
+	p := DefaultPolicy()
+	r := p.Sanitize(`<div onclick="steal()"><script>alert(1)</script><a href="javascript:alert(1)">x</a><p>ok</p></div>`, "")
+
+	for _, bad := range []string{"<script", "onclick", "javascript:"} {
+		if strings.Contains(r.HTML, bad) {
+			t.Errorf("HTML still contains %q: %s", bad, r.HTML)
+		}
+	}
+	if !strings.Contains(r.HTML, "<p>ok</p>") {
+		t.Errorf("HTML lost safe content: %s", r.HTML)
+	}
+	if !hasWarning(r, WarnScriptRemoved, "script") || !hasWarning(r, WarnScriptRemoved, "onclick") {
+		t.Errorf("missing script warnings: %+v", r.Warnings)
+	}
+}
+
+func TestSanitize_FlagMode(t *testing.T) {
+	p, err := NewPolicy(&Options{ScriptMode: ScriptFlag})
+	if err != nil {
+		t.Fatalf("NewPolicy() error = %v", err)
+	}
+	r := p.Sanitize(`<button onclick="go()">Go</button><script>init()</script>`, "")
+
+	if !strings.Contains(r.HTML, "onclick") || !strings.Contains(r.HTML, "<script>init()</script>") {
+		t.Errorf("flag mode should keep scripts: %s", r.HTML)
+	}
+	if !hasWarning(r, WarnScriptFlagged, "onclick") || !hasWarning(r, WarnScriptFlagged, "script") {
+		t.Errorf("missing flag warnings: %+v", r.Warnings)
+	}
+}
+
+func TestSanitize_TagsAndAttributes(t *testing.T) {
+	r := DefaultPolicy().Sanitize(`<iframe src="https://evil.test"></iframe><blink>Hi</blink><p foo="bar" data-x="1" aria-label="l">t</p>`, "")
+
+	if strings.Contains(r.HTML, "iframe") || strings.Contains(r.HTML, "evil") {
+		t.Errorf("iframe not removed: %s", r.HTML)
+	}
+	if strings.Contains(r.HTML, "blink") || !strings.Contains(r.HTML, "Hi") {
+		t.Errorf("unknown tag should be unwrapped: %s", r.HTML)
+	}
+	if strings.Contains(r.HTML, "foo=") || !strings.Contains(r.HTML, `data-x="1"`) || !strings.Contains(r.HTML, `aria-label="l"`) {
+		t.Errorf("attributes not filtered: %s", r.HTML)
+	}
+	if !hasWarning(r, WarnTagRemoved, "iframe") || !hasWarning(r, WarnAttributeRemoved, "foo") {
+		t.Errorf("missing warnings: %+v", r.Warnings)
+	}
+}
+
+func TestSanitize_ExtractStyles(t *testing.T) {
+	html := `<!DOCTYPE html><html><head><style>h1 { color: red; }</style></head><body><h1>T</h1><style>p{margin:0}</style></body></html>`
+	r := DefaultPolicy().Sanitize(html, "body { margin: 0; }")
+
+	if strings.Contains(r.HTML, "<style") {
+		t.Errorf("style blocks not removed: %s", r.HTML)
+	}
+	if !strings.Contains(r.HTML, "<!DOCTYPE html>") || !strings.Contains(r.HTML, "<h1>T</h1>") {
+		t.Errorf("document structure lost: %s", r.HTML)
+	}
+	want := "body { margin: 0; }\n\nh1 { color: red; }\n\np{margin:0}"
+	if r.CSS != want {
+		t.Errorf("CSS = %q, want %q", r.CSS, want)
+	}
+	if !hasWarning(r, WarnStyleExtracted, "") {
+		t.Errorf("missing style warning: %+v", r.Warnings)
+	}
+}
+
+func TestSanitize_ExternalURLs(t *testing.T) {
+	html := `<img src="https://cdn.test/a.png"><a href="https://site.test/">link</a><img src="/assets/1/x.png">`
+	css := `.hero { background: url('https://cdn.test/bg.jpg'); } .x { background: url(/local.png); }`
+
+	keep := DefaultPolicy().Sanitize(html, css)
+	if !strings.Contains(keep.HTML, `src="https://cdn.test/a.png"`) || len(keep.Warnings) != 0 {
+		t.Errorf("keep mode changed URLs: %s %+v", keep.HTML, keep.Warnings)
+	}
+
+	p, err := NewPolicy(&Options{ExternalURLs: ExternalRewrite, RewritePrefix: "/proxy?url="})
+	if err != nil {
+		t.Fatalf("NewPolicy() error = %v", err)
+	}
+	r := p.Sanitize(html, css)
+	if !strings.Contains(r.HTML, `src="/proxy?url=https%3A%2F%2Fcdn.test%2Fa.png"`) {
+		t.Errorf("image not rewritten: %s", r.HTML)
+	}
+	if !strings.Contains(r.HTML, `href="https://site.test/"`) || !strings.Contains(r.HTML, `src="/assets/1/x.png"`) {
+		t.Errorf("links and local URLs must be kept: %s", r.HTML)
+	}
+	if !strings.Contains(r.CSS, `url("/proxy?url=https%3A%2F%2Fcdn.test%2Fbg.jpg")`) || !strings.Contains(r.CSS, "url(/local.png)") {
+		t.Errorf("CSS not rewritten: %s", r.CSS)
+	}
+	if !hasWarning(r, WarnExternalURLRewritten, "cdn.test") {
+		t.Errorf("missing rewrite warning: %+v", r.Warnings)
+	}
+
+	strip, _ := NewPolicy(&Options{ExternalURLs: ExternalStrip})
+	if r := strip.Sanitize(html, ""); strings.Contains(r.HTML, "cdn.test") {
+		t.Errorf("strip mode kept external image: %s", r.HTML)
+	}
+}
+
+func TestSanitize_CSS(t *testing.T) {
+	r := DefaultPolicy().Sanitize("", `a { width: expression(alert(1)); background: url("javascript:alert(1)"); }`)
+	if strings.Contains(r.CSS, "expression(") || strings.Contains(r.CSS, "javascript:") {
+		t.Errorf("CSS not sanitized: %s", r.CSS)
+	}
+	if !hasWarning(r, WarnCSSExpressionRemoved, "") {
+		t.Errorf("missing expression warning: %+v", r.Warnings)
+	}
+}
+
+func TestSanitize_Idempotent(t *testing.T) {
+	p := DefaultPolicy()
+	first := p.Sanitize(`<div class="a"><svg viewBox="0 0 10 10"><path d="M0 0L10 10"/></svg><style>x{}</style><p>Hello &amp; bye</p></div>`, "")
+	second := p.Sanitize(first.HTML, first.CSS)
+
+	if second.HTML != first.HTML || second.CSS != first.CSS {
+		t.Errorf("not idempotent:\n%s\n%s", first.HTML, second.HTML)
+	}
+	if len(second.Warnings) != 0 {
+		t.Errorf("clean input produced warnings: %+v", second.Warnings)
+	}
+	if !strings.Contains(first.HTML, "viewBox") {
+		t.Errorf("SVG attribute case lost: %s", first.HTML)
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	if _, err := NewPolicy(&Options{ScriptMode: "allow"}); err == nil {
+		t.Error("expected error for unknown script mode")
+	}
+	if _, err := NewPolicy(&Options{ExternalURLs: ExternalRewrite}); err == nil {
+		t.Error("expected error for rewrite without prefix")
+	}
+
+	extra, _ := NewPolicy(&Options{ExtraTags: []string{"marquee", "script"}, ExtraAttrs: []string{"onload"}})
+	if !extra.allowTag("marquee") || extra.allowTag("script") || extra.allowAttr("onload") {
+		t.Error("extra tags/attrs not applied safely")
+	}
+	if extra.Version() == DefaultPolicy().Version() {
+		t.Error("version should change with options")
+	}
+	if !strings.HasPrefix(DefaultPolicy().Version(), "v1-") {
+		t.Errorf("Version() = %q", DefaultPolicy().Version())
+	}
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrNotFound   = errors.New("object not found")
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Storage 对象存储接口
+// key 为 "/" 分隔的相对路径，由调用方保证唯一
+type Storage interface {
+	// Put 写入对象，已存在时覆盖
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open 读取对象，不存在时返回 ErrNotFound
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除对象，不存在时不报错
+	Delete(ctx context.Context, key string) error
+}
+
+// Config 存储配置
+type Config struct {
+	Driver   string // local, memory
+	LocalDir string // local 驱动的根目录
+}
+
+var (
+	defaultStorage Storage = NewMemoryStorage()
+	mu             sync.RWMutex
+)
+
+// Init 根据配置初始化全局存储
+func Init(cfg *Config) error {
+	var s Storage
+	switch cfg.Driver {
+	case "", "memory":
+		s = NewMemoryStorage()
+	case "local":
+		local, err := NewLocalStorage(cfg.LocalDir)
+		if err != nil {
+			return err
+		}
+		s = local
+	default:
+		return fmt.Errorf("unknown storage driver: %s", cfg.Driver)
+	}
+
+	mu.Lock()
+	defaultStorage = s
+	mu.Unlock()
+	return nil
+}
+
+// Default 获取全局存储（未初始化时为内存存储）
+func Default() Storage {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultStorage
+}
+
+// validateKey 拒绝空 key、绝对路径和路径穿越
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testStorage 对所有实现执行相同的用例
+func testStorage(t *testing.T, s Storage) {
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "1/logo.png", strings.NewReader("data")); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+
+	rc, err := s.Open(ctx, "1/logo.png")
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	_ = rc.Close()
+	if string(data) != "data" {
+		t.Errorf("Open() = %q, want %q", data, "data")
+	}
+
+	if err := s.Delete(ctx, "1/logo.png"); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	if _, err := s.Open(ctx, "1/logo.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open() after delete error = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, "1/logo.png"); err != nil {
+		t.Errorf("Delete() of missing object error: %v", err)
+	}
+
+	for _, key := range []string{"", "/abs", "../escape", "a/../../b", "a//b", `a\b`} {
+		if err := s.Put(ctx, key, strings.NewReader("x")); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q) error = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
+
+func TestMemoryStorage(t *testing.T) {
+	testStorage(t, NewMemoryStorage())
+}
+
+func TestLocalStorage(t *testing.T) {
+	s, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error: %v", err)
+	}
+	testStorage(t, s)
+}
+
+func TestInit(t *testing.T) {
+	if err := Init(&Config{Driver: "s3"}); err == nil {
+		t.Error("expected error for unknown driver")
+	}
+	if err := Init(&Config{Driver: "local", LocalDir: t.TempDir()}); err != nil {
+		t.Fatalf("Init(local) error: %v", err)
+	}
+	if _, ok := Default().(*LocalStorage); !ok {
+		t.Errorf("Default() = %T, want *LocalStorage", Default())
+	}
+	_ = Init(&Config{Driver: "memory"})
+}
@@ -0,0 +1,24 @@
+-- Migration: Add page analytics
+-- Run this script to store daily view counts of published pages rolled up from Redis
+
+CREATE TABLE IF NOT EXISTS `page_view_daily` (
+    `project_id` BIGINT UNSIGNED NOT NULL,
+    `date` DATE NOT NULL COMMENT 'UTC day',
+    `views` BIGINT NOT NULL DEFAULT 0,
+    `visitors` BIGINT NOT NULL DEFAULT 0 COMMENT 'Unique visitors of the day (HyperLogLog estimate)',
+    `desktop` BIGINT NOT NULL DEFAULT 0,
+    `mobile` BIGINT NOT NULL DEFAULT 0,
+    `tablet` BIGINT NOT NULL DEFAULT 0,
+    `updated_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (`project_id`, `date`),
+    CONSTRAINT `fk_view_daily_project` FOREIGN KEY (`project_id`) REFERENCES `projects` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Daily views of published pages';
+
+CREATE TABLE IF NOT EXISTS `page_view_referrers` (
+    `project_id` BIGINT UNSIGNED NOT NULL,
+    `date` DATE NOT NULL COMMENT 'UTC day',
+    `referrer` VARCHAR(255) NOT NULL COMMENT 'Referring host, (direct) for none',
+    `views` BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (`project_id`, `date`, `referrer`),
+    CONSTRAINT `fk_view_referrer_project` FOREIGN KEY (`project_id`) REFERENCES `projects` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Daily top referrers of published pages';
@@ -0,0 +1,18 @@
+-- Migration: Add project assets
+-- Run this script to support image and font uploads (files live in the configured storage backend)
+
+CREATE TABLE IF NOT EXISTS `project_assets` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `project_id` BIGINT UNSIGNED NOT NULL,
+    `user_id` BIGINT UNSIGNED NOT NULL,
+    `filename` VARCHAR(255) NOT NULL COMMENT 'Original upload name',
+    `storage_key` VARCHAR(255) NOT NULL COMMENT 'Content-addressed storage key, also the public URL path',
+    `content_type` VARCHAR(100) NOT NULL,
+    `size` BIGINT NOT NULL,
+    `sha256` CHAR(64) NOT NULL,
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_asset_project_key` (`project_id`, `storage_key`),
+    INDEX `idx_asset_user_id` (`user_id`),
+    CONSTRAINT `fk_asset_project` FOREIGN KEY (`project_id`) REFERENCES `projects` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Uploaded project assets';
@@ -0,0 +1,29 @@
+-- Migration: Add project comments
+-- Run this script to let owners and members leave threaded review comments anchored to page elements
+
+CREATE TABLE IF NOT EXISTS `project_comments` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `project_id` BIGINT UNSIGNED NOT NULL,
+    `parent_id` BIGINT UNSIGNED NULL DEFAULT NULL COMMENT 'Thread root, NULL for threads',
+    `user_id` BIGINT UNSIGNED NOT NULL,
+    `snapshot_id` BIGINT UNSIGNED NOT NULL COMMENT 'Revision the anchor refers to',
+    `anchor` VARCHAR(512) NOT NULL DEFAULT '' COMMENT 'CSS selector or element path',
+    `body` TEXT NOT NULL,
+    `resolved` TINYINT(1) NOT NULL DEFAULT 0,
+    `resolved_by` BIGINT UNSIGNED NULL DEFAULT NULL,
+    `resolved_at` DATETIME(3) NULL DEFAULT NULL,
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (`id`),
+    INDEX `idx_comment_project_id` (`project_id`),
+    INDEX `idx_comment_parent_id` (`parent_id`),
+    CONSTRAINT `fk_comment_project` FOREIGN KEY (`project_id`) REFERENCES `projects` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Project review comments';
+
+CREATE TABLE IF NOT EXISTS `comment_mentions` (
+    `comment_id` BIGINT UNSIGNED NOT NULL,
+    `user_id` BIGINT UNSIGNED NOT NULL,
+    PRIMARY KEY (`comment_id`, `user_id`),
+    INDEX `idx_mention_user_id` (`user_id`),
+    CONSTRAINT `fk_mention_comment` FOREIGN KEY (`comment_id`) REFERENCES `project_comments` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Users mentioned in comments';
@@ -0,0 +1,29 @@
+-- Migration: Add content-addressed storage for project html and css
+-- Run this script to store project and snapshot content as shared, reference-counted blobs
+-- Existing rows keep their inline html/css until the API server moves them into content_blobs in the background
+
+-- Create content_blobs table
+CREATE TABLE IF NOT EXISTS `content_blobs` (
+    `hash` CHAR(64) NOT NULL COMMENT 'Hex SHA-256 of the content',
+    `size` BIGINT NOT NULL COMMENT 'Bytes before encoding',
+    `encoding` VARCHAR(16) NOT NULL DEFAULT 'raw' COMMENT 'raw or gzip',
+    `data` LONGBLOB,
+    `ref_count` BIGINT NOT NULL DEFAULT 0 COMMENT 'Project and snapshot columns pointing at the blob',
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (`hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Deduplicated project html and css';
+
+ALTER TABLE `projects`
+    ADD COLUMN `html_hash` CHAR(64) NOT NULL DEFAULT '' COMMENT 'Blob holding html, empty while stored inline' AFTER `css`,
+    ADD COLUMN `css_hash` CHAR(64) NOT NULL DEFAULT '' COMMENT 'Blob holding css, empty while stored inline' AFTER `html_hash`,
+    ADD COLUMN `html_head` TEXT COMMENT 'Leading part of html for list thumbnails' AFTER `css_hash`,
+    ADD COLUMN `content_size` BIGINT NOT NULL DEFAULT 0 COMMENT 'Bytes of html + css' AFTER `html_head`;
+
+ALTER TABLE `project_snapshots`
+    ADD COLUMN `html_hash` CHAR(64) NOT NULL DEFAULT '' COMMENT 'Blob holding html, empty while stored inline' AFTER `css`,
+    ADD COLUMN `css_hash` CHAR(64) NOT NULL DEFAULT '' COMMENT 'Blob holding css, empty while stored inline' AFTER `html_hash`;
+
+-- Backfill list columns so project lists and quotas are right before content is moved
+UPDATE `projects`
+SET `html_head` = SUBSTRING(`html`, 1, 2048),
+    `content_size` = COALESCE(LENGTH(`html`), 0) + COALESCE(LENGTH(`css`), 0);
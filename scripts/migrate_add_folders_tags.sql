@@ -0,0 +1,26 @@
+-- Migration: Add project folders and tags
+-- Run this script to let users organize projects into folders (one level of nesting) and tag them
+
+CREATE TABLE IF NOT EXISTS `project_folders` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `user_id` BIGINT UNSIGNED NOT NULL,
+    `parent_id` BIGINT UNSIGNED NULL DEFAULT NULL COMMENT 'Root folder containing this folder',
+    `name` VARCHAR(100) NOT NULL,
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (`id`),
+    INDEX `idx_folder_user_id` (`user_id`),
+    INDEX `idx_folder_parent_id` (`parent_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Project folders';
+
+ALTER TABLE `projects`
+    ADD COLUMN `folder_id` BIGINT UNSIGNED NULL DEFAULT NULL COMMENT 'Folder containing the project',
+    ADD INDEX `idx_project_folder_id` (`folder_id`);
+
+CREATE TABLE IF NOT EXISTS `project_tags` (
+    `project_id` BIGINT UNSIGNED NOT NULL,
+    `tag` VARCHAR(32) NOT NULL,
+    PRIMARY KEY (`project_id`, `tag`),
+    INDEX `idx_tag_tag` (`tag`),
+    CONSTRAINT `fk_tag_project` FOREIGN KEY (`project_id`) REFERENCES `projects` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Project tags';
@@ -0,0 +1,35 @@
+-- Migration: Add public gallery
+-- Run this script to let owners list published projects in the gallery and visitors star them
+-- Trending rankings live in Redis and are rebuilt from these tables by the reconciler
+
+-- Create gallery_entries table
+CREATE TABLE IF NOT EXISTS `gallery_entries` (
+    `project_id` BIGINT UNSIGNED NOT NULL,
+    `user_id` BIGINT UNSIGNED NOT NULL,
+    `category` VARCHAR(32) NOT NULL,
+    `description` VARCHAR(500) NOT NULL DEFAULT '',
+    `stars` BIGINT NOT NULL DEFAULT 0 COMMENT 'Denormalized count of project_stars',
+    `trending_score` DOUBLE NOT NULL DEFAULT 0 COMMENT 'Trending score as of the last reconcile',
+    `listed_at` DATETIME(3) NOT NULL,
+    `updated_at` DATETIME(3) NULL DEFAULT NULL,
+    PRIMARY KEY (`project_id`),
+    INDEX `idx_gallery_user_id` (`user_id`),
+    INDEX `idx_gallery_category` (`category`),
+    INDEX `idx_gallery_stars` (`stars`),
+    INDEX `idx_gallery_trending_score` (`trending_score`),
+    INDEX `idx_gallery_listed_at` (`listed_at`),
+    CONSTRAINT `fk_gallery_project` FOREIGN KEY (`project_id`) REFERENCES `projects` (`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_gallery_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Projects listed in the public gallery';
+
+-- Create project_stars table, stars are kept when a project leaves the gallery
+CREATE TABLE IF NOT EXISTS `project_stars` (
+    `project_id` BIGINT UNSIGNED NOT NULL,
+    `user_id` BIGINT UNSIGNED NOT NULL,
+    `created_at` DATETIME(3) NULL DEFAULT NULL,
+    PRIMARY KEY (`project_id`, `user_id`),
+    INDEX `idx_star_user_id` (`user_id`),
+    INDEX `idx_star_created_at` (`created_at`),
+    CONSTRAINT `fk_star_project` FOREIGN KEY (`project_id`) REFERENCES `projects` (`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_star_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Stars on gallery projects';
@@ -0,0 +1,6 @@
+-- Migration: Add context reports of generation jobs
+-- Run this script to keep how each job's conversation was fitted into the context budget
+-- Summaries of earlier turns are cached in Redis (generation:summary:<hash>) and expire after generation.summary_ttl
+
+ALTER TABLE `generation_jobs`
+    ADD COLUMN `context` TEXT NULL COMMENT 'JSON report of the history included, truncated, summarized or dropped' AFTER `result`;
@@ -0,0 +1,37 @@
+-- Migration: Add generation metering and credits
+-- Run this script to record the tokens, time and cost of each generation and let admins grant credits
+-- Daily and monthly credit budgets are configured per plan (plans.limits.*.daily_credits / monthly_credits)
+
+-- Create generation_usage table
+CREATE TABLE IF NOT EXISTS `generation_usage` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `job_id` CHAR(36) NOT NULL COMMENT 'Generation job, kept after the job is purged',
+    `user_id` BIGINT UNSIGNED NOT NULL,
+    `project_id` BIGINT UNSIGNED NULL DEFAULT NULL,
+    `provider` VARCHAR(32) NOT NULL,
+    `status` VARCHAR(16) NOT NULL COMMENT 'Final status of the job',
+    `input_tokens` BIGINT NOT NULL DEFAULT 0,
+    `output_tokens` BIGINT NOT NULL DEFAULT 0,
+    `wall_time_ms` BIGINT NOT NULL DEFAULT 0,
+    `cost_micros` BIGINT NOT NULL DEFAULT 0 COMMENT 'Cost in millionths of a dollar',
+    `credits` BIGINT NOT NULL DEFAULT 0,
+    `created_at` DATETIME(3) NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `uk_generation_usage_job` (`job_id`),
+    INDEX `idx_generation_usage_user_created` (`user_id`, `created_at`),
+    CONSTRAINT `fk_generation_usage_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Metered usage of generations';
+
+-- Create credit_grants table
+CREATE TABLE IF NOT EXISTS `credit_grants` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `user_id` BIGINT UNSIGNED NOT NULL,
+    `period` VARCHAR(8) NOT NULL COMMENT 'day or month the grant applies to',
+    `credits` BIGINT NOT NULL COMMENT 'Negative to take credits away',
+    `reason` VARCHAR(255) NOT NULL DEFAULT '',
+    `granted_by` BIGINT UNSIGNED NOT NULL COMMENT 'Admin user',
+    `created_at` DATETIME(3) NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_credit_grant_user_period` (`user_id`, `period`, `created_at`),
+    CONSTRAINT `fk_credit_grant_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Extra generation credits granted by admins';
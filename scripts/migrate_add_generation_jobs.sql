@@ -0,0 +1,30 @@
+-- Migration: Add durable generation jobs
+-- Run this script to keep generation jobs across restarts and share them between API instances
+-- Progress events are kept in Redis streams (generation:events:<id>) and expire after the job ends
+
+-- Create generation_jobs table
+CREATE TABLE IF NOT EXISTS `generation_jobs` (
+    `id` CHAR(36) NOT NULL,
+    `user_id` BIGINT UNSIGNED NOT NULL,
+    `project_id` BIGINT UNSIGNED NULL DEFAULT NULL COMMENT 'Project the page is generated for, if any',
+    `provider` VARCHAR(32) NOT NULL,
+    `status` VARCHAR(16) NOT NULL COMMENT 'pending, generating, completed or error',
+    `prompt` TEXT NOT NULL,
+    `input` MEDIUMTEXT NOT NULL COMMENT 'JSON of the full input, including history and current html',
+    `content` MEDIUMTEXT NOT NULL COMMENT 'Model output',
+    `result` MEDIUMTEXT NULL COMMENT 'JSON of the extracted html, css and message',
+    `error` VARCHAR(255) NOT NULL DEFAULT '',
+    `last_seq` BIGINT NOT NULL DEFAULT 0 COMMENT 'Seq of the latest progress event',
+    `instance` VARCHAR(128) NOT NULL DEFAULT '' COMMENT 'API instance running the job',
+    `heartbeat_at` DATETIME(3) NULL DEFAULT NULL,
+    `created_at` DATETIME(3) NULL DEFAULT NULL,
+    `updated_at` DATETIME(3) NULL DEFAULT NULL,
+    `finished_at` DATETIME(3) NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_generation_user_status` (`user_id`, `status`),
+    INDEX `idx_generation_project_id` (`project_id`),
+    INDEX `idx_generation_status_heartbeat` (`status`, `heartbeat_at`),
+    INDEX `idx_generation_finished_at` (`finished_at`),
+    CONSTRAINT `fk_generation_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_generation_project` FOREIGN KEY (`project_id`) REFERENCES `projects` (`id`) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Page generation jobs';
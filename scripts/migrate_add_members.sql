@@ -0,0 +1,13 @@
+-- Migration: Add project members
+-- Run this script to let project owners invite collaborators as editors or viewers
+
+CREATE TABLE IF NOT EXISTS `project_members` (
+    `project_id` BIGINT UNSIGNED NOT NULL,
+    `user_id` BIGINT UNSIGNED NOT NULL,
+    `role` VARCHAR(16) NOT NULL COMMENT 'editor or viewer; the owner is not listed',
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (`project_id`, `user_id`),
+    INDEX `idx_member_user_id` (`user_id`),
+    CONSTRAINT `fk_member_project` FOREIGN KEY (`project_id`) REFERENCES `projects` (`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_member_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Project collaborators';
@@ -0,0 +1,5 @@
+-- Migration: Add user plans
+-- Run this script to assign plans to users; an empty plan uses plans.default from the config
+
+ALTER TABLE `users`
+    ADD COLUMN `plan` VARCHAR(32) NOT NULL DEFAULT '' COMMENT 'Plan name from plans.limits, empty for the default plan';
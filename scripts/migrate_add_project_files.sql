@@ -0,0 +1,17 @@
+-- Migration: Add multi-file projects
+-- Run this script to let projects hold pages, stylesheets and scripts besides index.html and style.css
+-- Existing projects need no data changes: their html and css are served as index.html and style.css
+
+-- Create project_files table
+CREATE TABLE IF NOT EXISTS `project_files` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `project_id` BIGINT UNSIGNED NOT NULL,
+    `path` VARCHAR(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL COMMENT 'Relative path such as about/index.html',
+    `hash` CHAR(64) NOT NULL DEFAULT '' COMMENT 'Blob holding the content, empty for an empty file',
+    `size` BIGINT NOT NULL DEFAULT 0,
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_file_project_path` (`project_id`, `path`),
+    CONSTRAINT `fk_file_project` FOREIGN KEY (`project_id`) REFERENCES `projects` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Project files other than index.html and style.css';
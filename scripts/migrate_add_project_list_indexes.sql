@@ -0,0 +1,7 @@
+-- Migration: Add project list indexes
+-- Run this script to support keyset pagination of GET /projects by updated_at, created_at and name
+
+ALTER TABLE `projects`
+    ADD INDEX `idx_project_user_updated_at` (`user_id`, `updated_at`, `id`),
+    ADD INDEX `idx_project_user_created_at` (`user_id`, `created_at`, `id`),
+    ADD INDEX `idx_project_user_name` (`user_id`, `name`, `id`);
@@ -0,0 +1,29 @@
+-- Migration: Add versioned prompt templates
+-- Run this script to manage the generation system prompts without a redeploy and compare versions with A/B tests
+-- Without an enabled version the built-in prompts are used and recorded as version 0
+
+-- Create prompt_templates table
+CREATE TABLE IF NOT EXISTS `prompt_templates` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `name` VARCHAR(32) NOT NULL COMMENT 'create or modify',
+    `version` INT NOT NULL,
+    `body` MEDIUMTEXT NOT NULL COMMENT 'text/template source, immutable once created',
+    `variables` TEXT NULL COMMENT 'JSON of the default values of the template variables',
+    `weight` INT NOT NULL DEFAULT 0 COMMENT 'Share of traffic among enabled versions, 0 disables the version',
+    `description` VARCHAR(255) NOT NULL DEFAULT '',
+    `created_by` BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    `created_at` DATETIME(3) NULL DEFAULT NULL,
+    `updated_at` DATETIME(3) NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `uk_prompt_name_version` (`name`, `version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Versioned generation system prompts';
+
+-- Record the prompt version used by each generation
+ALTER TABLE `generation_jobs`
+    ADD COLUMN `prompt_name` VARCHAR(32) NOT NULL DEFAULT '' COMMENT 'System prompt template used' AFTER `provider`,
+    ADD COLUMN `prompt_version` INT NOT NULL DEFAULT 0 COMMENT '0 is the built-in prompt' AFTER `prompt_name`;
+
+ALTER TABLE `generation_usage`
+    ADD COLUMN `prompt_name` VARCHAR(32) NOT NULL DEFAULT '' AFTER `status`,
+    ADD COLUMN `prompt_version` INT NOT NULL DEFAULT 0 COMMENT '0 is the built-in prompt' AFTER `prompt_name`,
+    ADD INDEX `idx_generation_usage_prompt` (`prompt_name`, `created_at`);
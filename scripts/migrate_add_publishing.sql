@@ -0,0 +1,34 @@
+-- Migration: Add project snapshots and publishing
+-- Run this script to support publishing projects at /p/:slug
+
+-- Create project_snapshots table
+CREATE TABLE IF NOT EXISTS `project_snapshots` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `project_id` BIGINT UNSIGNED NOT NULL,
+    `user_id` BIGINT UNSIGNED NOT NULL,
+    `label` VARCHAR(255) NOT NULL DEFAULT '',
+    `html` LONGTEXT,
+    `css` LONGTEXT,
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (`id`),
+    INDEX `idx_snapshot_project_id` (`project_id`),
+    CONSTRAINT `fk_snapshot_project` FOREIGN KEY (`project_id`) REFERENCES `projects` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Immutable copies of project content';
+
+-- Create project_publications table
+CREATE TABLE IF NOT EXISTS `project_publications` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `project_id` BIGINT UNSIGNED NOT NULL,
+    `user_id` BIGINT UNSIGNED NOT NULL,
+    `slug` VARCHAR(64) NOT NULL,
+    `snapshot_id` BIGINT UNSIGNED NOT NULL COMMENT 'Snapshot served at /p/:slug',
+    `published_at` DATETIME(3) NOT NULL,
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_publication_project_id` (`project_id`),
+    UNIQUE INDEX `idx_publication_slug` (`slug`),
+    INDEX `idx_publication_user_id` (`user_id`),
+    CONSTRAINT `fk_publication_project` FOREIGN KEY (`project_id`) REFERENCES `projects` (`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_publication_snapshot` FOREIGN KEY (`snapshot_id`) REFERENCES `project_snapshots` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Public URLs of published projects';
@@ -0,0 +1,6 @@
+-- Migration: Add quality reports of generated pages
+-- Run this script to keep the quality report of the last generation saved into each project
+-- Reports of generation jobs are stored inside their JSON result and need no change
+
+ALTER TABLE `projects`
+    ADD COLUMN `quality` TEXT NULL COMMENT 'JSON quality report of the last generation saved into the project';
@@ -0,0 +1,15 @@
+-- Migration: Add files to project snapshots
+-- Run this script so snapshots and published pages keep a multi-file project's pages, stylesheets and scripts
+-- Existing snapshots have no extra files: republish a multi-file project to serve its other pages
+
+-- Create project_snapshot_files table
+CREATE TABLE IF NOT EXISTS `project_snapshot_files` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `snapshot_id` BIGINT UNSIGNED NOT NULL,
+    `path` VARCHAR(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL COMMENT 'Relative path such as about/index.html',
+    `hash` CHAR(64) NOT NULL DEFAULT '' COMMENT 'Blob holding the content, empty for an empty file',
+    `size` BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_snapshot_file_path` (`snapshot_id`, `path`),
+    CONSTRAINT `fk_snapshot_file_snapshot` FOREIGN KEY (`snapshot_id`) REFERENCES `project_snapshots` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Snapshot copies of project files other than index.html and style.css';
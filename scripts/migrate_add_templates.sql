@@ -0,0 +1,26 @@
+-- Migration: Add template gallery and project forking
+-- Run this script to support curated templates and fork attribution
+
+-- Create project_templates table (builtin templates are embedded in the binary)
+CREATE TABLE IF NOT EXISTS `project_templates` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `key` VARCHAR(64) NOT NULL COMMENT 'Public template ID',
+    `name` VARCHAR(255) NOT NULL,
+    `description` VARCHAR(1024) NOT NULL DEFAULT '',
+    `category` VARCHAR(64) NOT NULL DEFAULT '',
+    `html` LONGTEXT,
+    `css` LONGTEXT,
+    `sort` INT NOT NULL DEFAULT 0,
+    `enabled` TINYINT(1) NOT NULL DEFAULT 1,
+    `created_by` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT 'Admin user ID',
+    `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_template_key` (`key`),
+    INDEX `idx_template_category` (`category`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Admin-curated project templates';
+
+-- Add fork attribution to projects
+ALTER TABLE `projects`
+    ADD COLUMN `forked_from_id` BIGINT UNSIGNED NULL DEFAULT NULL COMMENT 'Source project when forked' AFTER `messages`,
+    ADD INDEX `idx_project_forked_from_id` (`forked_from_id`);
@@ -0,0 +1,6 @@
+-- Migration: Add project trash (soft delete)
+-- Run this script to keep deleted projects recoverable until the trash purger removes them
+
+ALTER TABLE `projects`
+    ADD COLUMN `deleted_at` DATETIME(3) NULL DEFAULT NULL COMMENT 'Set when moved to trash',
+    ADD INDEX `idx_project_deleted_at` (`deleted_at`);